@@ -0,0 +1,41 @@
+package ai
+
+import (
+	"context"
+	"strings"
+)
+
+// WithSystemAddendum attaches instructions for requests made with ctx only, e.g. "the
+// student asked for a shorter answer", without changing the session's rules or the
+// user's prompt. Addenda accumulate: each call adds one after those already attached.
+// Providers layer them with LayerSystemPrompt and stores record them on the request
+// log, so the instructions a request was sent with can be seen and replayed.
+func WithSystemAddendum(ctx context.Context, addendum string) context.Context {
+	if strings.TrimSpace(addendum) == "" {
+		return ctx
+	}
+	prev, _ := ctx.Value(systemAddendumKey).([]string)
+	addenda := append(prev[:len(prev):len(prev)], addendum)
+	return context.WithValue(ctx, systemAddendumKey, addenda)
+}
+
+// SystemAddendumFromContext returns the addenda attached with WithSystemAddendum, in
+// the order they were attached and separated by blank lines, or "" if there are none.
+func SystemAddendumFromContext(ctx context.Context) string {
+	addenda, _ := ctx.Value(systemAddendumKey).([]string)
+	return strings.Join(addenda, "\n\n")
+}
+
+// LayerSystemPrompt returns base, the session-level system prompt, followed by the
+// addenda attached to ctx. The result depends only on its inputs, so equal requests
+// produce equal system prompts.
+func LayerSystemPrompt(ctx context.Context, base string) string {
+	addendum := SystemAddendumFromContext(ctx)
+	switch {
+	case addendum == "":
+		return base
+	case base == "":
+		return addendum
+	}
+	return base + "\n\n" + addendum
+}
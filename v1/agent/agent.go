@@ -0,0 +1,233 @@
+// Package agent runs multi-step model workflows (generate → validate → fix) on top of
+// tool calling, with step limits, a token budget and persisted intermediate steps.
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+	"github.com/meikuraledutech/ai/v1/tools"
+)
+
+var (
+	ErrMaxSteps       = errors.New("ai: agent step limit reached")
+	ErrBudgetExceeded = errors.New("ai: agent token budget exceeded")
+	ErrNoSession      = errors.New("ai: agent has no session to persist turns to")
+)
+
+const defaultMaxSteps = 10
+
+// Step is one provider round trip of an agent run.
+type Step struct {
+	Number      int
+	Result      *ai.Result
+	ToolResults []ai.ToolResult
+	Err         error // validation error for a final answer, if any
+	Usage       ai.Usage
+}
+
+// Validator checks a final answer. A non-nil error is sent back to the model as a
+// correction and the loop continues.
+type Validator func(ctx context.Context, result *ai.Result) error
+
+// Agent loops provider calls, executes tool calls and validates answers until the
+// model produces an answer that passes validation.
+type Agent struct {
+	provider ai.Provider
+	store    ai.Store
+	exec     *tools.Executor
+	validate Validator
+	onStep   func(ctx context.Context, step Step)
+	maxSteps int
+	budget   int
+}
+
+// New creates an Agent that calls provider.
+func New(provider ai.Provider) *Agent {
+	return &Agent{
+		provider: provider,
+		maxSteps: defaultMaxSteps,
+	}
+}
+
+// WithStore persists every intermediate step as session messages.
+func (a *Agent) WithStore(store ai.Store) *Agent {
+	a.store = store
+	return a
+}
+
+// WithExecutor makes the executor's tools available to the model.
+func (a *Agent) WithExecutor(exec *tools.Executor) *Agent {
+	a.exec = exec
+	return a
+}
+
+// Register exposes a typed Go function as a tool, deriving its parameter schema from
+// the function's argument struct. See tools.Typed for the accepted signature.
+func (a *Agent) Register(name string, fn any) error {
+	return a.RegisterWithDescription(name, "", fn)
+}
+
+// RegisterWithDescription is like Register with a tool description for the model.
+func (a *Agent) RegisterWithDescription(name, description string, fn any) error {
+	if a.exec == nil {
+		a.exec = tools.NewExecutor(a.provider)
+	}
+	return a.exec.RegisterFunc(name, description, fn)
+}
+
+// WithValidator sets the check applied to every final answer.
+func (a *Agent) WithValidator(v Validator) *Agent {
+	a.validate = v
+	return a
+}
+
+// WithMaxSteps limits the number of provider round trips per run.
+func (a *Agent) WithMaxSteps(n int) *Agent {
+	a.maxSteps = n
+	return a
+}
+
+// WithBudget limits the total tokens a run may consume. Zero means unlimited.
+func (a *Agent) WithBudget(totalTokens int) *Agent {
+	a.budget = totalTokens
+	return a
+}
+
+// OnStep registers a callback invoked after every step, e.g. for progress reporting.
+func (a *Agent) OnStep(fn func(ctx context.Context, step Step)) *Agent {
+	a.onStep = fn
+	return a
+}
+
+// Run executes the agent loop for prompt. It stops when an answer passes validation,
+// the step limit or budget is reached, or ctx is cancelled. The returned Result carries
+// usage summed over all steps. Every step shares one request ID, so the run's request
+// logs and messages can be correlated. A run with tools fails with ai.ErrUnsupported if
+// the provider reports it can't call them.
+func (a *Agent) Run(ctx context.Context, sessionID string, rules ai.Rules, history []ai.Message, prompt string) (*ai.Result, error) {
+	ctx = ai.WithSessionID(ctx, sessionID)
+	ctx, _ = ai.EnsureRequestID(ctx)
+	if a.exec != nil {
+		if caps, known := ai.CapabilitiesOf(a.provider); known && !caps.Tools {
+			return nil, fmt.Errorf("%w: tool calling", ai.ErrUnsupported)
+		}
+		ctx = ai.WithTools(ctx, a.exec.Tools()...)
+	}
+
+	var total ai.Usage
+	for n := 1; n <= a.maxSteps; n++ {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		if prompt != "" {
+			if err := a.persist(ctx, sessionID, ai.RoleUser, prompt, nil); err != nil {
+				return nil, err
+			}
+		}
+
+		result, err := a.provider.Send(ctx, rules, history, prompt)
+		if err != nil {
+			return nil, err
+		}
+		total.Add(result.Usage)
+
+		if prompt != "" {
+			history = append(history, ai.Message{SessionID: sessionID, Role: ai.RoleUser, Content: prompt})
+			prompt = ""
+		}
+
+		step := Step{Number: n, Result: result, Usage: total}
+
+		if len(result.ToolCalls) > 0 {
+			msgs, results, err := a.runTools(ctx, sessionID, result)
+			if err != nil {
+				return nil, err
+			}
+			history = append(history, msgs...)
+			step.ToolResults = results
+		} else {
+			if err := a.persist(ctx, sessionID, ai.RoleAssistant, result.Content, &result.Usage); err != nil {
+				return nil, err
+			}
+
+			if a.validate != nil {
+				step.Err = a.validate(ctx, result)
+			}
+			if step.Err == nil {
+				a.report(ctx, step)
+				result.Usage = total
+				return result, nil
+			}
+
+			history = append(history, ai.Message{SessionID: sessionID, Role: ai.RoleAssistant, Content: result.Content})
+			prompt = fmt.Sprintf("Your previous answer failed validation: %v. Please fix it and return the complete corrected answer.", step.Err)
+		}
+
+		a.report(ctx, step)
+
+		if a.budget > 0 && total.TotalTokens >= a.budget {
+			return nil, fmt.Errorf("%w: used %d of %d tokens", ErrBudgetExceeded, total.TotalTokens, a.budget)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %d steps", ErrMaxSteps, a.maxSteps)
+}
+
+// runTools persists and executes the tool calls of result, returning the history
+// messages for the call and its results.
+func (a *Agent) runTools(ctx context.Context, sessionID string, result *ai.Result) ([]ai.Message, []ai.ToolResult, error) {
+	if a.exec == nil {
+		return nil, nil, fmt.Errorf("%w: model requested tools but no executor is configured", tools.ErrUnknownTool)
+	}
+
+	callMsg, err := ai.ToolCallMessage(result.ToolCalls)
+	if err != nil {
+		return nil, nil, err
+	}
+	callMsg.SessionID = sessionID
+	if err := a.persist(ctx, sessionID, callMsg.Role, callMsg.Content, &result.Usage); err != nil {
+		return nil, nil, err
+	}
+	msgs := []ai.Message{callMsg}
+
+	results := a.exec.Execute(ctx, result.ToolCalls)
+	for _, r := range results {
+		msg, err := ai.ToolResultMessage(r)
+		if err != nil {
+			return nil, nil, err
+		}
+		msg.SessionID = sessionID
+		msgs = append(msgs, msg)
+	}
+
+	if a.store != nil && len(msgs) > 1 {
+		batch := make([]ai.NewMessage, 0, len(msgs)-1)
+		for _, msg := range msgs[1:] {
+			batch = append(batch, ai.NewMessage{Role: msg.Role, Content: msg.Content})
+		}
+		if _, err := ai.AddMessages(ctx, a.store, sessionID, batch); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	return msgs, results, nil
+}
+
+// persist stores a message when a store is configured.
+func (a *Agent) persist(ctx context.Context, sessionID, role, content string, usage *ai.Usage) error {
+	if a.store == nil {
+		return nil
+	}
+	_, err := a.store.AddMessage(ctx, sessionID, role, content, usage)
+	return err
+}
+
+func (a *Agent) report(ctx context.Context, step Step) {
+	if a.onStep != nil {
+		a.onStep(ctx, step)
+	}
+}
@@ -0,0 +1,112 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// revisePrompt asks for a revision once the model has critiqued its answer.
+const revisePrompt = "Revise your previous answer to address every point of the critique. Return the complete revised answer only."
+
+// Reflection is the outcome of a critique-and-revise run.
+type Reflection struct {
+	Result    *ai.Result // final revision, with usage summed over every turn
+	Drafts    []string   // the first draft followed by each revision
+	Critiques []string   // the critique of each round
+}
+
+// Reflect runs a critique-and-revise loop with provider, persisting every turn to store
+// in the session attached to ctx with ai.WithSessionID; see Agent.Reflect. It fails
+// with ErrNoSession when store is nil or ctx carries no session.
+func Reflect(ctx context.Context, provider ai.Provider, store ai.Store, rules ai.Rules, draft, critiquePrompt string, rounds int) (*Reflection, error) {
+	if store == nil {
+		return nil, fmt.Errorf("%w: no store", ErrNoSession)
+	}
+	return New(provider).WithStore(store).Reflect(ctx, ai.SessionIDFromContext(ctx), rules, draft, critiquePrompt, rounds)
+}
+
+// Reflect asks the model for a draft with the prompt draft, then for rounds rounds has
+// it critique its latest answer with critiquePrompt and revise the answer to address
+// the critique. Critiques are written as free text, without rules' output schema,
+// format or policy; drafts and revisions follow rules. Every turn shares one request
+// ID and is persisted when the agent has a store, which then requires sessionID; it
+// fails with ErrNoSession without one. The step limit doesn't apply, but the token
+// budget does.
+func (a *Agent) Reflect(ctx context.Context, sessionID string, rules ai.Rules, draft, critiquePrompt string, rounds int) (*Reflection, error) {
+	if draft == "" || critiquePrompt == "" {
+		return nil, ai.ErrEmptyPrompt
+	}
+	if rounds < 0 {
+		return nil, errors.New("ai: reflect: rounds must not be negative")
+	}
+	if a.store != nil && sessionID == "" {
+		return nil, ErrNoSession
+	}
+	ctx = ai.WithSessionID(ctx, sessionID)
+	ctx, _ = ai.EnsureRequestID(ctx)
+
+	critiqueRules := rules
+	critiqueRules.OutputSchema = ""
+	critiqueRules.ResponseFormat = ai.FormatText
+	critiqueRules.Policy = nil
+
+	var (
+		history []ai.Message
+		total   ai.Usage
+		step    int
+		out     Reflection
+	)
+	turn := func(rules ai.Rules, prompt string) (*ai.Result, error) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		if err := a.persist(ctx, sessionID, ai.RoleUser, prompt, nil); err != nil {
+			return nil, err
+		}
+		result, err := a.provider.Send(ctx, rules, history, prompt)
+		if err != nil {
+			return nil, err
+		}
+		total.Add(result.Usage)
+		if err := a.persist(ctx, sessionID, ai.RoleAssistant, result.Content, &result.Usage); err != nil {
+			return nil, err
+		}
+		history = append(history,
+			ai.Message{SessionID: sessionID, Role: ai.RoleUser, Content: prompt},
+			ai.Message{SessionID: sessionID, Role: ai.RoleAssistant, Content: result.Content},
+		)
+
+		step++
+		a.report(ctx, Step{Number: step, Result: result, Usage: total})
+		if a.budget > 0 && total.TotalTokens >= a.budget {
+			return nil, fmt.Errorf("%w: used %d of %d tokens", ErrBudgetExceeded, total.TotalTokens, a.budget)
+		}
+		return result, nil
+	}
+
+	result, err := turn(rules, draft)
+	if err != nil {
+		return nil, err
+	}
+	out.Drafts = append(out.Drafts, result.Content)
+
+	for range rounds {
+		critique, err := turn(critiqueRules, critiquePrompt)
+		if err != nil {
+			return nil, err
+		}
+		out.Critiques = append(out.Critiques, critique.Content)
+
+		if result, err = turn(rules, revisePrompt); err != nil {
+			return nil, err
+		}
+		out.Drafts = append(out.Drafts, result.Content)
+	}
+
+	result.Usage = total
+	out.Result = result
+	return &out, nil
+}
@@ -7,6 +7,17 @@ type Rules struct {
 	SystemPrompt string `json:"system_prompt"`
 	OutputSchema string `json:"output_schema"`
 	MaxTokens    int    `json:"max_tokens"`
+
+	Policy   *OutputPolicy `json:"policy,omitempty"`
+	Language string        `json:"language,omitempty"` // BCP 47 tag responses must be written in, e.g. "id"
+	Prompt   *PromptRef    `json:"prompt,omitempty"`   // library prompt rendered ahead of SystemPrompt
+	Schema   *SchemaRef    `json:"schema,omitempty"`   // registry schema used as OutputSchema
+
+	// Labels tag the session for reporting, e.g. "feature": "onboarding". They are
+	// copied onto every request log of the session.
+	Labels map[string]string `json:"labels,omitempty"`
+
+	ResponseFormat string `json:"response_format,omitempty"` // Format constant; empty means JSON
 }
 
 // Usage holds token counts from the AI provider response.
@@ -15,8 +26,44 @@ type Usage struct {
 	ResponseTokens int `json:"response_tokens"`
 	TotalTokens    int `json:"total_tokens"`
 	ThoughtTokens  int `json:"thought_tokens"`
+
+	// Breakdowns normalized across providers. CachedTokens and AudioTokens are
+	// included in PromptTokens/ResponseTokens; ToolTokens are extra input spent on
+	// tool use (e.g. fetched URL content) and included only in TotalTokens.
+	CachedTokens int `json:"cached_tokens,omitempty"`
+	ToolTokens   int `json:"tool_tokens,omitempty"`
+	AudioTokens  int `json:"audio_tokens,omitempty"`
+
+	// Model is the model that produced the usage, used to look up its price.
+	Model string `json:"model,omitempty"`
+}
+
+// Add accumulates the token counts of o into u.
+func (u *Usage) Add(o Usage) {
+	u.PromptTokens += o.PromptTokens
+	u.ResponseTokens += o.ResponseTokens
+	u.TotalTokens += o.TotalTokens
+	u.ThoughtTokens += o.ThoughtTokens
+	u.CachedTokens += o.CachedTokens
+	u.ToolTokens += o.ToolTokens
+	u.AudioTokens += o.AudioTokens
+	if u.Model == "" {
+		u.Model = o.Model
+	}
 }
 
+// Message roles.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+	RoleEvent     = "event" // non-LLM system event, excluded from provider history by default
+
+	// Instruction roles of conversations imported from other APIs. Providers map them
+	// with their RoleMap.
+	RoleSystem    = "system"
+	RoleDeveloper = "developer"
+)
+
 // Message is a single turn in a conversation.
 type Message struct {
 	ID        string    `json:"id"`
@@ -24,8 +71,28 @@ type Message struct {
 	Seq       int       `json:"seq"`
 	Role      string    `json:"role"`
 	Content   string    `json:"content"`
+	EventType string    `json:"event_type,omitempty"`
+	RequestID string    `json:"request_id,omitempty"`
 	Usage     *Usage    `json:"usage,omitempty"`
+	Cost      float64   `json:"cost,omitempty"` // USD, computed by the store when it was added
 	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"` // CreatedAt until the content is edited
+}
+
+// IsEvent reports whether the message is a system event rather than a conversation turn.
+func (m Message) IsEvent() bool {
+	return m.Role == RoleEvent
+}
+
+// ConversationHistory returns msgs without system events, i.e. the turns a provider should see.
+func ConversationHistory(msgs []Message) []Message {
+	out := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		if !m.IsEvent() {
+			out = append(out, m)
+		}
+	}
+	return out
 }
 
 // Session groups messages into a conversation.
@@ -37,8 +104,21 @@ type Session struct {
 
 // Result is what the provider returns — content + token usage.
 type Result struct {
-	Content string `json:"content"`
-	Usage   Usage  `json:"usage"`
+	Content   string     `json:"content"`
+	ToolCalls []ToolCall `json:"tool_calls,omitempty"`
+	RequestID string     `json:"request_id,omitempty"`
+	Usage     Usage      `json:"usage"`
+	DryRun    *DryRun    `json:"dry_run,omitempty"`
+
+	// ApprovalID is the PendingResult holding this response, when sent through an
+	// ApprovalGate. The caller must not store the turn itself.
+	ApprovalID string `json:"approval_id,omitempty"`
+
+	// CacheHit is set when a ResponseCache answered from a cached response.
+	CacheHit *CacheHit `json:"cache_hit,omitempty"`
+
+	// Citations are the sources the provider reported grounding the response in.
+	Citations []Citation `json:"citations,omitempty"`
 }
 
 // MigrationRecord tracks a single applied migration.
@@ -51,18 +131,48 @@ type MigrationRecord struct {
 
 // RequestLog tracks every AI request attempt for cost and debugging.
 type RequestLog struct {
-	ID            string    `json:"id"`
-	SessionID     string    `json:"session_id"`
-	Prompt        string    `json:"prompt"`
-	Response      string    `json:"response"`
-	AttemptNumber int       `json:"attempt_number"`
-	RetryCount    int       `json:"retry_count"`
-	FinalStatus   string    `json:"final_status"`
-	FailReason    string    `json:"fail_reason"`
-	ErrorMessage  string    `json:"error_message"`
-	Usage         Usage     `json:"usage"`
-	CreatedAt     time.Time `json:"created_at"`
-	UpdatedAt     time.Time `json:"updated_at"`
+	ID            string            `json:"id"`
+	SessionID     string            `json:"session_id"`
+	RequestID     string            `json:"request_id"`
+	ReplayOf      string            `json:"replay_of,omitempty"`
+	TenantID      string            `json:"tenant_id,omitempty"`
+	UserID        string            `json:"user_id,omitempty"`
+	PromptName    string            `json:"prompt_name,omitempty"`
+	PromptVersion int               `json:"prompt_version,omitempty"`
+	SchemaName    string            `json:"schema_name,omitempty"` // registry schema the response was validated against
+	SchemaVersion int               `json:"schema_version,omitempty"`
+	Labels        map[string]string `json:"labels,omitempty"` // the session's Rules.Labels when the request was made
+	Prompt        string            `json:"prompt"`
+	Response      string            `json:"response"`
+	AttemptNumber int               `json:"attempt_number"`
+	RetryCount    int               `json:"retry_count"`
+	FinalStatus   string            `json:"final_status"`
+	FailReason    string            `json:"fail_reason"`
+	ErrorMessage  string            `json:"error_message"`
+	Usage         Usage             `json:"usage"`
+	Cost          float64           `json:"cost"`
+	CreatedAt     time.Time         `json:"created_at"`
+	UpdatedAt     time.Time         `json:"updated_at"`
+
+	// Attribution is the estimated split of Usage.PromptTokens, when the provider records it.
+	Attribution PromptAttribution `json:"attribution"`
+
+	// SystemAddendum is the per-request instructions attached with WithSystemAddendum,
+	// layered after the session's system prompt.
+	SystemAddendum string `json:"system_addendum,omitempty"`
+
+	// CacheHitOf is the request whose cached response answered this one, with the
+	// similarity of their prompts, when a ResponseCache logs a hit.
+	CacheHitOf      string  `json:"cache_hit_of,omitempty"`
+	CacheSimilarity float64 `json:"cache_similarity,omitempty"`
+
+	// CancelledAfterTokens is how many response tokens a stream had produced when its
+	// caller cancelled it, when the store records it (see CancelledStreamStore).
+	CancelledAfterTokens int `json:"cancelled_after_tokens,omitempty"`
+
+	// RetryTranscript is the correction exchanges sent on validation retries, when the
+	// store records them (see RetryTranscriptStore).
+	RetryTranscript []RetryTurn `json:"retry_transcript,omitempty"`
 }
 
 // Status constants
@@ -70,16 +180,27 @@ const (
 	StatusSuccess = "success"
 	StatusFailed  = "failed"
 	StatusPending = "pending"
+
+	// StatusCancelled marks a request stopped with Canceller.Cancel.
+	StatusCancelled = "cancelled"
 )
 
 // FailReason constants
 const (
-	FailReasonIncompleteJSON = "incomplete_json"
-	FailReasonInvalidJSON    = "invalid_json"
-	FailReasonNetworkError   = "network_error"
-	FailReasonTimeout        = "timeout"
-	FailReasonAPIError       = "api_error"
-	FailReasonMaxRetries     = "max_retries_exceeded"
-	FailReasonUnknownError   = "unknown_error"
+	FailReasonIncompleteJSON  = "incomplete_json"
+	FailReasonInvalidJSON     = "invalid_json"
+	FailReasonNetworkError    = "network_error"
+	FailReasonTimeout         = "timeout"
+	FailReasonAPIError        = "api_error"
+	FailReasonMaxRetries      = "max_retries_exceeded"
+	FailReasonPolicyViolation = "policy_violation"
+	FailReasonPromptInjection = "prompt_injection"
+	FailReasonWrongLanguage   = "wrong_language"
+	FailReasonInvalidFormat   = "invalid_format"
+	FailReasonContextExceeded = "context_exceeded"
+	FailReasonCancelled       = "cancelled"
+	FailReasonSafetyBlocked   = "safety_blocked"
+	FailReasonRateLimited     = "rate_limited"
+	FailReasonOrphaned        = "orphaned"
+	FailReasonUnknownError    = "unknown_error"
 )
-
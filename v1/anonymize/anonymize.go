@@ -0,0 +1,185 @@
+// Package anonymize sanitizes exported sessions for sharing, e.g. with prompt
+// engineers: every message runs through a chain of anonymizers and IDs are replaced
+// with keyed hashes. The output is session export JSONL, readable with
+// ai.NewSessionDecoder.
+package anonymize
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// Anonymizer rewrites text to remove what identifies people.
+type Anonymizer interface {
+	Anonymize(ctx context.Context, text string) (string, error)
+}
+
+// Func adapts a function to an Anonymizer.
+type Func func(ctx context.Context, text string) (string, error)
+
+// Anonymize calls f.
+func (f Func) Anonymize(ctx context.Context, text string) (string, error) {
+	return f(ctx, text)
+}
+
+// PII returns an Anonymizer applying ai.RedactPII, which replaces email addresses and
+// long digit runs such as phone and ID numbers.
+func PII() Anonymizer {
+	return Func(func(_ context.Context, text string) (string, error) {
+		return ai.RedactPII(text), nil
+	})
+}
+
+// Regex returns an Anonymizer replacing the matches of pattern with replacement, which
+// may refer to submatches as in regexp.Regexp.ReplaceAllString.
+func Regex(pattern *regexp.Regexp, replacement string) Anonymizer {
+	return Func(func(_ context.Context, text string) (string, error) {
+		return pattern.ReplaceAllString(text, replacement), nil
+	})
+}
+
+// Hash returns an Anonymizer replacing the matches of pattern with "[label:<hash>]",
+// where hash is a keyed hash of the match. The same value gets the same placeholder
+// throughout a dataset, so e.g. which student wrote which answers stays visible without
+// revealing who they are. Keep key secret: without it, short values such as student
+// numbers can't be guessed back from their hashes.
+func Hash(pattern *regexp.Regexp, label string, key []byte) Anonymizer {
+	return Func(func(_ context.Context, text string) (string, error) {
+		return pattern.ReplaceAllStringFunc(text, func(m string) string {
+			return "[" + label + ":" + keyedHash(key, m) + "]"
+		}), nil
+	})
+}
+
+// keyedHash returns the first 8 bytes of the HMAC-SHA256 of value under key, in hex.
+func keyedHash(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil)[:8])
+}
+
+// Report counts what a Run wrote.
+type Report struct {
+	Sessions      int `json:"sessions"`
+	Messages      int `json:"messages"`
+	DroppedEvents int `json:"dropped_events"`
+}
+
+// Pipeline anonymizes sessions: the system prompt, the label values and the content of
+// every message go through its anonymizers in order, and session, message and request IDs are replaced
+// with keyed hashes of them. Usage, costs, roles and timestamps are kept.
+type Pipeline struct {
+	steps  []Anonymizer
+	idKey  []byte
+	events bool
+}
+
+// New returns a pipeline running text through steps in order. IDs are hashed with a
+// random key, so they can't be matched with those of another run or of production.
+func New(steps ...Anonymizer) *Pipeline {
+	key := make([]byte, 32)
+	rand.Read(key)
+	return &Pipeline{steps: steps, idKey: key}
+}
+
+// WithIDKey hashes IDs with key instead of a random one, so repeated exports of the
+// same sessions get the same IDs, e.g. to share a dataset incrementally.
+func (p *Pipeline) WithIDKey(key []byte) *Pipeline {
+	p.idKey = key
+	return p
+}
+
+// WithEvents keeps events, anonymized like the other messages. By default they are
+// dropped, as they carry application state rather than conversation.
+func (p *Pipeline) WithEvents(include bool) *Pipeline {
+	p.events = include
+	return p
+}
+
+// Session returns an anonymized copy of e. If an anonymizer fails, it returns its error
+// and nothing of the session.
+func (p *Pipeline) Session(ctx context.Context, e *ai.SessionExport) (*ai.SessionExport, error) {
+	out := &ai.SessionExport{Session: e.Session, Messages: make([]ai.Message, 0, len(e.Messages))}
+	out.Session.ID = p.id(e.Session.ID)
+
+	var err error
+	if out.Session.Rules.SystemPrompt, err = p.text(ctx, e.Session.Rules.SystemPrompt); err != nil {
+		return nil, fmt.Errorf("ai: anonymize session %s: system prompt: %w", e.Session.ID, err)
+	}
+	if labels := e.Session.Rules.Labels; labels != nil {
+		out.Session.Rules.Labels = make(map[string]string, len(labels))
+		for k, v := range labels {
+			if out.Session.Rules.Labels[k], err = p.text(ctx, v); err != nil {
+				return nil, fmt.Errorf("ai: anonymize session %s: label %q: %w", e.Session.ID, k, err)
+			}
+		}
+	}
+	for _, m := range e.Messages {
+		if m.IsEvent() && !p.events {
+			continue
+		}
+		if m.Content, err = p.text(ctx, m.Content); err != nil {
+			return nil, fmt.Errorf("ai: anonymize session %s: message %d: %w", e.Session.ID, m.Seq, err)
+		}
+		m.ID, m.SessionID, m.RequestID = p.id(m.ID), out.Session.ID, p.id(m.RequestID)
+		out.Messages = append(out.Messages, m)
+	}
+	return out, nil
+}
+
+// Run anonymizes every session of src and writes it to w as one line of JSON.
+// On error, the returned report counts what was written.
+func (p *Pipeline) Run(ctx context.Context, src ai.SessionSource, w io.Writer) (Report, error) {
+	var report Report
+	for {
+		e, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			return report, nil
+		}
+		if err != nil {
+			return report, err
+		}
+
+		out, err := p.Session(ctx, e)
+		if err != nil {
+			return report, err
+		}
+		if err := ai.WriteSessionExport(w, out); err != nil {
+			return report, err
+		}
+		report.Sessions++
+		report.Messages += len(out.Messages)
+		report.DroppedEvents += len(e.Messages) - len(out.Messages)
+	}
+}
+
+// text runs s through the anonymizers.
+func (p *Pipeline) text(ctx context.Context, s string) (string, error) {
+	if s == "" {
+		return s, nil
+	}
+	for _, step := range p.steps {
+		var err error
+		if s, err = step.Anonymize(ctx, s); err != nil {
+			return "", err
+		}
+	}
+	return s, nil
+}
+
+// id returns the hashed form of an ID; empty IDs stay empty.
+func (p *Pipeline) id(id string) string {
+	if id == "" {
+		return ""
+	}
+	return keyedHash(p.idKey, id)
+}
@@ -0,0 +1,105 @@
+package anonymize
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// DefaultEntityTypes are the entities an NER anonymizer removes by default.
+var DefaultEntityTypes = []string{"person", "organization", "location", "address", "email", "phone", "id_number"}
+
+const nerInstructions = `You find personal data in text for anonymization.
+List every span of the text that is one of these kinds of entity: %s.
+Copy each span exactly as it appears in the text. Do not list anything else, and return an empty list if there is nothing to remove.`
+
+// NER is an Anonymizer that asks a model to find named entities, such as people's
+// names, that patterns can't, and replaces every occurrence of each with a placeholder
+// of its type, e.g. "[PERSON]". Every text is one provider call, billed and logged like
+// any other; give it a provider without a store to keep the originals out of request
+// logs.
+type NER struct {
+	provider ai.Provider
+	types    []string
+	key      []byte
+}
+
+// NewNER returns an NER anonymizer finding DefaultEntityTypes with provider.
+func NewNER(provider ai.Provider) *NER {
+	return &NER{provider: provider, types: DefaultEntityTypes}
+}
+
+// WithTypes sets the entity types to find, e.g. "person" and "school".
+func (n *NER) WithTypes(types ...string) *NER {
+	n.types = types
+	return n
+}
+
+// WithHashKey makes placeholders carry a keyed hash of the entity, e.g.
+// "[PERSON:1a2b3c4d5e6f7a8b]", so the same name gets the same placeholder throughout
+// a dataset. See Hash.
+func (n *NER) WithHashKey(key []byte) *NER {
+	n.key = key
+	return n
+}
+
+// Rules returns the rules of the entity requests, whose output schema lists the
+// entities found.
+func (n *NER) Rules() ai.Rules {
+	enum, _ := json.Marshal(n.types)
+	return ai.Rules{
+		SystemPrompt: fmt.Sprintf(nerInstructions, strings.Join(n.types, ", ")),
+		OutputSchema: fmt.Sprintf(`{"type":"object","properties":{"entities":{"type":"array","items":{"type":"object","properties":{"text":{"type":"string"},"type":{"type":"string","enum":%s}},"required":["text","type"]}}},"required":["entities"]}`, enum),
+	}
+}
+
+// Anonymize replaces the entities the model finds in text. Spans that don't occur in
+// text are ignored; longer spans are replaced before the shorter ones they contain.
+func (n *NER) Anonymize(ctx context.Context, text string) (string, error) {
+	if strings.TrimSpace(text) == "" {
+		return text, nil
+	}
+	result, err := n.provider.Send(ctx, n.Rules(), nil, text)
+	if err != nil {
+		return "", err
+	}
+	var found struct {
+		Entities []struct {
+			Text string `json:"text"`
+			Type string `json:"type"`
+		} `json:"entities"`
+	}
+	if err := json.Unmarshal([]byte(result.Content), &found); err != nil {
+		return "", fmt.Errorf("ai: parse entities: %w", err)
+	}
+
+	types := map[string]string{} // span to entity type
+	for _, e := range found.Entities {
+		if e.Text = strings.TrimSpace(e.Text); e.Text != "" && strings.Contains(text, e.Text) {
+			types[e.Text] = e.Type
+		}
+	}
+	if len(types) == 0 {
+		return text, nil
+	}
+	spans := make([]string, 0, len(types))
+	for span := range types {
+		spans = append(spans, regexp.QuoteMeta(span))
+	}
+	slices.SortFunc(spans, func(a, b string) int { return cmp.Compare(len(b), len(a)) })
+
+	pattern := regexp.MustCompile(strings.Join(spans, "|"))
+	return pattern.ReplaceAllStringFunc(text, func(m string) string {
+		label := strings.ToUpper(types[m])
+		if n.key != nil {
+			return "[" + label + ":" + keyedHash(n.key, m) + "]"
+		}
+		return "[" + label + "]"
+	}), nil
+}
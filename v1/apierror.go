@@ -0,0 +1,56 @@
+package ai
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// APIError is an error response from a provider's API. It wraps ErrProviderFailed.
+type APIError struct {
+	StatusCode int           // HTTP status
+	Status     string        // provider status, e.g. "UNAVAILABLE" or "RESOURCE_EXHAUSTED"
+	Message    string        // provider error message
+	RetryAfter time.Duration // wait the provider asked for before retrying; zero if none
+	Body       string        // raw response body
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v: status %d: %s", ErrProviderFailed, e.StatusCode, e.Body)
+}
+
+func (e *APIError) Unwrap() error { return ErrProviderFailed }
+
+// Retryable reports whether the request may succeed if sent again: the provider was
+// rate limiting (429), overloaded or failed internally (500, 502, 503, 504). Other
+// statuses, such as an invalid request or key, fail the same way every time.
+func (e *APIError) Retryable() bool {
+	switch e.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// ParseRetryAfter parses a Retry-After header, given in seconds or as an HTTP date,
+// into a wait from now. It returns zero for an empty, invalid or past value.
+func ParseRetryAfter(header string, now time.Time) time.Duration {
+	header = strings.TrimSpace(header)
+	if header == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(header); err == nil {
+		if secs <= 0 {
+			return 0
+		}
+		return time.Duration(secs) * time.Second
+	}
+	t, err := http.ParseTime(header)
+	if err != nil || !t.After(now) {
+		return 0
+	}
+	return t.Sub(now)
+}
@@ -0,0 +1,190 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+var (
+	ErrApprovalNotFound = errors.New("ai: pending result not found")
+	ErrApprovalDecided  = errors.New("ai: pending result already decided")
+)
+
+// Approval statuses of a PendingResult.
+const (
+	ApprovalPending  = "pending_approval"
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// Session event types recorded by ApprovalGate. Their payload is the PendingResult ID.
+const (
+	EventApprovalRequested = "approval_requested"
+	EventApprovalApproved  = "approval_approved"
+	EventApprovalRejected  = "approval_rejected"
+)
+
+// PendingResult is a provider response held back until a reviewer approves it.
+type PendingResult struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id"`
+	RequestID string `json:"request_id,omitempty"`
+	Prompt    string `json:"prompt"`
+	Content   string `json:"content"`
+	Usage     Usage  `json:"usage"`
+
+	Status    string     `json:"status"`             // Approval constant
+	Reviewer  string     `json:"reviewer,omitempty"` // user ID of the ai.Actor that decided
+	Reason    string     `json:"reason,omitempty"`   // given when rejected
+	CreatedAt time.Time  `json:"created_at"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+// ApprovalStore is the optional store capability holding pending results.
+type ApprovalStore interface {
+	// AddPendingResult stores r as pending and returns it with CreatedAt set. r.ID is
+	// generated unless set.
+	AddPendingResult(ctx context.Context, r PendingResult) (*PendingResult, error)
+	// GetPendingResult returns a result in any status, or ErrApprovalNotFound.
+	GetPendingResult(ctx context.Context, id string) (*PendingResult, error)
+	// ListPendingResults returns the results of a session still awaiting review, oldest
+	// first.
+	ListPendingResults(ctx context.Context, sessionID string) ([]PendingResult, error)
+	// ApprovePendingResult marks a pending result approved and appends its prompt (when
+	// set) and content to the session as user and assistant messages, atomically.
+	// Returns ErrApprovalNotFound, or ErrApprovalDecided if the result isn't pending.
+	ApprovePendingResult(ctx context.Context, id string) (*PendingResult, []Message, error)
+	// RejectPendingResult marks a pending result rejected with reason, adding no
+	// messages. Returns ErrApprovalNotFound or ErrApprovalDecided like approval.
+	RejectPendingResult(ctx context.Context, id string, reason string) (*PendingResult, error)
+}
+
+// ApprovalGate is a Provider that holds responses for human review. Send returns the
+// provider's result as usual, with ApprovalID set, but instead of the caller storing
+// the turn, the gate stores it as a PendingResult; it reaches the session's messages
+// only when ApproveResult is called. Tool calls and dry runs pass through ungated.
+type ApprovalGate struct {
+	provider  Provider
+	approvals ApprovalStore
+	store     Store
+	notify    func(ctx context.Context, r PendingResult)
+}
+
+// NewApprovalGate gates the responses of provider, keeping them in approvals. Requests
+// must belong to a session, attached with WithSessionID or through history.
+func NewApprovalGate(provider Provider, approvals ApprovalStore) *ApprovalGate {
+	return &ApprovalGate{provider: provider, approvals: approvals}
+}
+
+// WithEvents records an approval_requested, approval_approved or approval_rejected
+// event on the session for every pending result and decision, so the session stays a
+// complete audit trail.
+func (g *ApprovalGate) WithEvents(store Store) *ApprovalGate {
+	g.store = store
+	return g
+}
+
+// WithNotifier sets a function called with every new pending result, e.g. to notify
+// reviewers. See WebhookApproval.
+func (g *ApprovalGate) WithNotifier(fn func(ctx context.Context, r PendingResult)) *ApprovalGate {
+	g.notify = fn
+	return g
+}
+
+// Send calls the provider and stores the response as pending.
+func (g *ApprovalGate) Send(ctx context.Context, rules Rules, history []Message, prompt string) (*Result, error) {
+	result, err := g.provider.Send(ctx, rules, history, prompt)
+	if err != nil || len(result.ToolCalls) > 0 || result.DryRun != nil {
+		return result, err
+	}
+
+	sessionID := SessionIDFromContext(ctx)
+	if sessionID == "" && len(history) > 0 {
+		sessionID = history[0].SessionID
+	}
+	if sessionID == "" {
+		return nil, errors.New("ai: approval gate: request has no session")
+	}
+
+	requestID := result.RequestID
+	if requestID == "" {
+		requestID = RequestIDFromContext(ctx)
+	}
+	pending, err := g.approvals.AddPendingResult(context.WithoutCancel(ctx), PendingResult{
+		SessionID: sessionID,
+		RequestID: requestID,
+		Prompt:    prompt,
+		Content:   result.Content,
+		Usage:     result.Usage,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: approval gate: %w", err)
+	}
+
+	g.event(ctx, pending.SessionID, EventApprovalRequested, pending.ID)
+	if g.notify != nil {
+		g.notify(ctx, *pending)
+	}
+
+	result.ApprovalID = pending.ID
+	return result, nil
+}
+
+// ApproveResult approves a pending result, appending its turn to the session. The
+// reviewer is the user ID of the ai.Actor attached to ctx.
+func (g *ApprovalGate) ApproveResult(ctx context.Context, id string) (*PendingResult, []Message, error) {
+	r, msgs, err := g.approvals.ApprovePendingResult(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	g.event(ctx, r.SessionID, EventApprovalApproved, r.ID)
+	return r, msgs, nil
+}
+
+// RejectResult rejects a pending result, discarding its turn.
+func (g *ApprovalGate) RejectResult(ctx context.Context, id string, reason string) (*PendingResult, error) {
+	r, err := g.approvals.RejectPendingResult(ctx, id, reason)
+	if err != nil {
+		return nil, err
+	}
+	g.event(ctx, r.SessionID, EventApprovalRejected, r.ID)
+	return r, nil
+}
+
+// Capabilities reports the wrapped provider's capabilities, without streaming: the
+// gate holds back complete responses only.
+func (g *ApprovalGate) Capabilities() Caps {
+	caps, _ := CapabilitiesOf(g.provider)
+	caps.Streaming = false
+	return caps
+}
+
+// Cancel stops the in-flight calls of requestID on the wrapped provider.
+func (g *ApprovalGate) Cancel(ctx context.Context, requestID string) error {
+	return CancelRequest(ctx, g.provider, requestID)
+}
+
+func (g *ApprovalGate) event(ctx context.Context, sessionID, eventType, id string) {
+	if g.store != nil {
+		AddEvent(context.WithoutCancel(ctx), g.store, sessionID, eventType, id)
+	}
+}
+
+// WebhookApproval returns a notifier that POSTs each new PendingResult as JSON to url,
+// for review tools that don't poll. Delivery errors are passed to onError if set. A nil
+// client uses http.DefaultClient.
+func WebhookApproval(url string, client *http.Client, onError func(error)) func(ctx context.Context, r PendingResult) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(ctx context.Context, r PendingResult) {
+		err := postJSON(ctx, client, url, r)
+		if err != nil && onError != nil {
+			onError(fmt.Errorf("ai: approval webhook: %w", err))
+		}
+	}
+}
@@ -0,0 +1,212 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ArtifactFormat is the version of the artifact file format MarshalArtifact writes.
+const ArtifactFormat = 1
+
+var (
+	ErrArtifactFormat = errors.New("ai: unsupported artifact format")
+)
+
+// RulesArtifact is a named, versioned Rules kept in a file, so prompts and schemas can
+// be reviewed in pull requests and promoted across environments like code.
+type RulesArtifact struct {
+	Name        string
+	Version     int
+	Description string
+	Rules       Rules
+	Options     SendOptions // request parameters, e.g. temperature; see SessionDefaultsStore
+}
+
+// artifactFile is the file layout of a RulesArtifact. The system prompt is split into
+// lines and the schema embedded as indented JSON, so diffs are line by line.
+type artifactFile struct {
+	Format         int               `json:"format"`
+	Name           string            `json:"name"`
+	Version        int               `json:"version"`
+	Description    string            `json:"description,omitempty"`
+	SystemPrompt   promptLines       `json:"system_prompt,omitempty"`
+	OutputSchema   json.RawMessage   `json:"output_schema,omitempty"`
+	ResponseFormat string            `json:"response_format,omitempty"`
+	MaxTokens      int               `json:"max_tokens,omitempty"`
+	Language       string            `json:"language,omitempty"`
+	Policy         *OutputPolicy     `json:"policy,omitempty"`
+	Prompt         *PromptRef        `json:"prompt,omitempty"`
+	Schema         *SchemaRef        `json:"schema,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Options        *SendOptions      `json:"options,omitempty"`
+}
+
+// promptLines is a prompt encoded as an array of lines. A single string is accepted
+// when decoding, for hand-written files.
+type promptLines string
+
+func (p promptLines) MarshalJSON() ([]byte, error) {
+	return json.Marshal(strings.Split(string(p), "\n"))
+}
+
+func (p *promptLines) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*p = promptLines(s)
+		return nil
+	}
+	var lines []string
+	if err := json.Unmarshal(data, &lines); err != nil {
+		return fmt.Errorf("system_prompt must be a string or an array of lines")
+	}
+	*p = promptLines(strings.Join(lines, "\n"))
+	return nil
+}
+
+// MarshalArtifact encodes a as an indented artifact file. The output is stable for
+// equal artifacts, so unchanged rules produce no diff.
+func MarshalArtifact(a RulesArtifact) ([]byte, error) {
+	if a.Name == "" {
+		return nil, errors.New("ai: artifact: name is required")
+	}
+	f := artifactFile{
+		Format:         ArtifactFormat,
+		Name:           a.Name,
+		Version:        a.Version,
+		Description:    a.Description,
+		SystemPrompt:   promptLines(a.Rules.SystemPrompt),
+		ResponseFormat: a.Rules.ResponseFormat,
+		MaxTokens:      a.Rules.MaxTokens,
+		Language:       a.Rules.Language,
+		Policy:         a.Rules.Policy,
+		Prompt:         a.Rules.Prompt,
+		Schema:         a.Rules.Schema,
+		Labels:         a.Rules.Labels,
+	}
+	if a.Rules.OutputSchema != "" {
+		if !json.Valid([]byte(a.Rules.OutputSchema)) {
+			return nil, fmt.Errorf("ai: artifact %s: output schema is not valid JSON", a.Name)
+		}
+		f.OutputSchema = json.RawMessage(a.Rules.OutputSchema)
+	}
+	if a.Options.Model != "" || a.Options.Temperature != nil || a.Options.Policy != nil || a.Options.Transforms != nil {
+		f.Options = &a.Options
+	}
+
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("ai: artifact %s: %w", a.Name, err)
+	}
+	return append(data, '\n'), nil
+}
+
+// UnmarshalArtifact decodes an artifact file. It returns ErrArtifactFormat for files of
+// a newer format.
+func UnmarshalArtifact(data []byte) (*RulesArtifact, error) {
+	var f artifactFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("ai: artifact: %w", err)
+	}
+	if f.Format != ArtifactFormat {
+		return nil, fmt.Errorf("%w: %d", ErrArtifactFormat, f.Format)
+	}
+	if f.Name == "" {
+		return nil, errors.New("ai: artifact: name is required")
+	}
+
+	a := &RulesArtifact{
+		Name:        f.Name,
+		Version:     f.Version,
+		Description: f.Description,
+		Rules: Rules{
+			SystemPrompt:   string(f.SystemPrompt),
+			ResponseFormat: f.ResponseFormat,
+			MaxTokens:      f.MaxTokens,
+			Language:       f.Language,
+			Policy:         f.Policy,
+			Prompt:         f.Prompt,
+			Schema:         f.Schema,
+			Labels:         f.Labels,
+		},
+	}
+	if len(f.OutputSchema) > 0 {
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, f.OutputSchema); err != nil {
+			return nil, fmt.Errorf("ai: artifact %s: output schema: %w", f.Name, err)
+		}
+		a.Rules.OutputSchema = compact.String()
+	}
+	if f.Options != nil {
+		a.Options = *f.Options
+	}
+	return a, nil
+}
+
+// WriteArtifact writes a to path as an artifact file.
+func WriteArtifact(path string, a RulesArtifact) error {
+	data, err := MarshalArtifact(a)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("ai: write artifact: %w", err)
+	}
+	return nil
+}
+
+// LoadArtifact reads the artifact file at path.
+func LoadArtifact(path string) (*RulesArtifact, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("ai: load artifact: %w", err)
+	}
+	a, err := UnmarshalArtifact(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return a, nil
+}
+
+// LoadArtifacts reads every .json file in dir as an artifact, ordered by name and
+// version. Two files with the same name and version are an error.
+func LoadArtifacts(dir string) ([]RulesArtifact, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, fmt.Errorf("ai: load artifacts: %w", err)
+	}
+
+	artifacts := make([]RulesArtifact, 0, len(paths))
+	seen := make(map[string]string, len(paths))
+	for _, path := range paths {
+		a, err := LoadArtifact(path)
+		if err != nil {
+			return nil, err
+		}
+		key := fmt.Sprintf("%s v%d", a.Name, a.Version)
+		if other, ok := seen[key]; ok {
+			return nil, fmt.Errorf("ai: load artifacts: %s is in both %s and %s", key, other, path)
+		}
+		seen[key] = path
+		artifacts = append(artifacts, *a)
+	}
+	sort.Slice(artifacts, func(i, j int) bool {
+		if artifacts[i].Name != artifacts[j].Name {
+			return artifacts[i].Name < artifacts[j].Name
+		}
+		return artifacts[i].Version < artifacts[j].Version
+	})
+	return artifacts, nil
+}
+
+// Preset returns a preset with the artifact's name and rules, for registering in a
+// PresetRegistry or saving to a PresetStore. Options are not part of presets; apply
+// them with SessionDefaultsStore.
+func (a RulesArtifact) Preset() Preset {
+	return Preset{Name: a.Name, Rules: a.Rules}
+}
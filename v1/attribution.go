@@ -0,0 +1,48 @@
+package ai
+
+import "context"
+
+// PromptAttribution splits a request's prompt tokens between the system instruction
+// (including the output schema), the conversation history and the new prompt. Providers
+// report only the total, so the split is estimated with EstimateTokens and scaled to it.
+type PromptAttribution struct {
+	SystemTokens  int `json:"system_tokens"`
+	HistoryTokens int `json:"history_tokens"`
+	PromptTokens  int `json:"prompt_tokens"`
+}
+
+// PromptAttributionStore is the optional store capability recording attributions on
+// request logs.
+type PromptAttributionStore interface {
+	SetPromptAttribution(ctx context.Context, requestLogID string, a PromptAttribution) error
+}
+
+// EstimatePromptAttribution estimates the token split of a request. Events in history
+// are skipped, as providers don't send them by default.
+func EstimatePromptAttribution(rules Rules, history []Message, prompt string) PromptAttribution {
+	a := PromptAttribution{
+		SystemTokens: EstimateTokens(rules.SystemPrompt) + EstimateTokens(rules.OutputSchema),
+		PromptTokens: EstimateTokens(prompt),
+	}
+	for _, m := range history {
+		if !m.IsEvent() {
+			a.HistoryTokens += EstimateTokens(m.Content)
+		}
+	}
+	return a
+}
+
+// Scale returns a with its parts scaled proportionally to sum to total, the prompt
+// tokens the provider reported. A zero total leaves the estimate unchanged.
+func (a PromptAttribution) Scale(total int) PromptAttribution {
+	sum := a.SystemTokens + a.HistoryTokens + a.PromptTokens
+	if total <= 0 || sum == 0 {
+		return a
+	}
+	system := (a.SystemTokens*total + sum/2) / sum
+	history := (a.HistoryTokens*total + sum/2) / sum
+	if system+history > total {
+		history = total - system
+	}
+	return PromptAttribution{SystemTokens: system, HistoryTokens: history, PromptTokens: total - system - history}
+}
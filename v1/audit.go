@@ -0,0 +1,33 @@
+package ai
+
+import (
+	"context"
+	"time"
+)
+
+// Audit actions recorded for store mutations.
+const (
+	AuditCreateSession = "create_session"
+	AuditDeleteSession = "delete_session"
+	AuditAddMessage    = "add_message"
+	AuditAddEvent      = "add_event"
+	AuditEditMessage   = "edit_message"
+	AuditApproveResult = "approve_result"
+	AuditRejectResult  = "reject_result"
+)
+
+// AuditEntry records who performed a store mutation and when.
+type AuditEntry struct {
+	ID        string    `json:"id"`
+	Actor     string    `json:"actor"`
+	Action    string    `json:"action"`
+	SessionID string    `json:"session_id"`
+	TargetID  string    `json:"target_id"`
+	RequestID string    `json:"request_id,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AuditStore reads the audit trail recorded by a store with auditing enabled.
+type AuditStore interface {
+	ListAuditEntries(ctx context.Context, sessionID string) ([]AuditEntry, error)
+}
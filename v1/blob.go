@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+var (
+	ErrBlobNotFound = errors.New("ai: blob not found")
+)
+
+// BlobStore holds large content outside the database, e.g. in S3 or GCS. Keys are
+// slash-separated paths chosen by the caller.
+type BlobStore interface {
+	PutBlob(ctx context.Context, key string, data []byte) error
+	// GetBlob returns the blob stored under key, or an error wrapping ErrBlobNotFound.
+	GetBlob(ctx context.Context, key string) ([]byte, error)
+}
+
+// ContentHash returns the hex SHA-256 of content.
+func ContentHash(content string) string {
+	sum := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(sum[:])
+}
+
+// BlobKey returns the content-addressed key stores use for offloaded content, so equal
+// content is stored once and a key can be checked against its blob.
+func BlobKey(content string) string {
+	h := ContentHash(content)
+	return "sha256/" + h[:2] + "/" + h
+}
+
+// FileBlobStore is a BlobStore keeping each blob in a file under a directory, for
+// development and single-host deployments.
+type FileBlobStore struct {
+	dir string
+}
+
+// NewFileBlobStore returns a store of blobs under dir, which is created on first write.
+func NewFileBlobStore(dir string) *FileBlobStore {
+	return &FileBlobStore{dir: dir}
+}
+
+// PutBlob writes data to the file for key, replacing it atomically.
+func (f *FileBlobStore) PutBlob(ctx context.Context, key string, data []byte) error {
+	path, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("ai: put blob: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".blob-*")
+	if err != nil {
+		return fmt.Errorf("ai: put blob: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("ai: put blob: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("ai: put blob: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return fmt.Errorf("ai: put blob: %w", err)
+	}
+	return nil
+}
+
+// GetBlob reads the file for key.
+func (f *FileBlobStore) GetBlob(ctx context.Context, key string) ([]byte, error) {
+	path, err := f.path(key)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrBlobNotFound, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: get blob: %w", err)
+	}
+	return data, nil
+}
+
+// path maps key to a file under dir, rejecting keys that would escape it.
+func (f *FileBlobStore) path(key string) (string, error) {
+	if key == "" || strings.HasPrefix(key, "/") || !fs.ValidPath(key) {
+		return "", fmt.Errorf("ai: invalid blob key %q", key)
+	}
+	return filepath.Join(f.dir, filepath.FromSlash(key)), nil
+}
@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrBlocked = errors.New("ai: blocked by safety filters")
+)
+
+// BlockedError is a request the provider refused to answer, or an answer it withheld,
+// for safety reasons, as opposed to an outage. It wraps ErrBlocked; request logs record
+// it as FailReasonSafetyBlocked.
+type BlockedError struct {
+	Reason     string   // provider reason, e.g. "SAFETY", "PROHIBITED_CONTENT" or "BLOCKLIST"
+	Categories []string // harm categories that triggered the block, e.g. "HARM_CATEGORY_HARASSMENT"
+	Prompt     bool     // the prompt was blocked, rather than the response
+}
+
+func (e *BlockedError) Error() string {
+	what := "response"
+	if e.Prompt {
+		what = "prompt"
+	}
+	msg := fmt.Sprintf("%v: %s blocked: %s", ErrBlocked, what, e.Reason)
+	if len(e.Categories) > 0 {
+		msg += " (" + strings.Join(e.Categories, ", ") + ")"
+	}
+	return msg
+}
+
+func (e *BlockedError) Unwrap() error { return ErrBlocked }
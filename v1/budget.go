@@ -0,0 +1,184 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Budget scopes: what spend is summed for a threshold.
+const (
+	BudgetScopeSession = "session" // spend of one session
+	BudgetScopeTenant  = "tenant"  // spend of one tenant (see WithTenantID)
+	BudgetScopeGlobal  = "global"  // all spend
+)
+
+// Budget windows: the period spend is summed over, in UTC calendar terms.
+const (
+	BudgetWindowTotal = ""      // all time
+	BudgetWindowDay   = "day"   // since midnight
+	BudgetWindowMonth = "month" // since the first of the month
+)
+
+// BudgetThreshold is a spend limit in USD that raises an alert once crossed.
+type BudgetThreshold struct {
+	Name   string  `json:"name"`
+	Scope  string  `json:"scope"`
+	Window string  `json:"window"`
+	Limit  float64 `json:"limit"`
+}
+
+// BudgetAlert reports a crossed threshold.
+type BudgetAlert struct {
+	Threshold BudgetThreshold `json:"threshold"`
+	// Key is the session or tenant ID the spend was summed for; empty for global scope.
+	Key         string    `json:"key,omitempty"`
+	Spent       float64   `json:"spent"`
+	WindowStart time.Time `json:"window_start"`
+	At          time.Time `json:"at"`
+}
+
+// SpendFilter selects the request logs whose cost is summed.
+type SpendFilter struct {
+	SessionID string
+	TenantID  string
+	UserID    string
+	Since     time.Time // zero for all time
+}
+
+// SpendStore sums stored request costs.
+type SpendStore interface {
+	Spend(ctx context.Context, filter SpendFilter) (float64, error)
+}
+
+// BudgetMonitor checks spend thresholds after each request and calls OnAlert once
+// per threshold, key and window when the limit is reached. Alerts already sent are
+// remembered in memory, so a restarted process may alert again.
+type BudgetMonitor struct {
+	Thresholds []BudgetThreshold
+	Store      SpendStore
+	OnAlert    func(ctx context.Context, alert BudgetAlert)
+
+	mu    sync.Mutex
+	fired map[string]bool
+}
+
+// NewBudgetMonitor returns a monitor of thresholds over store.
+func NewBudgetMonitor(store SpendStore, onAlert func(ctx context.Context, alert BudgetAlert), thresholds ...BudgetThreshold) *BudgetMonitor {
+	return &BudgetMonitor{Thresholds: thresholds, Store: store, OnAlert: onAlert}
+}
+
+// Check evaluates every threshold that applies to the session and tenant of a request
+// as of now. Thresholds with a scope whose ID is empty are skipped.
+func (m *BudgetMonitor) Check(ctx context.Context, sessionID string, tenantID string, now time.Time) error {
+	now = now.UTC()
+
+	for _, t := range m.Thresholds {
+		filter, key := SpendFilter{}, ""
+		switch t.Scope {
+		case BudgetScopeSession:
+			filter.SessionID, key = sessionID, sessionID
+		case BudgetScopeTenant:
+			filter.TenantID, key = tenantID, tenantID
+		case BudgetScopeGlobal:
+		default:
+			return fmt.Errorf("ai: budget %q: unknown scope %q", t.Name, t.Scope)
+		}
+		if t.Scope != BudgetScopeGlobal && key == "" {
+			continue
+		}
+
+		switch t.Window {
+		case BudgetWindowDay:
+			filter.Since = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		case BudgetWindowMonth:
+			filter.Since = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		case BudgetWindowTotal:
+		default:
+			return fmt.Errorf("ai: budget %q: unknown window %q", t.Name, t.Window)
+		}
+
+		id := fmt.Sprintf("%s|%s|%d", t.Name, key, filter.Since.Unix())
+		if m.alreadyFired(id) {
+			continue
+		}
+
+		spent, err := m.Store.Spend(ctx, filter)
+		if err != nil {
+			return fmt.Errorf("ai: check budget %q: %w", t.Name, err)
+		}
+		if spent < t.Limit || !m.markFired(id) {
+			continue
+		}
+
+		if m.OnAlert != nil {
+			m.OnAlert(ctx, BudgetAlert{Threshold: t, Key: key, Spent: spent, WindowStart: filter.Since, At: now})
+		}
+	}
+
+	return nil
+}
+
+func (m *BudgetMonitor) alreadyFired(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.fired[id]
+}
+
+// markFired records id and reports whether this call was the first to do so.
+func (m *BudgetMonitor) markFired(id string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.fired[id] {
+		return false
+	}
+	if m.fired == nil {
+		m.fired = make(map[string]bool)
+	}
+	m.fired[id] = true
+	return true
+}
+
+// WebhookAlert returns an OnAlert func that POSTs each alert as JSON to url. Delivery
+// errors are passed to onError if set. A nil client uses http.DefaultClient.
+func WebhookAlert(url string, client *http.Client, onError func(error)) func(ctx context.Context, alert BudgetAlert) {
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	return func(ctx context.Context, alert BudgetAlert) {
+		err := postJSON(ctx, client, url, alert)
+		if err != nil && onError != nil {
+			onError(fmt.Errorf("ai: budget webhook: %w", err))
+		}
+	}
+}
+
+// postJSON POSTs v as JSON and fails on non-2xx responses.
+func postJSON(ctx context.Context, client *http.Client, url string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("status %d", resp.StatusCode)
+	}
+	return nil
+}
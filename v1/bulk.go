@@ -0,0 +1,46 @@
+package ai
+
+import "context"
+
+// NewMessage is a message to append with AddMessages. Events set Role to RoleEvent and
+// carry their type in EventType.
+type NewMessage struct {
+	Role      string `json:"role"`
+	Content   string `json:"content"`
+	EventType string `json:"event_type,omitempty"`
+	Usage     *Usage `json:"usage,omitempty"`
+}
+
+// BulkMessageStore is the optional store capability for appending many messages in one
+// round trip, as imports and batch flows do.
+type BulkMessageStore interface {
+	// AddMessages appends msgs to a session in order, with consecutive seqs, atomically.
+	// Returns ErrSessionNotFound if the session doesn't exist.
+	AddMessages(ctx context.Context, sessionID string, msgs []NewMessage) ([]Message, error)
+}
+
+// AddMessages appends msgs to a session with store's AddMessages when it implements
+// BulkMessageStore, and one AddMessage or AddEvent call per message otherwise. The
+// fallback is not atomic: on error, the messages before the failing one are stored.
+// Events fail with ErrUnsupported when store isn't an EventStore.
+func AddMessages(ctx context.Context, store Store, sessionID string, msgs []NewMessage) ([]Message, error) {
+	if bulk, ok := store.(BulkMessageStore); ok {
+		return bulk.AddMessages(ctx, sessionID, msgs)
+	}
+
+	out := make([]Message, 0, len(msgs))
+	for _, m := range msgs {
+		var msg *Message
+		var err error
+		if m.Role == RoleEvent {
+			msg, err = AddEvent(ctx, store, sessionID, m.EventType, m.Content)
+		} else {
+			msg, err = store.AddMessage(ctx, sessionID, m.Role, m.Content, m.Usage)
+		}
+		if err != nil {
+			return out, err
+		}
+		out = append(out, *msg)
+	}
+	return out, nil
+}
@@ -0,0 +1,213 @@
+// Package bus publishes request-log writes as JSON events to a message bus such as
+// Kafka or NATS, so analytics can consume usage without polling the database. It has
+// no client dependency: adapt your producer to Publisher.
+package bus
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SchemaVersion is the version of the event layout, sent in every event. It changes
+// only when a field is removed or changes meaning; new fields don't change it.
+const SchemaVersion = 1
+
+// JSONSchema is the JSON Schema (draft 2020-12) of Event, for registries and consumers.
+//
+//go:embed event.schema.json
+var JSONSchema []byte
+
+// Event types.
+const (
+	EventCreated    = "request_log.created"    // a request started; carries the prompt metadata
+	EventUpdated    = "request_log.updated"    // an attempt finished; carries status and usage
+	EventAttributed = "request_log.attributed" // the prompt token split was estimated
+)
+
+// Event is the JSON body of every published message. Fields a write doesn't set are
+// omitted. Events for one request log share a key (its ID), so partitioned buses keep
+// them in order.
+type Event struct {
+	SchemaVersion int       `json:"schema_version"`
+	ID            string    `json:"id"`
+	Type          string    `json:"type"`
+	At            time.Time `json:"at"`
+	RequestLogID  string    `json:"request_log_id"`
+
+	// Identity, from the log (created) or the writer's context (all types)
+	SessionID string `json:"session_id,omitempty"`
+	RequestID string `json:"request_id,omitempty"`
+	ReplayOf  string `json:"replay_of,omitempty"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	UserID    string `json:"user_id,omitempty"`
+
+	// Created
+	PromptName    string `json:"prompt_name,omitempty"`
+	PromptVersion int    `json:"prompt_version,omitempty"`
+	AttemptNumber int    `json:"attempt_number,omitempty"`
+
+	// Updated
+	Status       string    `json:"status,omitempty"`
+	FailReason   string    `json:"fail_reason,omitempty"`
+	ErrorMessage string    `json:"error_message,omitempty"`
+	RetryCount   int       `json:"retry_count,omitempty"`
+	Usage        *ai.Usage `json:"usage,omitempty"`
+	Cost         float64   `json:"cost,omitempty"` // USD, only with WithPrices
+
+	// Attributed
+	Attribution *ai.PromptAttribution `json:"attribution,omitempty"`
+
+	// Only with WithContent
+	Prompt         string `json:"prompt,omitempty"`
+	SystemAddendum string `json:"system_addendum,omitempty"`
+	Response       string `json:"response,omitempty"`
+}
+
+// Publisher sends one message to the bus. Implementations wrap a Kafka producer, a NATS
+// connection or similar; key is the partition key.
+type Publisher interface {
+	Publish(ctx context.Context, key string, value []byte) error
+}
+
+// PublisherFunc adapts a function to Publisher.
+type PublisherFunc func(ctx context.Context, key string, value []byte) error
+
+// Publish calls f.
+func (f PublisherFunc) Publish(ctx context.Context, key string, value []byte) error {
+	return f(ctx, key, value)
+}
+
+// Logger is an ai.RequestLogger that publishes an Event for every write. Use it as a
+// secondary of ai.MultiStore so the primary store keeps assigning log IDs.
+type Logger struct {
+	pub     Publisher
+	content bool
+	prices  ai.PriceTable
+	newID   ai.IDGenerator
+	now     ai.Clock
+}
+
+// NewLogger returns a Logger publishing to pub.
+func NewLogger(pub Publisher) *Logger {
+	return &Logger{pub: pub, newID: ai.NewRequestID, now: time.Now}
+}
+
+// WithContent includes prompts and responses in events. They are left out by default,
+// since they may hold personal data that analytics doesn't need.
+func (l *Logger) WithContent() *Logger {
+	l.content = true
+	return l
+}
+
+// WithPrices adds the cost of the reported usage to updated events, priced by its model.
+func (l *Logger) WithPrices(table ai.PriceTable) *Logger {
+	l.prices = table
+	return l
+}
+
+// WithIDGenerator sets the generator of event IDs and of log IDs for logs that have
+// none. Defaults to ai.NewRequestID.
+func (l *Logger) WithIDGenerator(gen ai.IDGenerator) *Logger {
+	l.newID = gen
+	return l
+}
+
+// WithClock sets the clock that stamps events.
+func (l *Logger) WithClock(clock ai.Clock) *Logger {
+	l.now = clock
+	return l
+}
+
+// AddRequestLog publishes a request_log.created event. A log without an ID is given
+// one, so Logger can also be used on its own.
+func (l *Logger) AddRequestLog(ctx context.Context, log ai.RequestLog) (*ai.RequestLog, error) {
+	if log.ID == "" {
+		log.ID = l.newID()
+	}
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = l.now()
+	}
+	log.FinalStatus = ai.StatusPending
+
+	e := l.event(ctx, EventCreated, log.ID)
+	e.At = log.CreatedAt
+	e.SessionID, e.ReplayOf = log.SessionID, log.ReplayOf
+	if log.RequestID != "" {
+		e.RequestID = log.RequestID
+	}
+	if log.TenantID != "" {
+		e.TenantID = log.TenantID
+	}
+	if log.UserID != "" {
+		e.UserID = log.UserID
+	}
+	e.PromptName, e.PromptVersion, e.AttemptNumber = log.PromptName, log.PromptVersion, log.AttemptNumber
+	if l.content {
+		e.Prompt, e.SystemAddendum = log.Prompt, log.SystemAddendum
+		if e.SystemAddendum == "" {
+			e.SystemAddendum = ai.SystemAddendumFromContext(ctx)
+		}
+	}
+
+	if err := l.publish(ctx, e); err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// UpdateRequestLog publishes a request_log.updated event.
+func (l *Logger) UpdateRequestLog(ctx context.Context, id string, response string, status string, failReason string, errorMsg string, retryCount int, usage *ai.Usage) error {
+	e := l.event(ctx, EventUpdated, id)
+	e.Status, e.FailReason, e.ErrorMessage, e.RetryCount, e.Usage = status, failReason, errorMsg, retryCount, usage
+	if usage != nil {
+		e.Cost, _ = l.prices.Cost(*usage)
+	}
+	if l.content {
+		e.Response = response
+	}
+	return l.publish(ctx, e)
+}
+
+// SetPromptAttribution publishes a request_log.attributed event.
+func (l *Logger) SetPromptAttribution(ctx context.Context, requestLogID string, a ai.PromptAttribution) error {
+	e := l.event(ctx, EventAttributed, requestLogID)
+	e.Attribution = &a
+	return l.publish(ctx, e)
+}
+
+// event returns an event of type for a log, stamped with the identity attached to ctx.
+func (l *Logger) event(ctx context.Context, typ string, logID string) Event {
+	actor := ai.ActorFromContext(ctx)
+	return Event{
+		SchemaVersion: SchemaVersion,
+		ID:            l.newID(),
+		Type:          typ,
+		At:            l.now(),
+		RequestLogID:  logID,
+		RequestID:     ai.RequestIDFromContext(ctx),
+		TenantID:      actor.TenantID,
+		UserID:        actor.UserID,
+	}
+}
+
+func (l *Logger) publish(ctx context.Context, e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("ai: encode %s event: %w", e.Type, err)
+	}
+	if err := l.pub.Publish(ctx, e.RequestLogID, body); err != nil {
+		return fmt.Errorf("ai: publish %s event: %w", e.Type, err)
+	}
+	return nil
+}
+
+// Ensure Logger implements ai.RequestLogger and ai.PromptAttributionStore at compile time.
+var (
+	_ ai.RequestLogger          = (*Logger)(nil)
+	_ ai.PromptAttributionStore = (*Logger)(nil)
+)
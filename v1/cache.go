@@ -0,0 +1,310 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"math"
+	"sync"
+	"time"
+)
+
+var (
+	ErrCacheMiss = errors.New("ai: cache miss")
+)
+
+// Embedder turns text into an embedding vector, e.g. with Gemini's embedContent API.
+type Embedder interface {
+	Embed(ctx context.Context, text string) ([]float32, error)
+}
+
+// EmbedderFunc adapts a function to Embedder.
+type EmbedderFunc func(ctx context.Context, text string) ([]float32, error)
+
+// Embed calls f.
+func (f EmbedderFunc) Embed(ctx context.Context, text string) ([]float32, error) {
+	return f(ctx, text)
+}
+
+// CacheHit records where a cached result came from.
+type CacheHit struct {
+	RequestID  string  `json:"request_id"` // request that generated the response
+	Similarity float64 `json:"similarity"` // cosine similarity of the prompts; 1 for an exact match
+}
+
+// CacheEntry is a response stored by a ResponseCache. Scope identifies everything the
+// response depended on besides the prompt; see CacheScope.
+type CacheEntry struct {
+	Scope     string    `json:"scope"`
+	Prompt    string    `json:"prompt"`
+	Embedding []float32 `json:"embedding,omitempty"`
+	Result    Result    `json:"result"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// CacheStore keeps cached responses.
+type CacheStore interface {
+	// GetCached returns the entry for exactly scope and prompt, or ErrCacheMiss.
+	GetCached(ctx context.Context, scope string, prompt string) (*CacheEntry, error)
+	// NearestCached returns the entry of scope whose embedding is most similar to
+	// embedding, and their cosine similarity, or ErrCacheMiss if scope has none.
+	NearestCached(ctx context.Context, scope string, embedding []float32) (*CacheEntry, float64, error)
+	// PutCached stores e, replacing any entry with its scope and prompt.
+	PutCached(ctx context.Context, e CacheEntry) error
+}
+
+// CacheScope returns the scope of a request: a hash of its rules, history, system
+// addendum and tenant. Only requests with equal scopes can share a response.
+func CacheScope(ctx context.Context, rules Rules, history []Message) string {
+	turns := make([][2]string, 0, len(history))
+	for _, m := range history {
+		turns = append(turns, [2]string{m.Role, m.Content})
+	}
+	rules.Labels = nil // labels don't change the response
+	data, _ := json.Marshal(struct {
+		Rules    Rules
+		History  [][2]string
+		Addendum string
+		Tenant   string
+	}{rules, turns, SystemAddendumFromContext(ctx), TenantIDFromContext(ctx)})
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// CosineSimilarity returns the cosine similarity of a and b, or 0 if their lengths
+// differ or either is zero.
+func CosineSimilarity(a, b []float32) float64 {
+	if len(a) != len(b) || len(a) == 0 {
+		return 0
+	}
+	var dot, na, nb float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+		na += float64(a[i]) * float64(a[i])
+		nb += float64(b[i]) * float64(b[i])
+	}
+	if na == 0 || nb == 0 {
+		return 0
+	}
+	return dot / (math.Sqrt(na) * math.Sqrt(nb))
+}
+
+// ResponseCache is a Provider that answers repeated requests from a CacheStore
+// instead of calling the wrapped provider. A request hits when an entry of its scope
+// has the same prompt or, with WithSemanticMatch, a prompt similar enough, so
+// "make a Java bootcamp feedback form" can reuse the response to "create a feedback
+// form for the Java bootcamp". Hits are returned with Result.CacheHit set and zero
+// usage. Dry runs, requests with tools and tool-call results bypass the cache.
+type ResponseCache struct {
+	provider  Provider
+	store     CacheStore
+	embedder  Embedder
+	threshold float64
+	ttl       time.Duration
+	scope     func(ctx context.Context) string
+	logger    RequestLogger
+	now       Clock
+}
+
+// NewResponseCache caches the responses of provider in store, matching prompts exactly.
+func NewResponseCache(provider Provider, store CacheStore) *ResponseCache {
+	return &ResponseCache{provider: provider, store: store, now: time.Now}
+}
+
+// WithSemanticMatch also reuses the response to the most similar cached prompt in the
+// request's scope when the cosine similarity of their embeddings is at least
+// threshold, e.g. 0.95. If embedding fails, the request falls back to exact matching.
+func (c *ResponseCache) WithSemanticMatch(embedder Embedder, threshold float64) *ResponseCache {
+	c.embedder = embedder
+	c.threshold = threshold
+	return c
+}
+
+// WithTTL ignores entries older than ttl. Zero keeps them forever.
+func (c *ResponseCache) WithTTL(ttl time.Duration) *ResponseCache {
+	c.ttl = ttl
+	return c
+}
+
+// WithScopeKey adds fn's result to every request's scope, e.g. the user ID when the
+// wrapped provider injects user memory, so personalized responses aren't shared.
+func (c *ResponseCache) WithScopeKey(fn func(ctx context.Context) string) *ResponseCache {
+	c.scope = fn
+	return c
+}
+
+// WithRequestLog writes a request log for every hit, with CacheHitOf and
+// CacheSimilarity recording where the response came from. Misses are logged by the
+// wrapped provider as usual.
+func (c *ResponseCache) WithRequestLog(logger RequestLogger) *ResponseCache {
+	c.logger = logger
+	return c
+}
+
+// WithClock sets the clock used for entry ages.
+func (c *ResponseCache) WithClock(clock Clock) *ResponseCache {
+	c.now = clock
+	return c
+}
+
+// Send returns a cached response for the request, or calls the provider and caches
+// its response. Cache read and write errors are treated as misses.
+func (c *ResponseCache) Send(ctx context.Context, rules Rules, history []Message, prompt string) (*Result, error) {
+	if IsDryRun(ctx) || len(ToolsFromContext(ctx)) > 0 || prompt == "" {
+		return c.provider.Send(ctx, rules, history, prompt)
+	}
+
+	scope := CacheScope(ctx, rules, history)
+	if c.scope != nil {
+		sum := sha256.Sum256([]byte(scope + "\x00" + c.scope(ctx)))
+		scope = hex.EncodeToString(sum[:])
+	}
+
+	entry, similarity, embedding := c.lookup(ctx, scope, prompt)
+	if entry != nil {
+		return c.hit(ctx, rules, history, prompt, entry, similarity), nil
+	}
+
+	result, err := c.provider.Send(ctx, rules, history, prompt)
+	if err != nil || len(result.ToolCalls) > 0 || result.ApprovalID != "" {
+		return result, err
+	}
+	c.store.PutCached(context.WithoutCancel(ctx), CacheEntry{
+		Scope:     scope,
+		Prompt:    prompt,
+		Embedding: embedding,
+		Result:    *result,
+		CreatedAt: c.now(),
+	})
+	return result, nil
+}
+
+// Cancel stops the in-flight calls of requestID on the wrapped provider.
+func (c *ResponseCache) Cancel(ctx context.Context, requestID string) error {
+	return CancelRequest(ctx, c.provider, requestID)
+}
+
+// lookup finds a fresh entry for prompt, exactly or by similarity. It also returns the
+// prompt's embedding, if computed, for storing the response of a miss.
+func (c *ResponseCache) lookup(ctx context.Context, scope, prompt string) (*CacheEntry, float64, []float32) {
+	if e, err := c.store.GetCached(ctx, scope, prompt); err == nil && c.fresh(e) {
+		return e, 1, e.Embedding
+	}
+	if c.embedder == nil {
+		return nil, 0, nil
+	}
+
+	embedding, err := c.embedder.Embed(ctx, prompt)
+	if err != nil {
+		return nil, 0, nil
+	}
+	e, similarity, err := c.store.NearestCached(ctx, scope, embedding)
+	if err != nil || similarity < c.threshold || !c.fresh(e) {
+		return nil, 0, embedding
+	}
+	return e, similarity, embedding
+}
+
+func (c *ResponseCache) fresh(e *CacheEntry) bool {
+	return c.ttl <= 0 || c.now().Sub(e.CreatedAt) < c.ttl
+}
+
+// hit returns the cached result as the response to this request, logging it.
+func (c *ResponseCache) hit(ctx context.Context, rules Rules, history []Message, prompt string, e *CacheEntry, similarity float64) *Result {
+	requestID := RequestIDFromContext(ctx)
+	if requestID == "" {
+		requestID = NewRequestID()
+	}
+	result := &Result{
+		Content:   e.Result.Content,
+		RequestID: requestID,
+		Usage:     Usage{Model: e.Result.Usage.Model},
+		CacheHit:  &CacheHit{RequestID: e.Result.RequestID, Similarity: similarity},
+	}
+
+	if c.logger != nil {
+		sessionID := SessionIDFromContext(ctx)
+		if sessionID == "" && len(history) > 0 {
+			sessionID = history[0].SessionID
+		}
+		logCtx := context.WithoutCancel(WithRequestID(ctx, requestID))
+		log, err := c.logger.AddRequestLog(logCtx, RequestLog{
+			SessionID:       sessionID,
+			Labels:          rules.Labels,
+			Prompt:          prompt,
+			AttemptNumber:   1,
+			FinalStatus:     StatusPending,
+			CacheHitOf:      e.Result.RequestID,
+			CacheSimilarity: similarity,
+		})
+		if err == nil {
+			c.logger.UpdateRequestLog(logCtx, log.ID, result.Content, StatusSuccess, "", "", 0, &result.Usage)
+		}
+	}
+	return result
+}
+
+// MemoryCache is an in-process CacheStore holding up to a fixed number of entries,
+// evicting the oldest. Nearest-neighbour lookups scan the scope linearly, which suits
+// caches of a few thousand entries. It is safe for concurrent use.
+type MemoryCache struct {
+	mu      sync.Mutex
+	max     int
+	entries []CacheEntry // oldest first
+}
+
+// NewMemoryCache returns a cache of at most maxEntries entries; 0 means unbounded.
+func NewMemoryCache(maxEntries int) *MemoryCache {
+	return &MemoryCache{max: maxEntries}
+}
+
+// GetCached returns the entry for scope and prompt.
+func (m *MemoryCache) GetCached(ctx context.Context, scope string, prompt string) (*CacheEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := len(m.entries) - 1; i >= 0; i-- {
+		if e := m.entries[i]; e.Scope == scope && e.Prompt == prompt {
+			return &e, nil
+		}
+	}
+	return nil, ErrCacheMiss
+}
+
+// NearestCached returns the most similar entry of scope.
+func (m *MemoryCache) NearestCached(ctx context.Context, scope string, embedding []float32) (*CacheEntry, float64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	best, bestSimilarity := -1, -1.0
+	for i, e := range m.entries {
+		if e.Scope != scope || len(e.Embedding) == 0 {
+			continue
+		}
+		if s := CosineSimilarity(e.Embedding, embedding); s > bestSimilarity {
+			best, bestSimilarity = i, s
+		}
+	}
+	if best < 0 {
+		return nil, 0, ErrCacheMiss
+	}
+	e := m.entries[best]
+	return &e, bestSimilarity, nil
+}
+
+// PutCached stores e, evicting the oldest entry when the cache is full.
+func (m *MemoryCache) PutCached(ctx context.Context, e CacheEntry) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i, old := range m.entries {
+		if old.Scope == e.Scope && old.Prompt == e.Prompt {
+			m.entries = append(m.entries[:i], m.entries[i+1:]...)
+			break
+		}
+	}
+	m.entries = append(m.entries, e)
+	if m.max > 0 && len(m.entries) > m.max {
+		m.entries = append(m.entries[:0:0], m.entries[len(m.entries)-m.max:]...)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var (
+	ErrCancelled   = errors.New("ai: request cancelled")
+	ErrNotInFlight = errors.New("ai: request not in flight")
+)
+
+// Canceller is implemented by providers whose in-flight requests can be stopped by
+// request ID, e.g. from a "stop generating" button.
+type Canceller interface {
+	// Cancel stops the provider calls, including streams, of requestID and marks their
+	// request logs as cancelled. It returns ErrNotInFlight if none are running.
+	Cancel(ctx context.Context, requestID string) error
+}
+
+// CancelRequest cancels requestID on provider, or returns ErrNotInFlight if provider
+// is not a Canceller. Wrapping providers use it to forward Cancel.
+func CancelRequest(ctx context.Context, provider Provider, requestID string) error {
+	if c, ok := provider.(Canceller); ok {
+		return c.Cancel(ctx, requestID)
+	}
+	return ErrNotInFlight
+}
+
+// Cancelled reports whether ctx was cancelled by a CancelRegistry, as opposed to by
+// the caller or a deadline.
+func Cancelled(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), ErrCancelled)
+}
+
+// CancelledStreamStore is the optional store capability recording, on the request log
+// of a stream its caller cancelled, how many response tokens had been streamed.
+type CancelledStreamStore interface {
+	SetCancelledAfterTokens(ctx context.Context, requestLogID string, tokens int) error
+}
+
+// CancelRegistry tracks the in-flight calls of a provider so they can be cancelled by
+// request ID. Cancelling closes the call's connection, which stops the model
+// generating and billing for tokens. It is in-process: a request running on another
+// instance is not found. It is safe for concurrent use.
+type CancelRegistry struct {
+	mu    sync.Mutex
+	calls map[string][]*inFlightCall
+}
+
+type inFlightCall struct {
+	cancel context.CancelCauseFunc
+}
+
+// NewCancelRegistry returns an empty registry.
+func NewCancelRegistry() *CancelRegistry {
+	return &CancelRegistry{calls: make(map[string][]*inFlightCall)}
+}
+
+// Track registers a call for requestID. It returns the context the call must run
+// with, which Cancel cancels with cause ErrCancelled, and a release function the
+// provider calls once the call, or its stream, has finished.
+func (r *CancelRegistry) Track(ctx context.Context, requestID string) (context.Context, func()) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	call := &inFlightCall{cancel: cancel}
+
+	r.mu.Lock()
+	r.calls[requestID] = append(r.calls[requestID], call)
+	r.mu.Unlock()
+
+	var once sync.Once
+	return ctx, func() {
+		once.Do(func() {
+			r.mu.Lock()
+			calls := r.calls[requestID]
+			for i, c := range calls {
+				if c == call {
+					calls = append(calls[:i], calls[i+1:]...)
+					break
+				}
+			}
+			if len(calls) == 0 {
+				delete(r.calls, requestID)
+			} else {
+				r.calls[requestID] = calls
+			}
+			r.mu.Unlock()
+			cancel(nil)
+		})
+	}
+}
+
+// Cancel cancels every tracked call of requestID. The calls' providers record the
+// cancellation in their request logs as they stop.
+func (r *CancelRegistry) Cancel(ctx context.Context, requestID string) error {
+	r.mu.Lock()
+	calls := r.calls[requestID]
+	r.mu.Unlock()
+
+	if len(calls) == 0 {
+		return ErrNotInFlight
+	}
+	for _, c := range calls {
+		c.cancel(ErrCancelled)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CanonicalJSON rewrites a JSON document in a canonical form, so that two responses
+// differing only cosmetically are byte-for-byte equal and diffs between versions of a
+// document show only structural changes:
+//
+//   - object keys are sorted and whitespace between tokens is removed
+//   - numbers are written in their shortest form, e.g. 1.50 as 1.5 and 1e2 as 100;
+//     integers too large for a float64 are kept as written
+//   - string values have leading and trailing whitespace trimmed and CRLF line endings
+//     converted to LF
+//   - characters are written unescaped where JSON allows it, e.g. "<" rather than "\u003c"
+//
+// Array order is kept, as it is meaningful in forms. Text around the document, such as
+// a code fence, is an error.
+func CanonicalJSON(content string) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	var v any
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("ai: canonical JSON: %w", err)
+	}
+	if _, err := dec.Token(); err != io.EOF {
+		return "", fmt.Errorf("ai: canonical JSON: unexpected content after the document")
+	}
+
+	var b bytes.Buffer
+	if err := writeCanonical(&b, v); err != nil {
+		return "", fmt.Errorf("ai: canonical JSON: %w", err)
+	}
+	return b.String(), nil
+}
+
+func writeCanonical(b *bytes.Buffer, v any) error {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		slices.Sort(keys)
+		b.WriteByte('{')
+		for i, k := range keys {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			writeCanonicalString(b, k)
+			b.WriteByte(':')
+			if err := writeCanonical(b, v[k]); err != nil {
+				return err
+			}
+		}
+		b.WriteByte('}')
+	case []any:
+		b.WriteByte('[')
+		for i, e := range v {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			if err := writeCanonical(b, e); err != nil {
+				return err
+			}
+		}
+		b.WriteByte(']')
+	case string:
+		writeCanonicalString(b, strings.TrimSpace(strings.ReplaceAll(v, "\r\n", "\n")))
+	case json.Number:
+		n, err := canonicalNumber(v)
+		if err != nil {
+			return err
+		}
+		b.WriteString(n)
+	case bool:
+		b.WriteString(strconv.FormatBool(v))
+	case nil:
+		b.WriteString("null")
+	}
+	return nil
+}
+
+// writeCanonicalString writes s as a JSON string without HTML escaping.
+func writeCanonicalString(b *bytes.Buffer, s string) {
+	enc := json.NewEncoder(b)
+	enc.SetEscapeHTML(false)
+	enc.Encode(s)
+	b.Truncate(b.Len() - 1) // the newline Encode appends
+}
+
+// canonicalNumber returns the shortest form of n: plain digits between 1e-6 and 1e21,
+// exponent notation such as 1e-7 outside, and the digits as written for integers a
+// float64 can't hold exactly.
+func canonicalNumber(n json.Number) (string, error) {
+	s := string(n)
+	if i, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return strconv.FormatInt(i, 10), nil
+	}
+	if !strings.ContainsAny(s, ".eE") {
+		return s, nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return "", err
+	}
+	if f == 0 {
+		return "0", nil
+	}
+	if abs := math.Abs(f); abs >= 1e-6 && abs < 1e21 {
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	}
+	e := strconv.FormatFloat(f, 'e', -1, 64)
+	return strings.NewReplacer("e-0", "e-", "e+0", "e+").Replace(e), nil
+}
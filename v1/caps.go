@@ -0,0 +1,36 @@
+package ai
+
+import "errors"
+
+var (
+	ErrUnsupported = errors.New("ai: provider does not support the request")
+)
+
+// Caps describes what a provider can do, so routing, fallback and agent code can pick
+// or reject a provider without knowing its type.
+type Caps struct {
+	Streaming  bool `json:"streaming"`  // implements Streamer
+	Tools      bool `json:"tools"`      // honours tools declared with WithTools
+	Schema     bool `json:"schema"`     // enforces Rules.OutputSchema natively
+	Multimodal bool `json:"multimodal"` // reads non-text content, e.g. images or PDFs behind URLs
+
+	// Token limits of the model; zero means unknown.
+	MaxContextTokens int `json:"max_context_tokens,omitempty"`
+	MaxOutputTokens  int `json:"max_output_tokens,omitempty"`
+}
+
+// CapabilityReporter is implemented by providers that describe their capabilities.
+type CapabilityReporter interface {
+	Capabilities() Caps
+}
+
+// CapabilitiesOf returns the capabilities of p and whether p reported them. For a
+// provider that doesn't implement CapabilityReporter only Streaming is known, so
+// callers should treat the other fields as unknown rather than unsupported.
+func CapabilitiesOf(p Provider) (Caps, bool) {
+	if r, ok := p.(CapabilityReporter); ok {
+		return r.Capabilities(), true
+	}
+	_, streams := p.(Streamer)
+	return Caps{Streaming: streams}, false
+}
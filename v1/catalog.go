@@ -0,0 +1,225 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrContextExceeded   = errors.New("ai: input exceeds the model context")
+	ErrMaxTokensExceeded = errors.New("ai: max tokens exceed the model's output limit")
+)
+
+// PricesUpdated is when DefaultPrices was last checked against the providers' price lists.
+const PricesUpdated = "2026-10-01"
+
+// DefaultPrices is a catalog of list prices in USD per million tokens for common
+// models, at standard (non-batch) rates for prompts up to 200K tokens. Keys match
+// model IDs by prefix, so "gemini-2.5-flash-preview-09-2025" is priced as
+// "gemini-2.5-flash". Override entries with PriceTable.With rather than editing it.
+var DefaultPrices = PriceTable{
+	// Google Gemini
+	"gemini-3-pro":          {InputPerMillion: 2.00, OutputPerMillion: 12.00, CachedInputPerMillion: 0.20},
+	"gemini-3-flash":        {InputPerMillion: 0.50, OutputPerMillion: 3.00, CachedInputPerMillion: 0.05},
+	"gemini-2.5-pro":        {InputPerMillion: 1.25, OutputPerMillion: 10.00, CachedInputPerMillion: 0.125},
+	"gemini-2.5-flash":      {InputPerMillion: 0.30, OutputPerMillion: 2.50, CachedInputPerMillion: 0.03},
+	"gemini-2.5-flash-lite": {InputPerMillion: 0.10, OutputPerMillion: 0.40, CachedInputPerMillion: 0.01},
+	"gemini-2.0-flash":      {InputPerMillion: 0.10, OutputPerMillion: 0.40, CachedInputPerMillion: 0.025},
+	"gemini-2.0-flash-lite": {InputPerMillion: 0.075, OutputPerMillion: 0.30},
+
+	// OpenAI
+	"gpt-4o":       {InputPerMillion: 2.50, OutputPerMillion: 10.00, CachedInputPerMillion: 1.25},
+	"gpt-4o-mini":  {InputPerMillion: 0.15, OutputPerMillion: 0.60, CachedInputPerMillion: 0.075},
+	"gpt-4.1":      {InputPerMillion: 2.00, OutputPerMillion: 8.00, CachedInputPerMillion: 0.50},
+	"gpt-4.1-mini": {InputPerMillion: 0.40, OutputPerMillion: 1.60, CachedInputPerMillion: 0.10},
+	"gpt-4.1-nano": {InputPerMillion: 0.10, OutputPerMillion: 0.40, CachedInputPerMillion: 0.025},
+	"o3":           {InputPerMillion: 2.00, OutputPerMillion: 8.00, CachedInputPerMillion: 0.50},
+	"o4-mini":      {InputPerMillion: 1.10, OutputPerMillion: 4.40, CachedInputPerMillion: 0.275},
+
+	// Anthropic
+	"claude-opus-4":     {InputPerMillion: 15.00, OutputPerMillion: 75.00, CachedInputPerMillion: 1.50},
+	"claude-sonnet-4":   {InputPerMillion: 3.00, OutputPerMillion: 15.00, CachedInputPerMillion: 0.30},
+	"claude-3-5-haiku":  {InputPerMillion: 0.80, OutputPerMillion: 4.00, CachedInputPerMillion: 0.08},
+	"claude-haiku-4-5":  {InputPerMillion: 1.00, OutputPerMillion: 5.00, CachedInputPerMillion: 0.10},
+	"claude-sonnet-4-5": {InputPerMillion: 3.00, OutputPerMillion: 15.00, CachedInputPerMillion: 0.30},
+}
+
+// Lookup returns the price of model: an exact entry, or else the entry with the
+// longest key that prefixes model.
+func (t PriceTable) Lookup(model string) (Price, bool) {
+	return lookupModel(t, model)
+}
+
+// lookupModel returns the entry of m for model: an exact key, or else the longest key
+// that prefixes model.
+func lookupModel[V any](m map[string]V, model string) (V, bool) {
+	if v, ok := m[model]; ok {
+		return v, true
+	}
+
+	best, found := "", false
+	for key := range m {
+		if strings.HasPrefix(model, key) && len(key) > len(best) {
+			best, found = key, true
+		}
+	}
+	if !found {
+		var zero V
+		return zero, false
+	}
+	return m[best], true
+}
+
+// With returns a copy of t with the entries of overrides added or replaced.
+func (t PriceTable) With(overrides PriceTable) PriceTable {
+	merged := make(PriceTable, len(t)+len(overrides))
+	for k, v := range t {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+// PriceStore persists price overrides, e.g. negotiated rates.
+type PriceStore interface {
+	SetPrice(ctx context.Context, model string, price Price) error
+	DeletePrice(ctx context.Context, model string) error
+	ListPrices(ctx context.Context) (PriceTable, error)
+}
+
+// LoadPrices returns base overridden by the prices in store.
+func LoadPrices(ctx context.Context, store PriceStore, base PriceTable) (PriceTable, error) {
+	stored, err := store.ListPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return base.With(stored), nil
+}
+
+// ModelLimits are the token limits of a model.
+type ModelLimits struct {
+	ContextTokens int `json:"context_tokens"` // input and output combined
+	OutputTokens  int `json:"output_tokens"`  // largest response the model will generate
+}
+
+// LimitTable maps model IDs to their limits, matched by prefix like PriceTable.
+type LimitTable map[string]ModelLimits
+
+// DefaultLimits is a catalog of the published token limits of common models, checked
+// with DefaultPrices.
+var DefaultLimits = LimitTable{
+	// Google Gemini
+	"gemini-3-pro":     {ContextTokens: 1048576, OutputTokens: 65536},
+	"gemini-3-flash":   {ContextTokens: 1048576, OutputTokens: 65536},
+	"gemini-2.5-pro":   {ContextTokens: 1048576, OutputTokens: 65536},
+	"gemini-2.5-flash": {ContextTokens: 1048576, OutputTokens: 65536},
+	"gemini-2.0-flash": {ContextTokens: 1048576, OutputTokens: 8192},
+
+	// OpenAI
+	"gpt-4o":  {ContextTokens: 128000, OutputTokens: 16384},
+	"gpt-4.1": {ContextTokens: 1047576, OutputTokens: 32768},
+	"o3":      {ContextTokens: 200000, OutputTokens: 100000},
+	"o4-mini": {ContextTokens: 200000, OutputTokens: 100000},
+
+	// Anthropic
+	"claude-opus-4":     {ContextTokens: 200000, OutputTokens: 32000},
+	"claude-sonnet-4":   {ContextTokens: 200000, OutputTokens: 64000},
+	"claude-3-5-haiku":  {ContextTokens: 200000, OutputTokens: 8192},
+	"claude-haiku-4-5":  {ContextTokens: 200000, OutputTokens: 64000},
+	"claude-sonnet-4-5": {ContextTokens: 200000, OutputTokens: 64000},
+}
+
+// Lookup returns the limits of model: an exact entry, or else the entry with the
+// longest key that prefixes model.
+func (t LimitTable) Lookup(model string) (ModelLimits, bool) {
+	return lookupModel(t, model)
+}
+
+// With returns a copy of t with the entries of overrides added or replaced.
+func (t LimitTable) With(overrides LimitTable) LimitTable {
+	merged := make(LimitTable, len(t)+len(overrides))
+	for k, v := range t {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		merged[k] = v
+	}
+	return merged
+}
+
+// CheckMaxTokens returns an error wrapping ErrMaxTokensExceeded if maxTokens is above
+// the output limit of model, whose limits are given. Models with an unknown output
+// limit accept any value, and so does zero, which leaves the limit to the model.
+func CheckMaxTokens(model string, limits ModelLimits, maxTokens int) error {
+	if limits.OutputTokens <= 0 || maxTokens <= limits.OutputTokens {
+		return nil
+	}
+	if model == "" {
+		model = "the model"
+	}
+	return fmt.Errorf("%w: max_tokens is %d but %s generates at most %d tokens", ErrMaxTokensExceeded, maxTokens, model, limits.OutputTokens)
+}
+
+// CheckRules returns an error if rules are invalid or provider can't serve them as
+// given: an unknown ResponseFormat, FormatEnum with no enum in OutputSchema, an invalid
+// Policy pattern, or a MaxTokens above the output limit the provider reports in its
+// Caps. A nil provider skips the limit check; stores check rules that way in
+// CreateSession. Call it with the provider before Store.CreateSession to also reject a
+// MaxTokens the provider can't serve; providers check again at Send.
+func CheckRules(provider Provider, rules Rules) error {
+	if err := CheckResponseFormat(rules.ResponseFormat); err != nil {
+		return err
+	}
+	if rules.ResponseFormat == FormatEnum && rules.Schema == nil {
+		if _, err := EnumValues(rules.OutputSchema); err != nil {
+			return err
+		}
+	}
+	if _, err := rules.Policy.Compile(); err != nil {
+		return err
+	}
+	if provider == nil {
+		return nil
+	}
+	caps, _ := CapabilitiesOf(provider)
+	return CheckMaxTokens("", ModelLimits{ContextTokens: caps.MaxContextTokens, OutputTokens: caps.MaxOutputTokens}, rules.MaxTokens)
+}
+
+// DefaultTokenMargin is the share of the estimated input added by DeriveMaxTokens to
+// allow for EstimateTokens undercounting.
+const DefaultTokenMargin = 0.2
+
+// EstimateInputTokens estimates the input size of a request: the system instruction,
+// output schema, history turns and prompt. Events in history are skipped.
+func EstimateInputTokens(rules Rules, history []Message, prompt string) int {
+	a := EstimatePromptAttribution(rules, history, prompt)
+	return a.SystemTokens + a.HistoryTokens + a.PromptTokens
+}
+
+// DeriveMaxTokens returns the response token limit for a request of inputTokens: the
+// model's context minus the input grown by margin (a fraction, e.g. 0.2), capped at
+// the model's output limit and at requested when that is positive. It fails with
+// ErrContextExceeded when the input leaves no room. A model with unknown limits gets
+// requested unchanged.
+func DeriveMaxTokens(limits ModelLimits, inputTokens int, margin float64, requested int) (int, error) {
+	if limits.ContextTokens <= 0 {
+		return requested, nil
+	}
+
+	reserved := inputTokens + int(float64(inputTokens)*margin+0.5)
+	room := limits.ContextTokens - reserved
+	if room <= 0 {
+		return 0, fmt.Errorf("%w: about %d input tokens of %d", ErrContextExceeded, reserved, limits.ContextTokens)
+	}
+	if limits.OutputTokens > 0 && room > limits.OutputTokens {
+		room = limits.OutputTokens
+	}
+	if requested > 0 && room > requested {
+		room = requested
+	}
+	return room, nil
+}
@@ -0,0 +1,236 @@
+package chatimport
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// DefaultAnthropicRoleMap returns the role map of a new Anthropic decoder. "human" is
+// the user's role in Claude data exports. Tool use and tool result blocks aren't
+// mapped; they become RoleToolCall and RoleTool messages.
+func DefaultAnthropicRoleMap() ai.RoleMap {
+	return ai.RoleMap{
+		"user":      ai.RoleUser,
+		"human":     ai.RoleUser,
+		"assistant": ai.RoleAssistant,
+	}
+}
+
+// NewAnthropicDecoder returns a Decoder of Anthropic exports. It reads both the
+// conversations.json of a Claude data export and Messages API records: a request body
+// with the response's fields merged in, or nested under "response".
+//
+// The request's system prompt becomes the session's. Thinking, image and document
+// blocks are skipped. The response's usage is set on its message, with cache reads
+// and writes counted in PromptTokens as the other providers count them.
+func NewAnthropicDecoder(r io.Reader) *Decoder {
+	return newDecoder(r, "anthropic", DefaultAnthropicRoleMap(), convertAnthropic)
+}
+
+func convertAnthropic(d *Decoder, raw json.RawMessage) (*ai.SessionExport, error) {
+	var probe struct {
+		ChatMessages json.RawMessage `json:"chat_messages"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, err
+	}
+	if probe.ChatMessages != nil {
+		var c claudeConversation
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, err
+		}
+		return d.claude(c)
+	}
+	var rec messagesRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, err
+	}
+	return d.messages(rec)
+}
+
+type claudeConversation struct {
+	UUID         string          `json:"uuid"`
+	Name         string          `json:"name"`
+	CreatedAt    time.Time       `json:"created_at"`
+	ChatMessages []claudeMessage `json:"chat_messages"`
+}
+
+type claudeMessage struct {
+	UUID      string           `json:"uuid"`
+	Sender    string           `json:"sender"`
+	Text      string           `json:"text"`
+	Content   []anthropicBlock `json:"content"`
+	CreatedAt time.Time        `json:"created_at"`
+}
+
+// claude converts a conversation of a Claude data export.
+func (d *Decoder) claude(c claudeConversation) (*ai.SessionExport, error) {
+	names := map[string]string{}
+	var msgs []ai.Message
+	for _, m := range c.ChatMessages {
+		blocks := m.Content
+		if len(blocks) == 0 {
+			blocks = []anthropicBlock{{Type: "text", Text: m.Text}}
+		}
+		converted, err := d.blocks(m.Sender, blocks, names, m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+		if len(converted) > 0 {
+			converted[0].ID = m.UUID
+		}
+		msgs = append(msgs, converted...)
+	}
+
+	return export(c.UUID, Origin{Source: SourceClaude, ConversationID: c.UUID, Title: c.Name}, c.CreatedAt, "", msgs)
+}
+
+type messagesRecord struct {
+	ID       string             `json:"id"`
+	Model    string             `json:"model"`
+	System   json.RawMessage    `json:"system"`
+	Messages []anthropicMessage `json:"messages"`
+	Content  []anthropicBlock   `json:"content"`
+	Usage    *anthropicUsage    `json:"usage"`
+	Response *messagesRecord    `json:"response"`
+}
+
+type anthropicMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type anthropicBlock struct {
+	Type      string          `json:"type"`
+	Text      string          `json:"text"`
+	ID        string          `json:"id"`
+	Name      string          `json:"name"`
+	Input     json.RawMessage `json:"input"`
+	ToolUseID string          `json:"tool_use_id"`
+	Content   json.RawMessage `json:"content"`
+	IsError   bool            `json:"is_error"`
+}
+
+type anthropicUsage struct {
+	InputTokens              int `json:"input_tokens"`
+	OutputTokens             int `json:"output_tokens"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens"`
+}
+
+// usage converts u. Anthropic's input tokens exclude cache reads and writes.
+func (u anthropicUsage) usage(model string) *ai.Usage {
+	prompt := u.InputTokens + u.CacheCreationInputTokens + u.CacheReadInputTokens
+	return &ai.Usage{
+		PromptTokens:   prompt,
+		ResponseTokens: u.OutputTokens,
+		TotalTokens:    prompt + u.OutputTokens,
+		CachedTokens:   u.CacheReadInputTokens,
+		Model:          model,
+	}
+}
+
+// messages converts a Messages API request and its response.
+func (d *Decoder) messages(rec messagesRecord) (*ai.SessionExport, error) {
+	if r := rec.Response; r != nil {
+		rec.ID, rec.Content, rec.Usage = r.ID, r.Content, r.Usage
+		if r.Model != "" {
+			rec.Model = r.Model
+		}
+	}
+	system, err := contentText(rec.System)
+	if err != nil {
+		return nil, err
+	}
+
+	names := map[string]string{}
+	var msgs []ai.Message
+	for _, m := range rec.Messages {
+		var blocks []anthropicBlock
+		var text string
+		if err := json.Unmarshal(m.Content, &text); err == nil {
+			blocks = []anthropicBlock{{Type: "text", Text: text}}
+		} else if err := json.Unmarshal(m.Content, &blocks); err != nil {
+			return nil, err
+		}
+		converted, err := d.blocks(m.Role, blocks, names, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, converted...)
+	}
+	if len(rec.Content) > 0 {
+		converted, err := d.blocks("assistant", rec.Content, names, time.Time{})
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, converted...)
+		if rec.Usage != nil {
+			withUsage(msgs, rec.Usage.usage(rec.Model))
+		}
+	}
+
+	return export(rec.ID, Origin{Source: SourceAnthropic, ConversationID: rec.ID}, time.Time{}, system, msgs)
+}
+
+// blocks converts the content blocks of one turn: tool results first, as they answer
+// the previous turn, then the text, then tool calls. names maps the IDs of tool calls
+// seen so far to their tools.
+func (d *Decoder) blocks(role string, blocks []anthropicBlock, names map[string]string, created time.Time) ([]ai.Message, error) {
+	var msgs []ai.Message
+	var texts []string
+	var calls []ai.ToolCall
+	for _, b := range blocks {
+		switch b.Type {
+		case "text":
+			if b.Text != "" {
+				texts = append(texts, b.Text)
+			}
+		case "tool_use":
+			args, err := toolArgs(b.ID, b.Input)
+			if err != nil {
+				return nil, err
+			}
+			calls = append(calls, ai.ToolCall{ID: b.ID, Name: b.Name, Args: args})
+			names[b.ID] = b.Name
+		case "tool_result":
+			text, err := contentText(b.Content)
+			if err != nil {
+				return nil, err
+			}
+			result := ai.ToolResult{CallID: b.ToolUseID, Name: names[b.ToolUseID]}
+			if b.IsError {
+				result.Error = text
+			} else {
+				result.Response = toolResponse(text)
+			}
+			msg, err := ai.ToolResultMessage(result)
+			if err != nil {
+				return nil, err
+			}
+			msg.CreatedAt = created
+			msgs = append(msgs, msg)
+		}
+	}
+
+	if len(texts) > 0 {
+		r, err := d.role(role)
+		if err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, ai.Message{Role: r, Content: strings.Join(texts, "\n"), CreatedAt: created})
+	}
+	if len(calls) > 0 {
+		msg, err := ai.ToolCallMessage(calls)
+		if err != nil {
+			return nil, err
+		}
+		msg.CreatedAt = created
+		msgs = append(msgs, msg)
+	}
+	return msgs, nil
+}
@@ -0,0 +1,241 @@
+// Package chatimport converts conversation exports of other chat platforms into
+// sessions, so teams migrating to this package keep their history. A Decoder is an
+// ai.SessionSource; feed it to postgres.ImportSessions.
+package chatimport
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// EventImported is the event type of the first message of an imported session, whose
+// content is its Origin as JSON.
+const EventImported = "session_imported"
+
+// Sources of imported sessions.
+const (
+	SourceChatGPT   = "chatgpt"   // conversations.json of a ChatGPT data export
+	SourceOpenAI    = "openai"    // Chat Completions records
+	SourceClaude    = "claude"    // conversations.json of a Claude data export
+	SourceAnthropic = "anthropic" // Messages API records
+)
+
+// Origin records where an imported session came from.
+type Origin struct {
+	Source         string `json:"source"`
+	ConversationID string `json:"conversation_id,omitempty"`
+	Title          string `json:"title,omitempty"`
+}
+
+// Decoder is an ai.SessionSource converting the conversations of an export, given as
+// a JSON array or as one JSON object per line. Session and message IDs of the export
+// are kept where it has them, so importing the same export twice fails instead of
+// duplicating history.
+type Decoder struct {
+	r       *bufio.Reader
+	dec     *json.Decoder
+	name    string
+	roles   ai.RoleMap
+	convert func(d *Decoder, raw json.RawMessage) (*ai.SessionExport, error)
+	started bool
+	array   bool
+	n       int
+}
+
+func newDecoder(r io.Reader, name string, roles ai.RoleMap, convert func(*Decoder, json.RawMessage) (*ai.SessionExport, error)) *Decoder {
+	return &Decoder{r: bufio.NewReader(r), name: name, roles: roles, convert: convert}
+}
+
+// WithRoleMap adds the entries of roles, which map roles of the export to message
+// roles, to the decoder's map, replacing those of the same role. Roles not in the map
+// fail the conversation with an error wrapping ai.ErrUnknownRole.
+func (d *Decoder) WithRoleMap(roles ai.RoleMap) *Decoder {
+	d.roles = d.roles.Merge(roles)
+	return d
+}
+
+// Next converts the next conversation, or returns io.EOF at the end of the input.
+// Conversations without messages are skipped.
+func (d *Decoder) Next() (*ai.SessionExport, error) {
+	if !d.started {
+		d.started = true
+		if err := d.open(); err != nil {
+			return nil, err
+		}
+	}
+
+	for {
+		if d.array && !d.dec.More() {
+			return nil, io.EOF
+		}
+		var raw json.RawMessage
+		if err := d.dec.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil, io.EOF
+			}
+			return nil, fmt.Errorf("ai: decode %s export: %w", d.name, err)
+		}
+		d.n++
+
+		e, err := d.convert(d, raw)
+		if err != nil {
+			return nil, fmt.Errorf("ai: convert %s conversation %d: %w", d.name, d.n, err)
+		}
+		if e != nil {
+			return e, nil
+		}
+	}
+}
+
+// open skips a byte order mark and, if the input is an array, its opening bracket.
+func (d *Decoder) open() error {
+	if bom, _ := d.r.Peek(3); bytes.Equal(bom, []byte("\xef\xbb\xbf")) {
+		d.r.Discard(3)
+	}
+	d.dec = json.NewDecoder(d.r)
+	for {
+		b, err := d.r.Peek(1)
+		if err != nil {
+			return nil // empty input; Decode reports io.EOF
+		}
+		switch b[0] {
+		case ' ', '\t', '\r', '\n':
+			d.r.Discard(1)
+			continue
+		case '[':
+			d.array = true
+			if _, err := d.dec.Token(); err != nil {
+				return fmt.Errorf("ai: decode %s export: %w", d.name, err)
+			}
+		}
+		return nil
+	}
+}
+
+// role returns the message role of a role of the export.
+func (d *Decoder) role(role string) (string, error) {
+	if r, ok := d.roles[role]; ok {
+		return r, nil
+	}
+	return "", fmt.Errorf("%w %q", ai.ErrUnknownRole, role)
+}
+
+// export assembles a session from converted messages. Leading system and developer
+// messages are moved to the system prompt, after system, and the Origin event is
+// prepended. Messages without a timestamp take the one before them, keeping the
+// order by time. It returns nil if no messages are left.
+func export(sessionID string, origin Origin, created time.Time, system string, msgs []ai.Message) (*ai.SessionExport, error) {
+	prompts := []string{}
+	if system != "" {
+		prompts = append(prompts, system)
+	}
+	for len(msgs) > 0 && (msgs[0].Role == ai.RoleSystem || msgs[0].Role == ai.RoleDeveloper) {
+		if msgs[0].Content != "" {
+			prompts = append(prompts, msgs[0].Content)
+		}
+		msgs = msgs[1:]
+	}
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	if created.IsZero() {
+		created = msgs[0].CreatedAt
+	}
+	payload, err := json.Marshal(origin)
+	if err != nil {
+		return nil, err
+	}
+	e := &ai.SessionExport{
+		Session:  ai.Session{ID: sessionID, Rules: ai.Rules{SystemPrompt: strings.Join(prompts, "\n\n")}, CreatedAt: created},
+		Messages: make([]ai.Message, 0, len(msgs)+1),
+	}
+	e.Messages = append(e.Messages, ai.Message{Role: ai.RoleEvent, EventType: EventImported, Content: string(payload), CreatedAt: created})
+	e.Messages = append(e.Messages, msgs...)
+	for i := range e.Messages {
+		m := &e.Messages[i]
+		m.SessionID, m.Seq = sessionID, i+1
+		if m.CreatedAt.IsZero() && i > 0 {
+			m.CreatedAt = e.Messages[i-1].CreatedAt
+		}
+		m.UpdatedAt = m.CreatedAt
+	}
+	return e, nil
+}
+
+// contentText returns the text of content that is a string or an array of parts, as
+// both OpenAI and Anthropic send it. Parts other than text, e.g. images, are skipped.
+func contentText(content json.RawMessage) (string, error) {
+	if len(content) == 0 || string(content) == "null" {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(content, &s); err == nil {
+		return s, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(content, &parts); err != nil {
+		return "", errors.New("content must be a string or an array of parts")
+	}
+	var texts []string
+	for _, p := range parts {
+		if p.Type == "text" && p.Text != "" {
+			texts = append(texts, p.Text)
+		}
+	}
+	return strings.Join(texts, "\n"), nil
+}
+
+// toolArgs decodes the JSON object of a tool call's arguments.
+func toolArgs(call string, args []byte) (map[string]any, error) {
+	out := map[string]any{}
+	if len(bytes.TrimSpace(args)) == 0 {
+		return out, nil
+	}
+	if err := json.Unmarshal(args, &out); err != nil {
+		return nil, fmt.Errorf("tool call %s: arguments are not a JSON object: %w", call, err)
+	}
+	return out, nil
+}
+
+// toolResponse returns the response of a tool result: its JSON, or the text if it
+// isn't JSON.
+func toolResponse(text string) any {
+	if json.Valid([]byte(text)) {
+		return json.RawMessage(text)
+	}
+	return text
+}
+
+// unixTime converts fractional Unix seconds; zero stays the zero time.
+func unixTime(sec float64) time.Time {
+	if sec == 0 {
+		return time.Time{}
+	}
+	whole, frac := math.Modf(sec)
+	return time.Unix(int64(whole), int64(frac*1e9)).UTC()
+}
+
+// withUsage sets the usage of the last message, the response the usage was reported
+// for, if it is a model turn.
+func withUsage(msgs []ai.Message, u *ai.Usage) {
+	if u == nil || len(msgs) == 0 {
+		return
+	}
+	last := &msgs[len(msgs)-1]
+	if last.Role == ai.RoleAssistant || last.Role == ai.RoleToolCall {
+		last.Usage = u
+	}
+}
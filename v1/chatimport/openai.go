@@ -0,0 +1,264 @@
+package chatimport
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// DefaultOpenAIRoleMap returns the role map of a new OpenAI decoder. Tool calls and
+// tool messages aren't mapped; they become RoleToolCall and RoleTool messages.
+func DefaultOpenAIRoleMap() ai.RoleMap {
+	return ai.RoleMap{
+		"user":      ai.RoleUser,
+		"assistant": ai.RoleAssistant,
+		"system":    ai.RoleSystem,
+		"developer": ai.RoleDeveloper,
+	}
+}
+
+// NewOpenAIDecoder returns a Decoder of OpenAI exports. It reads both the
+// conversations.json of a ChatGPT data export and Chat Completions records: a request
+// body with the response's fields merged in, or nested under "response", as
+// stored-completion exports and request loggers write them.
+//
+// ChatGPT conversations follow the branch that was shown last; browsing, code
+// interpreter and other tool steps are skipped, and the model of each answer is kept
+// without token counts, which the export lacks. Chat Completions records keep tool
+// calls, and the response's usage is set on its message.
+func NewOpenAIDecoder(r io.Reader) *Decoder {
+	return newDecoder(r, "openai", DefaultOpenAIRoleMap(), convertOpenAI)
+}
+
+func convertOpenAI(d *Decoder, raw json.RawMessage) (*ai.SessionExport, error) {
+	var probe struct {
+		Mapping json.RawMessage `json:"mapping"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return nil, err
+	}
+	if probe.Mapping != nil {
+		var c chatGPTConversation
+		if err := json.Unmarshal(raw, &c); err != nil {
+			return nil, err
+		}
+		return d.chatGPT(c)
+	}
+	var rec completionRecord
+	if err := json.Unmarshal(raw, &rec); err != nil {
+		return nil, err
+	}
+	return d.completion(rec)
+}
+
+type chatGPTConversation struct {
+	ID             string                 `json:"id"`
+	ConversationID string                 `json:"conversation_id"`
+	Title          string                 `json:"title"`
+	CreateTime     float64                `json:"create_time"`
+	Mapping        map[string]chatGPTNode `json:"mapping"`
+	CurrentNode    string                 `json:"current_node"`
+}
+
+type chatGPTNode struct {
+	Message *chatGPTMessage `json:"message"`
+	Parent  string          `json:"parent"`
+}
+
+type chatGPTMessage struct {
+	ID     string `json:"id"`
+	Author struct {
+		Role string `json:"role"`
+	} `json:"author"`
+	CreateTime float64 `json:"create_time"`
+	Content    struct {
+		ContentType string            `json:"content_type"`
+		Parts       []json.RawMessage `json:"parts"`
+	} `json:"content"`
+	Recipient string `json:"recipient"`
+	Metadata  struct {
+		ModelSlug string `json:"model_slug"`
+		Hidden    bool   `json:"is_visually_hidden_from_conversation"`
+	} `json:"metadata"`
+}
+
+// chatGPT converts the branch of c ending at its current node.
+func (d *Decoder) chatGPT(c chatGPTConversation) (*ai.SessionExport, error) {
+	id := c.ConversationID
+	if id == "" {
+		id = c.ID
+	}
+	if c.CurrentNode == "" && len(c.Mapping) > 0 {
+		return nil, errors.New("conversation has no current_node")
+	}
+
+	var branch []*chatGPTMessage
+	for node, steps := c.CurrentNode, 0; node != ""; steps++ {
+		n, ok := c.Mapping[node]
+		if !ok || steps > len(c.Mapping) {
+			return nil, errors.New("conversation mapping is not a tree")
+		}
+		if n.Message != nil {
+			branch = append(branch, n.Message)
+		}
+		node = n.Parent
+	}
+
+	var msgs []ai.Message
+	for i := len(branch) - 1; i >= 0; i-- {
+		m := branch[i]
+		if m.Metadata.Hidden || m.Author.Role == "tool" || (m.Recipient != "" && m.Recipient != "all") {
+			continue
+		}
+		if ct := m.Content.ContentType; ct != "text" && ct != "multimodal_text" {
+			continue
+		}
+		var texts []string
+		for _, part := range m.Content.Parts {
+			var s string
+			if json.Unmarshal(part, &s) == nil && s != "" {
+				texts = append(texts, s)
+			}
+		}
+		if len(texts) == 0 {
+			continue
+		}
+
+		role, err := d.role(m.Author.Role)
+		if err != nil {
+			return nil, err
+		}
+		msg := ai.Message{ID: m.ID, Role: role, Content: strings.Join(texts, "\n"), CreatedAt: unixTime(m.CreateTime)}
+		if role == ai.RoleAssistant && m.Metadata.ModelSlug != "" {
+			msg.Usage = &ai.Usage{Model: m.Metadata.ModelSlug}
+		}
+		msgs = append(msgs, msg)
+	}
+
+	return export(id, Origin{Source: SourceChatGPT, ConversationID: id, Title: c.Title}, unixTime(c.CreateTime), "", msgs)
+}
+
+type completionRecord struct {
+	ID       string          `json:"id"`
+	Created  int64           `json:"created"`
+	Model    string          `json:"model"`
+	Messages []openAIMessage `json:"messages"`
+	Choices  []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage    *openAIUsage      `json:"usage"`
+	Response *completionRecord `json:"response"`
+}
+
+type openAIMessage struct {
+	Role      string          `json:"role"`
+	Content   json.RawMessage `json:"content"`
+	ToolCalls []struct {
+		ID       string `json:"id"`
+		Function struct {
+			Name      string `json:"name"`
+			Arguments string `json:"arguments"`
+		} `json:"function"`
+	} `json:"tool_calls"`
+	ToolCallID string `json:"tool_call_id"`
+}
+
+type openAIUsage struct {
+	PromptTokens        int `json:"prompt_tokens"`
+	CompletionTokens    int `json:"completion_tokens"`
+	TotalTokens         int `json:"total_tokens"`
+	PromptTokensDetails struct {
+		CachedTokens int `json:"cached_tokens"`
+		AudioTokens  int `json:"audio_tokens"`
+	} `json:"prompt_tokens_details"`
+	CompletionTokensDetails struct {
+		ReasoningTokens int `json:"reasoning_tokens"`
+		AudioTokens     int `json:"audio_tokens"`
+	} `json:"completion_tokens_details"`
+}
+
+// usage converts u. Reasoning tokens are part of OpenAI's completion tokens but are
+// counted apart, as ThoughtTokens, here.
+func (u openAIUsage) usage(model string) *ai.Usage {
+	reasoning := u.CompletionTokensDetails.ReasoningTokens
+	return &ai.Usage{
+		PromptTokens:   u.PromptTokens,
+		ResponseTokens: u.CompletionTokens - reasoning,
+		TotalTokens:    u.TotalTokens,
+		ThoughtTokens:  reasoning,
+		CachedTokens:   u.PromptTokensDetails.CachedTokens,
+		AudioTokens:    u.PromptTokensDetails.AudioTokens + u.CompletionTokensDetails.AudioTokens,
+		Model:          model,
+	}
+}
+
+// completion converts a Chat Completions request and its response.
+func (d *Decoder) completion(rec completionRecord) (*ai.SessionExport, error) {
+	if r := rec.Response; r != nil {
+		rec.ID, rec.Created, rec.Choices, rec.Usage = r.ID, r.Created, r.Choices, r.Usage
+		if r.Model != "" {
+			rec.Model = r.Model
+		}
+	}
+	var created time.Time
+	if rec.Created > 0 {
+		created = time.Unix(rec.Created, 0).UTC()
+	}
+
+	turns := rec.Messages
+	if len(rec.Choices) > 0 {
+		turns = append(turns, rec.Choices[0].Message)
+	}
+	names := map[string]string{} // tool call ID to function name
+	var msgs []ai.Message
+	for _, m := range turns {
+		text, err := contentText(m.Content)
+		if err != nil {
+			return nil, err
+		}
+
+		if m.Role == "tool" {
+			msg, err := ai.ToolResultMessage(ai.ToolResult{CallID: m.ToolCallID, Name: names[m.ToolCallID], Response: toolResponse(text)})
+			if err != nil {
+				return nil, err
+			}
+			msg.CreatedAt = created
+			msgs = append(msgs, msg)
+			continue
+		}
+
+		role, err := d.role(m.Role)
+		if err != nil {
+			return nil, err
+		}
+		if text != "" || len(m.ToolCalls) == 0 {
+			msgs = append(msgs, ai.Message{Role: role, Content: text, CreatedAt: created})
+		}
+		if len(m.ToolCalls) > 0 {
+			calls := make([]ai.ToolCall, len(m.ToolCalls))
+			for i, tc := range m.ToolCalls {
+				args, err := toolArgs(tc.ID, []byte(tc.Function.Arguments))
+				if err != nil {
+					return nil, err
+				}
+				calls[i] = ai.ToolCall{ID: tc.ID, Name: tc.Function.Name, Args: args}
+				names[tc.ID] = tc.Function.Name
+			}
+			msg, err := ai.ToolCallMessage(calls)
+			if err != nil {
+				return nil, err
+			}
+			msg.CreatedAt = created
+			msgs = append(msgs, msg)
+		}
+	}
+	if len(rec.Choices) > 0 && rec.Usage != nil {
+		withUsage(msgs, rec.Usage.usage(rec.Model))
+	}
+
+	return export(rec.ID, Origin{Source: SourceOpenAI, ConversationID: rec.ID}, created, "", msgs)
+}
@@ -0,0 +1,34 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrNoIncompleteStream = errors.New("ai: no incomplete stream")
+)
+
+// Checkpoint is the partial content of a streamed response, saved periodically so a
+// crash mid-generation doesn't lose everything.
+type Checkpoint struct {
+	ID           string    `json:"id"`
+	SessionID    string    `json:"session_id"`
+	RequestLogID string    `json:"request_log_id"`
+	Prompt       string    `json:"prompt"`
+	Content      string    `json:"content"`
+	Complete     bool      `json:"complete"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// CheckpointStore persists stream checkpoints.
+type CheckpointStore interface {
+	// SaveCheckpoint inserts or updates the checkpoint with cp.ID.
+	SaveCheckpoint(ctx context.Context, cp Checkpoint) error
+	// LatestIncompleteCheckpoint returns the newest incomplete checkpoint of a session,
+	// or ErrNoIncompleteStream.
+	LatestIncompleteCheckpoint(ctx context.Context, sessionID string) (*Checkpoint, error)
+	CompleteCheckpoint(ctx context.Context, id string) error
+}
@@ -0,0 +1,44 @@
+package ai
+
+import (
+	"context"
+)
+
+// Citation is a source a response drew on, from grounding or retrieval metadata.
+type Citation struct {
+	MessageID string `json:"message_id,omitempty"` // assistant message cited, when listed from a store
+	URI       string `json:"uri"`
+	Title     string `json:"title,omitempty"`
+	Text      string `json:"text,omitempty"`  // part of the response the source supports
+	Start     int    `json:"start,omitempty"` // byte offsets of Text in the raw response
+	End       int    `json:"end,omitempty"`
+	License   string `json:"license,omitempty"`
+}
+
+// CitationStore is the optional store capability keeping citations linked to the
+// assistant messages they support.
+type CitationStore interface {
+	// AddCitations appends citations to a message. It returns ErrMessageNotFound for
+	// an unknown message.
+	AddCitations(ctx context.Context, messageID string, citations []Citation) error
+	// ListCitations returns the citations of a message in the order they were added.
+	ListCitations(ctx context.Context, messageID string) ([]Citation, error)
+	// ListSessionCitations returns the citations of every message of a session, in
+	// message order, with MessageID set.
+	ListSessionCitations(ctx context.Context, sessionID string) ([]Citation, error)
+}
+
+// AddResultMessage stores result's content as an assistant message of sessionID and,
+// if the store is a CitationStore, links result's citations to it.
+func AddResultMessage(ctx context.Context, store Store, sessionID string, result *Result) (*Message, error) {
+	msg, err := store.AddMessage(ctx, sessionID, RoleAssistant, result.Content, &result.Usage)
+	if err != nil {
+		return nil, err
+	}
+	if cs, ok := store.(CitationStore); ok && len(result.Citations) > 0 {
+		if err := cs.AddCitations(ctx, msg.ID, result.Citations); err != nil {
+			return msg, err
+		}
+	}
+	return msg, nil
+}
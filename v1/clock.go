@@ -0,0 +1,9 @@
+package ai
+
+import "time"
+
+// Clock returns the current time. Stores and providers accept one so tests can pin time.
+type Clock func() time.Time
+
+// IDGenerator returns a new unique ID.
+type IDGenerator func() string
@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+)
+
+// CloneOptions selects what CloneSession copies. The zero value copies the rules and
+// every message.
+type CloneOptions struct {
+	// Rules replaces the source session's rules in the clone; nil copies them.
+	Rules *Rules
+
+	// FromSeq and ToSeq bound the copied messages, inclusive. Zero leaves that end open.
+	FromSeq, ToSeq int
+
+	// Roles copies only messages with these roles, e.g. RoleUser and RoleAssistant to
+	// leave out events and tool calls. Empty copies every role.
+	Roles []string
+
+	// KeepDocument also copies the source's latest assistant message, the current
+	// document of a generate-and-refine conversation, when the selection leaves it out.
+	KeepDocument bool
+}
+
+// Select returns the messages of msgs, ordered by seq, that o copies.
+func (o CloneOptions) Select(msgs []Message) []Message {
+	roles := make(map[string]bool, len(o.Roles))
+	for _, r := range o.Roles {
+		roles[r] = true
+	}
+
+	latest := -1
+	if o.KeepDocument {
+		for i, m := range msgs {
+			if m.Role == RoleAssistant {
+				latest = i
+			}
+		}
+	}
+
+	var out []Message
+	for i, m := range msgs {
+		selected := (o.FromSeq == 0 || m.Seq >= o.FromSeq) &&
+			(o.ToSeq == 0 || m.Seq <= o.ToSeq) &&
+			(len(roles) == 0 || roles[m.Role])
+		if selected || i == latest {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+// SessionCloner is the optional store capability copying sessions server-side.
+type SessionCloner interface {
+	// CloneSession creates a session with the rules and selected messages of
+	// sessionID, renumbered from seq 1, in one transaction. Returns ErrSessionNotFound
+	// if the source doesn't exist.
+	CloneSession(ctx context.Context, sessionID string, opts CloneOptions) (*Session, error)
+}
+
+// CloneSession copies a session with store's CloneSession when it implements
+// SessionCloner. Otherwise it reads the source and writes the clone with CreateSession
+// and AddMessages, deleting the clone again if its messages can't be added.
+func CloneSession(ctx context.Context, store Store, sessionID string, opts CloneOptions) (*Session, error) {
+	if c, ok := store.(SessionCloner); ok {
+		return c.CloneSession(ctx, sessionID, opts)
+	}
+
+	source, err := store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := store.ListMessages(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	rules := source.Rules
+	if opts.Rules != nil {
+		rules = *opts.Rules
+	}
+	clone, err := store.CreateSession(ctx, rules)
+	if err != nil {
+		return nil, err
+	}
+
+	selected := opts.Select(msgs)
+	if len(selected) == 0 {
+		return clone, nil
+	}
+	copies := make([]NewMessage, len(selected))
+	for i, m := range selected {
+		copies[i] = NewMessage{Role: m.Role, Content: m.Content, EventType: m.EventType, Usage: m.Usage}
+	}
+	if _, err := AddMessages(ctx, store, clone.ID, copies); err != nil {
+		DeleteSession(context.WithoutCancel(ctx), store, clone.ID)
+		return nil, fmt.Errorf("ai: clone session: %w", err)
+	}
+	return clone, nil
+}
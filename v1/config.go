@@ -1,6 +1,7 @@
 package ai
 
 import (
+	"encoding/json"
 	"os"
 	"strconv"
 )
@@ -12,6 +13,7 @@ type Config struct {
 	GeminiAPI   string
 	ModelID     string
 	MaxTokens   int
+	Prices      PriceTable // DefaultPrices with AI_PRICES overrides
 }
 
 // LoadConfig loads configuration from environment variables with sensible defaults.
@@ -21,6 +23,7 @@ func LoadConfig() Config {
 		GeminiAPI:   os.Getenv("GEMINI_API"),
 		ModelID:     os.Getenv("MODEL_ID"),
 		MaxTokens:   16384, // default
+		Prices:      DefaultPrices,
 	}
 
 	// Parse MAX_TOKENS if provided
@@ -30,5 +33,13 @@ func LoadConfig() Config {
 		}
 	}
 
+	// Parse AI_PRICES if provided: a JSON object of model → Price overrides
+	if p := os.Getenv("AI_PRICES"); p != "" {
+		var overrides PriceTable
+		if err := json.Unmarshal([]byte(p), &overrides); err == nil {
+			cfg.Prices = DefaultPrices.With(overrides)
+		}
+	}
+
 	return cfg
 }
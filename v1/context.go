@@ -0,0 +1,91 @@
+package ai
+
+import "context"
+
+// contextKey is the type of context keys defined by this package.
+type contextKey int
+
+const (
+	urlsKey contextKey = iota
+	toolsKey
+	sessionIDKey
+	actorKey
+	requestIDKey
+	dryRunKey
+	replayOfKey
+	systemAddendumKey
+	samplingConsentKey
+	sendOptionsKey
+)
+
+// Actor identifies who a request is made for: an external user and the tenant they
+// belong to. Stores stamp it on request logs and audit entries, so spend and activity
+// can be grouped per user and per tenant.
+type Actor struct {
+	UserID   string `json:"user_id,omitempty"`
+	TenantID string `json:"tenant_id,omitempty"`
+}
+
+// WithActor attaches the actor a request is made for, replacing any attached before.
+func WithActor(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, actorKey, actor)
+}
+
+// ActorFromContext returns the actor attached with WithActor, WithUserID or WithTenantID.
+func ActorFromContext(ctx context.Context) Actor {
+	a, _ := ctx.Value(actorKey).(Actor)
+	return a
+}
+
+// WithUserID attaches the external user a request is made for, keeping the attached
+// tenant. Equivalent to setting Actor.UserID.
+func WithUserID(ctx context.Context, userID string) context.Context {
+	a := ActorFromContext(ctx)
+	a.UserID = userID
+	return WithActor(ctx, a)
+}
+
+// UserIDFromContext returns the user ID of the attached actor.
+func UserIDFromContext(ctx context.Context) string {
+	return ActorFromContext(ctx).UserID
+}
+
+// WithTenantID attaches the tenant a request is made for, keeping the attached user.
+// Equivalent to setting Actor.TenantID.
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	a := ActorFromContext(ctx)
+	a.TenantID = tenantID
+	return WithActor(ctx, a)
+}
+
+// TenantIDFromContext returns the tenant ID of the attached actor.
+func TenantIDFromContext(ctx context.Context) string {
+	return ActorFromContext(ctx).TenantID
+}
+
+// WithSessionID attaches the session a request belongs to, so providers can log
+// requests made before the session has any history.
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey, sessionID)
+}
+
+// SessionIDFromContext returns the session ID attached with WithSessionID.
+// For compatibility it also accepts the legacy "session_id" string key.
+func SessionIDFromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(sessionIDKey).(string); ok {
+		return id
+	}
+	id, _ := ctx.Value("session_id").(string)
+	return id
+}
+
+// WithURLs attaches URLs to pass to the provider as context for requests made with ctx.
+func WithURLs(ctx context.Context, urls ...string) context.Context {
+	return context.WithValue(ctx, urlsKey, urls)
+}
+
+// URLsFromContext returns the URLs attached with WithURLs.
+func URLsFromContext(ctx context.Context) []string {
+	urls, _ := ctx.Value(urlsKey).([]string)
+	return urls
+}
@@ -0,0 +1,15 @@
+package ai
+
+import "context"
+
+// Creds are the provider credentials of one call.
+type Creds struct {
+	APIKey string // empty uses the provider's key
+	Model  string // empty uses the provider's model; SendOptions.Model still overrides it
+}
+
+// CredentialResolver returns the credentials of the call ctx belongs to, e.g. the API
+// key a tenant brought, looked up by TenantIDFromContext. Providers call it once per
+// Send or Stream, before checking quotas, so each key's usage is counted against its
+// own quota. An error fails the call.
+type CredentialResolver func(ctx context.Context) (Creds, error)
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// collapsedNotice replaces assistant messages collapsed by a HistoryDeduplicator.
+const collapsedNotice = "[Collapsed: this response was repeated later in the conversation.]"
+
+// CollapsedRange records a run of repeated assistant messages collapsed into the last
+// one. Assistant messages with seq from FromSeq up to, but not including, ToSeq were
+// replaced when sending; ToSeq was kept.
+type CollapsedRange struct {
+	SessionID string    `json:"session_id"`
+	FromSeq   int       `json:"from_seq"`
+	ToSeq     int       `json:"to_seq"`
+	Count     int       `json:"count"` // assistant messages collapsed, excluding ToSeq
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// CollapseStore is the optional store capability recording collapsed ranges.
+type CollapseStore interface {
+	// MarkCollapsed records r. A range with the same session and FromSeq is replaced, so
+	// a run that grows is stored once.
+	MarkCollapsed(ctx context.Context, r CollapsedRange) error
+	// ListCollapsed returns a session's collapsed ranges ordered by FromSeq.
+	ListCollapsed(ctx context.Context, sessionID string) ([]CollapsedRange, error)
+}
+
+// HistoryDeduplicator collapses consecutive assistant responses that repeat each other,
+// e.g. after users regenerate and get the same form back, so the provider isn't billed
+// for the same payload several times. The last response of a run is kept and the
+// earlier ones are replaced by a short notice, which preserves turn order.
+type HistoryDeduplicator struct {
+	// Similarity is the minimum word overlap (Jaccard, 0-1) for two responses to count
+	// as repeats. Zero or 1 only collapses responses identical up to case and whitespace.
+	Similarity float64
+
+	// Store, when set, records each collapsed range. Failures are ignored.
+	Store CollapseStore
+
+	now Clock
+}
+
+// NewHistoryDeduplicator creates a deduplicator with the given similarity threshold.
+func NewHistoryDeduplicator(similarity float64) *HistoryDeduplicator {
+	return &HistoryDeduplicator{Similarity: similarity}
+}
+
+// WithStore records collapsed ranges in store.
+func (d *HistoryDeduplicator) WithStore(store CollapseStore) *HistoryDeduplicator {
+	d.Store = store
+	return d
+}
+
+// WithClock sets the clock used for CollapsedRange.UpdatedAt.
+func (d *HistoryDeduplicator) WithClock(clock Clock) *HistoryDeduplicator {
+	d.now = clock
+	return d
+}
+
+// Transform returns a copy of history with repeated assistant responses collapsed.
+// Assistant messages separated only by other roles count as consecutive.
+func (d *HistoryDeduplicator) Transform(ctx context.Context, history []Message) ([]Message, error) {
+	out := make([]Message, len(history))
+	copy(out, history)
+
+	var ranges []CollapsedRange
+	prev := -1  // index of the previous assistant message
+	start := -1 // index of the first assistant message in the current run
+	for i, msg := range history {
+		if msg.Role != RoleAssistant {
+			continue
+		}
+		if prev >= 0 && d.repeats(history[prev].Content, msg.Content) {
+			if start < 0 {
+				start = prev
+			}
+			out[prev].Content = collapsedNotice
+		} else {
+			ranges = d.appendRange(ranges, history, start, prev)
+			start = -1
+		}
+		prev = i
+	}
+	ranges = d.appendRange(ranges, history, start, prev)
+
+	if d.Store != nil {
+		for _, r := range ranges {
+			d.Store.MarkCollapsed(context.WithoutCancel(ctx), r)
+		}
+	}
+
+	return out, nil
+}
+
+// appendRange adds the run from history[start] to history[end] to ranges, if there is one.
+func (d *HistoryDeduplicator) appendRange(ranges []CollapsedRange, history []Message, start, end int) []CollapsedRange {
+	if start < 0 || history[end].SessionID == "" {
+		return ranges
+	}
+	count := 0
+	for _, msg := range history[start:end] {
+		if msg.Role == RoleAssistant {
+			count++
+		}
+	}
+	now := d.now
+	if now == nil {
+		now = time.Now
+	}
+	return append(ranges, CollapsedRange{
+		SessionID: history[end].SessionID,
+		FromSeq:   history[start].Seq,
+		ToSeq:     history[end].Seq,
+		Count:     count,
+		UpdatedAt: now(),
+	})
+}
+
+// repeats reports whether b repeats a under the similarity threshold.
+func (d *HistoryDeduplicator) repeats(a, b string) bool {
+	wa, wb := strings.Fields(strings.ToLower(a)), strings.Fields(strings.ToLower(b))
+	if d.Similarity <= 0 || d.Similarity >= 1 {
+		return strings.Join(wa, " ") == strings.Join(wb, " ")
+	}
+	return jaccard(wa, wb) >= d.Similarity
+}
+
+// jaccard returns the overlap of two word sets: |a ∩ b| / |a ∪ b|.
+func jaccard(a, b []string) float64 {
+	set := make(map[string]int, len(a))
+	for _, w := range a {
+		set[w] |= 1
+	}
+	for _, w := range b {
+		set[w] |= 2
+	}
+	if len(set) == 0 {
+		return 1
+	}
+	both := 0
+	for _, v := range set {
+		if v == 3 {
+			both++
+		}
+	}
+	return float64(both) / float64(len(set))
+}
@@ -0,0 +1,35 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrMessageNotFound = errors.New("ai: message not found")
+)
+
+// MessageEdit is the content a message held before one edit, kept so edited and
+// regenerated messages have an audit trail.
+type MessageEdit struct {
+	ID        string    `json:"id"`
+	MessageID string    `json:"message_id"`
+	SessionID string    `json:"session_id"`
+	Seq       int       `json:"seq"`
+	Content   string    `json:"content"`              // content before the edit
+	EditedBy  string    `json:"edited_by,omitempty"`  // user ID of the ai.Actor that edited it
+	RequestID string    `json:"request_id,omitempty"` // request the edit was made in
+	EditedAt  time.Time `json:"edited_at"`
+}
+
+// MessageEditor is the optional store capability for changing stored messages while
+// keeping their previous contents.
+type MessageEditor interface {
+	// EditMessage replaces the content of a message, records the previous content as a
+	// MessageEdit and sets UpdatedAt. Usage and cost are kept. Returns ErrMessageNotFound
+	// if the message doesn't exist.
+	EditMessage(ctx context.Context, messageID string, content string) (*Message, error)
+	// ListMessageEdits returns the edit history of a message, oldest first.
+	ListMessageEdits(ctx context.Context, messageID string) ([]MessageEdit, error)
+}
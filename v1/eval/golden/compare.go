@@ -0,0 +1,273 @@
+package golden
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Tolerance relaxes the comparison of the values at a path. When several tolerances
+// match a path, the last one applies.
+type Tolerance struct {
+	// Path is a JSON Pointer to the values it applies to, in which a "*" segment
+	// matches any key or index, e.g. "/questions/*/explanation". "" is the whole
+	// output, including plain-text outputs.
+	Path string `json:"path"`
+
+	Ignore        bool    `json:"ignore,omitempty"`         // skip the value entirely
+	Delta         float64 `json:"delta,omitempty"`          // numbers may differ by up to Delta
+	MinSimilarity float64 `json:"min_similarity,omitempty"` // strings need this word overlap, 0 to 1
+	Unordered     bool    `json:"unordered,omitempty"`      // arrays match in any order
+}
+
+// Diff is one difference between a golden and an output.
+type Diff struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+	Want   string `json:"want,omitempty"` // JSON of the golden value
+	Got    string `json:"got,omitempty"`  // JSON of the output value
+}
+
+func (d Diff) String() string {
+	path := d.Path
+	if path == "" {
+		path = "/"
+	}
+	s := path + ": " + d.Reason
+	if d.Want != "" || d.Got != "" {
+		s += fmt.Sprintf(" (want %s, got %s)", orNone(d.Want), orNone(d.Got))
+	}
+	return s
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "nothing"
+	}
+	return s
+}
+
+// Compare diffs got against want under tolerances. When both parse as JSON they are
+// compared structurally; otherwise they are compared as text, and only tolerances at
+// path "" apply.
+func Compare(want, got string, tolerances []Tolerance) []Diff {
+	c := comparer{tolerances: tolerances}
+
+	var w, g any
+	if json.Unmarshal([]byte(want), &w) == nil && json.Unmarshal([]byte(got), &g) == nil {
+		c.compare("", w, g)
+		return c.diffs
+	}
+	c.compare("", strings.TrimSpace(want), strings.TrimSpace(got))
+	return c.diffs
+}
+
+type comparer struct {
+	tolerances []Tolerance
+	diffs      []Diff
+}
+
+// tolerance returns the tolerance for path, or the zero Tolerance for exact matching.
+func (c *comparer) tolerance(path string) Tolerance {
+	var out Tolerance
+	for _, t := range c.tolerances {
+		if matchPath(t.Path, path) {
+			out = t
+		}
+	}
+	return out
+}
+
+func (c *comparer) diff(path, reason string, want, got any) {
+	d := Diff{Path: path, Reason: reason}
+	if want != nil {
+		d.Want = encode(want)
+	}
+	if got != nil {
+		d.Got = encode(got)
+	}
+	c.diffs = append(c.diffs, d)
+}
+
+func (c *comparer) compare(path string, want, got any) {
+	t := c.tolerance(path)
+	if t.Ignore {
+		return
+	}
+
+	switch w := want.(type) {
+	case map[string]any:
+		g, ok := got.(map[string]any)
+		if !ok {
+			c.diff(path, "type changed", want, got)
+			return
+		}
+		keys := make([]string, 0, len(w)+len(g))
+		for k := range w {
+			keys = append(keys, k)
+		}
+		for k := range g {
+			if _, ok := w[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			sub := path + "/" + escape(k)
+			wv, inWant := w[k]
+			gv, inGot := g[k]
+			switch {
+			case !inGot:
+				if !c.tolerance(sub).Ignore {
+					c.diff(sub, "missing", wv, nil)
+				}
+			case !inWant:
+				if !c.tolerance(sub).Ignore {
+					c.diff(sub, "unexpected", nil, gv)
+				}
+			default:
+				c.compare(sub, wv, gv)
+			}
+		}
+
+	case []any:
+		g, ok := got.([]any)
+		if !ok {
+			c.diff(path, "type changed", want, got)
+			return
+		}
+		if t.Unordered {
+			c.compareUnordered(path, w, g)
+			return
+		}
+		for i := range max(len(w), len(g)) {
+			sub := path + "/" + strconv.Itoa(i)
+			switch {
+			case i >= len(g):
+				c.diff(sub, "missing", w[i], nil)
+			case i >= len(w):
+				c.diff(sub, "unexpected", nil, g[i])
+			default:
+				c.compare(sub, w[i], g[i])
+			}
+		}
+
+	case float64:
+		g, ok := got.(float64)
+		if !ok {
+			c.diff(path, "type changed", want, got)
+			return
+		}
+		if math.Abs(w-g) > t.Delta {
+			c.diff(path, "changed", want, got)
+		}
+
+	case string:
+		g, ok := got.(string)
+		if !ok {
+			c.diff(path, "type changed", want, got)
+			return
+		}
+		if t.MinSimilarity > 0 {
+			if sim := similarity(w, g); sim < t.MinSimilarity {
+				c.diff(path, fmt.Sprintf("similarity %.2f below %.2f", sim, t.MinSimilarity), want, got)
+			}
+			return
+		}
+		if w != g {
+			c.diff(path, "changed", want, got)
+		}
+
+	default: // bool or null
+		if want != got {
+			c.diff(path, "changed", want, got)
+		}
+	}
+}
+
+// compareUnordered pairs every golden element with an output element it matches
+// exactly under the tolerances, in any order.
+func (c *comparer) compareUnordered(path string, want, got []any) {
+	used := make([]bool, len(got))
+	for i, w := range want {
+		sub := path + "/" + strconv.Itoa(i)
+		found := false
+		for j, g := range got {
+			if used[j] {
+				continue
+			}
+			probe := comparer{tolerances: c.tolerances}
+			if probe.compare(sub, w, g); len(probe.diffs) == 0 {
+				used[j], found = true, true
+				break
+			}
+		}
+		if !found {
+			c.diff(sub, "no matching element", w, nil)
+		}
+	}
+	for j, g := range got {
+		if !used[j] {
+			c.diff(path+"/"+strconv.Itoa(j), "unexpected", nil, g)
+		}
+	}
+}
+
+// matchPath reports whether path matches pattern, segment by segment.
+func matchPath(pattern, path string) bool {
+	if pattern == path {
+		return true
+	}
+	ps, ss := strings.Split(pattern, "/"), strings.Split(path, "/")
+	if len(ps) != len(ss) {
+		return false
+	}
+	for i := range ps {
+		if ps[i] != "*" && ps[i] != ss[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// escape encodes a key as a JSON Pointer segment.
+func escape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+func encode(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprint(v)
+	}
+	return string(b)
+}
+
+// similarity is the Jaccard similarity of the lower-cased words of a and b.
+func similarity(a, b string) float64 {
+	wa, wb := words(a), words(b)
+	if len(wa) == 0 && len(wb) == 0 {
+		return 1
+	}
+	shared := 0
+	for w := range wa {
+		if wb[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(wa)+len(wb)-shared)
+}
+
+func words(s string) map[string]bool {
+	out := make(map[string]bool)
+	for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		out[w] = true
+	}
+	return out
+}
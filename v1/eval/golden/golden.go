@@ -0,0 +1,197 @@
+// Package golden replays a fixed set of recorded prompts through a provider and diffs
+// the outputs against stored goldens, so prompt and model changes show their drift
+// before they ship. Suites run as Go tests:
+//
+//	var update = flag.Bool("update", false, "rewrite goldens")
+//
+//	func TestPrompts(t *testing.T) {
+//		suite, err := golden.Load("testdata/golden")
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		suite.Update = *update
+//		golden.Check(t, provider, suite)
+//	}
+package golden
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// CasesFile is the file of a suite directory listing its cases.
+const CasesFile = "cases.json"
+
+var (
+	ErrNoGolden = errors.New("ai: golden: no golden recorded")
+)
+
+// Case is one recorded prompt. Its golden is stored in the suite directory as
+// <Name>.golden.
+type Case struct {
+	Name    string       `json:"name"`
+	Rules   ai.Rules     `json:"rules"`
+	History []ai.Message `json:"history,omitempty"`
+	Prompt  string       `json:"prompt"`
+
+	// Tolerances relax the comparison for this case, after the suite's.
+	Tolerances []Tolerance `json:"tolerances,omitempty"`
+}
+
+// Suite is a set of cases and the directory holding their goldens.
+type Suite struct {
+	Dir        string      `json:"-"`
+	Cases      []Case      `json:"cases"`
+	Tolerances []Tolerance `json:"tolerances,omitempty"` // apply to every case
+
+	// Update rewrites the goldens with the current outputs instead of comparing.
+	Update bool `json:"-"`
+}
+
+// Load reads the suite in dir from its CasesFile.
+func Load(dir string) (*Suite, error) {
+	data, err := os.ReadFile(filepath.Join(dir, CasesFile))
+	if err != nil {
+		return nil, fmt.Errorf("ai: golden: load suite: %w", err)
+	}
+	var s Suite
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil, fmt.Errorf("ai: golden: parse %s: %w", filepath.Join(dir, CasesFile), err)
+	}
+	seen := make(map[string]bool, len(s.Cases))
+	for _, c := range s.Cases {
+		if c.Name == "" || strings.ContainsAny(c.Name, `/\`) {
+			return nil, fmt.Errorf("ai: golden: invalid case name %q", c.Name)
+		}
+		if seen[c.Name] {
+			return nil, fmt.Errorf("ai: golden: duplicate case %q", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	s.Dir = dir
+	return &s, nil
+}
+
+// Drift is the outcome of one case.
+type Drift struct {
+	Case   string   `json:"case"`
+	Output string   `json:"output,omitempty"`
+	Diffs  []Diff   `json:"diffs,omitempty"`
+	Err    error    `json:"-"` // the call failed or there is no golden
+	Usage  ai.Usage `json:"usage"`
+}
+
+// Drifted reports whether the case failed or its output differs from the golden.
+func (d Drift) Drifted() bool {
+	return d.Err != nil || len(d.Diffs) > 0
+}
+
+// Report is the outcome of a suite run.
+type Report struct {
+	Cases []Drift  `json:"cases"`
+	Usage ai.Usage `json:"usage"` // summed over every case
+}
+
+// Drifted returns the cases that failed or differ from their golden.
+func (r *Report) Drifted() []Drift {
+	var out []Drift
+	for _, d := range r.Cases {
+		if d.Drifted() {
+			out = append(out, d)
+		}
+	}
+	return out
+}
+
+// WriteTo writes a summary of the drifted cases to w, one diff per line.
+func (r *Report) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	drifted := r.Drifted()
+	fmt.Fprintf(&b, "%d of %d cases drifted\n", len(drifted), len(r.Cases))
+	for _, d := range drifted {
+		if d.Err != nil {
+			fmt.Fprintf(&b, "%s: %v\n", d.Case, d.Err)
+			continue
+		}
+		for _, diff := range d.Diffs {
+			fmt.Fprintf(&b, "%s: %s\n", d.Case, diff)
+		}
+	}
+	n, err := w.Write(b.Bytes())
+	return int64(n), err
+}
+
+// Run sends every case of s to provider and compares each output with its golden. With
+// s.Update set, goldens are rewritten instead and no diffs are reported. It returns an
+// error only when a golden can't be read or written; failed calls are reported per case.
+func Run(ctx context.Context, provider ai.Provider, s *Suite) (*Report, error) {
+	report := &Report{}
+	for _, c := range s.Cases {
+		d, err := s.run(ctx, provider, c)
+		if err != nil {
+			return report, err
+		}
+		report.Usage.Add(d.Usage)
+		report.Cases = append(report.Cases, d)
+	}
+	return report, nil
+}
+
+// Check runs s as subtests of t, one per case, failing those that drift.
+func Check(t *testing.T, provider ai.Provider, s *Suite) {
+	t.Helper()
+	for _, c := range s.Cases {
+		t.Run(c.Name, func(t *testing.T) {
+			d, err := s.run(t.Context(), provider, c)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if d.Err != nil {
+				t.Fatal(d.Err)
+			}
+			for _, diff := range d.Diffs {
+				t.Error(diff)
+			}
+		})
+	}
+}
+
+// run sends c and compares or updates its golden.
+func (s *Suite) run(ctx context.Context, provider ai.Provider, c Case) (Drift, error) {
+	d := Drift{Case: c.Name}
+	result, err := provider.Send(ctx, c.Rules, c.History, c.Prompt)
+	if err != nil {
+		d.Err = err
+		return d, nil
+	}
+	d.Output, d.Usage = result.Content, result.Usage
+
+	path := filepath.Join(s.Dir, c.Name+".golden")
+	if s.Update {
+		if err := os.WriteFile(path, []byte(result.Content), 0o644); err != nil {
+			return d, fmt.Errorf("ai: golden: write %s: %w", path, err)
+		}
+		return d, nil
+	}
+
+	want, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		d.Err = fmt.Errorf("%w for %s", ErrNoGolden, c.Name)
+		return d, nil
+	}
+	if err != nil {
+		return d, fmt.Errorf("ai: golden: read %s: %w", path, err)
+	}
+	d.Diffs = Compare(string(want), result.Content, append(s.Tolerances[:len(s.Tolerances):len(s.Tolerances)], c.Tolerances...))
+	return d, nil
+}
@@ -0,0 +1,70 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// SessionExport is a session with all its messages, the unit of export and import.
+type SessionExport struct {
+	Session  Session   `json:"session"`
+	Messages []Message `json:"messages"`
+}
+
+// SessionSource yields sessions to import. Next returns io.EOF when there are no more.
+type SessionSource interface {
+	Next() (*SessionExport, error)
+}
+
+// ImportProgress reports how much of an import has been committed.
+type ImportProgress struct {
+	Sessions int `json:"sessions"`
+	Messages int `json:"messages"`
+}
+
+// ExportSession reads a session and its messages from store.
+func ExportSession(ctx context.Context, store Store, sessionID string) (*SessionExport, error) {
+	session, err := store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := store.ListMessages(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	return &SessionExport{Session: *session, Messages: msgs}, nil
+}
+
+// WriteSessionExport writes e to w as one line of JSON. A stream of such lines can be
+// read back with NewSessionDecoder.
+func WriteSessionExport(w io.Writer, e *SessionExport) error {
+	if err := json.NewEncoder(w).Encode(e); err != nil {
+		return fmt.Errorf("ai: write session export: %w", err)
+	}
+	return nil
+}
+
+// SessionDecoder is a SessionSource reading JSON session exports, one per line, such as
+// those written by WriteSessionExport.
+type SessionDecoder struct {
+	dec *json.Decoder
+}
+
+// NewSessionDecoder creates a SessionDecoder reading from r.
+func NewSessionDecoder(r io.Reader) *SessionDecoder {
+	return &SessionDecoder{dec: json.NewDecoder(r)}
+}
+
+// Next decodes the next session, or returns io.EOF at the end of the input.
+func (d *SessionDecoder) Next() (*SessionExport, error) {
+	var e SessionExport
+	if err := d.dec.Decode(&e); err != nil {
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		return nil, fmt.Errorf("ai: decode session export: %w", err)
+	}
+	return &e, nil
+}
@@ -0,0 +1,217 @@
+package ai
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// Response formats for Rules.ResponseFormat. The empty format is JSON.
+const (
+	FormatJSON     = "json"
+	FormatText     = "text"
+	FormatMarkdown = "markdown"
+	FormatYAML     = "yaml"
+	FormatCSV      = "csv"
+	FormatXML      = "xml"
+
+	// FormatEnum asks for exactly one of the strings listed in the "enum" of
+	// Rules.OutputSchema, e.g. {"type": "string", "enum": ["easy", "medium", "hard"]}.
+	FormatEnum = "enum"
+)
+
+// formatNames are the display names used in instructions and corrections.
+var formatNames = map[string]string{
+	FormatJSON:     "JSON",
+	FormatText:     "plain text",
+	FormatMarkdown: "Markdown",
+	FormatYAML:     "YAML",
+	FormatCSV:      "CSV",
+	FormatXML:      "XML",
+	FormatEnum:     "enum value",
+}
+
+// codeFence matches a response wrapped entirely in one fenced code block.
+var codeFence = regexp.MustCompile("(?s)^```([A-Za-z0-9_-]*)[ \t]*\r?\n(.*?)\r?\n?```$")
+
+// yamlTopLevel matches the unindented lines a YAML document may contain: mapping keys,
+// sequence items, comments and document markers.
+var yamlTopLevel = regexp.MustCompile(`^(#|-( |$)|---|\.\.\.|("[^"]*"|'[^']*'|[^\s#:][^:#]*):( |$))`)
+
+// IsJSONFormat reports whether format selects JSON output.
+func IsJSONFormat(format string) bool {
+	return format == "" || format == FormatJSON
+}
+
+// CheckResponseFormat returns an error if format isn't one of the Format constants.
+func CheckResponseFormat(format string) error {
+	if format == "" {
+		return nil
+	}
+	if _, ok := formatNames[format]; !ok {
+		return fmt.Errorf("ai: unknown response format %q", format)
+	}
+	return nil
+}
+
+// FormatInstruction returns the system instruction describing format, or "" for JSON,
+// which providers request through their structured output mode instead.
+func FormatInstruction(format string) string {
+	switch format {
+	case "", FormatJSON:
+		return ""
+	case FormatText:
+		return "Respond in plain text without Markdown formatting."
+	case FormatMarkdown:
+		return "Respond in Markdown. Do not wrap the whole response in a code block."
+	case FormatCSV:
+		return "Respond with only a CSV document with a header row, without code fences or commentary."
+	case FormatEnum:
+		return "Respond with exactly one of the allowed values, without quotes or commentary."
+	default:
+		return fmt.Sprintf("Respond with only a %s document, without code fences or commentary.", formatNames[format])
+	}
+}
+
+// FormatResponse validates and cleans up a non-JSON response: a code fence wrapping the
+// whole response is removed and surrounding whitespace trimmed. It returns a
+// *ValidationError when content isn't a valid document of the format. JSON content
+// is returned unchanged; providers check it themselves.
+func FormatResponse(format string, content string) (string, error) {
+	if IsJSONFormat(format) {
+		return content, nil
+	}
+
+	content = strings.TrimSpace(content)
+	if m := codeFence.FindStringSubmatch(content); m != nil && (format != FormatMarkdown || m[1] == "markdown" || m[1] == "md") {
+		content = strings.TrimSpace(m[2])
+	}
+
+	var err error
+	switch {
+	case content == "":
+		err = errors.New("empty response")
+	case format == FormatYAML:
+		err = checkYAML(content)
+	case format == FormatCSV:
+		err = checkCSV(content)
+	case format == FormatXML:
+		err = checkXML(content)
+	}
+	if err != nil {
+		name := formatNames[format]
+		return "", &ValidationError{
+			Reason:     FailReasonInvalidFormat,
+			Message:    fmt.Sprintf("%s validation failed: %v", name, err),
+			Correction: fmt.Sprintf("Your previous response was not valid %s (%v). Please regenerate the complete response as %s only, without code fences or commentary.", name, err, name),
+		}
+	}
+	return content, nil
+}
+
+// EnumValues returns the allowed values of an enum schema, the strings in its top-level
+// "enum" array.
+func EnumValues(schema string) ([]string, error) {
+	var s struct {
+		Enum []string `json:"enum"`
+	}
+	if err := json.Unmarshal([]byte(schema), &s); err != nil {
+		return nil, fmt.Errorf("ai: enum schema: %w", err)
+	}
+	if len(s.Enum) == 0 {
+		return nil, errors.New(`ai: enum schema has no "enum" values`)
+	}
+	return s.Enum, nil
+}
+
+// CheckEnum returns content as one of the values of the enum schema, with surrounding
+// whitespace and quotes removed, or a *ValidationError if it is none of them. Run it
+// after FormatResponse.
+func CheckEnum(schema string, content string) (string, error) {
+	values, err := EnumValues(schema)
+	if err != nil {
+		return "", err
+	}
+	content = strings.Trim(strings.TrimSpace(content), `"`)
+	for _, v := range values {
+		if content == v {
+			return content, nil
+		}
+	}
+
+	allowed := strings.Join(values, ", ")
+	return "", &ValidationError{
+		Reason:     FailReasonInvalidFormat,
+		Message:    fmt.Sprintf("enum validation failed: %q is not one of %s", truncateRunes(content, 80), allowed),
+		Correction: fmt.Sprintf("Your previous response was not one of the allowed values. Please respond with exactly one of: %s.", allowed),
+	}
+}
+
+// checkYAML is a structural check, not a parser: indentation must use spaces and every
+// unindented line must be a key, sequence item, comment or document marker. It catches
+// prose and truncated commentary rather than every YAML syntax error.
+func checkYAML(content string) error {
+	for i, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]; strings.Contains(indent, "\t") {
+			return fmt.Errorf("line %d is indented with tabs", i+1)
+		}
+		if line[0] != ' ' && !yamlTopLevel.MatchString(line) {
+			return fmt.Errorf("line %d is not a key or list item", i+1)
+		}
+	}
+	return nil
+}
+
+// checkCSV requires every record to have as many fields as the header.
+func checkCSV(content string) error {
+	records, err := csv.NewReader(strings.NewReader(content)).ReadAll()
+	if err != nil {
+		return err
+	}
+	if len(records) < 2 {
+		return errors.New("no rows after the header")
+	}
+	return nil
+}
+
+// checkXML requires a single well-formed root element.
+func checkXML(content string) error {
+	dec := xml.NewDecoder(bytes.NewReader([]byte(content)))
+	depth, roots := 0, 0
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if depth == 0 {
+				roots++
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			if depth == 0 && len(bytes.TrimSpace(t)) > 0 {
+				return errors.New("text outside the root element")
+			}
+		}
+	}
+	if roots != 1 {
+		return fmt.Errorf("%d root elements", roots)
+	}
+	return nil
+}
@@ -0,0 +1,34 @@
+package gemini
+
+import (
+	"context"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// WithPayloadCapture stores the exact request and response bodies of every API call in
+// store, linked to the request log, so provider-side issues can be reproduced. redact,
+// if set, is applied to both bodies before they are stored. Requires WithStore.
+func (g *GeminiProvider) WithPayloadCapture(store ai.PayloadStore, redact func([]byte) []byte) *GeminiProvider {
+	g.payloads = store
+	g.redact = redact
+	return g
+}
+
+// capture stores one exchange when payload capture is enabled. Errors are ignored, like
+// request log errors: debugging aids must not fail the request.
+func (g *GeminiProvider) capture(ctx context.Context, opts callOptions, statusCode int, req, resp []byte) {
+	if g.payloads == nil || opts.logID == "" {
+		return
+	}
+	if g.redact != nil {
+		req, resp = g.redact(req), g.redact(resp)
+	}
+	g.payloads.SavePayload(context.WithoutCancel(ctx), ai.Payload{
+		RequestLogID: opts.logID,
+		Attempt:      opts.attempt,
+		StatusCode:   statusCode,
+		Request:      req,
+		Response:     resp,
+	})
+}
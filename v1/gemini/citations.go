@@ -0,0 +1,90 @@
+package gemini
+
+import (
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// geminiGroundingMetadata is the grounding of a candidate in retrieved sources, e.g.
+// pages read by the url_context tool or search results.
+type geminiGroundingMetadata struct {
+	GroundingChunks []struct {
+		Web              *geminiSource `json:"web,omitempty"`
+		RetrievedContext *geminiSource `json:"retrievedContext,omitempty"`
+	} `json:"groundingChunks"`
+	GroundingSupports []struct {
+		Segment struct {
+			StartIndex int    `json:"startIndex"`
+			EndIndex   int    `json:"endIndex"`
+			Text       string `json:"text"`
+		} `json:"segment"`
+		GroundingChunkIndices []int `json:"groundingChunkIndices"`
+	} `json:"groundingSupports"`
+}
+
+type geminiSource struct {
+	URI   string `json:"uri"`
+	Title string `json:"title"`
+}
+
+// geminiCitationMetadata lists sources a candidate recites from.
+type geminiCitationMetadata struct {
+	CitationSources []struct {
+		StartIndex int    `json:"startIndex"`
+		EndIndex   int    `json:"endIndex"`
+		URI        string `json:"uri"`
+		Title      string `json:"title"`
+		License    string `json:"license"`
+	} `json:"citationSources"`
+}
+
+// citations converts the grounding and citation metadata of c. Every supported
+// segment yields a citation per source; sources no segment refers to are cited
+// without text.
+func citations(c geminiCandidate) []ai.Citation {
+	var out []ai.Citation
+
+	if g := c.GroundingMetadata; g != nil {
+		sources := make([]*geminiSource, len(g.GroundingChunks))
+		for i, chunk := range g.GroundingChunks {
+			sources[i] = chunk.Web
+			if sources[i] == nil {
+				sources[i] = chunk.RetrievedContext
+			}
+		}
+
+		used := make([]bool, len(sources))
+		for _, s := range g.GroundingSupports {
+			for _, i := range s.GroundingChunkIndices {
+				if i < 0 || i >= len(sources) || sources[i] == nil {
+					continue
+				}
+				used[i] = true
+				out = append(out, ai.Citation{
+					URI:   sources[i].URI,
+					Title: sources[i].Title,
+					Text:  s.Segment.Text,
+					Start: s.Segment.StartIndex,
+					End:   s.Segment.EndIndex,
+				})
+			}
+		}
+		for i, src := range sources {
+			if src != nil && !used[i] {
+				out = append(out, ai.Citation{URI: src.URI, Title: src.Title})
+			}
+		}
+	}
+
+	if m := c.CitationMetadata; m != nil {
+		for _, s := range m.CitationSources {
+			out = append(out, ai.Citation{
+				URI:     s.URI,
+				Title:   s.Title,
+				Start:   s.StartIndex,
+				End:     s.EndIndex,
+				License: s.License,
+			})
+		}
+	}
+	return out
+}
@@ -0,0 +1,58 @@
+package gemini
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"testing"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+func TestClassifyError(t *testing.T) {
+	apiErr := func(status int) error {
+		return &ai.APIError{StatusCode: status, Body: http.StatusText(status)}
+	}
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Post", URL: "https://generativelanguage.googleapis.com", Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want string
+	}{
+		{"cancelled", ai.ErrCancelled, ai.FailReasonCancelled},
+		{"context canceled", context.Canceled, ai.FailReasonCancelled},
+		{"url error wrapping context canceled", urlErr(context.Canceled), ai.FailReasonCancelled},
+		{"wrapped url error wrapping context canceled", fmt.Errorf("ai: send: %w", urlErr(context.Canceled)), ai.FailReasonCancelled},
+		{"deadline exceeded", context.DeadlineExceeded, ai.FailReasonTimeout},
+		{"url error wrapping deadline exceeded", urlErr(context.DeadlineExceeded), ai.FailReasonTimeout},
+		{"net timeout", &net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}, ai.FailReasonTimeout},
+		{"url error wrapping net timeout", urlErr(&net.OpError{Op: "read", Net: "tcp", Err: os.ErrDeadlineExceeded}), ai.FailReasonTimeout},
+		{"net error", &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, ai.FailReasonNetworkError},
+		{"url error wrapping dns error", urlErr(&net.DNSError{Err: "no such host", Name: "example.invalid"}), ai.FailReasonNetworkError},
+		{"api error 400", apiErr(http.StatusBadRequest), ai.FailReasonAPIError},
+		{"api error 403", apiErr(http.StatusForbidden), ai.FailReasonAPIError},
+		{"api error 429", apiErr(http.StatusTooManyRequests), ai.FailReasonAPIError},
+		{"api error 500", apiErr(http.StatusInternalServerError), ai.FailReasonAPIError},
+		{"api error 503", apiErr(http.StatusServiceUnavailable), ai.FailReasonAPIError},
+		{"wrapped api error", fmt.Errorf("ai: send: %w", apiErr(http.StatusBadGateway)), ai.FailReasonAPIError},
+		{"blocked", ai.ErrBlocked, ai.FailReasonSafetyBlocked},
+		{"wrapped blocked", fmt.Errorf("%w: prompt blocked for SAFETY", ai.ErrBlocked), ai.FailReasonSafetyBlocked},
+		{"rate limited before sending", &ai.RateLimitError{}, ai.FailReasonRateLimited},
+		{"rate limited by the api", &ai.RateLimitError{Err: apiErr(http.StatusTooManyRequests)}, ai.FailReasonRateLimited},
+		{"unknown", errors.New("something else"), ai.FailReasonUnknownError},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classifyError(tt.err); got != tt.want {
+				t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+			}
+		})
+	}
+}
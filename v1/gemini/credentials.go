@@ -0,0 +1,35 @@
+package gemini
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// WithCredentials resolves the API key and model of each call with resolver, e.g. to
+// call Gemini with a tenant's own key. Sessions, logs and the other stores stay shared.
+// Empty fields of the resolved Creds fall back to the provider's key and model.
+func (g *GeminiProvider) WithCredentials(resolver ai.CredentialResolver) *GeminiProvider {
+	g.creds = resolver
+	return g
+}
+
+// credentials returns the API key and model of the call ctx belongs to.
+func (g *GeminiProvider) credentials(ctx context.Context) (apiKey, model string, err error) {
+	apiKey, model = g.apiKey, g.modelID
+	if g.creds == nil {
+		return apiKey, model, nil
+	}
+	creds, err := g.creds(ctx)
+	if err != nil {
+		return "", "", fmt.Errorf("ai: resolve credentials: %w", err)
+	}
+	if creds.APIKey != "" {
+		apiKey = creds.APIKey
+	}
+	if creds.Model != "" {
+		model = creds.Model
+	}
+	return apiKey, model, nil
+}
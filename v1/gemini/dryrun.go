@@ -0,0 +1,52 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// WithPricing sets the model price used to estimate dry-run costs.
+func (g *GeminiProvider) WithPricing(price ai.Price) *GeminiProvider {
+	g.price = price
+	return g
+}
+
+// dryRun builds the request Send would make and estimates its cost. It fails on the
+// problems Send would silently work around, such as an output schema that isn't JSON.
+func (g *GeminiProvider) dryRun(ctx context.Context, rules ai.Rules, history []ai.Message, prompt string) (*ai.Result, error) {
+	if rules.OutputSchema != "" && !json.Valid([]byte(rules.OutputSchema)) {
+		return nil, fmt.Errorf("ai: dry run: output schema is not valid JSON")
+	}
+
+	call, err := g.prepare(ctx, rules, history, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	reqBody, err := g.buildRequest(call.rules, call.history, call.prompt, call.opts)
+	if err != nil {
+		return nil, err
+	}
+
+	payload, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("ai: marshal request: %w", err)
+	}
+
+	promptTokens := g.estimateInput(call.rules, call.history, call.prompt)
+
+	dry := &ai.DryRun{
+		Payload:         payload,
+		PromptTokens:    promptTokens,
+		MaxOutputTokens: call.rules.MaxTokens,
+	}
+	dry.EstimatedCost = g.price.Cost(ai.Usage{PromptTokens: promptTokens, ResponseTokens: dry.MaxOutputTokens})
+
+	return &ai.Result{
+		Usage:  ai.Usage{PromptTokens: promptTokens, TotalTokens: promptTokens},
+		DryRun: dry,
+	}, nil
+}
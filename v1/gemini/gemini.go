@@ -4,23 +4,96 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/meikuraledutech/ai/v1"
 )
 
-const baseURL = "https://generativelanguage.googleapis.com/v1beta/models"
-const maxAttempts = 2
+const defaultBaseURL = "https://generativelanguage.googleapis.com/v1beta/models"
+
+// Response MIME types of Gemini's structured output modes.
+const (
+	jsonMimeType = "application/json"
+	enumMimeType = "text/x.enum"
+)
 
 // GeminiProvider implements ai.Provider using the Gemini REST API.
 type GeminiProvider struct {
-	apiKey  string
-	modelID string
-	client  *http.Client
-	store   ai.Store
+	apiKey     string
+	modelID    string
+	baseURL    string
+	client     *http.Client
+	store      ai.Store
+	scanner    *ai.InjectionScanner
+	transforms []ai.HistoryTransform
+	quotas     ai.QuotaStore
+	quota      ai.Quota
+	budget     *ai.BudgetMonitor
+	prompts    ai.PromptStore
+	schemas    ai.SchemaStore
+	price      ai.Price
+	now        ai.Clock
+	newID      ai.IDGenerator
+
+	checkpoints     ai.CheckpointStore
+	checkpointBytes int
+
+	payloads ai.PayloadStore
+	redact   func([]byte) []byte
+
+	includeEvents bool
+	canonical     bool
+	urlFetcher    *ai.URLFetcher
+	memory        ai.MemoryStore
+	userMemory    ai.UserMemoryStore
+	memoryPolicy  ai.MemoryPolicy
+	glossary      ai.GlossaryStore
+	personas      ai.PersonaStore
+	defaults      ai.SessionDefaultsStore
+	named         map[string]ai.HistoryTransform // transforms SendOptions can name
+	sampler       *ai.Sampler
+
+	limits        *ai.ModelLimits // overrides ai.DefaultLimits
+	autoMaxTokens bool
+	tokenMargin   float64
+
+	stopOnComplete bool // end JSON streams at the document's closing bracket
+
+	jsonMimeType     string // responseMimeType of JSON requests; empty disables JSON mode
+	propertyOrdering bool   // add propertyOrdering to response schemas
+
+	inflight *ai.CancelRegistry // calls that Cancel can stop
+
+	speechModel string // Synthesize model; empty means DefaultSpeechModel
+
+	creds ai.CredentialResolver // per-call key and model; nil uses apiKey and modelID
+
+	limitsMu   sync.Mutex
+	rateLimits ai.RateLimits
+	limiters   map[string]*ai.RateLimiter // by KeyID, created on first use
+
+	roles       ai.RoleMap // nil means DefaultRoleMap
+	strictRoles bool       // reject roles not in the role map
+
+	retryPolicy RetryPolicy
+}
+
+// callOptions carries per-call request settings resolved by Send.
+type callOptions struct {
+	apiKey      string    // key the call is made with, from the CredentialResolver or the provider's
+	model       string    // model called, from SendOptions, the CredentialResolver or the provider's
+	temperature *float64  // from SendOptions; nil leaves Gemini's default
+	urlContext  bool      // enable Gemini's url_context tool
+	tools       []ai.Tool // function declarations
+	logID       string    // request log that captured payloads are linked to
+	attempt     int       // attempt number, from 1
 }
 
 // New creates a new GeminiProvider.
@@ -28,9 +101,50 @@ func New(apiKey, modelID string) *GeminiProvider {
 	return &GeminiProvider{
 		apiKey:  apiKey,
 		modelID: modelID,
+		baseURL: defaultBaseURL,
 		client:  &http.Client{},
 		store:   nil,
+		now:     time.Now,
+		newID:   ai.NewRequestID,
+
+		jsonMimeType: jsonMimeType,
+		inflight:     ai.NewCancelRegistry(),
+		retryPolicy:  DefaultRetryPolicy,
+	}
+}
+
+// WithClock sets the clock used for quota windows and memory expiry.
+func (g *GeminiProvider) WithClock(clock ai.Clock) *GeminiProvider {
+	g.now = clock
+	return g
+}
+
+// WithIDGenerator sets the generator used for request and checkpoint IDs.
+func (g *GeminiProvider) WithIDGenerator(gen ai.IDGenerator) *GeminiProvider {
+	g.newID = gen
+	return g
+}
+
+// requestID returns ctx with a request ID, generating one if none is attached.
+func (g *GeminiProvider) requestID(ctx context.Context) (context.Context, string) {
+	if id := ai.RequestIDFromContext(ctx); id != "" {
+		return ctx, id
 	}
+	id := g.newID()
+	return ai.WithRequestID(ctx, id), id
+}
+
+// WithHTTPClient sets the HTTP client used for API calls, e.g. to add a proxy,
+// timeouts or a recording transport in tests.
+func (g *GeminiProvider) WithHTTPClient(client *http.Client) *GeminiProvider {
+	g.client = client
+	return g
+}
+
+// WithBaseURL sets the models endpoint, e.g. a proxy or a mock server in tests.
+func (g *GeminiProvider) WithBaseURL(url string) *GeminiProvider {
+	g.baseURL = strings.TrimSuffix(url, "/")
+	return g
 }
 
 // WithStore configures request logging for this provider.
@@ -39,107 +153,383 @@ func (g *GeminiProvider) WithStore(store ai.Store) *GeminiProvider {
 	return g
 }
 
+// WithInjectionScanner scans user messages in history for prompt injection before each request.
+func (g *GeminiProvider) WithInjectionScanner(scanner *ai.InjectionScanner) *GeminiProvider {
+	g.scanner = scanner
+	return g
+}
+
+// WithHistoryTransforms applies transforms to the history of every request, in order,
+// after injection scanning. See ai.MessageTruncator.
+func (g *GeminiProvider) WithHistoryTransforms(transforms ...ai.HistoryTransform) *GeminiProvider {
+	g.transforms = append(g.transforms, transforms...)
+	return g
+}
+
+// WithNamedTransform registers a history transform that SendOptions.Transforms can
+// apply to individual calls or sessions by name.
+func (g *GeminiProvider) WithNamedTransform(name string, transform ai.HistoryTransform) *GeminiProvider {
+	if g.named == nil {
+		g.named = make(map[string]ai.HistoryTransform)
+	}
+	g.named[name] = transform
+	return g
+}
+
+// WithSessionDefaults applies the default SendOptions each session has in store to its
+// calls, under the options attached to the call's context.
+func (g *GeminiProvider) WithSessionDefaults(store ai.SessionDefaultsStore) *GeminiProvider {
+	g.defaults = store
+	return g
+}
+
+// WithQuota enforces a token quota for this provider's API key, tracked in store.
+// Send returns ai.ErrQuotaExceeded once the daily or monthly limit is reached.
+func (g *GeminiProvider) WithQuota(store ai.QuotaStore, quota ai.Quota) *GeminiProvider {
+	g.quotas = store
+	g.quota = quota
+	return g
+}
+
+// WithBudget checks monitor's spend thresholds after every request. Costs come from
+// the request logs, so the store needs prices (see postgres.PGStore.WithPrices).
+func (g *GeminiProvider) WithBudget(monitor *ai.BudgetMonitor) *GeminiProvider {
+	g.budget = monitor
+	return g
+}
+
+// WithPrompts resolves Rules.Prompt from the prompt library in store at Send time. The
+// rendered prompt goes ahead of Rules.SystemPrompt, and its name and version are recorded
+// on the request log.
+func (g *GeminiProvider) WithPrompts(store ai.PromptStore) *GeminiProvider {
+	g.prompts = store
+	return g
+}
+
+// WithSchemas resolves Rules.Schema from the schema registry in store at Send time. The
+// registry definition replaces Rules.OutputSchema, and its name and version are recorded
+// on the request log.
+func (g *GeminiProvider) WithSchemas(store ai.SchemaStore) *GeminiProvider {
+	g.schemas = store
+	return g
+}
+
+// checkBudget runs the budget monitor for a finished request. Like request logging,
+// failures never fail the request.
+func (g *GeminiProvider) checkBudget(ctx context.Context, sessionID string) {
+	if g.budget == nil {
+		return
+	}
+	g.budget.Check(context.WithoutCancel(ctx), sessionID, ai.TenantIDFromContext(ctx), g.now())
+}
+
+// attribute records the estimated prompt token split of an attempt on its request log
+// when the store supports it. Like request logging, failures are ignored.
+func (g *GeminiProvider) attribute(ctx context.Context, logID string, rules ai.Rules, history []ai.Message, prompt string, usage ai.Usage) {
+	store, ok := g.store.(ai.PromptAttributionStore)
+	if !ok || logID == "" {
+		return
+	}
+	if !g.includeEvents {
+		history = ai.ConversationHistory(history)
+	}
+	a := ai.EstimatePromptAttribution(rules, history, prompt).Scale(usage.PromptTokens)
+	store.SetPromptAttribution(context.WithoutCancel(ctx), logID, a)
+}
+
+// recordRetry records a correction exchange on the request log, when the store
+// supports it.
+func (g *GeminiProvider) recordRetry(ctx context.Context, logID string, turn ai.RetryTurn) {
+	store, ok := g.store.(ai.RetryTranscriptStore)
+	if !ok || logID == "" {
+		return
+	}
+	store.AddRetryTurn(context.WithoutCancel(ctx), logID, turn)
+}
+
+// WithCanonicalJSON rewrites accepted JSON responses with ai.CanonicalJSON before they
+// are returned and logged, so stored versions of a document differ only where its
+// structure does. A response that isn't valid JSON is then retried as invalid_json.
+func (g *GeminiProvider) WithCanonicalJSON(canonical bool) *GeminiProvider {
+	g.canonical = canonical
+	return g
+}
+
+// WithEventsInHistory includes system events (role "event") in the request history.
+// By default events are persisted but never sent to the model.
+func (g *GeminiProvider) WithEventsInHistory(include bool) *GeminiProvider {
+	g.includeEvents = include
+	return g
+}
+
+// WithURLFetcher fetches URLs attached with ai.WithURLs on the library side and inlines
+// their text into the prompt. Without a fetcher, URLs are passed to Gemini's url_context tool.
+func (g *GeminiProvider) WithURLFetcher(fetcher *ai.URLFetcher) *GeminiProvider {
+	g.urlFetcher = fetcher
+	return g
+}
+
+// WithSessionMemory injects the session's stored facts into the system instruction
+// of every request, so long sessions need less replayed history.
+func (g *GeminiProvider) WithSessionMemory(memory ai.MemoryStore) *GeminiProvider {
+	g.memory = memory
+	return g
+}
+
+// WithUserMemory injects memories of the user attached with ai.WithActor into the
+// system instruction, filtered by policy.
+func (g *GeminiProvider) WithUserMemory(memory ai.UserMemoryStore, policy ai.MemoryPolicy) *GeminiProvider {
+	g.userMemory = memory
+	g.memoryPolicy = policy
+	return g
+}
+
+// WithGlossary defines, in the system instruction, the glossary terms of the tenant
+// attached to ctx that appear in the prompt (see ai.ResolveGlossary), so domain
+// vocabulary means the same thing in every request.
+func (g *GeminiProvider) WithGlossary(store ai.GlossaryStore) *GeminiProvider {
+	g.glossary = store
+	return g
+}
+
+// WithPersonas merges the persona of the request's session (see
+// ai.PersonaStore.SetSessionPersona) into the system instruction, after the session's
+// own system prompt and before any per-request addenda.
+func (g *GeminiProvider) WithPersonas(store ai.PersonaStore) *GeminiProvider {
+	g.personas = store
+	return g
+}
+
+// WithSampler copies successful exchanges into the sampler, which keeps the consenting
+// share it samples for prompt review. Tool calls and failed responses are not sampled.
+func (g *GeminiProvider) WithSampler(sampler *ai.Sampler) *GeminiProvider {
+	g.sampler = sampler
+	return g
+}
+
+// WithModelLimits sets the model's token limits, for models ai.DefaultLimits doesn't
+// list or lists differently.
+func (g *GeminiProvider) WithModelLimits(limits ai.ModelLimits) *GeminiProvider {
+	g.limits = &limits
+	return g
+}
+
+// WithAutoMaxTokens derives each request's maxOutputTokens from the room left in the
+// model's context after the estimated input grown by margin (see ai.DeriveMaxTokens),
+// so long conversations aren't cut short by a static limit or rejected for exceeding
+// the model's maximum. Rules.MaxTokens, when set, stays an upper bound. Requests whose
+// input leaves no room fail with ai.ErrContextExceeded before they are sent.
+func (g *GeminiProvider) WithAutoMaxTokens(margin float64) *GeminiProvider {
+	g.autoMaxTokens = true
+	g.tokenMargin = margin
+	return g
+}
+
+// WithResponseMimeType sets the responseMimeType requested for JSON-format rules,
+// "application/json" by default. The empty string turns Gemini's JSON mode off: the
+// output schema is then described in the system instruction instead of enforced, and
+// responses are still validated as JSON. Rules with a non-JSON ResponseFormat, such as
+// ai.FormatText for prose, never use JSON mode.
+func (g *GeminiProvider) WithResponseMimeType(mime string) *GeminiProvider {
+	g.jsonMimeType = mime
+	return g
+}
+
+// WithPropertyOrdering adds Gemini's propertyOrdering to every object in the response
+// schema, listing the properties in the order Rules.OutputSchema writes them, so
+// responses follow the schema's order instead of the alphabetical default. Objects
+// that set propertyOrdering themselves are left alone.
+func (g *GeminiProvider) WithPropertyOrdering() *GeminiProvider {
+	g.propertyOrdering = true
+	return g
+}
+
+// modelLimits returns the limits of the provider's model.
+func (g *GeminiProvider) modelLimits() ai.ModelLimits {
+	return g.limitsOf(g.modelID)
+}
+
+// limitsOf returns the limits set with WithModelLimits for the provider's model, or
+// else those in ai.DefaultLimits, zero for models it doesn't list. A request may call
+// another model through credentials or send options.
+func (g *GeminiProvider) limitsOf(model string) ai.ModelLimits {
+	if g.limits != nil && model == g.modelID {
+		return *g.limits
+	}
+	limits, _ := ai.DefaultLimits.Lookup(model)
+	return limits
+}
+
+// Capabilities reports what Gemini supports through this provider.
+func (g *GeminiProvider) Capabilities() ai.Caps {
+	limits := g.modelLimits()
+	return ai.Caps{
+		Streaming:        true,
+		Tools:            true,
+		Schema:           true,
+		Multimodal:       true,
+		MaxContextTokens: limits.ContextTokens,
+		MaxOutputTokens:  limits.OutputTokens,
+	}
+}
+
 // Send calls the Gemini generateContent API with validation and auto-retry.
-// Validates JSON response by checking bracket matching. Auto-retries up to 2 times if validation fails.
+// Validates the response (JSON bracket matching, output policy). Failed calls and invalid responses are
+// retried according to the retry policy, two attempts by default (see WithRetry).
+// The request ID from ctx, or a generated one, is returned on the Result and on any *ai.RequestError.
 func (g *GeminiProvider) Send(ctx context.Context, rules ai.Rules, history []ai.Message, prompt string) (*ai.Result, error) {
-	if prompt == "" {
+	if prompt == "" && !endsWithToolResults(history) {
 		return nil, ai.ErrEmptyPrompt
 	}
 
-	// Extract sessionID from history or context for logging
-	sessionID := ""
-	if len(history) > 0 {
-		sessionID = history[0].SessionID
+	ctx, requestID := g.requestID(ctx)
+	ctx, release := g.inflight.Track(ctx, requestID)
+	defer release()
+
+	send := g.send
+	if ai.IsDryRun(ctx) {
+		send = g.dryRun
 	}
-	// Fallback: check context if history is empty (first message in session)
-	if sessionID == "" {
-		if ctxSessionID, ok := ctx.Value("session_id").(string); ok {
-			sessionID = ctxSessionID
-		}
+
+	result, err := send(ctx, rules, history, prompt)
+	if err != nil {
+		return nil, &ai.RequestError{RequestID: requestID, Err: err}
 	}
+	result.RequestID = requestID
+	return result, nil
+}
 
-	// Initialize request log if store is available
-	var logID string
-	if g.store != nil {
-		log, err := g.store.AddRequestLog(ctx, ai.RequestLog{
-			SessionID:     sessionID,
-			Prompt:        prompt,
-			AttemptNumber: 1,
-			FinalStatus:   ai.StatusPending,
-		})
-		if err == nil {
-			logID = log.ID
-		}
+// send runs the validate-and-retry loop for Send.
+func (g *GeminiProvider) send(ctx context.Context, rules ai.Rules, history []ai.Message, prompt string) (*ai.Result, error) {
+	call, err := g.prepare(ctx, rules, history, prompt)
+	if err != nil {
+		return nil, err
 	}
+	defer g.checkBudget(ctx, call.sessionID)
+	logID, rules, history, prompt, opts := call.logID, call.rules, call.history, call.prompt, call.opts
 
 	// Retry loop: up to 2 attempts
 	var lastErr error
 	var lastResult *ai.Result
 
+	maxAttempts := g.retryPolicy.attempts()
 	for attempt := 1; attempt <= maxAttempts; attempt++ {
 		// Send request to API
-		result, err := g.sendOnce(ctx, rules, history, prompt)
+		opts.attempt = attempt
+		result, err := g.sendOnce(ctx, rules, history, prompt, opts)
 
 		// Handle API errors
 		if err != nil {
+			if ai.Cancelled(ctx) {
+				g.cancelLog(ctx, logID, "", attempt-1, nil)
+				return nil, ai.ErrCancelled
+			}
+
 			failReason := classifyError(err)
 			lastErr = err
 
 			if g.store != nil && logID != "" {
 				g.store.UpdateRequestLog(ctx, logID,
-					"",                          // response
-					ai.StatusFailed,             // status
-					failReason,                  // fail_reason
-					err.Error(),                 // error_message
-					attempt-1,                   // retry_count
-					nil,                         // usage
+					"",              // response
+					ai.StatusFailed, // status
+					failReason,      // fail_reason
+					err.Error(),     // error_message
+					attempt-1,       // retry_count
+					nil,             // usage
 				)
 			}
 
-			// Retry if not last attempt
-			if attempt < maxAttempts {
+			// Retry retryable errors after the backoff, if the caller is still waiting
+			if delay, ok := g.retryPolicy.delay(attempt, err); ok && wait(ctx, delay) == nil {
 				continue
 			}
 			return nil, lastErr
 		}
 
-		// Validate JSON response
-		valid, failReason := validateJSON(result.Content)
-		if valid {
-			// Success: JSON is valid
+		g.attribute(ctx, logID, rules, history, prompt, result.Usage)
+
+		// Count tokens against the key's quota, including rejected attempts
+		if g.quotas != nil {
+			g.quotas.AddKeyUsage(ctx, ai.KeyID(opts.apiKey), result.Usage.TotalTokens, g.now())
+		}
+		g.limiter(opts.apiKey).AddTokens(result.Usage.TotalTokens)
+
+		// Tool calls are returned to the caller for execution without validation
+		if len(result.ToolCalls) > 0 {
 			if g.store != nil && logID != "" {
 				g.store.UpdateRequestLog(ctx, logID,
-					result.Content,           // response
-					ai.StatusSuccess,         // status
-					"",                       // fail_reason
-					"",                       // error_message
-					attempt-1,                // retry_count
-					&result.Usage,            // usage
+					result.Content,   // response
+					ai.StatusSuccess, // status
+					"",               // fail_reason
+					"",               // error_message
+					attempt-1,        // retry_count
+					&result.Usage,    // usage
 				)
 			}
 			return result, nil
 		}
 
-		// JSON validation failed
+		// Validate response (JSON completeness, output policy)
+		content, verr := g.validate(rules, call.policy, result.Content)
+		if verr == nil {
+			result.Content = content
+			if g.store != nil && logID != "" {
+				g.store.UpdateRequestLog(ctx, logID,
+					result.Content,   // response
+					ai.StatusSuccess, // status
+					"",               // fail_reason
+					"",               // error_message
+					attempt-1,        // retry_count
+					&result.Usage,    // usage
+				)
+			}
+			g.sample(ctx, call, result.Content)
+			return result, nil
+		}
+
+		var ve *ai.ValidationError
+		if !errors.As(verr, &ve) {
+			if g.store != nil && logID != "" {
+				g.store.UpdateRequestLog(ctx, logID,
+					result.Content,            // response
+					ai.StatusFailed,           // status
+					ai.FailReasonUnknownError, // fail_reason
+					verr.Error(),              // error_message
+					attempt-1,                 // retry_count
+					&result.Usage,             // usage
+				)
+			}
+			return nil, verr
+		}
+
+		// Validation failed
 		lastResult = result
 
 		if g.store != nil && logID != "" {
 			g.store.UpdateRequestLog(ctx, logID,
-				result.Content,           // response
-				ai.StatusPending,         // status
-				failReason,               // fail_reason
-				"JSON validation failed", // error_message
-				attempt-1,                // retry_count
-				&result.Usage,            // usage
+				result.Content,   // response
+				ai.StatusPending, // status
+				ve.Reason,        // fail_reason
+				ve.Message,       // error_message
+				attempt-1,        // retry_count
+				&result.Usage,    // usage
 			)
 		}
 
 		// Retry if not last attempt
 		if attempt < maxAttempts {
-			// Add incomplete response and retry message to history for next attempt
+			// Add rejected response and corrective message to history for next attempt
+			g.recordRetry(ctx, logID, ai.RetryTurn{
+				Attempt:    attempt,
+				Response:   result.Content,
+				FailReason: ve.Reason,
+				Error:      ve.Message,
+				Correction: ve.Correction,
+				At:         g.now(),
+			})
 			history = append(history,
 				ai.Message{Role: "assistant", Content: result.Content},
-				ai.Message{Role: "user", Content: "Your previous response had incomplete JSON (mismatched brackets). Please regenerate the complete, valid JSON response."},
+				ai.Message{Role: "user", Content: ve.Correction},
 			)
 			continue
 		}
@@ -147,123 +537,634 @@ func (g *GeminiProvider) Send(ctx context.Context, rules ai.Rules, history []ai.
 		// Max attempts exceeded
 		if g.store != nil && logID != "" {
 			g.store.UpdateRequestLog(ctx, logID,
-				lastResult.Content,               // response
-				ai.StatusFailed,                  // status
-				ai.FailReasonMaxRetries,          // fail_reason
-				"JSON validation failed after max retries", // error_message
-				attempt-1,                        // retry_count
-				&lastResult.Usage,                // usage
+				lastResult.Content,              // response
+				ai.StatusFailed,                 // status
+				ai.FailReasonMaxRetries,         // fail_reason
+				ve.Message+" after max retries", // error_message
+				attempt-1,                       // retry_count
+				&lastResult.Usage,               // usage
 			)
 		}
 
-		return nil, fmt.Errorf("ai: JSON validation failed after %d attempts: %w", maxAttempts, ai.ErrProviderFailed)
+		return nil, fmt.Errorf("ai: %s after %d attempts: %w", ve.Message, maxAttempts, ai.ErrProviderFailed)
 	}
 
 	return nil, lastErr
 }
 
+// prepared is a request after the pre-processing shared by Send and Stream.
+type prepared struct {
+	sessionID string
+	logID     string
+	library   *ai.Prompt // library prompt the rules referenced, if any
+	rules     ai.Rules
+	policy    *ai.CompiledPolicy
+	history   []ai.Message
+	prompt    string
+	opts      callOptions
+}
+
+// prepare checks quotas, opens the request log and applies history scanning, memory
+// injection, URL context and tool declarations. Dry runs skip quotas and logging.
+func (g *GeminiProvider) prepare(ctx context.Context, rules ai.Rules, history []ai.Message, prompt string) (*prepared, error) {
+	dry := ai.IsDryRun(ctx)
+
+	apiKey, model, err := g.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if g.quotas != nil && !dry {
+		if err := g.quota.Check(ctx, g.quotas, ai.KeyID(apiKey), g.now()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Extract sessionID from history or context for logging
+	sessionID := ""
+	if len(history) > 0 {
+		sessionID = history[0].SessionID
+	}
+	// Fallback: check context if history is empty (first message in session)
+	if sessionID == "" {
+		sessionID = ai.SessionIDFromContext(ctx)
+	}
+
+	// Resolve the registry schema
+	var registrySchema *ai.Schema
+	if rules.Schema != nil {
+		if g.schemas == nil {
+			return nil, fmt.Errorf("ai: rules reference schema %q but no schema store is configured", rules.Schema.Name)
+		}
+		sc, err := ai.ResolveSchema(ctx, g.schemas, *rules.Schema)
+		if err != nil {
+			return nil, err
+		}
+		registrySchema = sc
+		rules.OutputSchema = sc.Definition
+	}
+
+	if err := ai.CheckResponseFormat(rules.ResponseFormat); err != nil {
+		return nil, err
+	}
+	if rules.ResponseFormat == ai.FormatEnum {
+		if _, err := ai.EnumValues(rules.OutputSchema); err != nil {
+			return nil, err
+		}
+	}
+	if err := g.checkRoles(history); err != nil {
+		return nil, err
+	}
+
+	// Resolve the call's options over the session's defaults
+	sendOpts, err := ai.ResolveSendOptions(ctx, g.defaults, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	var named []ai.HistoryTransform
+	for _, name := range sendOpts.Transforms {
+		t, ok := g.named[name]
+		if !ok {
+			return nil, fmt.Errorf("ai: unknown history transform %q", name)
+		}
+		named = append(named, t)
+	}
+	if sendOpts.Policy != nil {
+		rules.Policy = sendOpts.Policy
+	}
+	policy, err := rules.Policy.Compile()
+	if err != nil {
+		return nil, err
+	}
+	if sendOpts.Model != "" {
+		model = sendOpts.Model
+	}
+	if err := ai.CheckMaxTokens(model, g.limitsOf(model), rules.MaxTokens); err != nil {
+		return nil, err
+	}
+
+	// Resolve the library prompt for the tenant
+	var libraryPrompt *ai.Prompt
+	if rules.Prompt != nil {
+		if g.prompts == nil {
+			return nil, fmt.Errorf("ai: rules reference prompt %q but no prompt store is configured", rules.Prompt.Name)
+		}
+		p, text, err := ai.ResolvePrompt(ctx, g.prompts, *rules.Prompt)
+		if err != nil {
+			return nil, err
+		}
+		libraryPrompt = p
+		rules.SystemPrompt = joinInstructions(text, rules.SystemPrompt)
+	}
+
+	// Speak as the session's persona
+	if g.personas != nil && sessionID != "" {
+		persona, err := g.personas.SessionPersona(ctx, sessionID)
+		if err != nil {
+			return nil, err
+		}
+		rules.SystemPrompt = joinInstructions(rules.SystemPrompt, ai.RenderPersona(persona))
+	}
+
+	// Layer per-request instructions over the session's system prompt
+	rules.SystemPrompt = ai.LayerSystemPrompt(ctx, rules.SystemPrompt)
+
+	// Initialize request log if store is available
+	var logID string
+	if g.store != nil && !dry {
+		entry := ai.RequestLog{
+			SessionID:      sessionID,
+			Labels:         rules.Labels,
+			Prompt:         prompt,
+			SystemAddendum: ai.SystemAddendumFromContext(ctx),
+			AttemptNumber:  1,
+			FinalStatus:    ai.StatusPending,
+		}
+		if libraryPrompt != nil {
+			entry.PromptName, entry.PromptVersion = libraryPrompt.Name, libraryPrompt.Version
+		}
+		if registrySchema != nil {
+			entry.SchemaName, entry.SchemaVersion = registrySchema.Name, registrySchema.Version
+		}
+		log, err := g.store.AddRequestLog(ctx, entry)
+		if err == nil {
+			logID = log.ID
+		}
+	}
+
+	// Scan replayed history for injected instructions
+	if g.scanner != nil {
+		scanned, err := g.scanner.Scan(ctx, history)
+		if err != nil {
+			g.failLog(ctx, logID, ai.FailReasonPromptInjection, err)
+			return nil, err
+		}
+		history = scanned
+	}
+
+	// Apply history transforms, in order
+	for _, t := range append(g.transforms[:len(g.transforms):len(g.transforms)], named...) {
+		transformed, err := t.Transform(ctx, history)
+		if err != nil {
+			g.failLog(ctx, logID, ai.FailReasonUnknownError, err)
+			return nil, err
+		}
+		history = transformed
+	}
+
+	// Inject stored memory into the system instruction
+	rules, err = g.withMemory(ctx, rules, sessionID)
+	if err != nil {
+		g.failLog(ctx, logID, ai.FailReasonUnknownError, err)
+		return nil, err
+	}
+
+	// Define glossary terms the prompt uses
+	if g.glossary != nil {
+		terms, err := ai.ResolveGlossary(ctx, g.glossary)
+		if err != nil {
+			g.failLog(ctx, logID, ai.FailReasonUnknownError, err)
+			return nil, err
+		}
+		rules.SystemPrompt = joinInstructions(rules.SystemPrompt, ai.RenderGlossary(ai.MatchGlossary(terms, prompt)))
+	}
+	rules.SystemPrompt = joinInstructions(rules.SystemPrompt, ai.FormatInstruction(rules.ResponseFormat), g.schemaInstruction(rules), ai.LanguageInstruction(rules.Language))
+
+	// Attach URL context
+	prompt, opts, err := g.withURLContext(ctx, prompt)
+	if err != nil {
+		g.failLog(ctx, logID, ai.FailReasonUnknownError, err)
+		return nil, err
+	}
+	opts.tools = ai.ToolsFromContext(ctx)
+	opts.logID, opts.attempt = logID, 1
+	opts.apiKey, opts.model, opts.temperature = apiKey, model, sendOpts.Temperature
+
+	// Fit the response into the room the input leaves
+	if g.autoMaxTokens {
+		maxTokens, err := ai.DeriveMaxTokens(g.limitsOf(model), g.estimateInput(rules, history, prompt), g.tokenMargin, rules.MaxTokens)
+		if err != nil {
+			g.failLog(ctx, logID, ai.FailReasonContextExceeded, err)
+			return nil, err
+		}
+		rules.MaxTokens = maxTokens
+	}
+
+	return &prepared{
+		sessionID: sessionID,
+		logID:     logID,
+		library:   libraryPrompt,
+		rules:     rules,
+		policy:    policy,
+		history:   history,
+		prompt:    prompt,
+		opts:      opts,
+	}, nil
+}
+
+// estimateInput estimates the input tokens of a request as buildRequest would send it.
+func (g *GeminiProvider) estimateInput(rules ai.Rules, history []ai.Message, prompt string) int {
+	tokens := ai.EstimateTokens(rules.SystemPrompt) + ai.EstimateTokens(rules.OutputSchema) + ai.EstimateTokens(prompt)
+	for _, m := range history {
+		if m.IsEvent() && !g.includeEvents {
+			continue
+		}
+		tokens += ai.EstimateTokens(m.Content)
+	}
+	return tokens
+}
+
+// sample offers a successful exchange to the sampler.
+func (g *GeminiProvider) sample(ctx context.Context, call *prepared, response string) {
+	if g.sampler == nil {
+		return
+	}
+	s := ai.Sample{
+		Model:        call.opts.model,
+		SystemPrompt: call.rules.SystemPrompt,
+		Prompt:       call.prompt,
+		Response:     response,
+	}
+	if call.library != nil {
+		s.PromptName, s.PromptVersion = call.library.Name, call.library.Version
+	}
+	g.sampler.Record(context.WithoutCancel(ctx), s)
+}
+
+// failLog marks a request log as failed before any attempt was made.
+func (g *GeminiProvider) failLog(ctx context.Context, logID string, failReason string, err error) {
+	if g.store == nil || logID == "" {
+		return
+	}
+	g.store.UpdateRequestLog(ctx, logID,
+		"",              // response
+		ai.StatusFailed, // status
+		failReason,      // fail_reason
+		err.Error(),     // error_message
+		0,               // retry_count
+		nil,             // usage
+	)
+}
+
+// Cancel stops the in-flight Send or Stream calls of requestID. A stopped Send returns
+// ai.ErrCancelled, a stopped stream ends with it, and the request log is marked
+// ai.StatusCancelled with the content and usage received so far.
+func (g *GeminiProvider) Cancel(ctx context.Context, requestID string) error {
+	return g.inflight.Cancel(ctx, requestID)
+}
+
+// cancelLog marks a request log as cancelled.
+func (g *GeminiProvider) cancelLog(ctx context.Context, logID string, response string, retryCount int, usage *ai.Usage) {
+	if g.store == nil || logID == "" {
+		return
+	}
+	g.store.UpdateRequestLog(context.WithoutCancel(ctx), logID,
+		response,                // response
+		ai.StatusCancelled,      // status
+		ai.FailReasonCancelled,  // fail_reason
+		ai.ErrCancelled.Error(), // error_message
+		retryCount,              // retry_count
+		usage,                   // usage
+	)
+}
+
 // sendOnce makes a single API request without validation or retry.
-func (g *GeminiProvider) sendOnce(ctx context.Context, rules ai.Rules, history []ai.Message, prompt string) (*ai.Result, error) {
-	reqBody := g.buildRequest(rules, history, prompt)
+func (g *GeminiProvider) sendOnce(ctx context.Context, rules ai.Rules, history []ai.Message, prompt string, opts callOptions) (*ai.Result, error) {
+	reqBody, err := g.buildRequest(rules, history, prompt, opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.limiter(opts.apiKey).Reserve(); err != nil {
+		return nil, err
+	}
 
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("ai: marshal request: %w", err)
 	}
 
-	url := fmt.Sprintf("%s/%s:generateContent?key=%s", baseURL, g.modelID, g.apiKey)
-
-	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonBody))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint(opts.apiKey, opts.model, "generateContent"), bytes.NewReader(jsonBody))
 	if err != nil {
 		return nil, fmt.Errorf("ai: create request: %w", err)
 	}
 	req.Header.Set("Content-Type", "application/json")
+	if id := ai.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(ai.RequestIDHeader, id)
+	}
 
 	resp, err := g.client.Do(req)
 	if err != nil {
+		g.capture(ctx, opts, 0, jsonBody, nil)
 		return nil, fmt.Errorf("ai: send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
+	g.capture(ctx, opts, resp.StatusCode, jsonBody, body)
 	if err != nil {
 		return nil, fmt.Errorf("ai: read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return nil, fmt.Errorf("%w: status %d: %s", ai.ErrProviderFailed, resp.StatusCode, string(body))
+		return nil, g.rateLimited(opts.apiKey, g.apiError(resp, body))
 	}
 
-	return g.parseResponse(body)
+	return g.parseResponse(body, opts.model)
+}
+
+// endpoint returns the URL of a method of model, e.g. "generateContent", called with
+// apiKey.
+func (g *GeminiProvider) endpoint(apiKey, model, method string) string {
+	return fmt.Sprintf("%s/%s:%s?key=%s", g.baseURL, model, method, apiKey)
 }
 
-func (g *GeminiProvider) buildRequest(rules ai.Rules, history []ai.Message, prompt string) map[string]any {
+func (g *GeminiProvider) buildRequest(rules ai.Rules, history []ai.Message, prompt string, opts callOptions) (map[string]any, error) {
 	contents := make([]map[string]any, 0, len(history)+1)
+	var system []string
 
 	for _, msg := range history {
-		role := msg.Role
-		if role == "assistant" {
-			role = "model"
+		text := msg.Content
+		var role string
+		switch msg.Role {
+		case ai.RoleEvent:
+			if !g.includeEvents {
+				continue
+			}
+			// Events are presented to the model as user-side context.
+			role = ContentUser
+			text = fmt.Sprintf("[event: %s] %s", msg.EventType, msg.Content)
+		case ai.RoleToolCall:
+			parts, err := functionCallParts(msg)
+			if err != nil {
+				return nil, err
+			}
+			contents = append(contents, map[string]any{"role": "model", "parts": parts})
+			continue
+		case ai.RoleTool:
+			part, err := functionResponsePart(msg)
+			if err != nil {
+				return nil, err
+			}
+			// Responses to parallel calls must share a single user turn.
+			if n := len(contents); n > 0 && contents[n-1]["role"] == "user" && isFunctionResponse(contents[n-1]) {
+				contents[n-1]["parts"] = append(contents[n-1]["parts"].([]map[string]any), part)
+			} else {
+				contents = append(contents, map[string]any{"role": "user", "parts": []map[string]any{part}})
+			}
+			continue
+		default:
+			var err error
+			if role, err = g.contentRole(msg.Role); err != nil {
+				return nil, err
+			}
+			if role == SystemInstruction {
+				system = append(system, text)
+				continue
+			}
 		}
 		contents = append(contents, map[string]any{
 			"role":  role,
-			"parts": []map[string]any{{"text": msg.Content}},
+			"parts": []map[string]any{{"text": text}},
 		})
 	}
 
-	contents = append(contents, map[string]any{
-		"role":  "user",
-		"parts": []map[string]any{{"text": prompt}},
-	})
+	if prompt != "" {
+		contents = append(contents, map[string]any{
+			"role":  "user",
+			"parts": []map[string]any{{"text": prompt}},
+		})
+	}
 
+	generationConfig := map[string]any{}
 	req := map[string]any{
-		"contents": contents,
-		"generationConfig": map[string]any{
-			"responseMimeType": "application/json",
-		},
+		"contents":         contents,
+		"generationConfig": generationConfig,
+	}
+
+	// Gemini rejects JSON and enum mode combined with function calling.
+	if mime := g.responseMimeType(rules); len(opts.tools) == 0 && mime != "" {
+		generationConfig["responseMimeType"] = mime
+
+		if rules.OutputSchema != "" && (mime == jsonMimeType || mime == enumMimeType) {
+			if schema, err := g.responseSchema(rules.OutputSchema); err == nil {
+				generationConfig["responseSchema"] = schema
+			}
+		}
 	}
 
 	if rules.MaxTokens > 0 {
-		req["generationConfig"].(map[string]any)["maxOutputTokens"] = rules.MaxTokens
+		generationConfig["maxOutputTokens"] = rules.MaxTokens
+	}
+	if opts.temperature != nil {
+		generationConfig["temperature"] = *opts.temperature
 	}
 
-	if rules.OutputSchema != "" {
-		var schema map[string]any
-		if err := json.Unmarshal([]byte(rules.OutputSchema), &schema); err == nil {
-			req["generationConfig"].(map[string]any)["responseSchema"] = schema
-		}
+	var tools []map[string]any
+	if len(opts.tools) > 0 {
+		tools = append(tools, functionDeclarations(opts.tools))
+	}
+	if opts.urlContext {
+		tools = append(tools, map[string]any{"url_context": map[string]any{}})
+	}
+	if len(tools) > 0 {
+		req["tools"] = tools
 	}
 
-	if rules.SystemPrompt != "" {
+	systemPrompt := rules.SystemPrompt
+	if len(system) > 0 {
+		systemPrompt = strings.TrimPrefix(strings.Join(append([]string{systemPrompt}, system...), "\n\n"), "\n\n")
+	}
+	if systemPrompt != "" {
 		req["systemInstruction"] = map[string]any{
-			"parts": []map[string]any{{"text": rules.SystemPrompt}},
+			"parts": []map[string]any{{"text": systemPrompt}},
+		}
+	}
+
+	return req, nil
+}
+
+// responseMimeType returns the responseMimeType for rules, or "" to leave the output
+// mode unconstrained.
+func (g *GeminiProvider) responseMimeType(rules ai.Rules) string {
+	switch {
+	case rules.ResponseFormat == ai.FormatEnum:
+		return enumMimeType
+	case ai.IsJSONFormat(rules.ResponseFormat):
+		return g.jsonMimeType
+	}
+	return ""
+}
+
+// responseSchema decodes the output schema sent as responseSchema.
+func (g *GeminiProvider) responseSchema(raw string) (map[string]any, error) {
+	if g.propertyOrdering {
+		return orderedSchema(raw)
+	}
+	var schema map[string]any
+	err := json.Unmarshal([]byte(raw), &schema)
+	return schema, err
+}
+
+// isFunctionResponse reports whether a content entry holds function responses.
+func isFunctionResponse(content map[string]any) bool {
+	parts, _ := content["parts"].([]map[string]any)
+	if len(parts) == 0 {
+		return false
+	}
+	_, ok := parts[0]["functionResponse"]
+	return ok
+}
+
+// withMemory appends rendered session facts and user memories to the rules' system prompt.
+func (g *GeminiProvider) withMemory(ctx context.Context, rules ai.Rules, sessionID string) (ai.Rules, error) {
+	if g.memory != nil && sessionID != "" {
+		facts, err := g.memory.ListSessionFacts(ctx, sessionID)
+		if err != nil {
+			return rules, err
+		}
+		rules.SystemPrompt = joinInstructions(rules.SystemPrompt, ai.RenderFacts("Known facts about this session", facts))
+	}
+
+	if userID := ai.UserIDFromContext(ctx); g.userMemory != nil && userID != "" {
+		memories, err := g.userMemory.ListUserMemories(ctx, userID)
+		if err != nil {
+			return rules, err
 		}
+		memories = g.memoryPolicy.Select(memories, g.now())
+		rules.SystemPrompt = joinInstructions(rules.SystemPrompt, ai.RenderFacts("Known preferences of this user", memories))
 	}
 
-	return req
+	return rules, nil
+}
+
+// schemaInstruction describes the output of JSON-format rules when JSON mode is off
+// and can't enforce it.
+func (g *GeminiProvider) schemaInstruction(rules ai.Rules) string {
+	if g.jsonMimeType != "" || !ai.IsJSONFormat(rules.ResponseFormat) {
+		return ""
+	}
+	if rules.OutputSchema == "" {
+		return "Respond with only a JSON document, without code fences or commentary."
+	}
+	return "Respond with only a JSON document matching this JSON Schema, without code fences or commentary:\n" + rules.OutputSchema
 }
 
-func (g *GeminiProvider) parseResponse(body []byte) (*ai.Result, error) {
+// joinInstructions joins non-empty system instruction blocks with a blank line.
+func joinInstructions(blocks ...string) string {
+	var nonEmpty []string
+	for _, b := range blocks {
+		if b != "" {
+			nonEmpty = append(nonEmpty, b)
+		}
+	}
+	return strings.Join(nonEmpty, "\n\n")
+}
+
+// withURLContext adds URLs attached to ctx to the prompt. With a URLFetcher the page text
+// is inlined; otherwise the URLs are listed and the url_context tool is enabled.
+func (g *GeminiProvider) withURLContext(ctx context.Context, prompt string) (string, callOptions, error) {
+	var opts callOptions
+
+	urls := ai.URLsFromContext(ctx)
+	if len(urls) == 0 {
+		return prompt, opts, nil
+	}
+
+	var b strings.Builder
+	if g.urlFetcher != nil {
+		for _, u := range urls {
+			text, err := g.urlFetcher.Fetch(ctx, u)
+			if err != nil {
+				return "", opts, err
+			}
+			fmt.Fprintf(&b, "Content of %s:\n%s\n\n", u, text)
+		}
+	} else {
+		opts.urlContext = true
+		b.WriteString("Use the content of these URLs as context:\n")
+		for _, u := range urls {
+			b.WriteString(u + "\n")
+		}
+		b.WriteString("\n")
+	}
+	b.WriteString(prompt)
+
+	return b.String(), opts, nil
+}
+
+func (g *GeminiProvider) parseResponse(body []byte, model string) (*ai.Result, error) {
 	var resp geminiResponse
 	if err := json.Unmarshal(body, &resp); err != nil {
 		return nil, fmt.Errorf("ai: parse response: %w", err)
 	}
 
+	if err := blocked(resp); err != nil {
+		return nil, err
+	}
 	if len(resp.Candidates) == 0 || len(resp.Candidates[0].Content.Parts) == 0 {
 		return nil, fmt.Errorf("%w: empty response from Gemini", ai.ErrProviderFailed)
 	}
 
+	parts := resp.Candidates[0].Content.Parts
+	var text strings.Builder
+	for _, p := range parts {
+		if !p.Thought {
+			text.WriteString(p.Text)
+		}
+	}
+
 	return &ai.Result{
-		Content: resp.Candidates[0].Content.Parts[0].Text,
-		Usage: ai.Usage{
-			PromptTokens:   resp.UsageMetadata.PromptTokenCount,
-			ResponseTokens: resp.UsageMetadata.CandidatesTokenCount,
-			TotalTokens:    resp.UsageMetadata.TotalTokenCount,
-			ThoughtTokens:  resp.UsageMetadata.ThoughtsTokenCount,
-		},
+		Content:   text.String(),
+		ToolCalls: toolCalls(parts),
+		Usage:     toUsage(resp.UsageMetadata, model),
+		Citations: citations(resp.Candidates[0]),
 	}, nil
 }
 
+// validate runs the response checks for rules, with their policy compiled, and returns
+// the accepted content. A rejected response is reported as an *ai.ValidationError.
+func (g *GeminiProvider) validate(rules ai.Rules, policy *ai.CompiledPolicy, content string) (string, error) {
+	if !ai.IsJSONFormat(rules.ResponseFormat) {
+		var err error
+		if content, err = ai.FormatResponse(rules.ResponseFormat, content); err != nil {
+			return "", err
+		}
+		if rules.ResponseFormat == ai.FormatEnum {
+			if content, err = ai.CheckEnum(rules.OutputSchema, content); err != nil {
+				return "", err
+			}
+		}
+	} else if valid, failReason := validateJSON(content); !valid {
+		return "", &ai.ValidationError{
+			Reason:     failReason,
+			Message:    "JSON validation failed",
+			Correction: "Your previous response had incomplete JSON (mismatched brackets). Please regenerate the complete, valid JSON response.",
+		}
+	} else if g.canonical {
+		canonical, err := ai.CanonicalJSON(content)
+		if err != nil {
+			return "", &ai.ValidationError{
+				Reason:     ai.FailReasonInvalidJSON,
+				Message:    err.Error(),
+				Correction: "Your previous response was not valid JSON. Please regenerate the complete response as a single valid JSON document with no other text.",
+			}
+		}
+		content = canonical
+	}
+
+	if policy != nil {
+		var err error
+		if content, err = policy.Enforce(content); err != nil {
+			return "", err
+		}
+	}
+
+	if err := ai.CheckLanguage(rules.Language, content); err != nil {
+		return "", err
+	}
+
+	return content, nil
+}
+
 // validateJSON checks if JSON is complete by counting brackets.
 // Returns (valid, failReason) - if invalid, failReason indicates the type of error.
 func validateJSON(s string) (bool, string) {
@@ -286,37 +1187,50 @@ func validateJSON(s string) (bool, string) {
 	return true, ""
 }
 
-// classifyError categorizes an error to determine the fail reason.
+// classifyError categorizes an error to determine the fail reason. Context errors are
+// matched through wrapping, so a url.Error from a cancelled or expired request is
+// classified by its context error rather than as a network error.
 func classifyError(err error) string {
-	if err == context.DeadlineExceeded {
+	var apiErr *ai.APIError
+	switch {
+	case errors.Is(err, ai.ErrCancelled), errors.Is(err, context.Canceled):
+		return ai.FailReasonCancelled
+	case errors.Is(err, context.DeadlineExceeded):
 		return ai.FailReasonTimeout
+	case errors.Is(err, ai.ErrBlocked):
+		return ai.FailReasonSafetyBlocked
+	case errors.Is(err, ai.ErrRateLimited):
+		return ai.FailReasonRateLimited
+	case errors.As(err, &apiErr):
+		return ai.FailReasonAPIError
 	}
 
 	// Check for net errors (network/timeout)
-	if netErr, ok := err.(net.Error); ok {
+	var netErr net.Error
+	if errors.As(err, &netErr) {
 		if netErr.Timeout() {
 			return ai.FailReasonTimeout
 		}
 		return ai.FailReasonNetworkError
 	}
 
-	// Check for context errors
-	if err == context.Canceled {
-		return ai.FailReasonNetworkError
-	}
-
 	// Default to unknown error
 	return ai.FailReasonUnknownError
 }
 
 // Gemini API response types.
 type geminiResponse struct {
-	Candidates    []geminiCandidate `json:"candidates"`
-	UsageMetadata geminiUsage       `json:"usageMetadata"`
+	Candidates     []geminiCandidate     `json:"candidates"`
+	UsageMetadata  geminiUsage           `json:"usageMetadata"`
+	PromptFeedback *geminiPromptFeedback `json:"promptFeedback,omitempty"`
 }
 
 type geminiCandidate struct {
-	Content geminiContent `json:"content"`
+	Content           geminiContent            `json:"content"`
+	FinishReason      string                   `json:"finishReason,omitempty"`
+	SafetyRatings     []geminiSafetyRating     `json:"safetyRatings,omitempty"`
+	GroundingMetadata *geminiGroundingMetadata `json:"groundingMetadata,omitempty"`
+	CitationMetadata  *geminiCitationMetadata  `json:"citationMetadata,omitempty"`
 }
 
 type geminiContent struct {
@@ -325,15 +1239,56 @@ type geminiContent struct {
 }
 
 type geminiPart struct {
-	Text string `json:"text"`
+	Text         string              `json:"text"`
+	Thought      bool                `json:"thought,omitempty"`
+	FunctionCall *geminiFunctionCall `json:"functionCall,omitempty"`
+	InlineData   *geminiBlob         `json:"inlineData,omitempty"`
+}
+
+type geminiBlob struct {
+	MIMEType string `json:"mimeType"`
+	Data     string `json:"data"` // base64
 }
 
 type geminiUsage struct {
-	PromptTokenCount     int `json:"promptTokenCount"`
-	CandidatesTokenCount int `json:"candidatesTokenCount"`
-	TotalTokenCount      int `json:"totalTokenCount"`
-	ThoughtsTokenCount   int `json:"thoughtsTokenCount"`
+	PromptTokenCount        int                   `json:"promptTokenCount"`
+	CandidatesTokenCount    int                   `json:"candidatesTokenCount"`
+	TotalTokenCount         int                   `json:"totalTokenCount"`
+	ThoughtsTokenCount      int                   `json:"thoughtsTokenCount"`
+	CachedContentTokenCount int                   `json:"cachedContentTokenCount"`
+	ToolUsePromptTokenCount int                   `json:"toolUsePromptTokenCount"`
+	PromptTokensDetails     []geminiModalityCount `json:"promptTokensDetails"`
+	CandidatesTokensDetails []geminiModalityCount `json:"candidatesTokensDetails"`
+}
+
+type geminiModalityCount struct {
+	Modality   string `json:"modality"`
+	TokenCount int    `json:"tokenCount"`
+}
+
+// toUsage maps Gemini usage metadata of a model to ai.Usage.
+func toUsage(u geminiUsage, model string) ai.Usage {
+	audio := 0
+	for _, d := range append(u.PromptTokensDetails, u.CandidatesTokensDetails...) {
+		if d.Modality == "AUDIO" {
+			audio += d.TokenCount
+		}
+	}
+
+	return ai.Usage{
+		PromptTokens:   u.PromptTokenCount,
+		ResponseTokens: u.CandidatesTokenCount,
+		TotalTokens:    u.TotalTokenCount,
+		ThoughtTokens:  u.ThoughtsTokenCount,
+		CachedTokens:   u.CachedContentTokenCount,
+		ToolTokens:     u.ToolUsePromptTokenCount,
+		AudioTokens:    audio,
+		Model:          model,
+	}
 }
 
-// Ensure GeminiProvider implements ai.Provider at compile time.
-var _ ai.Provider = (*GeminiProvider)(nil)
+// Ensure GeminiProvider implements ai.Provider and ai.CapabilityReporter at compile time.
+var (
+	_ ai.Provider           = (*GeminiProvider)(nil)
+	_ ai.CapabilityReporter = (*GeminiProvider)(nil)
+)
@@ -0,0 +1,52 @@
+package gemini
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// WithRateLimits refuses requests once a key has used up its requests or tokens of
+// the current minute, with an *ai.RateLimitError saying when to try again, instead of
+// sending them for Gemini to reject. Each key, including those resolved with
+// WithCredentials, is limited separately. Without limits, only the cooldowns Gemini's
+// 429 responses ask for are tracked.
+func (g *GeminiProvider) WithRateLimits(limits ai.RateLimits) *GeminiProvider {
+	g.limitsMu.Lock()
+	g.rateLimits, g.limiters = limits, nil
+	g.limitsMu.Unlock()
+	return g
+}
+
+// LimiterStatus implements ai.RateLimitReporter for the provider's own key.
+func (g *GeminiProvider) LimiterStatus() ai.LimiterStatus {
+	return g.limiter(g.apiKey).Status()
+}
+
+// limiter returns the rate limiter of apiKey.
+func (g *GeminiProvider) limiter(apiKey string) *ai.RateLimiter {
+	g.limitsMu.Lock()
+	defer g.limitsMu.Unlock()
+	keyID := ai.KeyID(apiKey)
+	l, ok := g.limiters[keyID]
+	if !ok {
+		if g.limiters == nil {
+			g.limiters = make(map[string]*ai.RateLimiter)
+		}
+		l = ai.NewRateLimiter(g.rateLimits).WithClock(func() time.Time { return g.now() })
+		g.limiters[keyID] = l
+	}
+	return l
+}
+
+// rateLimited returns err as an *ai.RateLimitError, starting the key's cooldown, if it
+// is a 429 response.
+func (g *GeminiProvider) rateLimited(apiKey string, err error) error {
+	var apiErr *ai.APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		return err
+	}
+	return g.limiter(apiKey).Cooldown(apiErr.RetryAfter, err)
+}
@@ -0,0 +1,123 @@
+package gemini
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// RetryPolicy configures how Send retries failed attempts.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts, including the first and validation retries
+	BaseDelay   time.Duration // wait before the first retry of a failed call, doubled before each later one
+	MaxDelay    time.Duration // upper bound on the wait; a longer Retry-After fails instead. Zero for none
+}
+
+// DefaultRetryPolicy makes up to two attempts, waiting a second, or as long as Gemini
+// asks up to 30 seconds, before retrying a failed call.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 2, BaseDelay: time.Second, MaxDelay: 30 * time.Second}
+
+// WithRetry sets the retry policy of Send. Calls failing with a retryable API error
+// (429 or 5xx) or a network error are retried after a backoff, or after the delay
+// Gemini requested with a Retry-After header or a RetryInfo detail. Other API errors
+// and safety blocks are not retried. Responses failing validation are retried at once
+// with a correction.
+func (g *GeminiProvider) WithRetry(policy RetryPolicy) *GeminiProvider {
+	g.retryPolicy = policy
+	return g
+}
+
+// attempts returns the number of attempts Send makes.
+func (p RetryPolicy) attempts() int {
+	return max(p.MaxAttempts, 1)
+}
+
+// delay returns how long to wait before retrying a call whose attempt failed with
+// err, or false when it shouldn't be retried.
+func (p RetryPolicy) delay(attempt int, err error) (time.Duration, bool) {
+	var apiErr *ai.APIError
+	isAPI := errors.As(err, &apiErr)
+	// A call the rate limiter refused would be refused again
+	if attempt >= p.attempts() || errors.Is(err, ai.ErrBlocked) || (errors.Is(err, ai.ErrRateLimited) && !isAPI) {
+		return 0, false
+	}
+
+	if isAPI {
+		if !apiErr.Retryable() {
+			return 0, false
+		}
+		if apiErr.RetryAfter > 0 {
+			if p.MaxDelay > 0 && apiErr.RetryAfter > p.MaxDelay {
+				return 0, false
+			}
+			return apiErr.RetryAfter, true
+		}
+	}
+
+	d := p.BaseDelay << (attempt - 1)
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d, true
+}
+
+// wait sleeps for d, returning early with ctx's error if it is done first.
+func wait(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+// geminiError is the body of a Gemini error response.
+type geminiError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+		Status  string `json:"status"`
+		Details []struct {
+			Type       string `json:"@type"`
+			RetryDelay string `json:"retryDelay"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+const retryInfoType = "type.googleapis.com/google.rpc.RetryInfo"
+
+// apiError builds the error for a non-200 response. The wait Gemini asks for comes
+// from the Retry-After header or, failing that, a RetryInfo detail in the body.
+func (g *GeminiProvider) apiError(resp *http.Response, body []byte) *ai.APIError {
+	e := &ai.APIError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: ai.ParseRetryAfter(resp.Header.Get("Retry-After"), g.now()),
+		Body:       string(body),
+	}
+
+	var parsed geminiError
+	if json.Unmarshal(body, &parsed) != nil {
+		return e
+	}
+	e.Status, e.Message = parsed.Error.Status, parsed.Error.Message
+	if e.RetryAfter == 0 {
+		for _, d := range parsed.Error.Details {
+			if d.Type != retryInfoType {
+				continue
+			}
+			if delay, err := time.ParseDuration(d.RetryDelay); err == nil && delay > 0 {
+				e.RetryAfter = delay
+			}
+		}
+	}
+	return e
+}
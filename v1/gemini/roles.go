@@ -0,0 +1,79 @@
+package gemini
+
+import (
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// Gemini roles a RoleMap can map message roles to. Messages mapped to
+// SystemInstruction are added to the system instruction, after the rules' system
+// prompt, instead of to the contents.
+const (
+	ContentUser       = "user"
+	ContentModel      = "model"
+	SystemInstruction = "system_instruction"
+)
+
+// DefaultRoleMap returns the role map of a new provider. Tool calls, tool results and
+// events aren't mapped; they are always sent as function calls, function responses and
+// user-side context.
+func DefaultRoleMap() ai.RoleMap {
+	return ai.RoleMap{
+		ai.RoleUser:      ContentUser,
+		ai.RoleAssistant: ContentModel,
+		ai.RoleSystem:    SystemInstruction,
+		ai.RoleDeveloper: SystemInstruction,
+	}
+}
+
+// WithRoleMap adds the entries of roles to the role map, replacing those of the same
+// role. Roles not in the map are sent as user turns, or rejected with WithStrictRoles.
+func (g *GeminiProvider) WithRoleMap(roles ai.RoleMap) *GeminiProvider {
+	g.roles = g.roleMap().Merge(roles)
+	return g
+}
+
+// WithStrictRoles makes requests whose history has a role not in the role map fail
+// with an error wrapping ai.ErrUnknownRole, before anything is sent or logged.
+func (g *GeminiProvider) WithStrictRoles(strict bool) *GeminiProvider {
+	g.strictRoles = strict
+	return g
+}
+
+func (g *GeminiProvider) roleMap() ai.RoleMap {
+	if g.roles == nil {
+		return DefaultRoleMap()
+	}
+	return g.roles
+}
+
+// contentRole returns the Gemini role of a message role.
+func (g *GeminiProvider) contentRole(role string) (string, error) {
+	target, ok := g.roleMap()[role]
+	if !ok {
+		if g.strictRoles {
+			return "", fmt.Errorf("%w %q", ai.ErrUnknownRole, role)
+		}
+		return ContentUser, nil
+	}
+	switch target {
+	case ContentUser, ContentModel, SystemInstruction:
+		return target, nil
+	}
+	return "", fmt.Errorf("ai: role %q maps to %q, which is not a Gemini role", role, target)
+}
+
+// checkRoles reports the first message of history whose role can't be mapped.
+func (g *GeminiProvider) checkRoles(history []ai.Message) error {
+	for i, m := range history {
+		switch m.Role {
+		case ai.RoleEvent, ai.RoleToolCall, ai.RoleTool:
+			continue
+		}
+		if _, err := g.contentRole(m.Role); err != nil {
+			return fmt.Errorf("%w in history[%d]", err, i)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package gemini
+
+import (
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// blockedFinishReasons are candidate finish reasons meaning the response was withheld
+// for safety rather than completed.
+var blockedFinishReasons = map[string]bool{
+	"SAFETY":             true,
+	"PROHIBITED_CONTENT": true,
+	"BLOCKLIST":          true,
+	"SPII":               true,
+	"IMAGE_SAFETY":       true,
+}
+
+type geminiPromptFeedback struct {
+	BlockReason   string               `json:"blockReason"`
+	SafetyRatings []geminiSafetyRating `json:"safetyRatings"`
+}
+
+type geminiSafetyRating struct {
+	Category    string `json:"category"`
+	Probability string `json:"probability"`
+	Blocked     bool   `json:"blocked"`
+}
+
+// blocked returns an *ai.BlockedError if the prompt of resp was blocked, or its first
+// candidate was stopped for safety, and nil otherwise.
+func blocked(resp geminiResponse) error {
+	if f := resp.PromptFeedback; f != nil && f.BlockReason != "" {
+		return &ai.BlockedError{Reason: f.BlockReason, Categories: blockedCategories(f.SafetyRatings), Prompt: true}
+	}
+	if len(resp.Candidates) > 0 {
+		c := resp.Candidates[0]
+		if blockedFinishReasons[c.FinishReason] {
+			return &ai.BlockedError{Reason: c.FinishReason, Categories: blockedCategories(c.SafetyRatings)}
+		}
+	}
+	return nil
+}
+
+// blockedCategories returns the categories of the ratings that blocked content. Gemini
+// doesn't always flag them, so ratings of HIGH probability count as well.
+func blockedCategories(ratings []geminiSafetyRating) []string {
+	var out []string
+	for _, r := range ratings {
+		if r.Blocked || r.Probability == "HIGH" {
+			out = append(out, r.Category)
+		}
+	}
+	return out
+}
@@ -0,0 +1,116 @@
+package gemini
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// schemaKind tells decodeSchema what a JSON value holds, so propertyOrdering is only
+// added to schemas and not to property maps that happen to have a "properties" key.
+type schemaKind int
+
+const (
+	plainValue  schemaKind = iota // any JSON value
+	schemaValue                   // a schema object
+	schemaMap                     // an object of schemas, e.g. "properties"
+	schemaList                    // an array of schemas, e.g. "anyOf"
+)
+
+// schemaKeywords maps the keywords holding subschemas to their kind.
+var schemaKeywords = map[string]schemaKind{
+	"properties":           schemaMap,
+	"$defs":                schemaMap,
+	"definitions":          schemaMap,
+	"items":                schemaValue,
+	"additionalProperties": schemaValue,
+	"anyOf":                schemaList,
+	"oneOf":                schemaList,
+	"allOf":                schemaList,
+	"prefixItems":          schemaList,
+}
+
+// orderedSchema decodes a JSON Schema and sets Gemini's propertyOrdering on every
+// object schema that lacks one, listing its properties in the order they are written.
+// Gemini otherwise generates properties in alphabetical order.
+func orderedSchema(raw string) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader([]byte(raw)))
+	v, _, err := decodeSchema(dec, schemaValue)
+	if err != nil {
+		return nil, err
+	}
+	schema, ok := v.(map[string]any)
+	if !ok {
+		return nil, fmt.Errorf("schema is not an object")
+	}
+	return schema, nil
+}
+
+// decodeSchema decodes the next value of dec as kind. For objects it also returns
+// their keys in order.
+func decodeSchema(dec *json.Decoder, kind schemaKind) (any, []string, error) {
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	switch tok {
+	case json.Delim('{'):
+		m := map[string]any{}
+		var keys, properties []string
+		for dec.More() {
+			keyTok, err := dec.Token()
+			if err != nil {
+				return nil, nil, err
+			}
+			key := keyTok.(string)
+
+			child := plainValue
+			switch kind {
+			case schemaValue:
+				child = schemaKeywords[key]
+			case schemaMap:
+				child = schemaValue
+			}
+			v, childKeys, err := decodeSchema(dec, child)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[key] = v
+			keys = append(keys, key)
+			if kind == schemaValue && key == "properties" {
+				properties = childKeys
+			}
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, nil, err
+		}
+		if _, set := m["propertyOrdering"]; len(properties) > 1 && !set {
+			m["propertyOrdering"] = properties
+		}
+		return m, keys, nil
+
+	case json.Delim('['):
+		child := plainValue
+		if kind == schemaList {
+			child = schemaValue
+		}
+		var list []any
+		for dec.More() {
+			v, _, err := decodeSchema(dec, child)
+			if err != nil {
+				return nil, nil, err
+			}
+			list = append(list, v)
+		}
+		if _, err := dec.Token(); err != nil {
+			return nil, nil, err
+		}
+		if list == nil {
+			list = []any{}
+		}
+		return list, nil, nil
+	}
+
+	return tok, nil, nil
+}
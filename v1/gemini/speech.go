@@ -0,0 +1,189 @@
+package gemini
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// Defaults of the speech methods.
+const (
+	DefaultSpeechModel = "gemini-2.5-flash-preview-tts"
+	DefaultVoice       = "Kore"
+)
+
+const transcribePrompt = "Transcribe this audio verbatim, in the language it is spoken in. Respond with the transcript only."
+
+// WithSpeechModel sets the text-to-speech model used by Synthesize, DefaultSpeechModel
+// by default. Transcribe uses the provider's model.
+func (g *GeminiProvider) WithSpeechModel(modelID string) *GeminiProvider {
+	g.speechModel = modelID
+	return g
+}
+
+// Transcribe implements ai.Transcriber by sending audio inline to the provider's model
+// with a transcription prompt. Clips must fit in one request (about 20 MB).
+func (g *GeminiProvider) Transcribe(ctx context.Context, audio ai.Audio) (*ai.Transcript, error) {
+	if len(audio.Data) == 0 {
+		return nil, ai.ErrEmptyPrompt
+	}
+	body := map[string]any{
+		"contents": []map[string]any{{
+			"role": "user",
+			"parts": []map[string]any{
+				{"inlineData": map[string]any{"mimeType": audio.MIMEType, "data": base64.StdEncoding.EncodeToString(audio.Data)}},
+				{"text": transcribePrompt},
+			},
+		}},
+	}
+
+	apiKey, model, err := g.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.generate(ctx, apiKey, model, body)
+	if err != nil {
+		return nil, err
+	}
+	var text strings.Builder
+	for _, p := range resp.Candidates[0].Content.Parts {
+		if !p.Thought {
+			text.WriteString(p.Text)
+		}
+	}
+	return &ai.Transcript{
+		Text:  strings.TrimSpace(text.String()),
+		Usage: toUsage(resp.UsageMetadata, model),
+	}, nil
+}
+
+// Synthesize implements ai.Synthesizer with the speech model, in voice or DefaultVoice
+// for "". Gemini returns raw 16-bit PCM; its MIME type gives the sample rate.
+func (g *GeminiProvider) Synthesize(ctx context.Context, text string, voice string) (*ai.Speech, error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ai.ErrEmptyPrompt
+	}
+	if voice == "" {
+		voice = DefaultVoice
+	}
+	apiKey, _, err := g.credentials(ctx)
+	if err != nil {
+		return nil, err
+	}
+	model := g.speechModel
+	if model == "" {
+		model = DefaultSpeechModel
+	}
+	body := map[string]any{
+		"contents": []map[string]any{{
+			"role":  "user",
+			"parts": []map[string]any{{"text": text}},
+		}},
+		"generationConfig": map[string]any{
+			"responseModalities": []string{"AUDIO"},
+			"speechConfig": map[string]any{
+				"voiceConfig": map[string]any{
+					"prebuiltVoiceConfig": map[string]any{"voiceName": voice},
+				},
+			},
+		},
+	}
+
+	resp, err := g.generate(ctx, apiKey, model, body)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range resp.Candidates[0].Content.Parts {
+		if p.InlineData == nil {
+			continue
+		}
+		data, err := base64.StdEncoding.DecodeString(p.InlineData.Data)
+		if err != nil {
+			return nil, fmt.Errorf("ai: decode speech: %w", err)
+		}
+		return &ai.Speech{
+			Audio: ai.Audio{Data: data, MIMEType: p.InlineData.MIMEType},
+			Usage: toUsage(resp.UsageMetadata, model),
+		}, nil
+	}
+	return nil, fmt.Errorf("%w: no audio in Gemini response", ai.ErrProviderFailed)
+}
+
+// generate calls generateContent of model with apiKey and body, retrying as Send does,
+// and counts the tokens against the key's quota. The response has at least one part.
+func (g *GeminiProvider) generate(ctx context.Context, apiKey, model string, body map[string]any) (*geminiResponse, error) {
+	jsonBody, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("ai: marshal request: %w", err)
+	}
+	url := g.endpoint(apiKey, model, "generateContent")
+
+	limiter := g.limiter(apiKey)
+	for attempt := 1; ; attempt++ {
+		if err := limiter.Reserve(); err != nil {
+			return nil, err
+		}
+		resp, err := g.post(ctx, url, jsonBody)
+		if err == nil {
+			if g.quotas != nil {
+				g.quotas.AddKeyUsage(ctx, ai.KeyID(apiKey), resp.UsageMetadata.TotalTokenCount, g.now())
+			}
+			limiter.AddTokens(resp.UsageMetadata.TotalTokenCount)
+			return resp, nil
+		}
+		err = g.rateLimited(apiKey, err)
+		if delay, ok := g.retryPolicy.delay(attempt, err); !ok || wait(ctx, delay) != nil {
+			return nil, err
+		}
+	}
+}
+
+func (g *GeminiProvider) post(ctx context.Context, url string, jsonBody []byte) (*geminiResponse, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("ai: create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if id := ai.RequestIDFromContext(ctx); id != "" {
+		req.Header.Set(ai.RequestIDHeader, id)
+	}
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ai: send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ai: read response: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, g.apiError(resp, body)
+	}
+
+	var out geminiResponse
+	if err := json.Unmarshal(body, &out); err != nil {
+		return nil, fmt.Errorf("ai: parse response: %w", err)
+	}
+	if err := blocked(out); err != nil {
+		return nil, err
+	}
+	if len(out.Candidates) == 0 || len(out.Candidates[0].Content.Parts) == 0 {
+		return nil, fmt.Errorf("%w: empty response from Gemini", ai.ErrProviderFailed)
+	}
+	return &out, nil
+}
+
+// Ensure GeminiProvider implements ai.Transcriber and ai.Synthesizer at compile time.
+var (
+	_ ai.Transcriber = (*GeminiProvider)(nil)
+	_ ai.Synthesizer = (*GeminiProvider)(nil)
+)
@@ -0,0 +1,488 @@
+package gemini
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// defaultCheckpointBytes is how much new content triggers a checkpoint save.
+const defaultCheckpointBytes = 2048
+
+// resumePrompt asks the model to continue a response that was cut off.
+const resumePrompt = "Your previous response was cut off. Continue exactly where it stopped. Do not repeat any text that was already written and do not add any preamble."
+
+// WithCheckpoints saves partial streamed content to store every `every` bytes, so a
+// crash mid-generation can be recovered with ResumeIncomplete. Zero uses 2KB.
+func (g *GeminiProvider) WithCheckpoints(store ai.CheckpointStore, every int) *GeminiProvider {
+	if every <= 0 {
+		every = defaultCheckpointBytes
+	}
+	g.checkpoints = store
+	g.checkpointBytes = every
+	return g
+}
+
+// WithStopOnJSONComplete ends a stream with an OutputSchema as soon as a complete,
+// valid JSON document has arrived, closing the connection so Gemini stops generating
+// and discarding anything after the closing bracket. It saves the output tokens of
+// models that keep writing after the answer. The response's usage is then the last
+// Gemini reported, or an estimate if it reported none.
+func (g *GeminiProvider) WithStopOnJSONComplete() *GeminiProvider {
+	g.stopOnComplete = true
+	return g
+}
+
+// Stream calls the Gemini streamGenerateContent API and emits content as it arrives.
+// Responses are validated once complete but cannot be retried; a validation failure
+// is reported on the final chunk and in the request log. Errors are *ai.RequestError.
+func (g *GeminiProvider) Stream(ctx context.Context, rules ai.Rules, history []ai.Message, prompt string) (<-chan ai.StreamChunk, error) {
+	if prompt == "" && !endsWithToolResults(history) {
+		return nil, ai.ErrEmptyPrompt
+	}
+
+	ctx, requestID := g.requestID(ctx)
+	done := ctx.Done()
+	ctx, release := g.inflight.Track(ctx, requestID)
+	call, err := g.prepare(ctx, rules, history, prompt)
+	if err != nil {
+		release()
+		return nil, &ai.RequestError{RequestID: requestID, Err: err}
+	}
+
+	resp, err := g.openStream(ctx, call)
+	if err != nil {
+		if ai.Cancelled(ctx) {
+			g.cancelLog(ctx, call.logID, "", 0, nil)
+			err = ai.ErrCancelled
+		} else {
+			g.failLog(ctx, call.logID, classifyError(err), err)
+		}
+		release()
+		return nil, &ai.RequestError{RequestID: requestID, Err: err}
+	}
+
+	ch := make(chan ai.StreamChunk)
+	go func() {
+		defer release()
+		g.readStream(ctx, done, call, prompt, resp, ch)
+	}()
+	return ch, nil
+}
+
+// openStream posts the streaming request and checks the response status.
+func (g *GeminiProvider) openStream(ctx context.Context, call *prepared) (*http.Response, error) {
+	reqBody, err := g.buildRequest(call.rules, call.history, call.prompt, call.opts)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.limiter(call.opts.apiKey).Reserve(); err != nil {
+		return nil, err
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("ai: marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, g.endpoint(call.opts.apiKey, call.opts.model, "streamGenerateContent")+"&alt=sse", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("ai: create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(ai.RequestIDHeader, ai.RequestIDFromContext(ctx))
+
+	resp, err := g.client.Do(req)
+	if err != nil {
+		g.capture(ctx, call.opts, 0, jsonBody, nil)
+		return nil, fmt.Errorf("ai: send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		g.capture(ctx, call.opts, resp.StatusCode, jsonBody, body)
+		return nil, g.rateLimited(call.opts.apiKey, g.apiError(resp, body))
+	}
+
+	if g.payloads != nil {
+		resp.Body = &capturingBody{ReadCloser: resp.Body, onClose: func(raw []byte) {
+			g.capture(ctx, call.opts, resp.StatusCode, jsonBody, raw)
+		}}
+	}
+
+	return resp, nil
+}
+
+// readStream decodes SSE events from resp into ch, saving checkpoints and updating the
+// request log. originalPrompt is the caller's prompt, before context expansion. done is
+// closed when the caller stops reading; a cancelled stream still reports its error
+// until then.
+func (g *GeminiProvider) readStream(ctx context.Context, done <-chan struct{}, call *prepared, originalPrompt string, resp *http.Response, ch chan<- ai.StreamChunk) {
+	defer close(ch)
+	defer resp.Body.Close()
+	defer g.checkBudget(ctx, call.sessionID)
+
+	// Store writes must outlive a cancelled request.
+	logCtx := context.WithoutCancel(ctx)
+
+	var cp *ai.Checkpoint
+	if g.checkpoints != nil && call.sessionID != "" {
+		id := call.logID
+		if id == "" {
+			id = g.newID()
+		}
+		cp = &ai.Checkpoint{ID: id, SessionID: call.sessionID, RequestLogID: call.logID, Prompt: originalPrompt}
+		g.checkpoints.SaveCheckpoint(logCtx, *cp)
+	}
+
+	var content strings.Builder
+	var usage ai.Usage
+	var cited []ai.Citation
+	pending := 0
+
+	// Tools disable JSON mode, so only schema-constrained JSON streams stop early.
+	var doc *ai.JSONDocumentScanner
+	if g.stopOnComplete && call.rules.OutputSchema != "" && ai.IsJSONFormat(call.rules.ResponseFormat) && len(call.opts.tools) == 0 {
+		doc = &ai.JSONDocumentScanner{}
+	}
+	stopped := false
+
+	fail := func(err error) {
+		if cp != nil {
+			cp.Content = content.String()
+			g.checkpoints.SaveCheckpoint(logCtx, *cp)
+		}
+		// The prompt and the tokens streamed so far are billed all the same
+		if usage.TotalTokens == 0 {
+			usage = estimatedUsage(g.estimateInput(call.rules, call.history, call.prompt), content.String(), call.opts.model)
+		}
+		if ai.Cancelled(ctx) {
+			g.cancelLog(logCtx, call.logID, content.String(), 0, &usage)
+			err = ai.ErrCancelled
+		} else if g.store != nil && call.logID != "" {
+			g.store.UpdateRequestLog(logCtx, call.logID,
+				content.String(),   // response
+				ai.StatusFailed,    // status
+				classifyError(err), // fail_reason
+				err.Error(),        // error_message
+				0,                  // retry_count
+				&usage,             // usage
+			)
+		}
+		if ctx.Err() != nil {
+			g.cancelledAfter(logCtx, call.logID, usage.ResponseTokens)
+		}
+		g.account(logCtx, call, usage)
+		err = &ai.RequestError{RequestID: ai.RequestIDFromContext(ctx), Err: err}
+		select {
+		case ch <- ai.StreamChunk{Err: err, Usage: &usage}:
+		case <-done:
+		}
+	}
+
+	scanner := bufio.NewScanner(resp.Body)
+	scanner.Buffer(make([]byte, 64<<10), 16<<20)
+
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+
+		var chunk geminiResponse
+		if err := json.Unmarshal([]byte(strings.TrimSpace(data)), &chunk); err != nil {
+			fail(fmt.Errorf("ai: parse stream chunk: %w", err))
+			return
+		}
+
+		if chunk.UsageMetadata.TotalTokenCount > 0 {
+			usage = toUsage(chunk.UsageMetadata, call.opts.model)
+		}
+		if err := blocked(chunk); err != nil {
+			fail(err)
+			return
+		}
+		if len(chunk.Candidates) > 0 {
+			if cs := citations(chunk.Candidates[0]); len(cs) > 0 {
+				cited = cs
+			}
+		}
+
+		delta := chunkText(chunk)
+		if delta == "" {
+			continue
+		}
+		if doc != nil {
+			if n, complete := doc.Scan(delta); complete {
+				if completeJSON(content.String() + delta[:n]) {
+					delta, stopped = delta[:n], true
+				} else {
+					doc = nil // let validation report the broken document
+				}
+			}
+		}
+		content.WriteString(delta)
+
+		select {
+		case ch <- ai.StreamChunk{Delta: delta}:
+		case <-ctx.Done():
+			fail(ctx.Err())
+			return
+		}
+
+		if pending += len(delta); cp != nil && pending >= g.checkpointBytes {
+			cp.Content = content.String()
+			g.checkpoints.SaveCheckpoint(logCtx, *cp)
+			pending = 0
+		}
+		if stopped {
+			break
+		}
+	}
+
+	if stopped {
+		if usage.TotalTokens == 0 {
+			usage = estimatedUsage(g.estimateInput(call.rules, call.history, call.prompt), content.String(), call.opts.model)
+		}
+	} else if err := scanner.Err(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			err = ctxErr
+		}
+		fail(fmt.Errorf("ai: read stream: %w", err))
+		return
+	}
+
+	g.account(logCtx, call, usage)
+
+	final := ai.StreamChunk{Done: true, Usage: &usage, Citations: cited}
+	status, failReason, errMsg := ai.StatusSuccess, "", ""
+
+	if validated, err := g.validate(call.rules, call.policy, content.String()); err != nil {
+		final.Err = &ai.RequestError{RequestID: ai.RequestIDFromContext(ctx), Err: err}
+		status, errMsg = ai.StatusFailed, err.Error()
+		failReason = ai.FailReasonUnknownError
+		var ve *ai.ValidationError
+		if errors.As(err, &ve) {
+			failReason = ve.Reason
+		}
+	} else {
+		g.sample(logCtx, call, validated)
+	}
+
+	if cp != nil {
+		if final.Err == nil {
+			g.checkpoints.CompleteCheckpoint(logCtx, cp.ID)
+		} else {
+			cp.Content = content.String()
+			g.checkpoints.SaveCheckpoint(logCtx, *cp)
+		}
+	}
+
+	if g.store != nil && call.logID != "" {
+		g.store.UpdateRequestLog(logCtx, call.logID,
+			content.String(), // response
+			status,           // status
+			failReason,       // fail_reason
+			errMsg,           // error_message
+			0,                // retry_count
+			&usage,           // usage
+		)
+	}
+
+	select {
+	case ch <- final:
+	case <-done:
+	}
+}
+
+// account records the usage of a stream, finished or not, on its request log's
+// attribution, the key's quota and its rate limiter.
+func (g *GeminiProvider) account(ctx context.Context, call *prepared, usage ai.Usage) {
+	g.attribute(ctx, call.logID, call.rules, call.history, call.prompt, usage)
+	if g.quotas != nil {
+		g.quotas.AddKeyUsage(ctx, ai.KeyID(call.opts.apiKey), usage.TotalTokens, g.now())
+	}
+	g.limiter(call.opts.apiKey).AddTokens(usage.TotalTokens)
+}
+
+// cancelledAfter records on a request log how many response tokens its stream had
+// produced when the caller cancelled it, when the store supports it.
+func (g *GeminiProvider) cancelledAfter(ctx context.Context, logID string, tokens int) {
+	store, ok := g.store.(ai.CancelledStreamStore)
+	if !ok || logID == "" {
+		return
+	}
+	store.SetCancelledAfterTokens(context.WithoutCancel(ctx), logID, tokens)
+}
+
+// completeJSON reports whether content, from its first opening bracket on, is one
+// valid JSON document.
+func completeJSON(content string) bool {
+	i := strings.IndexAny(content, "{[")
+	return i >= 0 && json.Valid([]byte(content[i:]))
+}
+
+// estimatedUsage estimates the usage of a stream that ended before Gemini reported any.
+func estimatedUsage(promptTokens int, content string, model string) ai.Usage {
+	responseTokens := ai.EstimateTokens(content)
+	return ai.Usage{
+		PromptTokens:   promptTokens,
+		ResponseTokens: responseTokens,
+		TotalTokens:    promptTokens + responseTokens,
+		Model:          model,
+	}
+}
+
+// ResumeIncomplete recovers the newest incomplete stream of a session. It asks the model
+// to continue the checkpointed content and returns the combined response, re-requesting
+// the full response if the combination doesn't validate. The continuation is logged,
+// rate limited and counted against quotas and budgets like Send. Requires WithStore and
+// WithCheckpoints; returns ai.ErrNoIncompleteStream if there is nothing to resume.
+func (g *GeminiProvider) ResumeIncomplete(ctx context.Context, sessionID string) (*ai.Result, error) {
+	if g.store == nil || g.checkpoints == nil {
+		return nil, fmt.Errorf("ai: resume requires a store and checkpoint store")
+	}
+
+	cp, err := g.checkpoints.LatestIncompleteCheckpoint(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := g.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	history, err := g.store.ListMessages(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The prompt may or may not have been stored before the crash.
+	if n := len(history); n == 0 || history[n-1].Role != ai.RoleUser || history[n-1].Content != cp.Prompt {
+		history = append(history, ai.Message{SessionID: sessionID, Role: ai.RoleUser, Content: cp.Prompt})
+	}
+
+	ctx = ai.WithSessionID(ctx, sessionID)
+
+	var result *ai.Result
+	if cp.Content != "" {
+		tailHistory := append(history, ai.Message{SessionID: sessionID, Role: ai.RoleAssistant, Content: cp.Content})
+		result, _ = g.continueResponse(ctx, session.Rules, tailHistory, cp.Content)
+	}
+
+	// Nothing checkpointed, or the continuation didn't produce a valid document.
+	if result == nil {
+		result, err = g.Send(ctx, session.Rules, history[:len(history)-1], cp.Prompt)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := g.checkpoints.CompleteCheckpoint(ctx, cp.ID); err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// continueResponse asks the model to continue partial, the last message of history, and
+// returns partial and the continuation combined if that validates. The continuation is
+// prepared, logged, rate limited and counted against quotas and budgets like Send; its
+// request log records the combined response.
+func (g *GeminiProvider) continueResponse(ctx context.Context, rules ai.Rules, history []ai.Message, partial string) (*ai.Result, error) {
+	ctx, requestID := g.requestID(ctx)
+	ctx, release := g.inflight.Track(ctx, requestID)
+	defer release()
+
+	call, err := g.prepare(ctx, rules, history, resumePrompt)
+	if err != nil {
+		return nil, err
+	}
+	defer g.checkBudget(ctx, call.sessionID)
+
+	tail, err := g.sendOnce(ctx, call.rules, call.history, call.prompt, call.opts)
+	if err != nil {
+		g.failLog(ctx, call.logID, classifyError(err), err)
+		return nil, err
+	}
+	g.account(ctx, call, tail.Usage)
+
+	content, err := g.validate(call.rules, call.policy, partial+tail.Content)
+	if err != nil {
+		reason := ai.FailReasonUnknownError
+		var ve *ai.ValidationError
+		if errors.As(err, &ve) {
+			reason = ve.Reason
+		}
+		if g.store != nil && call.logID != "" {
+			g.store.UpdateRequestLog(ctx, call.logID,
+				partial+tail.Content, // response
+				ai.StatusFailed,      // status
+				reason,               // fail_reason
+				err.Error(),          // error_message
+				0,                    // retry_count
+				&tail.Usage,          // usage
+			)
+		}
+		return nil, err
+	}
+
+	if g.store != nil && call.logID != "" {
+		g.store.UpdateRequestLog(ctx, call.logID,
+			content,          // response
+			ai.StatusSuccess, // status
+			"",               // fail_reason
+			"",               // error_message
+			0,                // retry_count
+			&tail.Usage,      // usage
+		)
+	}
+	tail.Content = content
+	tail.RequestID = requestID
+	return tail, nil
+}
+
+// capturingBody records a response body as it is read and hands it to onClose.
+type capturingBody struct {
+	io.ReadCloser
+	buf     bytes.Buffer
+	onClose func([]byte)
+}
+
+func (b *capturingBody) Read(p []byte) (int, error) {
+	n, err := b.ReadCloser.Read(p)
+	b.buf.Write(p[:n])
+	return n, err
+}
+
+func (b *capturingBody) Close() error {
+	b.onClose(b.buf.Bytes())
+	return b.ReadCloser.Close()
+}
+
+// chunkText returns the non-thought text of a stream chunk.
+func chunkText(chunk geminiResponse) string {
+	if len(chunk.Candidates) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, p := range chunk.Candidates[0].Content.Parts {
+		if !p.Thought {
+			b.WriteString(p.Text)
+		}
+	}
+	return b.String()
+}
+
+// Ensure GeminiProvider implements ai.Streamer at compile time.
+var _ ai.Streamer = (*GeminiProvider)(nil)
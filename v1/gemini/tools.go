@@ -0,0 +1,88 @@
+package gemini
+
+import (
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// functionDeclarations maps ai.Tool declarations to a Gemini tools entry.
+func functionDeclarations(tools []ai.Tool) map[string]any {
+	decls := make([]map[string]any, 0, len(tools))
+	for _, t := range tools {
+		decl := map[string]any{
+			"name":        t.Name,
+			"description": t.Description,
+		}
+		if t.Parameters != nil {
+			decl["parameters"] = t.Parameters
+		}
+		decls = append(decls, decl)
+	}
+	return map[string]any{"functionDeclarations": decls}
+}
+
+// functionCallParts converts a RoleToolCall message into model functionCall parts.
+func functionCallParts(msg ai.Message) ([]map[string]any, error) {
+	calls, err := msg.ToolCalls()
+	if err != nil {
+		return nil, err
+	}
+
+	parts := make([]map[string]any, 0, len(calls))
+	for _, c := range calls {
+		parts = append(parts, map[string]any{
+			"functionCall": map[string]any{"name": c.Name, "args": c.Args},
+		})
+	}
+	return parts, nil
+}
+
+// functionResponsePart converts a RoleTool message into a functionResponse part.
+// Gemini requires the response to be an object, so other values are wrapped.
+func functionResponsePart(msg ai.Message) (map[string]any, error) {
+	result, err := msg.ToolResult()
+	if err != nil {
+		return nil, err
+	}
+
+	response, ok := result.Response.(map[string]any)
+	if !ok {
+		response = map[string]any{"result": result.Response}
+	}
+	if result.Error != "" {
+		response = map[string]any{"error": result.Error}
+	}
+
+	return map[string]any{
+		"functionResponse": map[string]any{"name": result.Name, "response": response},
+	}, nil
+}
+
+// endsWithToolResults reports whether the model's next turn answers tool output,
+// in which case Send may be called without a new prompt.
+func endsWithToolResults(history []ai.Message) bool {
+	return len(history) > 0 && history[len(history)-1].Role == ai.RoleTool
+}
+
+// toolCalls extracts the function calls from response parts.
+func toolCalls(parts []geminiPart) []ai.ToolCall {
+	var calls []ai.ToolCall
+	for i, p := range parts {
+		if p.FunctionCall == nil {
+			continue
+		}
+		id := p.FunctionCall.ID
+		if id == "" {
+			id = fmt.Sprintf("%s-%d", p.FunctionCall.Name, i)
+		}
+		calls = append(calls, ai.ToolCall{ID: id, Name: p.FunctionCall.Name, Args: p.FunctionCall.Args})
+	}
+	return calls
+}
+
+type geminiFunctionCall struct {
+	ID   string         `json:"id,omitempty"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
@@ -0,0 +1,121 @@
+package ai
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// GlossaryTerm defines a piece of domain vocabulary, e.g. a bootcamp name or a rubric
+// label, for the model. Terms with an empty TenantID are the defaults; a tenant's term
+// overrides the default with the same name.
+type GlossaryTerm struct {
+	TenantID   string    `json:"tenant_id,omitempty"`
+	Term       string    `json:"term"`
+	Definition string    `json:"definition"`
+	Aliases    []string  `json:"aliases,omitempty"` // other spellings that also match, e.g. "FSJ"
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// GlossaryStore is the optional store capability persisting glossary terms.
+type GlossaryStore interface {
+	// SetGlossaryTerm creates or replaces the term with t's TenantID and Term, and
+	// returns it with UpdatedAt set.
+	SetGlossaryTerm(ctx context.Context, t GlossaryTerm) (*GlossaryTerm, error)
+	// ListGlossaryTerms returns the terms of exactly tenantID, ordered by term. The
+	// empty tenantID lists the defaults.
+	ListGlossaryTerms(ctx context.Context, tenantID string) ([]GlossaryTerm, error)
+	DeleteGlossaryTerm(ctx context.Context, tenantID string, term string) error
+}
+
+// ResolveGlossary returns the glossary of the tenant attached to ctx: the default terms
+// with the tenant's own terms replacing or adding to them, ordered by term.
+func ResolveGlossary(ctx context.Context, store GlossaryStore) ([]GlossaryTerm, error) {
+	terms, err := store.ListGlossaryTerms(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	tenantID := TenantIDFromContext(ctx)
+	if tenantID == "" {
+		return terms, nil
+	}
+	overrides, err := store.ListGlossaryTerms(ctx, tenantID)
+	if err != nil {
+		return nil, err
+	}
+
+	byTerm := make(map[string]GlossaryTerm, len(terms)+len(overrides))
+	for _, t := range append(terms, overrides...) {
+		byTerm[strings.ToLower(t.Term)] = t
+	}
+	merged := make([]GlossaryTerm, 0, len(byTerm))
+	for _, t := range byTerm {
+		merged = append(merged, t)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Term < merged[j].Term })
+	return merged, nil
+}
+
+// MatchGlossary returns the terms of glossary that appear in text, by their term or an
+// alias, in glossary order. Matching ignores case and only counts whole words, so
+// "Java" doesn't match "JavaScript".
+func MatchGlossary(glossary []GlossaryTerm, text string) []GlossaryTerm {
+	text = strings.ToLower(text)
+
+	var out []GlossaryTerm
+	for _, t := range glossary {
+		for _, name := range append([]string{t.Term}, t.Aliases...) {
+			if containsWord(text, strings.ToLower(name)) {
+				out = append(out, t)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// containsWord reports whether word occurs in text with no letter or digit directly
+// before or after it.
+func containsWord(text, word string) bool {
+	if word == "" {
+		return false
+	}
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], word)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(word)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && (unicode.IsLetter(r) || unicode.IsDigit(r))
+}
+
+// RenderGlossary formats terms as a context block for a system instruction. It returns
+// "" when there are no terms.
+func RenderGlossary(terms []GlossaryTerm) string {
+	if len(terms) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Glossary of terms used in this conversation:")
+	for _, t := range terms {
+		b.WriteString("\n- ")
+		b.WriteString(t.Term)
+		b.WriteString(": ")
+		b.WriteString(t.Definition)
+	}
+	return b.String()
+}
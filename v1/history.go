@@ -0,0 +1,85 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// HistoryTransform rewrites history before a provider sends it. Transforms must not
+// modify the messages they are given; they return a new slice.
+type HistoryTransform interface {
+	Transform(ctx context.Context, history []Message) ([]Message, error)
+}
+
+// HistoryTransformFunc adapts a function to HistoryTransform.
+type HistoryTransformFunc func(ctx context.Context, history []Message) ([]Message, error)
+
+// Transform calls f.
+func (f HistoryTransformFunc) Transform(ctx context.Context, history []Message) ([]Message, error) {
+	return f(ctx, history)
+}
+
+// MessageTruncator shrinks individual history messages over a token budget, such as a
+// pasted syllabus, so one message can't crowd out the rest of the context. Tokens are
+// estimated with EstimateTokens. Events are left alone.
+type MessageTruncator struct {
+	MaxTokens int // per-message budget; messages within it are kept as is
+
+	// Summarize, when set, replaces an oversized message with a summary of at most
+	// maxTokens instead of cutting it. A failed summary falls back to truncation.
+	Summarize func(ctx context.Context, content string, maxTokens int) (string, error)
+}
+
+// NewMessageTruncator creates a truncator that cuts messages over maxTokens.
+func NewMessageTruncator(maxTokens int) *MessageTruncator {
+	return &MessageTruncator{MaxTokens: maxTokens}
+}
+
+// Transform returns a copy of history with oversized messages truncated or summarized,
+// each followed by a note telling the model what happened.
+func (t *MessageTruncator) Transform(ctx context.Context, history []Message) ([]Message, error) {
+	if t.MaxTokens <= 0 {
+		return history, nil
+	}
+
+	out := make([]Message, len(history))
+	copy(out, history)
+
+	for i, msg := range out {
+		tokens := EstimateTokens(msg.Content)
+		if msg.Role == RoleEvent || tokens <= t.MaxTokens {
+			continue
+		}
+
+		if t.Summarize != nil {
+			summary, err := t.Summarize(ctx, msg.Content, t.MaxTokens)
+			if err == nil && EstimateTokens(summary) <= t.MaxTokens {
+				out[i].Content = summary + fmt.Sprintf("\n\n[Summarized: the original message was about %d tokens.]", tokens)
+				continue
+			}
+		}
+
+		out[i].Content = truncateRunes(msg.Content, t.MaxTokens*4) + fmt.Sprintf("\n\n[Truncated: showing about %d of %d tokens.]", t.MaxTokens, tokens)
+	}
+
+	return out, nil
+}
+
+// truncateRunes cuts s to at most n runes, backing up to the last whitespace when one is
+// close so words aren't split.
+func truncateRunes(s string, n int) string {
+	runes := []rune(s)
+	if len(runes) <= n {
+		return s
+	}
+	cut := n
+	for i := n; i > n-n/10 && i > 0; i-- {
+		if unicode.IsSpace(runes[i]) {
+			cut = i
+			break
+		}
+	}
+	return strings.TrimRightFunc(string(runes[:cut]), unicode.IsSpace)
+}
@@ -0,0 +1,240 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	ErrHistorySummaryNotFound = errors.New("ai: history summary not found")
+)
+
+// DefaultSummaryPrompt is the system prompt of the requests a HistorySummarizer makes.
+const DefaultSummaryPrompt = `You summarize conversations for an assistant that will continue them without seeing the earlier turns.
+Keep every fact, requirement, decision, piece of generated content the user may refer back to, and open question. Drop greetings and repetition.
+If a previous summary is given, merge it with the new turns into a single summary. Write plain text in the language of the conversation, in at most about 300 words.`
+
+// HistorySummary is a rolling summary of a session's earliest turns: every message up
+// to and including ThroughSeq.
+type HistorySummary struct {
+	SessionID  string    `json:"session_id"`
+	ThroughSeq int       `json:"through_seq"`
+	Turns      int       `json:"turns"` // user turns summarized
+	Content    string    `json:"content"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// HistorySummaryStore is the optional store capability keeping history summaries.
+type HistorySummaryStore interface {
+	// AddHistorySummary stores s, replacing a summary of the same session and ThroughSeq.
+	AddHistorySummary(ctx context.Context, s HistorySummary) error
+	// LatestHistorySummary returns the session's summary with the highest ThroughSeq,
+	// or ErrHistorySummaryNotFound.
+	LatestHistorySummary(ctx context.Context, sessionID string) (*HistorySummary, error)
+}
+
+// HistorySummarizer is a HistoryTransform that sends the latest summary of a session's
+// earlier turns plus its recent turns verbatim, instead of the whole history. A turn
+// starts at each user message. Once the turns after the summary reach the number kept
+// verbatim plus every, the summary is extended over all but the most recent of them,
+// in the background, and later requests use it; so every turns a summary is made, and
+// between the recent turns and twice as many are sent verbatim. Until a session has a
+// summary, its history is sent whole.
+//
+// The summary goes ahead of the first verbatim turn, which is always a user message,
+// so roles keep alternating with any provider. Summary requests are made with the
+// given provider in the request's session and tenant, and logged by it as configured.
+type HistorySummarizer struct {
+	store    HistorySummaryStore
+	provider Provider
+	every    int
+	recent   int
+	prompt   string
+	onError  func(err error)
+	now      Clock
+
+	mu       sync.Mutex
+	running  map[string]bool // sessions with a summary being made
+	inFlight sync.WaitGroup
+}
+
+// NewHistorySummarizer returns a summarizer summarizing every turns with provider,
+// keeping summaries in store. The most recent every turns are kept verbatim too; see
+// WithRecentTurns.
+func NewHistorySummarizer(store HistorySummaryStore, provider Provider, every int) *HistorySummarizer {
+	every = max(every, 1)
+	return &HistorySummarizer{
+		store:    store,
+		provider: provider,
+		every:    every,
+		recent:   every,
+		prompt:   DefaultSummaryPrompt,
+		now:      time.Now,
+		running:  make(map[string]bool),
+	}
+}
+
+// WithRecentTurns sets how many of the latest turns are always sent verbatim. More
+// costs more tokens per request and keeps more detail.
+func (s *HistorySummarizer) WithRecentTurns(n int) *HistorySummarizer {
+	s.recent = max(n, 1)
+	return s
+}
+
+// WithPrompt replaces DefaultSummaryPrompt as the system prompt of summary requests.
+func (s *HistorySummarizer) WithPrompt(prompt string) *HistorySummarizer {
+	s.prompt = prompt
+	return s
+}
+
+// WithErrorHandler sets a function called with the errors of making and storing
+// summaries, which never fail a request.
+func (s *HistorySummarizer) WithErrorHandler(fn func(err error)) *HistorySummarizer {
+	s.onError = fn
+	return s
+}
+
+// WithClock sets the clock that stamps summaries.
+func (s *HistorySummarizer) WithClock(clock Clock) *HistorySummarizer {
+	s.now = clock
+	return s
+}
+
+// Wait blocks until the summaries being made have been stored, e.g. before shutting
+// down.
+func (s *HistorySummarizer) Wait() {
+	s.inFlight.Wait()
+}
+
+// Transform returns the session's latest summary and the turns after it, and starts
+// extending the summary when enough turns have accumulated. A summary that doesn't
+// line up with history, such as one made after the point a replay starts from, is
+// ignored, and so is a failing store: history is then sent whole.
+func (s *HistorySummarizer) Transform(ctx context.Context, history []Message) ([]Message, error) {
+	if len(history) == 0 || history[0].SessionID == "" {
+		return history, nil
+	}
+	sessionID := history[0].SessionID
+
+	summary, err := s.store.LatestHistorySummary(ctx, sessionID)
+	if err != nil {
+		if !errors.Is(err, ErrHistorySummaryNotFound) {
+			s.report(fmt.Errorf("ai: history summary of session %s: %w", sessionID, err))
+		}
+		summary = nil
+	}
+
+	start := 0 // first message after the summary
+	if summary != nil {
+		start = -1
+		for i, m := range history {
+			if m.Seq == summary.ThroughSeq && i+1 < len(history) {
+				start = i + 1
+				break
+			}
+		}
+		if start < 0 {
+			summary, start = nil, 0
+		}
+	}
+
+	turns := turnStarts(history, start)
+	if len(turns) >= s.recent+s.every && !IsDryRun(ctx) {
+		s.extend(ctx, sessionID, summary, history[start:turns[len(turns)-s.recent]])
+	}
+
+	if summary == nil {
+		return history, nil
+	}
+	raw := history[start:]
+	out := make([]Message, len(raw))
+	copy(out, raw)
+	for i := range out {
+		if out[i].Role == RoleUser {
+			out[i].Content = fmt.Sprintf("[Summary of the %d earlier turns of this conversation]\n%s\n[End of summary]\n\n%s", summary.Turns, summary.Content, out[i].Content)
+			break
+		}
+	}
+	return out, nil
+}
+
+// turnStarts returns the indexes of the user messages of history from start on.
+func turnStarts(history []Message, start int) []int {
+	var starts []int
+	for i := start; i < len(history); i++ {
+		if history[i].Role == RoleUser {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+// extend summarizes previous and msgs into a new summary in the background, unless one
+// is already being made for the session.
+func (s *HistorySummarizer) extend(ctx context.Context, sessionID string, previous *HistorySummary, msgs []Message) {
+	s.mu.Lock()
+	if s.running[sessionID] {
+		s.mu.Unlock()
+		return
+	}
+	s.running[sessionID] = true
+	s.mu.Unlock()
+
+	// A fresh context, so the summary request doesn't inherit the request's ID, tools
+	// or system addendum, or its cancellation.
+	summaryCtx := WithSessionID(WithActor(context.Background(), ActorFromContext(ctx)), sessionID)
+	summaryCtx = WithRequestID(summaryCtx, NewRequestID())
+	msgs = append([]Message(nil), msgs...)
+
+	s.inFlight.Add(1)
+	go func() {
+		defer func() {
+			s.mu.Lock()
+			delete(s.running, sessionID)
+			s.mu.Unlock()
+			s.inFlight.Done()
+		}()
+		if err := s.summarize(summaryCtx, sessionID, previous, msgs); err != nil {
+			s.report(fmt.Errorf("ai: summarize session %s: %w", sessionID, err))
+		}
+	}()
+}
+
+// summarize makes and stores the summary of previous and msgs.
+func (s *HistorySummarizer) summarize(ctx context.Context, sessionID string, previous *HistorySummary, msgs []Message) error {
+	var b strings.Builder
+	next := HistorySummary{SessionID: sessionID, ThroughSeq: msgs[len(msgs)-1].Seq}
+	if previous != nil {
+		next.Turns = previous.Turns
+		fmt.Fprintf(&b, "Previous summary:\n%s\n\nNew turns:\n", previous.Content)
+	} else {
+		b.WriteString("Conversation:\n")
+	}
+	for _, m := range msgs {
+		if m.IsEvent() {
+			continue
+		}
+		if m.Role == RoleUser {
+			next.Turns++
+		}
+		fmt.Fprintf(&b, "%s: %s\n", m.Role, m.Content)
+	}
+
+	result, err := s.provider.Send(ctx, Rules{SystemPrompt: s.prompt, ResponseFormat: FormatText}, nil, b.String())
+	if err != nil {
+		return err
+	}
+	next.Content = strings.TrimSpace(result.Content)
+	next.CreatedAt = s.now()
+	return s.store.AddHistorySummary(ctx, next)
+}
+
+func (s *HistorySummarizer) report(err error) {
+	if s.onError != nil {
+		s.onError(err)
+	}
+}
@@ -0,0 +1,346 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultHookQueue is the number of async hook calls that can be pending before
+// writers block.
+const defaultHookQueue = 256
+
+// Store hook event types.
+const (
+	HookSessionCreated = "session_created"
+	HookSessionDeleted = "session_deleted"
+	HookMessageAdded   = "message_added" // messages and events
+	HookLogFailed      = "request_log_failed"
+)
+
+// StoreEvent is a write a HookedStore passes to hooks. Fields not relevant to Type are
+// zero.
+type StoreEvent struct {
+	Type      string
+	SessionID string
+	Session   *Session // HookSessionCreated
+	Message   *Message // HookMessageAdded
+
+	// HookLogFailed: the request log and the failure it was updated with.
+	RequestLogID string
+	FailReason   string
+	ErrorMessage string
+	RetryCount   int
+
+	At time.Time
+}
+
+// StoreHook is a callback run after a store write.
+type StoreHook func(ctx context.Context, e StoreEvent) error
+
+type hookCall struct {
+	ctx   context.Context
+	hooks []StoreHook
+	event StoreEvent
+}
+
+// HookedStore is a Store that runs registered hooks after its writes succeed, so
+// applications can maintain derived data, such as the latest form of each session,
+// without polling. Hooks run:
+//
+//   - after CreateSession and DeleteSession, with HookSessionCreated and
+//     HookSessionDeleted
+//   - after AddMessage and AddEvent, with HookMessageAdded and the stored message, and
+//     after AddMessages once for each stored message
+//   - after UpdateRequestLog sets the failed status, with HookLogFailed. A request
+//     retried after a failed attempt may still succeed later.
+//
+// Hooks registered with On run synchronously, in order, before the write returns;
+// those registered with OnAsync run in the background, one at a time in the order of
+// the writes. A hook's error never fails the write, which has already been stored: it
+// is passed to the error handler, and the remaining hooks still run. Call Close before
+// exiting to run the queued async hooks and stop their worker. Writes made
+// through other paths, such as imports, clones or the wrapped store directly, don't
+// run hooks. Prompt attributions, retry turns and cancelled-stream counts are
+// forwarded to the wrapped store when it records them. It is safe for concurrent use.
+type HookedStore struct {
+	Store
+
+	mu      sync.RWMutex
+	hooks   map[string][]StoreHook // synchronous
+	async   map[string][]StoreHook
+	onError func(e StoreEvent, err error)
+	now     Clock
+
+	queue       chan hookCall
+	start       sync.Once
+	inFlight    sync.WaitGroup
+	qmu         sync.RWMutex // held for writing only to close the queue
+	closed      bool
+	closing     chan struct{} // closed by Close, releasing blocked writers
+	closingOnce sync.Once
+	abort       chan struct{} // closed when Close gives up on the queued calls
+	abortOnce   sync.Once
+	done        chan struct{}
+}
+
+// WithHooks returns store with no hooks registered.
+func WithHooks(store Store) *HookedStore {
+	return &HookedStore{
+		Store:   store,
+		hooks:   make(map[string][]StoreHook),
+		async:   make(map[string][]StoreHook),
+		now:     time.Now,
+		queue:   make(chan hookCall, defaultHookQueue),
+		closing: make(chan struct{}),
+		abort:   make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+// On registers hook to run synchronously after writes of eventType. Keep such hooks
+// fast: they add to the latency of every write.
+func (h *HookedStore) On(eventType string, hook StoreHook) *HookedStore {
+	h.mu.Lock()
+	h.hooks[eventType] = append(h.hooks[eventType], hook)
+	h.mu.Unlock()
+	return h
+}
+
+// OnAsync registers hook to run in the background after writes of eventType, with
+// the write's context values but not its cancellation. When 256 calls are pending,
+// writers block until there is room or their context is done, in which case the call
+// is dropped and reported.
+func (h *HookedStore) OnAsync(eventType string, hook StoreHook) *HookedStore {
+	h.mu.Lock()
+	h.async[eventType] = append(h.async[eventType], hook)
+	h.mu.Unlock()
+	return h
+}
+
+// WithErrorHandler sets a function called with the errors of hooks and with dropped
+// async calls.
+func (h *HookedStore) WithErrorHandler(fn func(e StoreEvent, err error)) *HookedStore {
+	h.onError = fn
+	return h
+}
+
+// WithClock sets the clock that stamps events.
+func (h *HookedStore) WithClock(clock Clock) *HookedStore {
+	h.now = clock
+	return h
+}
+
+// Wait blocks until the async hook calls queued so far have run, e.g. before shutting
+// down.
+func (h *HookedStore) Wait() {
+	h.inFlight.Wait()
+}
+
+// Close stops queueing async hook calls and waits for the queued ones to run, then stops
+// the worker. Writes after Close still run the synchronous hooks; their async calls are
+// dropped and reported. If ctx is done first, the calls not yet started are dropped and
+// ctx's error is returned.
+func (h *HookedStore) Close(ctx context.Context) error {
+	h.start.Do(func() { go h.work() })
+	h.closingOnce.Do(func() { close(h.closing) })
+
+	h.qmu.Lock()
+	if !h.closed {
+		h.closed = true
+		close(h.queue)
+	}
+	h.qmu.Unlock()
+
+	select {
+	case <-h.done:
+		return nil
+	case <-ctx.Done():
+		h.abortOnce.Do(func() { close(h.abort) })
+		<-h.done
+		return ctx.Err()
+	}
+}
+
+// CreateSession creates a session in the wrapped store, then runs the
+// HookSessionCreated hooks. Rules that CheckRules rejects are not passed on, and its
+// error is returned.
+func (h *HookedStore) CreateSession(ctx context.Context, rules Rules) (*Session, error) {
+	if err := CheckRules(nil, rules); err != nil {
+		return nil, fmt.Errorf("ai: create session: %w", err)
+	}
+	session, err := h.Store.CreateSession(ctx, rules)
+	if err != nil {
+		return nil, err
+	}
+	h.fire(ctx, StoreEvent{Type: HookSessionCreated, SessionID: session.ID, Session: session})
+	return session, nil
+}
+
+// DeleteSession deletes a session from the wrapped store, then runs the
+// HookSessionDeleted hooks. It fails with ErrUnsupported when the wrapped store isn't a
+// SessionDeleter.
+func (h *HookedStore) DeleteSession(ctx context.Context, sessionID string) error {
+	if err := DeleteSession(ctx, h.Store, sessionID); err != nil {
+		return err
+	}
+	h.fire(ctx, StoreEvent{Type: HookSessionDeleted, SessionID: sessionID})
+	return nil
+}
+
+// AddMessage adds a message to the wrapped store, then runs the HookMessageAdded
+// hooks.
+func (h *HookedStore) AddMessage(ctx context.Context, sessionID string, role string, content string, usage *Usage) (*Message, error) {
+	msg, err := h.Store.AddMessage(ctx, sessionID, role, content, usage)
+	if err != nil {
+		return nil, err
+	}
+	h.fire(ctx, StoreEvent{Type: HookMessageAdded, SessionID: sessionID, Message: msg})
+	return msg, nil
+}
+
+// AddEvent adds an event to the wrapped store, then runs the HookMessageAdded hooks. It
+// fails with ErrUnsupported when the wrapped store isn't an EventStore.
+func (h *HookedStore) AddEvent(ctx context.Context, sessionID string, eventType string, payload string) (*Message, error) {
+	msg, err := AddEvent(ctx, h.Store, sessionID, eventType, payload)
+	if err != nil {
+		return nil, err
+	}
+	h.fire(ctx, StoreEvent{Type: HookMessageAdded, SessionID: sessionID, Message: msg})
+	return msg, nil
+}
+
+// AddMessages appends msgs to the wrapped store with AddMessages, atomically when it is
+// a BulkMessageStore, then runs the HookMessageAdded hooks for each stored message,
+// including those stored before a failing one.
+func (h *HookedStore) AddMessages(ctx context.Context, sessionID string, msgs []NewMessage) ([]Message, error) {
+	added, err := AddMessages(ctx, h.Store, sessionID, msgs)
+	for i := range added {
+		h.fire(ctx, StoreEvent{Type: HookMessageAdded, SessionID: sessionID, Message: &added[i]})
+	}
+	return added, err
+}
+
+// UpdateRequestLog updates a request log in the wrapped store, then runs the
+// HookLogFailed hooks if status is StatusFailed. The event's SessionID is only set
+// when ctx carries one.
+func (h *HookedStore) UpdateRequestLog(ctx context.Context, id string, response string, status string, failReason string, errorMsg string, retryCount int, usage *Usage) error {
+	if err := h.Store.UpdateRequestLog(ctx, id, response, status, failReason, errorMsg, retryCount, usage); err != nil {
+		return err
+	}
+	if status == StatusFailed {
+		h.fire(ctx, StoreEvent{
+			Type:         HookLogFailed,
+			SessionID:    SessionIDFromContext(ctx),
+			RequestLogID: id,
+			FailReason:   failReason,
+			ErrorMessage: errorMsg,
+			RetryCount:   retryCount,
+		})
+	}
+	return nil
+}
+
+// SetPromptAttribution records a on the wrapped store when it implements
+// PromptAttributionStore, and does nothing otherwise.
+func (h *HookedStore) SetPromptAttribution(ctx context.Context, requestLogID string, a PromptAttribution) error {
+	store, ok := h.Store.(PromptAttributionStore)
+	if !ok {
+		return nil
+	}
+	return store.SetPromptAttribution(ctx, requestLogID, a)
+}
+
+// SetCancelledAfterTokens records tokens on the wrapped store when it implements
+// CancelledStreamStore, and does nothing otherwise.
+func (h *HookedStore) SetCancelledAfterTokens(ctx context.Context, requestLogID string, tokens int) error {
+	store, ok := h.Store.(CancelledStreamStore)
+	if !ok {
+		return nil
+	}
+	return store.SetCancelledAfterTokens(ctx, requestLogID, tokens)
+}
+
+// AddRetryTurn records turn on the wrapped store when it implements
+// RetryTranscriptStore, and does nothing otherwise.
+func (h *HookedStore) AddRetryTurn(ctx context.Context, requestLogID string, turn RetryTurn) error {
+	store, ok := h.Store.(RetryTranscriptStore)
+	if !ok {
+		return nil
+	}
+	return store.AddRetryTurn(ctx, requestLogID, turn)
+}
+
+// fire runs the synchronous hooks of e and queues its async hooks.
+func (h *HookedStore) fire(ctx context.Context, e StoreEvent) {
+	h.mu.RLock()
+	syncHooks, asyncHooks := h.hooks[e.Type], h.async[e.Type]
+	h.mu.RUnlock()
+	if len(syncHooks) == 0 && len(asyncHooks) == 0 {
+		return
+	}
+	e.At = h.now()
+
+	h.run(ctx, syncHooks, e)
+	if len(asyncHooks) == 0 {
+		return
+	}
+
+	h.start.Do(func() { go h.work() })
+	h.qmu.RLock()
+	defer h.qmu.RUnlock()
+	if h.closed {
+		h.report(e, fmt.Errorf("ai: %s hooks dropped: hooked store closed", e.Type))
+		return
+	}
+
+	h.inFlight.Add(1)
+	select {
+	case h.queue <- hookCall{ctx: context.WithoutCancel(ctx), hooks: asyncHooks, event: e}:
+	case <-ctx.Done():
+		h.inFlight.Done()
+		h.report(e, fmt.Errorf("ai: %s hooks dropped: %w", e.Type, ctx.Err()))
+	case <-h.closing:
+		h.inFlight.Done()
+		h.report(e, fmt.Errorf("ai: %s hooks dropped: hooked store closed", e.Type))
+	}
+}
+
+// work runs the queued async hook calls in order until the queue is closed and drained.
+func (h *HookedStore) work() {
+	defer close(h.done)
+	for call := range h.queue {
+		select {
+		case <-h.abort:
+			h.report(call.event, fmt.Errorf("ai: %s hooks dropped: hooked store closed", call.event.Type))
+		default:
+			h.run(call.ctx, call.hooks, call.event)
+		}
+		h.inFlight.Done()
+	}
+}
+
+// run calls hooks in order, reporting their errors and panics.
+func (h *HookedStore) run(ctx context.Context, hooks []StoreHook, e StoreEvent) {
+	for _, hook := range hooks {
+		h.report(e, callHook(ctx, hook, e))
+	}
+}
+
+// callHook calls hook, turning a panic into an error so one faulty hook can't take down
+// the writer or the async worker.
+func callHook(ctx context.Context, hook StoreHook, e StoreEvent) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("ai: %s hook panicked: %v", e.Type, r)
+		}
+	}()
+	return hook(ctx, e)
+}
+
+func (h *HookedStore) report(e StoreEvent, err error) {
+	if err != nil && h.onError != nil {
+		h.onError(e, err)
+	}
+}
@@ -0,0 +1,120 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidID = errors.New("ai: invalid id")
+)
+
+// maxIDLength bounds stored IDs; generated ones (UUIDs, hex request IDs) are far shorter.
+const maxIDLength = 128
+
+// SessionID, MessageID and RequestLogID are distinct types for the string IDs of
+// sessions, messages and request logs, so code holding one can't pass it where another
+// is expected without a visible conversion. They encode as plain strings in JSON and
+// in the database.
+//
+// Store methods and struct fields keep plain strings, so existing stores and callers
+// are unaffected; convert with string(id) and SessionID(s) at the boundary.
+type (
+	SessionID    string
+	MessageID    string
+	RequestLogID string
+)
+
+// ParseSessionID validates s as a session ID. See ValidateID.
+func ParseSessionID(s string) (SessionID, error) {
+	if err := ValidateID("session", s); err != nil {
+		return "", err
+	}
+	return SessionID(s), nil
+}
+
+// ParseMessageID validates s as a message ID. See ValidateID.
+func ParseMessageID(s string) (MessageID, error) {
+	if err := ValidateID("message", s); err != nil {
+		return "", err
+	}
+	return MessageID(s), nil
+}
+
+// ParseRequestLogID validates s as a request log ID. See ValidateID.
+func ParseRequestLogID(s string) (RequestLogID, error) {
+	if err := ValidateID("request log", s); err != nil {
+		return "", err
+	}
+	return RequestLogID(s), nil
+}
+
+// ValidateID checks that s can be an ID of the named kind: 1 to 128 ASCII letters,
+// digits or the characters "-", "_", ".", ":". That covers UUIDs, hex and ULIDs, and
+// any IDGenerator output worth storing, while rejecting empty values, whitespace and
+// values pasted from elsewhere. It returns an error wrapping ErrInvalidID.
+func ValidateID(kind, s string) error {
+	if s == "" {
+		return fmt.Errorf("%w: empty %s id", ErrInvalidID, kind)
+	}
+	if len(s) > maxIDLength {
+		return fmt.Errorf("%w: %s id longer than %d bytes", ErrInvalidID, kind, maxIDLength)
+	}
+	for i := 0; i < len(s); i++ {
+		if c := s[i]; !isIDByte(c) {
+			return fmt.Errorf("%w: %s id %q has invalid character %q", ErrInvalidID, kind, s, c)
+		}
+	}
+	return nil
+}
+
+func isIDByte(c byte) bool {
+	return 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' ||
+		c == '-' || c == '_' || c == '.' || c == ':'
+}
+
+// String returns the ID.
+func (id SessionID) String() string { return string(id) }
+
+// Validate reports whether the ID is well formed.
+func (id SessionID) Validate() error { return ValidateID("session", string(id)) }
+
+// UnmarshalText accepts only valid IDs, so decoding rejects malformed input. An empty
+// value decodes to the zero ID.
+func (id *SessionID) UnmarshalText(text []byte) error {
+	return unmarshalID((*string)(id), "session", text)
+}
+
+// String returns the ID.
+func (id MessageID) String() string { return string(id) }
+
+// Validate reports whether the ID is well formed.
+func (id MessageID) Validate() error { return ValidateID("message", string(id)) }
+
+// UnmarshalText accepts only valid IDs, so decoding rejects malformed input. An empty
+// value decodes to the zero ID.
+func (id *MessageID) UnmarshalText(text []byte) error {
+	return unmarshalID((*string)(id), "message", text)
+}
+
+// String returns the ID.
+func (id RequestLogID) String() string { return string(id) }
+
+// Validate reports whether the ID is well formed.
+func (id RequestLogID) Validate() error { return ValidateID("request log", string(id)) }
+
+// UnmarshalText accepts only valid IDs, so decoding rejects malformed input. An empty
+// value decodes to the zero ID.
+func (id *RequestLogID) UnmarshalText(text []byte) error {
+	return unmarshalID((*string)(id), "request log", text)
+}
+
+func unmarshalID(dst *string, kind string, text []byte) error {
+	if len(text) > 0 {
+		if err := ValidateID(kind, string(text)); err != nil {
+			return err
+		}
+	}
+	*dst = string(text)
+	return nil
+}
@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrIntegrity = errors.New("ai: integrity check failed")
+)
+
+// Integrity problems.
+const (
+	IntegrityHashMismatch = "hash_mismatch" // stored content doesn't match its SHA-256
+	IntegrityBlobMissing  = "blob_missing"  // offloaded content can't be read back
+)
+
+// IntegrityProblem is one stored row whose content can't be trusted.
+type IntegrityProblem struct {
+	MessageID    string `json:"message_id,omitempty"`
+	Seq          int    `json:"seq,omitempty"`
+	RequestLogID string `json:"request_log_id,omitempty"`
+	Problem      string `json:"problem"`
+	Detail       string `json:"detail,omitempty"`
+}
+
+// IntegrityReport is the result of checking a session's stored content against the
+// SHA-256 recorded when it was written.
+type IntegrityReport struct {
+	SessionID string             `json:"session_id"`
+	Verified  int                `json:"verified"` // messages and responses whose hash matched
+	Unhashed  int                `json:"unhashed"` // rows written before hashes were recorded
+	Problems  []IntegrityProblem `json:"problems,omitempty"`
+}
+
+// Err returns an error wrapping ErrIntegrity that summarizes the problems, or nil when
+// there are none.
+func (r *IntegrityReport) Err() error {
+	if len(r.Problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("%w: session %s: %d problem(s), first %s", ErrIntegrity, r.SessionID, len(r.Problems), r.Problems[0].Problem)
+}
+
+// IntegrityVerifier is the optional store capability for content integrity checks.
+type IntegrityVerifier interface {
+	// VerifyIntegrity rehashes every message and request-log response of a session,
+	// reading offloaded content back from its blob, or returns ErrSessionNotFound.
+	VerifyIntegrity(ctx context.Context, sessionID string) (*IntegrityReport, error)
+}
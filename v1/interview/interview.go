@@ -0,0 +1,157 @@
+// Package interview runs guided, multi-turn data collection such as intake chatbots:
+// each turn the model emits the next question and the state collected so far as JSON,
+// and the session persists every turn.
+package interview
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+var (
+	ErrComplete    = errors.New("ai: interview already complete")
+	ErrInvalidTurn = errors.New("ai: invalid interview turn")
+)
+
+// EventComplete is the session event recorded when an interview finishes. Its payload
+// is the final state.
+const EventComplete = "interview_complete"
+
+// startPrompt opens the interview; it is stored as the session's first user turn.
+const startPrompt = "Start the interview."
+
+const instructions = `You are conducting a structured interview to collect the information described below.
+Ask one question at a time. After every answer, update the state with everything the user has told you so far, keeping earlier answers unless the user corrects them.
+Respond with a JSON object with "question" (the next question to ask, or a short closing message when done), "state" (the information collected so far, following the state schema) and "done" (true once everything required has been collected or the user declines to continue).`
+
+// Turn is one model turn of an interview.
+type Turn struct {
+	Question string          `json:"question"`
+	State    json.RawMessage `json:"state"`
+	Done     bool            `json:"done"`
+}
+
+// Interviewer asks the questions needed to fill a state object described by a JSON
+// Schema, one turn at a time.
+type Interviewer struct {
+	provider ai.Provider
+	store    ai.Store
+	brief    string
+	schema   string
+}
+
+// New creates an interviewer collecting a state matching stateSchema, a JSON Schema
+// object. brief tells the model what the interview is for and how to ask, e.g.
+// "You are the admissions assistant of a coding bootcamp."
+func New(provider ai.Provider, store ai.Store, brief string, stateSchema string) *Interviewer {
+	return &Interviewer{provider: provider, store: store, brief: brief, schema: stateSchema}
+}
+
+// Rules returns the session rules of the interview: the brief and instructions as the
+// system prompt, and the turn schema, embedding the state schema, as the output schema.
+func (iv *Interviewer) Rules() ai.Rules {
+	schema := fmt.Sprintf(`{"type":"object","properties":{"question":{"type":"string"},"state":%s,"done":{"type":"boolean"}},"required":["question","state","done"]}`, iv.schema)
+	return ai.Rules{
+		SystemPrompt: strings.TrimSpace(iv.brief + "\n\n" + instructions),
+		OutputSchema: schema,
+	}
+}
+
+// Start creates the interview's session and returns it with the first question.
+func (iv *Interviewer) Start(ctx context.Context) (string, *Turn, error) {
+	if !json.Valid([]byte(iv.schema)) {
+		return "", nil, fmt.Errorf("ai: interview: state schema is not valid JSON")
+	}
+	session, err := iv.store.CreateSession(ctx, iv.Rules())
+	if err != nil {
+		return "", nil, err
+	}
+	turn, err := iv.ask(ctx, session.ID, session.Rules, nil, startPrompt)
+	if err != nil {
+		return session.ID, nil, err
+	}
+	return session.ID, turn, nil
+}
+
+// NextQuestion records userAnswer to the last question of sessionID and returns the
+// model's next turn, with the updated state. When the turn is Done, an EventComplete
+// event is recorded and further answers fail with ErrComplete.
+func (iv *Interviewer) NextQuestion(ctx context.Context, sessionID string, userAnswer string) (*Turn, error) {
+	if strings.TrimSpace(userAnswer) == "" {
+		return nil, ai.ErrEmptyPrompt
+	}
+	session, err := iv.store.GetSession(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	msgs, err := iv.store.ListMessages(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range msgs {
+		if m.EventType == EventComplete {
+			return nil, ErrComplete
+		}
+	}
+	return iv.ask(ctx, sessionID, session.Rules, ai.ConversationHistory(msgs), userAnswer)
+}
+
+// State returns the latest turn of sessionID, whose State is everything collected so
+// far. It returns ai.ErrMessageNotFound before the first turn.
+func (iv *Interviewer) State(ctx context.Context, sessionID string) (*Turn, error) {
+	msgs, err := iv.store.ListMessages(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == ai.RoleAssistant {
+			return parseTurn(msgs[i].Content)
+		}
+	}
+	return nil, ai.ErrMessageNotFound
+}
+
+// ask sends prompt, then stores it and the model's turn together.
+func (iv *Interviewer) ask(ctx context.Context, sessionID string, rules ai.Rules, history []ai.Message, prompt string) (*Turn, error) {
+	ctx = ai.WithSessionID(ctx, sessionID)
+	result, err := iv.provider.Send(ctx, rules, history, prompt)
+	if err != nil {
+		return nil, err
+	}
+	turn, err := parseTurn(result.Content)
+	if err != nil {
+		return nil, err
+	}
+
+	batch := []ai.NewMessage{
+		{Role: ai.RoleUser, Content: prompt},
+		{Role: ai.RoleAssistant, Content: result.Content, Usage: &result.Usage},
+	}
+	if turn.Done {
+		batch = append(batch, ai.NewMessage{Role: ai.RoleEvent, EventType: EventComplete, Content: string(turn.State)})
+	}
+	if _, err := ai.AddMessages(ctx, iv.store, sessionID, batch); err != nil {
+		return nil, err
+	}
+	return turn, nil
+}
+
+func parseTurn(content string) (*Turn, error) {
+	start, end := strings.IndexByte(content, '{'), strings.LastIndexByte(content, '}')
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("%w: no JSON object", ErrInvalidTurn)
+	}
+	var turn Turn
+	if err := json.Unmarshal([]byte(content[start:end+1]), &turn); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidTurn, err)
+	}
+	if len(turn.State) == 0 {
+		turn.State = json.RawMessage("{}")
+	}
+	return &turn, nil
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultOrphanTimeout is how long a request log may stay pending before a
+// RequestLogJanitor fails it.
+const defaultOrphanTimeout = 30 * time.Minute
+
+// OrphanedRequestLogStore is the optional store capability reconciling request logs
+// left pending by a process that stopped mid-call.
+type OrphanedRequestLogStore interface {
+	// FailOrphanedRequestLogs marks the logs pending without an update since before as
+	// failed with FailReasonOrphaned, and returns how many.
+	FailOrphanedRequestLogs(ctx context.Context, before time.Time) (int64, error)
+	// CountPendingRequestLogs returns how many logs are pending.
+	CountPendingRequestLogs(ctx context.Context) (int64, error)
+}
+
+// JanitorStats are the counters of a RequestLogJanitor, for dashboards.
+type JanitorStats struct {
+	Runs         int64      `json:"runs"`
+	Orphaned     int64      `json:"orphaned"`      // logs failed since the janitor started
+	LastOrphaned int64      `json:"last_orphaned"` // logs failed by the last run
+	Pending      int64      `json:"pending"`       // logs pending after the last run, i.e. in flight
+	LastRunAt    *time.Time `json:"last_run_at,omitempty"`
+}
+
+// RequestLogJanitor fails request logs stuck in StatusPending, e.g. because the
+// process crashed mid-call, so dashboards stop counting them in flight. Run one per
+// deployment; the timeout must be longer than the slowest request, retries and streams
+// included. A request that does finish after its log was failed still records its
+// outcome, as UpdateRequestLog overwrites the status. It is safe for concurrent use.
+type RequestLogJanitor struct {
+	store   OrphanedRequestLogStore
+	timeout time.Duration
+	now     Clock
+
+	mu    sync.Mutex
+	stats JanitorStats
+}
+
+// NewRequestLogJanitor returns a janitor failing logs pending for longer than timeout,
+// or 30 minutes if it is zero.
+func NewRequestLogJanitor(store OrphanedRequestLogStore, timeout time.Duration) *RequestLogJanitor {
+	if timeout <= 0 {
+		timeout = defaultOrphanTimeout
+	}
+	return &RequestLogJanitor{store: store, timeout: timeout, now: time.Now}
+}
+
+// WithClock sets the clock the timeout is measured with.
+func (j *RequestLogJanitor) WithClock(clock Clock) *RequestLogJanitor {
+	j.now = clock
+	return j
+}
+
+// ReconcileOnce fails the orphaned logs and returns how many.
+func (j *RequestLogJanitor) ReconcileOnce(ctx context.Context) (int64, error) {
+	now := j.now()
+	n, err := j.store.FailOrphanedRequestLogs(ctx, now.Add(-j.timeout))
+	if err != nil {
+		return 0, fmt.Errorf("ai: reconcile request logs: %w", err)
+	}
+	pending, err := j.store.CountPendingRequestLogs(ctx)
+	if err != nil {
+		return n, fmt.Errorf("ai: reconcile request logs: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.stats.Runs++
+	j.stats.Orphaned += n
+	j.stats.LastOrphaned = n
+	j.stats.Pending = pending
+	j.stats.LastRunAt = &now
+	return n, nil
+}
+
+// Run calls ReconcileOnce every interval until ctx is done, passing its errors to
+// onError (which may be nil) and continuing. It returns ctx's error.
+func (j *RequestLogJanitor) Run(ctx context.Context, interval time.Duration, onError func(err error)) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := j.ReconcileOnce(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Stats returns the counters so far.
+func (j *RequestLogJanitor) Stats() JanitorStats {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.stats
+}
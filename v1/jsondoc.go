@@ -0,0 +1,55 @@
+package ai
+
+// JSONDocumentScanner finds the end of the first JSON object or array in text that
+// arrives in pieces, such as streamed deltas, without rescanning earlier pieces. Text
+// before the document's opening bracket is skipped.
+type JSONDocumentScanner struct {
+	depth    int
+	started  bool
+	inString bool
+	escape   bool
+	done     bool
+}
+
+// Scan consumes the next piece of text and returns how many of its bytes belong to the
+// text up to and including the document's closing bracket, and whether the document is
+// complete. Once it is, later pieces are trailing text and Scan returns 0, true.
+func (s *JSONDocumentScanner) Scan(piece string) (int, bool) {
+	if s.done {
+		return 0, true
+	}
+	for i := 0; i < len(piece); i++ {
+		c := piece[i]
+		switch {
+		case s.inString:
+			switch {
+			case s.escape:
+				s.escape = false
+			case c == '\\':
+				s.escape = true
+			case c == '"':
+				s.inString = false
+			}
+		case !s.started:
+			if c == '{' || c == '[' {
+				s.started = true
+				s.depth = 1
+			}
+		case c == '"':
+			s.inString = true
+		case c == '{' || c == '[':
+			s.depth++
+		case c == '}' || c == ']':
+			if s.depth--; s.depth == 0 {
+				s.done = true
+				return i + 1, true
+			}
+		}
+	}
+	return len(piece), false
+}
+
+// Done reports whether the document is complete.
+func (s *JSONDocumentScanner) Done() bool {
+	return s.done
+}
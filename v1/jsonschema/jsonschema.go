@@ -0,0 +1,242 @@
+// Package jsonschema derives JSON schemas from Go types, for tool declarations and
+// generated API specs.
+//
+// Struct fields use their `json` tag name. Fields without `omitempty` are required.
+// Embedded structs without a json name are flattened as encoding/json does. A
+// `description` tag documents the field and an `enum` tag lists allowed values,
+// comma-separated and parsed as the field's kind:
+//
+//	type LookupArgs struct {
+//	    Code  string `json:"code" description:"Course code, e.g. JAVA-101"`
+//	    Level string `json:"level,omitempty" enum:"beginner,advanced"`
+//	}
+package jsonschema
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// For returns the JSON schema of t.
+func For(t reflect.Type) map[string]any {
+	return schemaFor(t, map[reflect.Type]bool{})
+}
+
+// Of returns the JSON schema of v's type.
+func Of(v any) map[string]any {
+	return For(reflect.TypeOf(v))
+}
+
+func schemaFor(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return map[string]any{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": schemaFor(t.Elem(), seen)}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": schemaFor(t.Elem(), seen)}
+	case reflect.Struct:
+		return structSchema(t, seen)
+	default:
+		// interfaces and other dynamic values accept anything
+		return map[string]any{}
+	}
+}
+
+func structSchema(t reflect.Type, seen map[reflect.Type]bool) map[string]any {
+	if seen[t] {
+		return map[string]any{"type": "object"}
+	}
+	seen[t] = true
+	defer delete(seen, t)
+
+	props := map[string]any{}
+	var required []string
+
+	for _, f := range visibleFields(t) {
+		prop := schemaFor(f.Type, seen)
+		if desc := f.Tag.Get("description"); desc != "" {
+			prop["description"] = desc
+		}
+		if enum := f.Tag.Get("enum"); enum != "" {
+			prop["enum"] = enumValues(f.Type, enum)
+		}
+		props[f.name] = prop
+
+		if !f.omitempty {
+			required = append(required, f.name)
+		}
+	}
+
+	schema := map[string]any{"type": "object", "properties": props}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// field is a struct field as encoding/json sees it, possibly promoted from an embedded
+// struct.
+type field struct {
+	reflect.StructField
+	name      string
+	omitempty bool
+	tagged    bool // named by its json tag
+	depth     int  // embedding depth
+	order     int  // position among all collected fields
+}
+
+// visibleFields returns the fields encoding/json encodes for t, in order: embedded
+// structs without a json name are flattened into their parent, and of fields sharing a
+// name only the shallowest survives, or the tagged one when several are equally deep.
+func visibleFields(t reflect.Type) []field {
+	var all []field
+	var walk func(t reflect.Type, depth int, path map[reflect.Type]bool)
+	walk = func(t reflect.Type, depth int, path map[reflect.Type]bool) {
+		if path[t] {
+			return
+		}
+		path[t] = true
+		defer delete(path, t)
+
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			tag := f.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, opts, _ := strings.Cut(tag, ",")
+
+			if f.Anonymous {
+				ft := f.Type
+				if ft.Kind() == reflect.Pointer {
+					if !f.IsExported() {
+						// encoding/json can't allocate unexported embedded pointers
+						continue
+					}
+					ft = ft.Elem()
+				}
+				if name == "" && ft.Kind() == reflect.Struct {
+					// Promoted even when the embedded type is unexported
+					walk(ft, depth+1, path)
+					continue
+				}
+				if !f.IsExported() {
+					continue
+				}
+			} else if !f.IsExported() {
+				continue
+			}
+
+			tagged := name != ""
+			if !tagged {
+				name = f.Name
+			}
+			all = append(all, field{
+				StructField: f,
+				name:        name,
+				omitempty:   strings.Contains(opts, "omitempty"),
+				tagged:      tagged,
+				depth:       depth,
+				order:       len(all),
+			})
+		}
+	}
+	walk(t, 0, map[reflect.Type]bool{})
+
+	byName := map[string][]field{}
+	for _, f := range all {
+		byName[f.name] = append(byName[f.name], f)
+	}
+	var fields []field
+	for _, f := range all {
+		if dominant, ok := dominantField(byName[f.name]); ok && dominant.order == f.order {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// dominantField returns the field that wins among fields sharing a name, following
+// encoding/json: the shallowest, then the only tagged one. ok is false when none wins,
+// in which case encoding/json drops them all.
+func dominantField(fields []field) (field, bool) {
+	depth := fields[0].depth
+	for _, f := range fields[1:] {
+		depth = min(depth, f.depth)
+	}
+	var shallow []field
+	for _, f := range fields {
+		if f.depth == depth {
+			shallow = append(shallow, f)
+		}
+	}
+	if len(shallow) == 1 {
+		return shallow[0], true
+	}
+	var tagged []field
+	for _, f := range shallow {
+		if f.tagged {
+			tagged = append(tagged, f)
+		}
+	}
+	if len(tagged) == 1 {
+		return tagged[0], true
+	}
+	return field{}, false
+}
+
+// enumValues parses the comma-separated values of an enum tag as t's kind, so that an
+// int field gets integer values and a bool field booleans. Values that don't parse are
+// kept as strings.
+func enumValues(t reflect.Type, tag string) []any {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	var values []any
+	for _, s := range strings.Split(tag, ",") {
+		var v any = s
+		switch t.Kind() {
+		case reflect.Bool:
+			if b, err := strconv.ParseBool(s); err == nil {
+				v = b
+			}
+		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+			if n, err := strconv.ParseInt(s, 10, t.Bits()); err == nil {
+				v = n
+			}
+		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+			if n, err := strconv.ParseUint(s, 10, t.Bits()); err == nil {
+				v = n
+			}
+		case reflect.Float32, reflect.Float64:
+			if n, err := strconv.ParseFloat(s, t.Bits()); err == nil {
+				v = n
+			}
+		}
+		values = append(values, v)
+	}
+	return values
+}
@@ -0,0 +1,14 @@
+module github.com/meikuraledutech/ai/v1/langchaingo
+
+go 1.24.4
+
+require github.com/meikuraledutech/ai v0.0.0
+
+require (
+	github.com/dlclark/regexp2 v1.10.0 // indirect
+	github.com/google/uuid v1.6.0 // indirect
+	github.com/pkoukk/tiktoken-go v0.1.6 // indirect
+	github.com/tmc/langchaingo v0.1.14
+)
+
+replace github.com/meikuraledutech/ai => ../..
@@ -0,0 +1,279 @@
+// Package langchaingo adapts between ai.Provider and langchaingo's llms.Model, so
+// existing chains can run on this package's providers, and langchaingo models can run
+// behind its stores, logging and middleware.
+//
+// It lives in its own module so the langchaingo dependency stays out of the core.
+package langchaingo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/meikuraledutech/ai/v1"
+	"github.com/tmc/langchaingo/llms"
+)
+
+// Model exposes an ai.Provider as an llms.Model.
+type Model struct {
+	provider ai.Provider
+	rules    ai.Rules
+}
+
+// NewModel wraps provider. rules are the base for every call; system messages are
+// appended to their SystemPrompt and call options override MaxTokens. Unless rules set
+// a ResponseFormat, responses are plain text and JSONMode switches them to JSON.
+func NewModel(provider ai.Provider, rules ai.Rules) *Model {
+	return &Model{provider: provider, rules: rules}
+}
+
+// GenerateContent maps messages onto rules, history and a prompt and sends them. The
+// last message must be a human message and becomes the prompt. When a StreamingFunc is
+// set and the provider implements ai.Streamer, the response is streamed through it.
+func (m *Model) GenerateContent(ctx context.Context, messages []llms.MessageContent, options ...llms.CallOption) (*llms.ContentResponse, error) {
+	var opts llms.CallOptions
+	for _, o := range options {
+		o(&opts)
+	}
+
+	rules, history, prompt, err := m.translate(messages, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var result *ai.Result
+	if s, ok := m.provider.(ai.Streamer); ok && opts.StreamingFunc != nil {
+		result, err = stream(ctx, s, rules, history, prompt, opts.StreamingFunc)
+	} else {
+		result, err = m.provider.Send(ctx, rules, history, prompt)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &llms.ContentResponse{Choices: []*llms.ContentChoice{{
+		Content:        result.Content,
+		StopReason:     "stop",
+		GenerationInfo: generationInfo(result.Usage),
+	}}}, nil
+}
+
+// Call sends a single human prompt.
+//
+// Deprecated: kept to satisfy llms.Model; use GenerateContent.
+func (m *Model) Call(ctx context.Context, prompt string, options ...llms.CallOption) (string, error) {
+	return llms.GenerateFromSinglePrompt(ctx, m, prompt, options...)
+}
+
+func (m *Model) translate(messages []llms.MessageContent, opts llms.CallOptions) (ai.Rules, []ai.Message, string, error) {
+	rules := m.rules
+	if len(messages) == 0 || messages[len(messages)-1].Role != llms.ChatMessageTypeHuman {
+		return rules, nil, "", errors.New("ai: langchaingo: the last message must be a human message")
+	}
+
+	var history []ai.Message
+	var system []string
+	var prompt string
+	for i, msg := range messages {
+		text, err := textOf(msg)
+		if err != nil {
+			return rules, nil, "", fmt.Errorf("ai: langchaingo: messages[%d]: %w", i, err)
+		}
+		switch {
+		case i == len(messages)-1:
+			prompt = text
+		case msg.Role == llms.ChatMessageTypeSystem:
+			system = append(system, text)
+		case msg.Role == llms.ChatMessageTypeHuman || msg.Role == llms.ChatMessageTypeGeneric:
+			history = append(history, ai.Message{Role: ai.RoleUser, Content: text})
+		case msg.Role == llms.ChatMessageTypeAI:
+			history = append(history, ai.Message{Role: ai.RoleAssistant, Content: text})
+		default:
+			return rules, nil, "", fmt.Errorf("ai: langchaingo: messages[%d]: role %q is not supported", i, msg.Role)
+		}
+	}
+	if len(system) > 0 {
+		rules.SystemPrompt = strings.TrimPrefix(strings.Join(append([]string{rules.SystemPrompt}, system...), "\n\n"), "\n\n")
+	}
+
+	if opts.MaxTokens > 0 {
+		rules.MaxTokens = opts.MaxTokens
+	}
+	if opts.JSONMode {
+		rules.ResponseFormat = ai.FormatJSON
+	} else if rules.ResponseFormat == "" {
+		rules.ResponseFormat = ai.FormatText
+	}
+	return rules, history, prompt, nil
+}
+
+// textOf joins the text parts of msg. Other part types are rejected rather than dropped.
+func textOf(msg llms.MessageContent) (string, error) {
+	var b strings.Builder
+	for _, p := range msg.Parts {
+		t, ok := p.(llms.TextContent)
+		if !ok {
+			return "", fmt.Errorf("part type %T is not supported", p)
+		}
+		b.WriteString(t.Text)
+	}
+	return b.String(), nil
+}
+
+// stream forwards deltas to fn and assembles the final result.
+func stream(ctx context.Context, s ai.Streamer, rules ai.Rules, history []ai.Message, prompt string, fn func(context.Context, []byte) error) (*ai.Result, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	chunks, err := s.Stream(ctx, rules, history, prompt)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &ai.Result{}
+	var b strings.Builder
+	for chunk := range chunks {
+		if chunk.Err != nil {
+			return nil, chunk.Err
+		}
+		if chunk.Delta != "" {
+			b.WriteString(chunk.Delta)
+			if err := fn(ctx, []byte(chunk.Delta)); err != nil {
+				return nil, err
+			}
+		}
+		if chunk.Done && chunk.Usage != nil {
+			result.Usage = *chunk.Usage
+		}
+		if chunk.Done {
+			result.Citations = chunk.Citations
+		}
+	}
+	result.Content = b.String()
+	return result, nil
+}
+
+// generationInfo reports usage under the key names langchaingo's own providers use.
+func generationInfo(u ai.Usage) map[string]any {
+	return map[string]any{
+		"PromptTokens":       u.PromptTokens,
+		"CompletionTokens":   u.ResponseTokens,
+		"TotalTokens":        u.TotalTokens,
+		"ThinkingTokens":     u.ThoughtTokens,
+		"PromptCachedTokens": u.CachedTokens,
+	}
+}
+
+// Provider exposes an llms.Model as an ai.Provider.
+type Provider struct {
+	model llms.Model
+	name  string
+}
+
+// NewProvider wraps model.
+func NewProvider(model llms.Model) *Provider {
+	return &Provider{model: model}
+}
+
+// WithModelName sets the model name stamped on Usage, used to look up its price.
+func (p *Provider) WithModelName(name string) *Provider {
+	p.name = name
+	return p
+}
+
+// Capabilities reports what the adapter passes through: text in and out, with no tools,
+// schema or streaming. Limits are looked up in ai.DefaultLimits by the model name.
+func (p *Provider) Capabilities() ai.Caps {
+	limits, _ := ai.DefaultLimits.Lookup(p.name)
+	return ai.Caps{MaxContextTokens: limits.ContextTokens, MaxOutputTokens: limits.OutputTokens}
+}
+
+// Send maps rules, history and prompt onto messages and generates the first choice.
+// Usage is read from the choice's GenerationInfo when the model reports it.
+func (p *Provider) Send(ctx context.Context, rules ai.Rules, history []ai.Message, prompt string) (*ai.Result, error) {
+	if strings.TrimSpace(prompt) == "" {
+		return nil, ai.ErrEmptyPrompt
+	}
+	limits, _ := ai.DefaultLimits.Lookup(p.name)
+	if err := ai.CheckMaxTokens(p.name, limits, rules.MaxTokens); err != nil {
+		return nil, err
+	}
+
+	var messages []llms.MessageContent
+	if system := ai.LayerSystemPrompt(ctx, rules.SystemPrompt); system != "" {
+		messages = append(messages, llms.TextParts(llms.ChatMessageTypeSystem, system))
+	}
+	for _, m := range ai.ConversationHistory(history) {
+		switch m.Role {
+		case ai.RoleUser:
+			messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, m.Content))
+		case ai.RoleAssistant:
+			messages = append(messages, llms.TextParts(llms.ChatMessageTypeAI, m.Content))
+		}
+	}
+	messages = append(messages, llms.TextParts(llms.ChatMessageTypeHuman, prompt))
+
+	var opts []llms.CallOption
+	if rules.MaxTokens > 0 {
+		opts = append(opts, llms.WithMaxTokens(rules.MaxTokens))
+	}
+	if p.name != "" {
+		opts = append(opts, llms.WithModel(p.name))
+	}
+	if ai.IsJSONFormat(rules.ResponseFormat) {
+		opts = append(opts, llms.WithJSONMode())
+	}
+
+	resp, err := p.model.GenerateContent(ctx, messages, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ai.ErrProviderFailed, err)
+	}
+	if len(resp.Choices) == 0 {
+		return nil, fmt.Errorf("%w: no choices returned", ai.ErrProviderFailed)
+	}
+
+	choice := resp.Choices[0]
+	usage := usageOf(choice.GenerationInfo)
+	usage.Model = p.name
+	return &ai.Result{Content: choice.Content, Usage: usage}, nil
+}
+
+// usageOf reads token counts from info, accepting the key spellings used across
+// langchaingo's providers.
+func usageOf(info map[string]any) ai.Usage {
+	u := ai.Usage{
+		PromptTokens:   intOf(info, "PromptTokens", "InputTokens", "input_tokens"),
+		ResponseTokens: intOf(info, "CompletionTokens", "OutputTokens", "output_tokens"),
+		TotalTokens:    intOf(info, "TotalTokens", "total_tokens"),
+		ThoughtTokens:  intOf(info, "ThinkingTokens", "ReasoningTokens"),
+		CachedTokens:   intOf(info, "PromptCachedTokens", "CachedTokens"),
+	}
+	if u.TotalTokens == 0 {
+		u.TotalTokens = u.PromptTokens + u.ResponseTokens
+	}
+	return u
+}
+
+func intOf(info map[string]any, keys ...string) int {
+	for _, k := range keys {
+		switch v := info[k].(type) {
+		case int:
+			return v
+		case int32:
+			return int(v)
+		case int64:
+			return int(v)
+		case float64:
+			return int(v)
+		}
+	}
+	return 0
+}
+
+// Ensure the adapters satisfy their interfaces at compile time.
+var (
+	_ llms.Model            = (*Model)(nil)
+	_ ai.Provider           = (*Provider)(nil)
+	_ ai.CapabilityReporter = (*Provider)(nil)
+)
@@ -0,0 +1,151 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// languageNames maps the BCP 47 primary subtags DetectLanguage recognises to English names.
+var languageNames = map[string]string{
+	"de": "German",
+	"en": "English",
+	"es": "Spanish",
+	"fr": "French",
+	"id": "Indonesian",
+	"nl": "Dutch",
+	"pt": "Portuguese",
+}
+
+// stopwords holds common function words per language. Detection counts hits against
+// these lists, which is cheap and good enough to catch a model drifting to English.
+var stopwords = map[string][]string{
+	"de": {"der", "die", "das", "und", "ist", "nicht", "ein", "eine", "zu", "mit", "sie", "wie", "welche", "für", "auf", "den", "ich", "sind"},
+	"en": {"the", "and", "of", "to", "is", "are", "what", "which", "your", "you", "with", "for", "this", "that", "how", "do", "does", "please", "have", "will"},
+	"es": {"el", "los", "las", "que", "y", "es", "por", "para", "con", "una", "su", "cuál", "qué", "como", "del", "usted", "está"},
+	"fr": {"le", "les", "des", "est", "et", "une", "pour", "dans", "vous", "quel", "quelle", "du", "avec", "pas", "votre", "sont"},
+	"id": {"yang", "dan", "di", "ke", "dari", "ini", "itu", "untuk", "dengan", "adalah", "apa", "anda", "tidak", "ada", "pada", "atau", "dalam", "bagaimana", "akan", "saya", "berapa", "sudah"},
+	"nl": {"het", "een", "van", "niet", "wat", "welke", "voor", "met", "zijn", "op", "je", "u", "hoe", "uw"},
+	"pt": {"os", "do", "da", "em", "um", "uma", "para", "com", "não", "qual", "você", "são", "seu", "sua"},
+}
+
+// stopwordIndex maps each stopword to the languages it belongs to.
+var stopwordIndex = func() map[string][]string {
+	index := make(map[string][]string)
+	for lang, words := range stopwords {
+		for _, w := range words {
+			index[w] = append(index[w], lang)
+		}
+	}
+	return index
+}()
+
+// minLanguageHits is the minimum number of stopword hits before DetectLanguage commits
+// to an answer. Shorter texts are reported as unknown.
+const minLanguageHits = 3
+
+// LanguageName returns the English name of a BCP 47 language tag such as "id" or "en-US",
+// or the tag itself when it isn't recognised.
+func LanguageName(tag string) string {
+	if name, ok := languageNames[primarySubtag(tag)]; ok {
+		return name
+	}
+	return tag
+}
+
+// LanguageInstruction returns the system instruction asking the model to respond in the
+// language of tag, or "" when tag is empty.
+func LanguageInstruction(tag string) string {
+	if tag == "" {
+		return ""
+	}
+	name := LanguageName(tag)
+	return fmt.Sprintf("Respond in %s. Write all text in %s, even when the prompt or conversation is in another language. Keep JSON keys exactly as defined by the output schema.", name, name)
+}
+
+// DetectLanguage returns the primary subtag of the language content is most likely written
+// in, or "" when it can't tell. JSON content is judged on its string values only, so
+// schema keys don't count towards English.
+func DetectLanguage(content string) string {
+	hits := make(map[string]int)
+	for _, word := range strings.FieldsFunc(strings.ToLower(languageText(content)), isWordSeparator) {
+		for _, lang := range stopwordIndex[word] {
+			hits[lang]++
+		}
+	}
+
+	best, bestHits, secondHits := "", 0, 0
+	for lang, n := range hits {
+		switch {
+		case n > bestHits || (n == bestHits && lang < best):
+			best, bestHits, secondHits = lang, n, bestHits
+		case n > secondHits:
+			secondHits = n
+		}
+	}
+	if bestHits < minLanguageHits || bestHits == secondHits {
+		return ""
+	}
+	return best
+}
+
+// CheckLanguage returns a *ValidationError when content is detected to be in a language
+// other than tag. Content whose language can't be detected passes.
+func CheckLanguage(tag string, content string) error {
+	if tag == "" {
+		return nil
+	}
+	detected := DetectLanguage(content)
+	if detected == "" || detected == primarySubtag(tag) {
+		return nil
+	}
+	return &ValidationError{
+		Reason:     FailReasonWrongLanguage,
+		Message:    fmt.Sprintf("response language %s does not match %s", detected, tag),
+		Correction: fmt.Sprintf("Your previous response was written in %s. Please regenerate the complete response with all text in %s, keeping the JSON keys unchanged.", LanguageName(detected), LanguageName(tag)),
+	}
+}
+
+// primarySubtag returns the lower-cased language part of a BCP 47 tag ("id-ID" -> "id").
+func primarySubtag(tag string) string {
+	if i := strings.IndexAny(tag, "-_"); i >= 0 {
+		tag = tag[:i]
+	}
+	return strings.ToLower(tag)
+}
+
+// languageText returns the text of content to run detection on: the string values of a
+// JSON document, or content itself when it isn't JSON.
+func languageText(content string) string {
+	var doc any
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return content
+	}
+
+	var b strings.Builder
+	var walk func(v any)
+	walk = func(v any) {
+		switch t := v.(type) {
+		case string:
+			b.WriteString(t)
+			b.WriteByte(' ')
+		case map[string]any:
+			for _, child := range t {
+				walk(child)
+			}
+		case []any:
+			for _, child := range t {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+
+	return b.String()
+}
+
+// isWordSeparator reports whether r separates words.
+func isWordSeparator(r rune) bool {
+	return !unicode.IsLetter(r) && r != '\''
+}
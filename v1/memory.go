@@ -0,0 +1,86 @@
+package ai
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// Fact is a key-value memory entry.
+type Fact struct {
+	Key       string     `json:"key"`
+	Value     string     `json:"value"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// MemoryStore persists facts extracted from a session, e.g. "course: Full Stack Java",
+// so they can be injected as compact context instead of replaying full history.
+type MemoryStore interface {
+	SetSessionFact(ctx context.Context, sessionID string, key string, value string) error
+	ListSessionFacts(ctx context.Context, sessionID string) ([]Fact, error)
+	DeleteSessionFact(ctx context.Context, sessionID string, key string) error
+}
+
+// UserMemoryStore persists memories keyed by an external user ID, so preferences carry
+// across sessions. A zero ttl never expires.
+type UserMemoryStore interface {
+	SetUserMemory(ctx context.Context, userID string, key string, value string, ttl time.Duration) error
+	ListUserMemories(ctx context.Context, userID string) ([]Fact, error)
+	DeleteUserMemory(ctx context.Context, userID string, key string) error
+	ExpireUserMemories(ctx context.Context) (int64, error)
+}
+
+// MemoryPolicy controls which user memories are injected into requests.
+type MemoryPolicy struct {
+	MaxFacts int           // max memories injected, most recently updated first; 0 = all
+	Keys     []string      // only inject these keys; empty = all
+	MaxAge   time.Duration // skip memories not updated within this window; 0 = no limit
+}
+
+// Select returns the facts allowed by the policy as of now. Expired facts are always
+// skipped. facts are expected most recently updated first.
+func (p MemoryPolicy) Select(facts []Fact, now time.Time) []Fact {
+	allowed := make(map[string]bool, len(p.Keys))
+	for _, k := range p.Keys {
+		allowed[k] = true
+	}
+
+	var out []Fact
+	for _, f := range facts {
+		if f.ExpiresAt != nil && !f.ExpiresAt.After(now) {
+			continue
+		}
+		if len(allowed) > 0 && !allowed[f.Key] {
+			continue
+		}
+		if p.MaxAge > 0 && now.Sub(f.UpdatedAt) > p.MaxAge {
+			continue
+		}
+		out = append(out, f)
+		if p.MaxFacts > 0 && len(out) == p.MaxFacts {
+			break
+		}
+	}
+	return out
+}
+
+// RenderFacts formats facts as a compact context block for a system instruction.
+// It returns "" when there are no facts.
+func RenderFacts(title string, facts []Fact) string {
+	if len(facts) == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString(title)
+	b.WriteString(":\n")
+	for _, f := range facts {
+		b.WriteString("- ")
+		b.WriteString(f.Key)
+		b.WriteString(": ")
+		b.WriteString(f.Value)
+		b.WriteString("\n")
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
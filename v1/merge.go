@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// MergeStrategy is how MergeSessions orders the merged messages.
+type MergeStrategy string
+
+const (
+	// MergeAppend adds the source's messages after the destination's, in seq order.
+	MergeAppend MergeStrategy = "append"
+	// MergeInterleave orders the messages of both sessions by creation time, the
+	// destination's first on ties, renumbering the destination's.
+	MergeInterleave MergeStrategy = "interleave"
+)
+
+// EventSessionMerged is the session event MergeSessions appends to the destination.
+// Its payload is the SessionMerge as JSON.
+const EventSessionMerged = "session_merged"
+
+// SessionMerge is the provenance of one merge: the session merged in and where each
+// copied message came from.
+type SessionMerge struct {
+	SourceSessionID string          `json:"source_session_id"`
+	Strategy        MergeStrategy   `json:"strategy"`
+	Messages        []MessageOrigin `json:"messages"`
+	MergedAt        time.Time       `json:"merged_at"`
+}
+
+// MessageOrigin maps a merged copy to the message it was copied from.
+type MessageOrigin struct {
+	MessageID       string `json:"message_id"` // the copy in the destination
+	SourceMessageID string `json:"source_message_id"`
+	SourceSeq       int    `json:"source_seq"`
+}
+
+// SessionMerges returns the merges recorded in msgs, oldest first. Events with a
+// malformed payload are skipped.
+func SessionMerges(msgs []Message) []SessionMerge {
+	var out []SessionMerge
+	for _, m := range msgs {
+		if m.Role != RoleEvent || m.EventType != EventSessionMerged {
+			continue
+		}
+		var merge SessionMerge
+		if json.Unmarshal([]byte(m.Content), &merge) == nil {
+			out = append(out, merge)
+		}
+	}
+	return out
+}
+
+// SessionMerger is the optional store capability merging sessions server-side.
+type SessionMerger interface {
+	// MergeSessions copies every message of src into dst, ordered by strategy and
+	// renumbered, and appends an EventSessionMerged event recording their origins, in
+	// one transaction. src is left as it was. Returns ErrSessionNotFound if either
+	// session doesn't exist.
+	MergeSessions(ctx context.Context, dst, src string, strategy MergeStrategy) (*SessionMerge, error)
+}
+
+// MergeSessions merges src into dst with store's MergeSessions when it implements
+// SessionMerger. Otherwise it supports MergeAppend only, copying the messages with
+// AddMessages and then adding the event; this is not atomic. Delete src once it is no
+// longer needed.
+func MergeSessions(ctx context.Context, store Store, dst, src string, strategy MergeStrategy) (*SessionMerge, error) {
+	if err := checkMerge(dst, src, strategy); err != nil {
+		return nil, err
+	}
+	if m, ok := store.(SessionMerger); ok {
+		return m.MergeSessions(ctx, dst, src, strategy)
+	}
+	if strategy != MergeAppend {
+		return nil, fmt.Errorf("ai: merge sessions: %s needs a store implementing SessionMerger", strategy)
+	}
+
+	if _, err := store.GetSession(ctx, dst); err != nil {
+		return nil, err
+	}
+	msgs, err := store.ListMessages(ctx, src)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		if _, err := store.GetSession(ctx, src); err != nil {
+			return nil, err
+		}
+	}
+
+	copies := make([]NewMessage, len(msgs))
+	for i, m := range msgs {
+		copies[i] = NewMessage{Role: m.Role, Content: m.Content, EventType: m.EventType, Usage: m.Usage}
+	}
+	added, err := AddMessages(ctx, store, dst, copies)
+	if err != nil {
+		return nil, fmt.Errorf("ai: merge sessions: %w", err)
+	}
+
+	merge := &SessionMerge{SourceSessionID: src, Strategy: strategy, Messages: make([]MessageOrigin, len(added)), MergedAt: time.Now()}
+	for i, m := range added {
+		merge.Messages[i] = MessageOrigin{MessageID: m.ID, SourceMessageID: msgs[i].ID, SourceSeq: msgs[i].Seq}
+	}
+	payload, err := json.Marshal(merge)
+	if err != nil {
+		return nil, fmt.Errorf("ai: merge sessions: %w", err)
+	}
+	if _, err := AddEvent(ctx, store, dst, EventSessionMerged, string(payload)); err != nil {
+		return nil, fmt.Errorf("ai: merge sessions: %w", err)
+	}
+	return merge, nil
+}
+
+// checkMerge validates the arguments of MergeSessions.
+func checkMerge(dst, src string, strategy MergeStrategy) error {
+	if dst == src {
+		return errors.New("ai: merge sessions: cannot merge a session into itself")
+	}
+	if strategy != MergeAppend && strategy != MergeInterleave {
+		return fmt.Errorf("ai: merge sessions: unknown strategy %q", strategy)
+	}
+	return nil
+}
+
+// MergeOrder returns dst and src, each in seq order, as one list ordered by strategy.
+// Interleaving keeps the order within each session. Stores implementing SessionMerger
+// use it to assign the merged seqs.
+func MergeOrder(dst, src []Message, strategy MergeStrategy) []Message {
+	out := make([]Message, 0, len(dst)+len(src))
+	if strategy != MergeInterleave {
+		return append(append(out, dst...), src...)
+	}
+	for len(dst) > 0 && len(src) > 0 {
+		if src[0].CreatedAt.Before(dst[0].CreatedAt) {
+			out, src = append(out, src[0]), src[1:]
+		} else {
+			out, dst = append(out, dst[0]), dst[1:]
+		}
+	}
+	return append(append(out, dst...), src...)
+}
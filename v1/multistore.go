@@ -0,0 +1,131 @@
+package ai
+
+import "context"
+
+// FanOutStore is a Store that copies request-log writes from its primary store to
+// secondary loggers. See MultiStore.
+type FanOutStore struct {
+	Store
+
+	secondaries []RequestLogger
+	onError     func(secondary RequestLogger, err error)
+}
+
+// MultiStore returns a Store backed by primary that also writes every request log to
+// secondaries, e.g. to stream logs into a data warehouse. Sessions, messages and reads
+// use primary only.
+//
+// Secondaries are best-effort: each is written after primary succeeds, in order, with
+// the log primary stored (including its ID), and their errors never reach the caller.
+// A failed primary write is not copied.
+func MultiStore(primary Store, secondaries ...RequestLogger) *FanOutStore {
+	return &FanOutStore{Store: primary, secondaries: secondaries}
+}
+
+// WithErrorHandler sets a function called with every failed secondary write.
+func (m *FanOutStore) WithErrorHandler(fn func(secondary RequestLogger, err error)) *FanOutStore {
+	m.onError = fn
+	return m
+}
+
+// AddRequestLog adds log to the primary store, then to each secondary.
+func (m *FanOutStore) AddRequestLog(ctx context.Context, log RequestLog) (*RequestLog, error) {
+	stored, err := m.Store.AddRequestLog(ctx, log)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range m.secondaries {
+		_, err := s.AddRequestLog(ctx, *stored)
+		m.report(s, err)
+	}
+	return stored, nil
+}
+
+// DeleteSession deletes a session from the primary store. It fails with
+// ErrUnsupported when primary isn't a SessionDeleter.
+func (m *FanOutStore) DeleteSession(ctx context.Context, sessionID string) error {
+	return DeleteSession(ctx, m.Store, sessionID)
+}
+
+// AddMessages appends msgs to the primary store with AddMessages, atomically when it is
+// a BulkMessageStore.
+func (m *FanOutStore) AddMessages(ctx context.Context, sessionID string, msgs []NewMessage) ([]Message, error) {
+	return AddMessages(ctx, m.Store, sessionID, msgs)
+}
+
+// AddEvent adds an event to the primary store. It fails with ErrUnsupported when
+// primary isn't an EventStore.
+func (m *FanOutStore) AddEvent(ctx context.Context, sessionID string, eventType string, payload string) (*Message, error) {
+	return AddEvent(ctx, m.Store, sessionID, eventType, payload)
+}
+
+// UpdateRequestLog updates the primary store, then each secondary.
+func (m *FanOutStore) UpdateRequestLog(ctx context.Context, id string, response string, status string, failReason string, errorMsg string, retryCount int, usage *Usage) error {
+	if err := m.Store.UpdateRequestLog(ctx, id, response, status, failReason, errorMsg, retryCount, usage); err != nil {
+		return err
+	}
+	for _, s := range m.secondaries {
+		m.report(s, s.UpdateRequestLog(ctx, id, response, status, failReason, errorMsg, retryCount, usage))
+	}
+	return nil
+}
+
+// SetPromptAttribution records a on the primary store and on each secondary that
+// implements PromptAttributionStore. It does nothing when primary doesn't.
+func (m *FanOutStore) SetPromptAttribution(ctx context.Context, requestLogID string, a PromptAttribution) error {
+	primary, ok := m.Store.(PromptAttributionStore)
+	if !ok {
+		return nil
+	}
+	if err := primary.SetPromptAttribution(ctx, requestLogID, a); err != nil {
+		return err
+	}
+	for _, s := range m.secondaries {
+		if as, ok := s.(PromptAttributionStore); ok {
+			m.report(s, as.SetPromptAttribution(ctx, requestLogID, a))
+		}
+	}
+	return nil
+}
+
+// SetCancelledAfterTokens records tokens on the primary store and on each secondary
+// that implements CancelledStreamStore. It does nothing when primary doesn't.
+func (m *FanOutStore) SetCancelledAfterTokens(ctx context.Context, requestLogID string, tokens int) error {
+	primary, ok := m.Store.(CancelledStreamStore)
+	if !ok {
+		return nil
+	}
+	if err := primary.SetCancelledAfterTokens(ctx, requestLogID, tokens); err != nil {
+		return err
+	}
+	for _, s := range m.secondaries {
+		if cs, ok := s.(CancelledStreamStore); ok {
+			m.report(s, cs.SetCancelledAfterTokens(ctx, requestLogID, tokens))
+		}
+	}
+	return nil
+}
+
+// AddRetryTurn records turn on the primary store and on each secondary that implements
+// RetryTranscriptStore. It does nothing when primary doesn't.
+func (m *FanOutStore) AddRetryTurn(ctx context.Context, requestLogID string, turn RetryTurn) error {
+	primary, ok := m.Store.(RetryTranscriptStore)
+	if !ok {
+		return nil
+	}
+	if err := primary.AddRetryTurn(ctx, requestLogID, turn); err != nil {
+		return err
+	}
+	for _, s := range m.secondaries {
+		if rs, ok := s.(RetryTranscriptStore); ok {
+			m.report(s, rs.AddRetryTurn(ctx, requestLogID, turn))
+		}
+	}
+	return nil
+}
+
+func (m *FanOutStore) report(s RequestLogger, err error) {
+	if err != nil && m.onError != nil {
+		m.onError(s, err)
+	}
+}
@@ -0,0 +1,429 @@
+// Package openaicompat serves any ai.Provider behind an OpenAI-compatible Chat
+// Completions API, so OpenAI client libraries and tools can talk to it unchanged.
+package openaicompat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SessionHeader names the header that binds a request to a stored session.
+const SessionHeader = "X-Session-ID"
+
+// Handler serves POST /v1/chat/completions and GET /v1/models.
+type Handler struct {
+	provider ai.Provider
+	store    ai.Store
+	model    string
+	rules    ai.Rules
+	now      ai.Clock
+}
+
+// New creates a Handler serving provider under the model name "default".
+func New(provider ai.Provider) *Handler {
+	return &Handler{provider: provider, model: "default", now: time.Now}
+}
+
+// WithModel sets the model name reported by /v1/models and in responses. Requests may
+// name any model; the configured provider always answers.
+func (h *Handler) WithModel(name string) *Handler {
+	h.model = name
+	return h
+}
+
+// WithRules sets the base rules of every request. System messages in a request are
+// appended to the system prompt.
+func (h *Handler) WithRules(rules ai.Rules) *Handler {
+	h.rules = rules
+	return h
+}
+
+// WithStore enables sessions: a request with the X-Session-ID header uses the stored
+// session's rules and history instead of the messages the client sent, apart from the
+// last user message, and the exchange is stored.
+func (h *Handler) WithStore(store ai.Store) *Handler {
+	h.store = store
+	return h
+}
+
+// WithClock sets the clock used for the "created" timestamps.
+func (h *Handler) WithClock(clock ai.Clock) *Handler {
+	h.now = clock
+	return h
+}
+
+// chatRequest is the subset of the Chat Completions request the handler understands.
+type chatRequest struct {
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	Stream         bool            `json:"stream"`
+	MaxTokens      int             `json:"max_tokens"`
+	MaxCompletion  int             `json:"max_completion_tokens"`
+	ResponseFormat *responseFormat `json:"response_format"`
+	Tools          json.RawMessage `json:"tools"`
+	StreamOptions  *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+type chatMessage struct {
+	Role    string          `json:"role"`
+	Content json.RawMessage `json:"content"`
+}
+
+type responseFormat struct {
+	Type       string `json:"type"` // text, json_object or json_schema
+	JSONSchema *struct {
+		Schema json.RawMessage `json:"schema"`
+	} `json:"json_schema"`
+}
+
+type responseMessage struct {
+	Role    string `json:"role,omitempty"`
+	Content string `json:"content"`
+}
+
+type choice struct {
+	Index        int              `json:"index"`
+	Message      *responseMessage `json:"message,omitempty"`
+	Delta        *responseMessage `json:"delta,omitempty"`
+	FinishReason *string          `json:"finish_reason"`
+}
+
+type usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type completion struct {
+	ID      string   `json:"id"`
+	Object  string   `json:"object"`
+	Created int64    `json:"created"`
+	Model   string   `json:"model"`
+	Choices []choice `json:"choices"`
+	Usage   *usage   `json:"usage,omitempty"`
+}
+
+// text returns the text of a message whose content is a string or an array of parts.
+func (m chatMessage) text() (string, error) {
+	if len(m.Content) == 0 || string(m.Content) == "null" {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(m.Content, &s); err == nil {
+		return s, nil
+	}
+	var parts []struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	if err := json.Unmarshal(m.Content, &parts); err != nil {
+		return "", errors.New("content must be a string or an array of parts")
+	}
+	var b strings.Builder
+	for _, p := range parts {
+		if p.Type != "text" {
+			return "", fmt.Errorf("content part type %q is not supported", p.Type)
+		}
+		b.WriteString(p.Text)
+	}
+	return b.String(), nil
+}
+
+// ServeHTTP routes requests to the chat completions and models endpoints.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch {
+	case r.URL.Path == "/v1/chat/completions" && r.Method == http.MethodPost:
+		h.chatCompletions(w, r)
+	case r.URL.Path == "/v1/models" && r.Method == http.MethodGet:
+		writeJSON(w, http.StatusOK, map[string]any{
+			"object": "list",
+			"data":   []map[string]any{{"id": h.model, "object": "model", "owned_by": "ai"}},
+		})
+	default:
+		writeError(w, http.StatusNotFound, "invalid_request_error", fmt.Sprintf("unknown endpoint %s %s", r.Method, r.URL.Path))
+	}
+}
+
+// badRequest is a problem with the client's request, reported with status 400.
+type badRequest string
+
+func (e badRequest) Error() string { return string(e) }
+
+// call is a chat completion request translated to a provider call.
+type call struct {
+	rules     ai.Rules
+	history   []ai.Message
+	prompt    string
+	sessionID string
+}
+
+// translate maps a Chat Completions request onto rules, history and a prompt. The last
+// message must come from the user and becomes the prompt.
+func (h *Handler) translate(r *http.Request, req chatRequest) (*call, error) {
+	if len(req.Tools) > 0 && string(req.Tools) != "null" {
+		return nil, badRequest("tools are not supported")
+	}
+	if len(req.Messages) == 0 || req.Messages[len(req.Messages)-1].Role != "user" {
+		return nil, badRequest(`the last message must have role "user"`)
+	}
+
+	c := &call{rules: h.rules, sessionID: r.Header.Get(SessionHeader)}
+	c.rules.ResponseFormat = ai.FormatText
+	if c.sessionID != "" {
+		if _, err := ai.ParseSessionID(c.sessionID); err != nil {
+			return nil, badRequest(err.Error())
+		}
+	}
+
+	if c.sessionID != "" && h.store != nil {
+		session, err := h.store.GetSession(r.Context(), c.sessionID)
+		if err != nil {
+			return nil, err
+		}
+		msgs, err := h.store.ListMessages(r.Context(), c.sessionID)
+		if err != nil {
+			return nil, err
+		}
+		c.rules, c.history = session.Rules, ai.ConversationHistory(msgs)
+	}
+
+	var system []string
+	for i, m := range req.Messages {
+		text, err := m.text()
+		if err != nil {
+			return nil, badRequest(fmt.Sprintf("messages[%d]: %v", i, err))
+		}
+		switch {
+		case i == len(req.Messages)-1:
+			c.prompt = text
+		case m.Role == "system" || m.Role == "developer":
+			system = append(system, text)
+		case c.sessionID != "" && h.store != nil:
+			// Stored history replaces the client's
+		case m.Role == "user":
+			c.history = append(c.history, ai.Message{Role: ai.RoleUser, Content: text})
+		case m.Role == "assistant":
+			c.history = append(c.history, ai.Message{Role: ai.RoleAssistant, Content: text})
+		default:
+			return nil, badRequest(fmt.Sprintf("messages[%d]: role %q is not supported", i, m.Role))
+		}
+	}
+	if len(system) > 0 {
+		c.rules.SystemPrompt = strings.Join(append([]string{c.rules.SystemPrompt}, system...), "\n\n")
+		c.rules.SystemPrompt = strings.TrimPrefix(c.rules.SystemPrompt, "\n\n")
+	}
+
+	if req.MaxCompletion > 0 {
+		c.rules.MaxTokens = req.MaxCompletion
+	} else if req.MaxTokens > 0 {
+		c.rules.MaxTokens = req.MaxTokens
+	}
+
+	if f := req.ResponseFormat; f != nil {
+		switch f.Type {
+		case "text":
+			c.rules.ResponseFormat = ai.FormatText
+		case "json_object":
+			c.rules.ResponseFormat = ai.FormatJSON
+		case "json_schema":
+			c.rules.ResponseFormat = ai.FormatJSON
+			if f.JSONSchema != nil && len(f.JSONSchema.Schema) > 0 {
+				c.rules.OutputSchema = string(f.JSONSchema.Schema)
+			}
+		default:
+			return nil, badRequest(fmt.Sprintf("response_format type %q is not supported", f.Type))
+		}
+	}
+
+	return c, nil
+}
+
+func (h *Handler) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid JSON body: "+err.Error())
+		return
+	}
+
+	c, err := h.translate(r, req)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	ctx := r.Context()
+	if id := r.Header.Get(ai.RequestIDHeader); id != "" {
+		ctx = ai.WithRequestID(ctx, id)
+	}
+	ctx, requestID := ai.EnsureRequestID(ctx)
+	if c.sessionID != "" {
+		ctx = ai.WithSessionID(ctx, c.sessionID)
+	}
+	w.Header().Set(ai.RequestIDHeader, requestID)
+
+	base := completion{ID: "chatcmpl-" + requestID, Created: h.now().Unix(), Model: h.model}
+
+	if streamer, ok := h.provider.(ai.Streamer); ok && req.Stream {
+		includeUsage := req.StreamOptions != nil && req.StreamOptions.IncludeUsage
+		h.stream(ctx, w, streamer, c, base, includeUsage)
+		return
+	}
+
+	result, err := h.provider.Send(ctx, c.rules, c.history, c.prompt)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+	h.persist(ctx, c, result.Content, &result.Usage)
+
+	stop := "stop"
+	base.Object = "chat.completion"
+	base.Choices = []choice{{Message: &responseMessage{Role: "assistant", Content: result.Content}, FinishReason: &stop}}
+	base.Usage = toUsage(result.Usage)
+
+	if req.Stream {
+		// The provider can't stream: send the whole response as a single chunk
+		ev := chunkOf(base, result.Content, &stop, base.Usage)
+		ev.Choices[0].Delta.Role = "assistant"
+		writeEvents(w, []completion{ev})
+		return
+	}
+	writeJSON(w, http.StatusOK, base)
+}
+
+// stream relays provider chunks as chat.completion.chunk events.
+func (h *Handler) stream(ctx context.Context, w http.ResponseWriter, streamer ai.Streamer, c *call, base completion, includeUsage bool) {
+	ch, err := streamer.Stream(ctx, c.rules, c.history, c.prompt)
+	if err != nil {
+		writeProviderError(w, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	flusher, _ := w.(http.Flusher)
+
+	send := func(v any) bool {
+		data, _ := json.Marshal(v)
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return false
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return true
+	}
+
+	var content strings.Builder
+	first := true
+	for chunk := range ch {
+		switch {
+		case chunk.Err != nil:
+			send(map[string]any{"error": map[string]any{"message": chunk.Err.Error(), "type": "server_error"}})
+		case chunk.Done:
+			var u *usage
+			if includeUsage && chunk.Usage != nil {
+				u = toUsage(*chunk.Usage)
+			}
+			stop := "stop"
+			send(chunkOf(base, "", &stop, u))
+			h.persist(ctx, c, content.String(), chunk.Usage)
+		default:
+			content.WriteString(chunk.Delta)
+			ev := chunkOf(base, chunk.Delta, nil, nil)
+			if first {
+				ev.Choices[0].Delta.Role = "assistant"
+				first = false
+			}
+			if !send(ev) {
+				for range ch {
+				}
+				return
+			}
+		}
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// persist stores the exchange when the request is bound to a session.
+func (h *Handler) persist(ctx context.Context, c *call, content string, u *ai.Usage) {
+	if h.store == nil || c.sessionID == "" {
+		return
+	}
+	ai.AddMessages(context.WithoutCancel(ctx), h.store, c.sessionID, []ai.NewMessage{
+		{Role: ai.RoleUser, Content: c.prompt},
+		{Role: ai.RoleAssistant, Content: content, Usage: u},
+	})
+}
+
+func chunkOf(base completion, delta string, finish *string, u *usage) completion {
+	base.Object = "chat.completion.chunk"
+	base.Choices = []choice{{Delta: &responseMessage{Content: delta}, FinishReason: finish}}
+	base.Usage = u
+	return base
+}
+
+func toUsage(u ai.Usage) *usage {
+	return &usage{PromptTokens: u.PromptTokens, CompletionTokens: u.ResponseTokens + u.ThoughtTokens, TotalTokens: u.TotalTokens}
+}
+
+func writeEvents(w http.ResponseWriter, events []completion) {
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	for _, ev := range events {
+		data, _ := json.Marshal(ev)
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+	fmt.Fprint(w, "data: [DONE]\n\n")
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, typ, message string) {
+	writeJSON(w, status, map[string]any{"error": map[string]any{"message": message, "type": typ}})
+}
+
+// writeProviderError maps library errors onto OpenAI error statuses and types.
+func writeProviderError(w http.ResponseWriter, err error) {
+	var bad badRequest
+	var re *ai.RequestError
+	var limited *ai.RateLimitError
+	switch {
+	case errors.As(err, &bad):
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+	case errors.Is(err, ai.ErrSessionNotFound):
+		writeError(w, http.StatusNotFound, "invalid_request_error", err.Error())
+	case errors.As(err, &limited):
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(limited.Status.RetryAfter().Seconds()))))
+		writeError(w, http.StatusTooManyRequests, "rate_limit_error", err.Error())
+	case errors.Is(err, ai.ErrQuotaExceeded):
+		writeError(w, http.StatusTooManyRequests, "rate_limit_error", err.Error())
+	case errors.Is(err, ai.ErrEmptyPrompt), errors.Is(err, ai.ErrPromptInjection), errors.Is(err, ai.ErrBlocked):
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+	case errors.Is(err, ai.ErrProviderFailed), errors.As(err, &re):
+		writeError(w, http.StatusBadGateway, "api_error", err.Error())
+	case errors.Is(err, ai.ErrStoreUnavailable):
+		writeError(w, http.StatusServiceUnavailable, "api_error", err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, "server_error", err.Error())
+	}
+}
@@ -0,0 +1,322 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	ErrOutboxFull   = errors.New("ai: request log outbox is full")
+	ErrOutboxClosed = errors.New("ai: request log outbox is closed")
+)
+
+// OutboxStats is a snapshot of a RequestLogOutbox's queue.
+type OutboxStats struct {
+	Queued   int   `json:"queued"`   // writes not yet stored, including one being retried
+	Capacity int   `json:"capacity"` // queue size
+	Written  int64 `json:"written"`  // writes stored
+	Retries  int64 `json:"retries"`  // attempts repeated because the store was unavailable
+	Failed   int64 `json:"failed"`   // writes the store rejected, e.g. for a deleted session
+	Dropped  int64 `json:"dropped"`  // writes refused while full, or abandoned by Close
+}
+
+// outboxWrite is one queued store call. ctx keeps the caller's values (request ID,
+// actor) without its cancellation.
+type outboxWrite struct {
+	ctx   context.Context
+	write func(ctx context.Context) error
+}
+
+// RequestLogOutbox wraps a Store so request-log writes are queued in memory and stored
+// in order by a background goroutine, keeping database latency and outages off the
+// provider call path. All other Store methods pass through.
+//
+// Writes that fail with ErrStoreUnavailable are retried with backoff until they
+// succeed, holding back the writes queued behind them; other failures are counted and
+// skipped. When the queue is full, writers block until there is room or their context
+// is done (or, with DropWhenFull, fail with ErrOutboxFull), so an outage slows callers
+// down instead of growing memory without bound. Queued writes are lost if the process
+// exits before Close.
+type RequestLogOutbox struct {
+	Store
+
+	queue        chan outboxWrite
+	dropWhenFull bool
+	retryDelay   time.Duration
+	maxDelay     time.Duration
+	onError      func(err error)
+	newID        IDGenerator
+	now          Clock
+
+	start       sync.Once
+	mu          sync.RWMutex // held for writing only to close the queue
+	closed      bool
+	closing     chan struct{} // closed by Close, releasing blocked writers
+	closingOnce sync.Once
+	abort       chan struct{} // closed when Close gives up on the queued writes
+	abortOnce   sync.Once
+	done        chan struct{}
+
+	pending, written, retries, failed, dropped atomic.Int64
+}
+
+// NewRequestLogOutbox wraps store with a queue of capacity writes.
+func NewRequestLogOutbox(store Store, capacity int) *RequestLogOutbox {
+	return &RequestLogOutbox{
+		Store:      store,
+		queue:      make(chan outboxWrite, capacity),
+		retryDelay: 100 * time.Millisecond,
+		maxDelay:   5 * time.Second,
+		newID:      NewRequestID,
+		now:        time.Now,
+		closing:    make(chan struct{}),
+		abort:      make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// DropWhenFull makes writes fail with ErrOutboxFull instead of blocking when the queue
+// is full.
+func (o *RequestLogOutbox) DropWhenFull() *RequestLogOutbox {
+	o.dropWhenFull = true
+	return o
+}
+
+// WithRetryDelay sets the wait before the first retry of an unavailable store, doubled
+// before each later one up to max. Defaults are 100ms and 5s.
+func (o *RequestLogOutbox) WithRetryDelay(base, max time.Duration) *RequestLogOutbox {
+	o.retryDelay, o.maxDelay = base, max
+	return o
+}
+
+// WithErrorHandler sets a function called with every failed store write, including
+// those that will be retried. It runs on the outbox goroutine.
+func (o *RequestLogOutbox) WithErrorHandler(fn func(err error)) *RequestLogOutbox {
+	o.onError = fn
+	return o
+}
+
+// WithIDGenerator sets the generator of request log IDs, which the outbox assigns
+// before the log is stored. Defaults to NewRequestID.
+func (o *RequestLogOutbox) WithIDGenerator(gen IDGenerator) *RequestLogOutbox {
+	o.newID = gen
+	return o
+}
+
+// WithClock sets the clock that timestamps queued request logs.
+func (o *RequestLogOutbox) WithClock(clock Clock) *RequestLogOutbox {
+	o.now = clock
+	return o
+}
+
+// AddRequestLog assigns the log an ID and creation time, queues it and returns it
+// without waiting for the store.
+func (o *RequestLogOutbox) AddRequestLog(ctx context.Context, log RequestLog) (*RequestLog, error) {
+	if log.ID == "" {
+		log.ID = o.newID()
+	}
+	if log.CreatedAt.IsZero() {
+		log.CreatedAt = o.now()
+	}
+	log.UpdatedAt = log.CreatedAt
+	log.FinalStatus = StatusPending
+
+	entry := log
+	err := o.enqueue(ctx, func(ctx context.Context) error {
+		_, err := o.Store.AddRequestLog(ctx, entry)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// UpdateRequestLog queues the update.
+func (o *RequestLogOutbox) UpdateRequestLog(ctx context.Context, id string, response string, status string, failReason string, errorMsg string, retryCount int, usage *Usage) error {
+	if usage != nil {
+		u := *usage
+		usage = &u
+	}
+	return o.enqueue(ctx, func(ctx context.Context) error {
+		return o.Store.UpdateRequestLog(ctx, id, response, status, failReason, errorMsg, retryCount, usage)
+	})
+}
+
+// SetPromptAttribution queues the attribution when the wrapped store records it, and
+// does nothing otherwise.
+func (o *RequestLogOutbox) SetPromptAttribution(ctx context.Context, requestLogID string, a PromptAttribution) error {
+	store, ok := o.Store.(PromptAttributionStore)
+	if !ok {
+		return nil
+	}
+	return o.enqueue(ctx, func(ctx context.Context) error {
+		return store.SetPromptAttribution(ctx, requestLogID, a)
+	})
+}
+
+// SetCancelledAfterTokens queues the count when the wrapped store records it, and
+// does nothing otherwise.
+func (o *RequestLogOutbox) SetCancelledAfterTokens(ctx context.Context, requestLogID string, tokens int) error {
+	store, ok := o.Store.(CancelledStreamStore)
+	if !ok {
+		return nil
+	}
+	return o.enqueue(ctx, func(ctx context.Context) error {
+		return store.SetCancelledAfterTokens(ctx, requestLogID, tokens)
+	})
+}
+
+// AddRetryTurn queues the turn when the wrapped store records it, and does nothing
+// otherwise.
+func (o *RequestLogOutbox) AddRetryTurn(ctx context.Context, requestLogID string, turn RetryTurn) error {
+	store, ok := o.Store.(RetryTranscriptStore)
+	if !ok {
+		return nil
+	}
+	return o.enqueue(ctx, func(ctx context.Context) error {
+		return store.AddRetryTurn(ctx, requestLogID, turn)
+	})
+}
+
+// DeleteSession deletes a session from the wrapped store directly, without queueing.
+// It fails with ErrUnsupported when the wrapped store isn't a SessionDeleter.
+func (o *RequestLogOutbox) DeleteSession(ctx context.Context, sessionID string) error {
+	return DeleteSession(ctx, o.Store, sessionID)
+}
+
+// AddMessages appends msgs to the wrapped store directly, without queueing, with
+// AddMessages: atomically when it is a BulkMessageStore.
+func (o *RequestLogOutbox) AddMessages(ctx context.Context, sessionID string, msgs []NewMessage) ([]Message, error) {
+	return AddMessages(ctx, o.Store, sessionID, msgs)
+}
+
+// AddEvent adds an event to the wrapped store directly, without queueing. It fails
+// with ErrUnsupported when the wrapped store isn't an EventStore.
+func (o *RequestLogOutbox) AddEvent(ctx context.Context, sessionID string, eventType string, payload string) (*Message, error) {
+	return AddEvent(ctx, o.Store, sessionID, eventType, payload)
+}
+
+// Stats returns the current queue counters.
+func (o *RequestLogOutbox) Stats() OutboxStats {
+	return OutboxStats{
+		Queued:   int(o.pending.Load()),
+		Capacity: cap(o.queue),
+		Written:  o.written.Load(),
+		Retries:  o.retries.Load(),
+		Failed:   o.failed.Load(),
+		Dropped:  o.dropped.Load(),
+	}
+}
+
+// Close stops accepting writes and waits for the queued ones to be stored. If ctx is
+// done first, the remaining writes are dropped and ctx's error is returned.
+func (o *RequestLogOutbox) Close(ctx context.Context) error {
+	o.start.Do(func() { go o.run() })
+	o.closingOnce.Do(func() { close(o.closing) })
+
+	o.mu.Lock()
+	if !o.closed {
+		o.closed = true
+		close(o.queue)
+	}
+	o.mu.Unlock()
+
+	select {
+	case <-o.done:
+		return nil
+	case <-ctx.Done():
+		o.abortOnce.Do(func() { close(o.abort) })
+		<-o.done
+		return ctx.Err()
+	}
+}
+
+func (o *RequestLogOutbox) enqueue(ctx context.Context, write func(ctx context.Context) error) error {
+	o.start.Do(func() { go o.run() })
+
+	o.mu.RLock()
+	defer o.mu.RUnlock()
+	if o.closed {
+		return ErrOutboxClosed
+	}
+
+	w := outboxWrite{ctx: context.WithoutCancel(ctx), write: write}
+	o.pending.Add(1)
+	if o.dropWhenFull {
+		select {
+		case o.queue <- w:
+			return nil
+		default:
+			o.refuse()
+			return ErrOutboxFull
+		}
+	}
+
+	select {
+	case o.queue <- w:
+		return nil
+	case <-ctx.Done():
+		o.refuse()
+		return ctx.Err()
+	case <-o.closing:
+		o.refuse()
+		return ErrOutboxClosed
+	}
+}
+
+// refuse undoes the pending count of a write that wasn't queued.
+func (o *RequestLogOutbox) refuse() {
+	o.pending.Add(-1)
+	o.dropped.Add(1)
+}
+
+// run stores queued writes in order until the queue is closed and drained.
+func (o *RequestLogOutbox) run() {
+	defer close(o.done)
+	for w := range o.queue {
+		o.store(w)
+		o.pending.Add(-1)
+	}
+}
+
+// store performs w, retrying while the store is unavailable.
+func (o *RequestLogOutbox) store(w outboxWrite) {
+	delay := o.retryDelay
+	for {
+		select {
+		case <-o.abort:
+			o.dropped.Add(1)
+			return
+		default:
+		}
+
+		err := w.write(w.ctx)
+		if err == nil {
+			o.written.Add(1)
+			return
+		}
+		if o.onError != nil {
+			o.onError(err)
+		}
+		if !errors.Is(err, ErrStoreUnavailable) {
+			o.failed.Add(1)
+			return
+		}
+
+		o.retries.Add(1)
+		t := time.NewTimer(delay)
+		select {
+		case <-o.abort:
+			t.Stop()
+		case <-t.C:
+		}
+		delay *= 2
+		if o.maxDelay > 0 && delay > o.maxDelay {
+			delay = o.maxDelay
+		}
+	}
+}
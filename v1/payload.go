@@ -0,0 +1,24 @@
+package ai
+
+import (
+	"context"
+	"time"
+)
+
+// Payload is a raw provider exchange captured for debugging, linked to a request log.
+// Streamed responses hold the raw event stream rather than a JSON document.
+type Payload struct {
+	ID           string    `json:"id"`
+	RequestLogID string    `json:"request_log_id"`
+	Attempt      int       `json:"attempt"`
+	StatusCode   int       `json:"status_code"`
+	Request      []byte    `json:"request"`
+	Response     []byte    `json:"response"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// PayloadStore persists captured provider payloads.
+type PayloadStore interface {
+	SavePayload(ctx context.Context, p Payload) error
+	ListPayloads(ctx context.Context, requestLogID string) ([]Payload, error)
+}
@@ -0,0 +1,65 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPersonaNotFound = errors.New("ai: persona not found")
+)
+
+// Persona is a voice a session can speak in, e.g. "strict examiner" or "friendly
+// tutor". It is kept apart from the system prompt and merged into it at request
+// time, so switching a session's tone doesn't mean rewriting its prompt.
+type Persona struct {
+	Name        string    `json:"name"`
+	Style       string    `json:"style"`                 // how to speak, e.g. "Warm and encouraging; short sentences."
+	Constraints []string  `json:"constraints,omitempty"` // rules the persona keeps, e.g. "Never reveal the answer."
+	UpdatedAt   time.Time `json:"updated_at"`
+}
+
+// PersonaStore is the optional store capability keeping a registry of personas and
+// the persona each session uses.
+type PersonaStore interface {
+	// SavePersona creates p or replaces the persona with its name, and returns it with
+	// UpdatedAt set.
+	SavePersona(ctx context.Context, p Persona) (*Persona, error)
+	// GetPersona returns the persona called name, or ErrPersonaNotFound.
+	GetPersona(ctx context.Context, name string) (*Persona, error)
+	// ListPersonas returns every persona, ordered by name.
+	ListPersonas(ctx context.Context) ([]Persona, error)
+	// DeletePersona removes a persona. Sessions using it go back to having none.
+	DeletePersona(ctx context.Context, name string) error
+
+	// SetSessionPersona makes the session speak as the persona called name, or as
+	// none for "". It returns ErrPersonaNotFound for an unknown name.
+	SetSessionPersona(ctx context.Context, sessionID string, name string) error
+	// SessionPersona returns the persona of a session, or nil if it has none.
+	SessionPersona(ctx context.Context, sessionID string) (*Persona, error)
+}
+
+// RenderPersona formats p as a block for a system instruction. It returns "" for nil.
+func RenderPersona(p *Persona) string {
+	if p == nil {
+		return ""
+	}
+
+	var b strings.Builder
+	b.WriteString("Speak as this persona: ")
+	b.WriteString(p.Name)
+	if p.Style != "" {
+		b.WriteString("\nStyle: ")
+		b.WriteString(p.Style)
+	}
+	if len(p.Constraints) > 0 {
+		b.WriteString("\nAlways:")
+		for _, c := range p.Constraints {
+			b.WriteString("\n- ")
+			b.WriteString(c)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,292 @@
+// Package pipeline composes multi-stage flows such as draft → critique → finalize from
+// declarative steps (model calls, validators, Go transforms and store writes), run with
+// shared request IDs, retries and step reporting.
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+var (
+	ErrUnknownStep = errors.New("ai: pipeline step not found")
+)
+
+// State is the data a run passes from step to step.
+type State struct {
+	Input   string            // input of the run
+	Content string            // output of the latest step, input of the next
+	Outputs map[string]string // output of every finished step, by name
+	Usage   ai.Usage          // summed over the run's model calls
+
+	// Correction is set while a step is rerun by a later step's RetryFrom: the
+	// failure to fix and the content that failed. Generate steps send it to the model.
+	Correction string
+	Rejected   string
+}
+
+// Output returns the output of the named step, or "" if it hasn't finished.
+func (s *State) Output(name string) string {
+	return s.Outputs[name]
+}
+
+// Step is one stage of a pipeline. Run reads the state and updates its Content.
+type Step struct {
+	Name string
+	Run  func(ctx context.Context, s *State) error
+
+	retries   int
+	retryFrom string
+}
+
+// Retry reruns the step up to n more times when it fails, e.g. for flaky store writes.
+func (s Step) Retry(n int) Step {
+	s.retries, s.retryFrom = n, ""
+	return s
+}
+
+// RetryFrom reruns the pipeline from the earlier step named from, up to n more times,
+// when this step fails. The failure is set as the state's Correction, so a validator
+// can send a draft back to the Generate step that wrote it.
+func (s Step) RetryFrom(from string, n int) Step {
+	s.retries, s.retryFrom = n, from
+	return s
+}
+
+// StepError reports the step a run failed at. It wraps the step's last error.
+type StepError struct {
+	Step     string
+	Attempts int
+	Err      error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("ai: pipeline step %q failed after %d attempts: %v", e.Step, e.Attempts, e.Err)
+}
+
+func (e *StepError) Unwrap() error { return e.Err }
+
+// StepReport describes one execution of a step, for logging and progress reporting.
+type StepReport struct {
+	Pipeline  string
+	Step      string
+	RequestID string // shared by every step of the run
+	Attempt   int
+	Duration  time.Duration
+	Usage     ai.Usage // of this execution's model calls
+	Err       error
+}
+
+// Pipeline runs steps in order, passing each step's output to the next.
+type Pipeline struct {
+	name   string
+	steps  []Step
+	onStep func(ctx context.Context, r StepReport)
+	now    ai.Clock
+}
+
+// New creates an empty pipeline. name identifies it in step reports.
+func New(name string) *Pipeline {
+	return &Pipeline{name: name, now: time.Now}
+}
+
+// Then appends steps to the pipeline.
+func (p *Pipeline) Then(steps ...Step) *Pipeline {
+	p.steps = append(p.steps, steps...)
+	return p
+}
+
+// OnStep registers a callback invoked after every step execution, successful or not.
+func (p *Pipeline) OnStep(fn func(ctx context.Context, r StepReport)) *Pipeline {
+	p.onStep = fn
+	return p
+}
+
+// WithClock sets the clock used to time steps.
+func (p *Pipeline) WithClock(clock ai.Clock) *Pipeline {
+	p.now = clock
+	return p
+}
+
+// Run executes the pipeline on input and returns the final state. Every model call of
+// the run shares one request ID, so its request logs can be correlated. A failing step
+// without retries left stops the run with a *StepError; the state so far is returned
+// with it.
+func (p *Pipeline) Run(ctx context.Context, input string) (*State, error) {
+	targets, err := p.retryTargets()
+	if err != nil {
+		return nil, err
+	}
+	ctx, requestID := ai.EnsureRequestID(ctx)
+
+	s := &State{Input: input, Content: input, Outputs: make(map[string]string)}
+	inputs := make([]string, len(p.steps)) // Content each step last started from
+	runs := make([]int, len(p.steps))
+	failures := make([]int, len(p.steps))
+
+	for i := 0; i < len(p.steps); {
+		if err := ctx.Err(); err != nil {
+			return s, err
+		}
+		step := p.steps[i]
+		inputs[i] = s.Content
+		runs[i]++
+
+		before, start := s.Usage, p.now()
+		err := step.Run(ctx, s)
+		p.report(ctx, StepReport{
+			Pipeline:  p.name,
+			Step:      step.Name,
+			RequestID: requestID,
+			Attempt:   runs[i],
+			Duration:  p.now().Sub(start),
+			Usage:     usageSince(before, s.Usage),
+			Err:       err,
+		})
+
+		if err == nil {
+			s.Outputs[step.Name] = s.Content
+			s.Correction, s.Rejected = "", ""
+			i++
+			continue
+		}
+
+		failures[i]++
+		if failures[i] > step.retries {
+			return s, &StepError{Step: step.Name, Attempts: failures[i], Err: err}
+		}
+		if t := targets[i]; t >= 0 {
+			s.Correction, s.Rejected = err.Error(), s.Content
+			s.Content = inputs[t]
+			i = t
+			continue
+		}
+		s.Content = inputs[i]
+	}
+	return s, nil
+}
+
+// retryTargets resolves the RetryFrom step of every step to its index, or -1.
+func (p *Pipeline) retryTargets() ([]int, error) {
+	targets := make([]int, len(p.steps))
+	for i, step := range p.steps {
+		targets[i] = -1
+		if step.retryFrom == "" {
+			continue
+		}
+		for j := 0; j < i; j++ {
+			if p.steps[j].Name == step.retryFrom {
+				targets[i] = j
+			}
+		}
+		if targets[i] < 0 {
+			return nil, fmt.Errorf("%w: %q retries from %q, which doesn't precede it", ErrUnknownStep, step.Name, step.retryFrom)
+		}
+	}
+	return targets, nil
+}
+
+func (p *Pipeline) report(ctx context.Context, r StepReport) {
+	if p.onStep != nil {
+		p.onStep(ctx, r)
+	}
+}
+
+// usageSince returns the usage added to before to make after.
+func usageSince(before, after ai.Usage) ai.Usage {
+	return ai.Usage{
+		PromptTokens:   after.PromptTokens - before.PromptTokens,
+		ResponseTokens: after.ResponseTokens - before.ResponseTokens,
+		TotalTokens:    after.TotalTokens - before.TotalTokens,
+		ThoughtTokens:  after.ThoughtTokens - before.ThoughtTokens,
+		CachedTokens:   after.CachedTokens - before.CachedTokens,
+		ToolTokens:     after.ToolTokens - before.ToolTokens,
+		AudioTokens:    after.AudioTokens - before.AudioTokens,
+		Model:          after.Model,
+	}
+}
+
+// PromptFunc builds a step's prompt from the state.
+type PromptFunc func(s *State) string
+
+// Template returns a PromptFunc filling {{input}} with the run's input, {{content}}
+// with the latest output and {{name}} with the output of the step called name.
+func Template(text string) PromptFunc {
+	return func(s *State) string {
+		pairs := []string{"{{input}}", s.Input, "{{content}}", s.Content}
+		for name, out := range s.Outputs {
+			pairs = append(pairs, "{{"+name+"}}", out)
+		}
+		return strings.NewReplacer(pairs...).Replace(text)
+	}
+}
+
+// Generate returns a step that sends the prompt built from the state to provider with
+// rules, and makes the response its output. When rerun with a Correction, the rejected
+// response and the correction are sent as a follow-up turn instead.
+func Generate(name string, provider ai.Provider, rules ai.Rules, prompt PromptFunc) Step {
+	return Step{Name: name, Run: func(ctx context.Context, s *State) error {
+		text := prompt(s)
+		var history []ai.Message
+		if s.Correction != "" {
+			history = []ai.Message{
+				{Role: ai.RoleUser, Content: text},
+				{Role: ai.RoleAssistant, Content: s.Rejected},
+			}
+			text = fmt.Sprintf("Your previous answer failed validation: %s. Please fix it and return the complete corrected answer.", s.Correction)
+		}
+
+		result, err := provider.Send(ctx, rules, history, text)
+		if err != nil {
+			return err
+		}
+		s.Usage.Add(result.Usage)
+		s.Content = result.Content
+		return nil
+	}}
+}
+
+// Validate returns a step that checks the latest output without changing it. Combine
+// it with RetryFrom to regenerate output that fails the check.
+func Validate(name string, check func(ctx context.Context, content string) error) Step {
+	return Step{Name: name, Run: func(ctx context.Context, s *State) error {
+		return check(ctx, s.Content)
+	}}
+}
+
+// Transform returns a step replacing the latest output with fn's result.
+func Transform(name string, fn func(ctx context.Context, content string) (string, error)) Step {
+	return Step{Name: name, Run: func(ctx context.Context, s *State) error {
+		out, err := fn(ctx, s.Content)
+		if err != nil {
+			return err
+		}
+		s.Content = out
+		return nil
+	}}
+}
+
+// Persist returns a step that writes the state somewhere, e.g. a finished form to the
+// application's database, without changing the output.
+func Persist(name string, fn func(ctx context.Context, s *State) error) Step {
+	return Step{Name: name, Run: fn}
+}
+
+// SaveMessage returns a step storing the latest output as an assistant message in the
+// session attached to ctx with ai.WithSessionID, with the run's usage.
+func SaveMessage(name string, store ai.Store) Step {
+	return Persist(name, func(ctx context.Context, s *State) error {
+		sessionID := ai.SessionIDFromContext(ctx)
+		if sessionID == "" {
+			return errors.New("ai: pipeline: no session to save the message in")
+		}
+		usage := s.Usage
+		_, err := store.AddMessage(ctx, sessionID, ai.RoleAssistant, s.Content, &usage)
+		return err
+	})
+}
@@ -0,0 +1,211 @@
+package ai
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Policy actions control what happens when a response violates an OutputPolicy.
+const (
+	PolicyActionRetry  = "retry"
+	PolicyActionRedact = "redact"
+)
+
+// redactedText replaces content removed by a redacting policy.
+const redactedText = "[redacted]"
+
+// profanity is the built-in word list used when OutputPolicy.BlockProfanity is set.
+var profanity = []string{
+	"fuck", "fucking", "shit", "bitch", "bastard", "asshole", "dick", "cunt", "damn", "crap",
+	"anjing", "bangsat", "bajingan", "kontol", "goblok",
+}
+
+var profanityPattern = regexp.MustCompile(`(?i)\b(` + strings.Join(profanity, "|") + `)\b`)
+
+// OutputPolicy holds post-response checks applied before a response is accepted.
+// Set it on Rules to enforce it for every request in a session.
+type OutputPolicy struct {
+	BannedPatterns []string `json:"banned_patterns,omitempty"` // regular expressions the response must not match
+	MaxOptions     int      `json:"max_options,omitempty"`     // max length of any "options" array in a JSON response
+	BlockProfanity bool     `json:"block_profanity,omitempty"` // reject responses containing built-in profanity
+	Action         string   `json:"action,omitempty"`          // "retry" (default) or "redact"
+}
+
+// Enforce checks content against the policy. It returns the accepted content, which is
+// redacted when Action is "redact", or a *ValidationError describing the violation.
+// In a JSON response only string values are redacted, and the document is re-encoded;
+// a match that remains, such as in an object key, is a violation. MaxOptions
+// violations cannot be redacted and always trigger a retry. Enforce compiles the
+// patterns on every call; Compile them once to check many responses.
+func (p *OutputPolicy) Enforce(content string) (string, error) {
+	c, err := p.Compile()
+	if err != nil {
+		return "", err
+	}
+	return c.Enforce(content)
+}
+
+// CompiledPolicy is an OutputPolicy with its patterns compiled.
+type CompiledPolicy struct {
+	policy   OutputPolicy
+	patterns []*regexp.Regexp
+}
+
+// Compile checks and compiles the policy's patterns. A nil policy compiles to nil,
+// which accepts every response; an invalid pattern is an error.
+func (p *OutputPolicy) Compile() (*CompiledPolicy, error) {
+	if p == nil {
+		return nil, nil
+	}
+	patterns, err := p.compile()
+	if err != nil {
+		return nil, err
+	}
+	return &CompiledPolicy{policy: *p, patterns: patterns}, nil
+}
+
+// Enforce checks content against the policy like OutputPolicy.Enforce. A nil
+// CompiledPolicy returns content unchanged.
+func (c *CompiledPolicy) Enforce(content string) (string, error) {
+	if c == nil {
+		return content, nil
+	}
+	p, patterns := &c.policy, c.patterns
+
+	if p.MaxOptions > 0 {
+		if n := maxOptionsCount(content); n > p.MaxOptions {
+			return "", &ValidationError{
+				Reason:     FailReasonPolicyViolation,
+				Message:    fmt.Sprintf("policy violation: %d options exceeds limit of %d", n, p.MaxOptions),
+				Correction: fmt.Sprintf("Your previous response had a question with %d options. Please regenerate the complete response with at most %d options per question.", n, p.MaxOptions),
+			}
+		}
+	}
+
+	violations := matching(patterns, content)
+	if len(violations) == 0 {
+		return content, nil
+	}
+
+	if p.Action == PolicyActionRedact {
+		if !json.Valid([]byte(content)) {
+			for _, re := range patterns {
+				content = re.ReplaceAllString(content, redactedText)
+			}
+			return content, nil
+		}
+		redacted, err := redactJSON(content, patterns)
+		if err != nil {
+			return "", err
+		}
+		if violations = matching(patterns, redacted); len(violations) == 0 {
+			return redacted, nil
+		}
+	}
+
+	return "", &ValidationError{
+		Reason:     FailReasonPolicyViolation,
+		Message:    fmt.Sprintf("policy violation: response matched %s", strings.Join(violations, ", ")),
+		Correction: "Your previous response contained content that is not allowed by the output policy. Please regenerate the complete response without banned or offensive content.",
+	}
+}
+
+// matching returns the patterns that match content.
+func matching(patterns []*regexp.Regexp, content string) []string {
+	var matched []string
+	for _, re := range patterns {
+		if re.MatchString(content) {
+			matched = append(matched, re.String())
+		}
+	}
+	return matched
+}
+
+// redactJSON redacts the string values of a JSON document and re-encodes it, so
+// redaction can't break the document's quoting or escapes. Object keys are left as
+// they are.
+func redactJSON(content string, patterns []*regexp.Regexp) (string, error) {
+	dec := json.NewDecoder(strings.NewReader(content))
+	dec.UseNumber()
+	var doc any
+	if err := dec.Decode(&doc); err != nil {
+		return "", fmt.Errorf("ai: policy redact: %w", err)
+	}
+
+	var walk func(v any) any
+	walk = func(v any) any {
+		switch t := v.(type) {
+		case string:
+			for _, re := range patterns {
+				t = re.ReplaceAllString(t, redactedText)
+			}
+			return t
+		case map[string]any:
+			for k, child := range t {
+				t[k] = walk(child)
+			}
+		case []any:
+			for i, child := range t {
+				t[i] = walk(child)
+			}
+		}
+		return v
+	}
+	doc = walk(doc)
+
+	var b strings.Builder
+	enc := json.NewEncoder(&b)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(doc); err != nil {
+		return "", fmt.Errorf("ai: policy redact: %w", err)
+	}
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+// compile returns the banned patterns plus the profanity pattern when enabled.
+func (p *OutputPolicy) compile() ([]*regexp.Regexp, error) {
+	patterns := make([]*regexp.Regexp, 0, len(p.BannedPatterns)+1)
+	for _, expr := range p.BannedPatterns {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("ai: policy pattern %q: %w", expr, err)
+		}
+		patterns = append(patterns, re)
+	}
+	if p.BlockProfanity {
+		patterns = append(patterns, profanityPattern)
+	}
+	return patterns, nil
+}
+
+// maxOptionsCount returns the length of the longest "options" array in a JSON document.
+// Non-JSON content yields 0.
+func maxOptionsCount(content string) int {
+	var doc any
+	if err := json.Unmarshal([]byte(content), &doc); err != nil {
+		return 0
+	}
+
+	max := 0
+	var walk func(v any)
+	walk = func(v any) {
+		switch t := v.(type) {
+		case map[string]any:
+			for k, child := range t {
+				if arr, ok := child.([]any); ok && k == "options" && len(arr) > max {
+					max = len(arr)
+				}
+				walk(child)
+			}
+		case []any:
+			for _, child := range t {
+				walk(child)
+			}
+		}
+	}
+	walk(doc)
+
+	return max
+}
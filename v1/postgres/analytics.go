@@ -0,0 +1,421 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// defaultAnalyticsTimeout bounds each analytics query.
+const defaultAnalyticsTimeout = 30 * time.Second
+
+// Tables Analytics can query.
+const (
+	TableSessions      = "ai_sessions"
+	TableMessages      = "ai_messages"
+	TableRequestLogs   = "ai_request_logs"
+	TableSamples       = "ai_samples"
+	TableShadowResults = "ai_shadow_results"
+)
+
+// analyticsColumns lists the columns of a table Analytics can read. Content, such as
+// prompts, responses and system prompts, is left out, so no query can return it.
+type analyticsColumns struct {
+	group  []string // can be grouped by, filtered on and aggregated
+	filter []string // can also be filtered on and aggregated, but not grouped by
+}
+
+// filterable reports whether column can be filtered on or aggregated.
+func (c analyticsColumns) filterable(column string) bool {
+	return slices.Contains(c.group, column) || slices.Contains(c.filter, column)
+}
+
+// tokenColumns are the usage columns shared by messages and request logs.
+var tokenColumns = []string{"prompt_tokens", "response_tokens", "total_tokens", "thought_tokens", "cached_tokens", "tool_tokens", "audio_tokens", "cost"}
+
+// analyticsTables are the tables Analytics can query, by name.
+var analyticsTables = map[string]analyticsColumns{
+	TableSessions: {
+		group:  []string{"language", "response_format", "max_tokens", "labels", "created_at"},
+		filter: []string{"id"},
+	},
+	TableMessages: {
+		group:  []string{"session_id", "role", "event_type", "model", "created_at", "updated_at"},
+		filter: append([]string{"id", "seq", "request_id"}, tokenColumns...),
+	},
+	TableRequestLogs: {
+		group: []string{
+			"tenant_id", "user_id", "session_id", "model", "final_status", "fail_reason",
+			"prompt_name", "prompt_version", "schema_name", "schema_version",
+			"attempt_number", "retry_count", "labels", "created_at", "updated_at",
+		},
+		filter: append([]string{
+			"id", "request_id", "replay_of", "cache_hit_of", "cache_similarity",
+			"system_prompt_tokens", "history_tokens", "new_prompt_tokens", "cancelled_after_tokens",
+		}, tokenColumns...),
+	},
+	TableSamples: {
+		group:  []string{"tenant_id", "prompt_name", "prompt_version", "model", "created_at"},
+		filter: []string{"id"},
+	},
+	TableShadowResults: {
+		group: []string{"tenant_id", "session_id", "primary_model", "shadow_model", "identical", "created_at"},
+		filter: []string{
+			"id", "primary_request_id", "shadow_request_id", "primary_latency_ms", "shadow_latency_ms",
+		},
+	},
+}
+
+// Time units of AnalyticsQuery.GroupByTime, as in date_trunc.
+var analyticsUnits = []string{"minute", "hour", "day", "week", "month", "quarter", "year"}
+
+// Comparison operators of AnalyticsQuery.Where.
+var analyticsOps = []string{"=", "<>", "<", "<=", ">", ">="}
+
+// Analytics runs aggregate queries built with AnalyticsQuery over the package's
+// tables, for questions the store has no method for, e.g. retries by model by day.
+// Only the Table* tables can be queried, and of those only the columns that don't hold
+// content. Queries run in read-only transactions, with a timeout.
+type Analytics struct {
+	store   *PGStore
+	timeout time.Duration
+}
+
+// Analytics returns the store's analytics query layer.
+func (s *PGStore) Analytics() *Analytics {
+	return &Analytics{store: s, timeout: defaultAnalyticsTimeout}
+}
+
+// WithTimeout sets the statement timeout of each query, 30 seconds by default.
+func (a *Analytics) WithTimeout(timeout time.Duration) *Analytics {
+	a.timeout = timeout
+	return a
+}
+
+// AnalyticsResult holds the rows of an analytics query. Columns are named by the
+// aliases of the query; a row holds a value for each, in order. Groups, minimums and
+// maximums are returned as their column's values, truncated times as time.Time, counts
+// as int64, and sums and averages as float64.
+type AnalyticsResult struct {
+	Columns []string
+	Rows    [][]any
+}
+
+// AnalyticsQuery builds an aggregate query over one table without SQL text: columns
+// are checked against the table, identifiers are quoted and values are passed as
+// parameters. Build one with From; every method returns the query, and the first
+// mistake is reported by Analytics.Query.
+type AnalyticsQuery struct {
+	table   string
+	allowed analyticsColumns
+	selects []analyticsExpr
+	filters []analyticsFilter
+	groups  int // selects[:groups] are the groups
+	order   []string
+	limit   int
+	err     error
+}
+
+type analyticsExpr struct {
+	alias  string
+	column string // empty for COUNT(*)
+	fn     string // aggregate, "date_trunc" or "label", or empty for a plain group
+	unit   string // of date_trunc
+	key    string // of label
+}
+
+type analyticsFilter struct {
+	column string
+	op     string
+	value  any
+}
+
+// From starts a query over table, one of the Table* constants.
+func From(table string) *AnalyticsQuery {
+	allowed, ok := analyticsTables[table]
+	q := &AnalyticsQuery{table: table, allowed: allowed}
+	if !ok {
+		q.fail("ai: analytics: table %q can't be queried", table)
+	}
+	return q
+}
+
+// GroupBy groups the rows by column, returned under the column's name.
+func (q *AnalyticsQuery) GroupBy(column string) *AnalyticsQuery {
+	return q.group(analyticsExpr{alias: column, column: column})
+}
+
+// GroupByTime groups the rows by the time column truncated to unit ("minute", "hour",
+// "day", "week", "month", "quarter" or "year"), returned as alias.
+func (q *AnalyticsQuery) GroupByTime(column, unit, alias string) *AnalyticsQuery {
+	if !slices.Contains(analyticsUnits, unit) {
+		return q.fail("ai: analytics: unknown time unit %q", unit)
+	}
+	return q.group(analyticsExpr{alias: alias, column: column, fn: "date_trunc", unit: unit})
+}
+
+// GroupByLabel groups the rows by the value of a label of the labels column, such as
+// the session labels copied onto request logs, returned as alias. Rows without the
+// label form a group of nil.
+func (q *AnalyticsQuery) GroupByLabel(key, alias string) *AnalyticsQuery {
+	return q.group(analyticsExpr{alias: alias, column: "labels", fn: "label", key: key})
+}
+
+// Count counts the rows of each group, returned as alias.
+func (q *AnalyticsQuery) Count(alias string) *AnalyticsQuery {
+	return q.add(analyticsExpr{alias: alias, fn: "count"})
+}
+
+// CountDistinct counts the distinct non-null values of column in each group.
+func (q *AnalyticsQuery) CountDistinct(column, alias string) *AnalyticsQuery {
+	return q.add(analyticsExpr{alias: alias, column: column, fn: "count_distinct"})
+}
+
+// Sum sums column over each group.
+func (q *AnalyticsQuery) Sum(column, alias string) *AnalyticsQuery {
+	return q.add(analyticsExpr{alias: alias, column: column, fn: "sum"})
+}
+
+// Avg averages column over each group.
+func (q *AnalyticsQuery) Avg(column, alias string) *AnalyticsQuery {
+	return q.add(analyticsExpr{alias: alias, column: column, fn: "avg"})
+}
+
+// Min returns the smallest value of column in each group.
+func (q *AnalyticsQuery) Min(column, alias string) *AnalyticsQuery {
+	return q.add(analyticsExpr{alias: alias, column: column, fn: "min"})
+}
+
+// Max returns the largest value of column in each group.
+func (q *AnalyticsQuery) Max(column, alias string) *AnalyticsQuery {
+	return q.add(analyticsExpr{alias: alias, column: column, fn: "max"})
+}
+
+// Where keeps the rows whose column compares to value with op ("=", "<>", "<", "<=",
+// ">" or ">="). Filters are combined with AND.
+func (q *AnalyticsQuery) Where(column, op string, value any) *AnalyticsQuery {
+	if !slices.Contains(analyticsOps, op) {
+		return q.fail("ai: analytics: unknown operator %q", op)
+	}
+	if !q.allowed.filterable(column) {
+		return q.fail("ai: analytics: %s can't be filtered on %q", q.table, column)
+	}
+	q.filters = append(q.filters, analyticsFilter{column: column, op: op, value: value})
+	return q
+}
+
+// WhereLabel keeps the rows whose labels column has the label key set to value.
+func (q *AnalyticsQuery) WhereLabel(key, value string) *AnalyticsQuery {
+	if !q.allowed.filterable("labels") {
+		return q.fail("ai: analytics: %s has no labels", q.table)
+	}
+	q.filters = append(q.filters, analyticsFilter{column: "labels", op: "@>", value: map[string]string{key: value}})
+	return q
+}
+
+// Since keeps the rows whose created_at is at or after t.
+func (q *AnalyticsQuery) Since(t time.Time) *AnalyticsQuery {
+	return q.Where("created_at", ">=", t)
+}
+
+// OrderBy orders the result by an alias of the query, ascending, or descending when
+// desc is set. Calls add to the order. Without one, rows are ordered by the groups.
+func (q *AnalyticsQuery) OrderBy(alias string, desc bool) *AnalyticsQuery {
+	if !q.hasAlias(alias) {
+		return q.fail("ai: analytics: order by %q, which the query doesn't select", alias)
+	}
+	dir := " ASC"
+	if desc {
+		dir = " DESC"
+	}
+	q.order = append(q.order, pgx.Identifier{alias}.Sanitize()+dir)
+	return q
+}
+
+// Limit returns at most n rows.
+func (q *AnalyticsQuery) Limit(n int) *AnalyticsQuery {
+	q.limit = n
+	return q
+}
+
+func (q *AnalyticsQuery) group(e analyticsExpr) *AnalyticsQuery {
+	if q.groups < len(q.selects) {
+		return q.fail("ai: analytics: group by %q after an aggregate", e.alias)
+	}
+	if !slices.Contains(q.allowed.group, e.column) {
+		return q.fail("ai: analytics: %s can't be grouped by %q", q.table, e.column)
+	}
+	q.groups++
+	return q.add(e)
+}
+
+func (q *AnalyticsQuery) add(e analyticsExpr) *AnalyticsQuery {
+	if e.alias == "" || q.hasAlias(e.alias) {
+		return q.fail("ai: analytics: alias %q is empty or repeated", e.alias)
+	}
+	if e.column != "" && !q.allowed.filterable(e.column) {
+		return q.fail("ai: analytics: %s can't be aggregated over %q", q.table, e.column)
+	}
+	q.selects = append(q.selects, e)
+	return q
+}
+
+func (q *AnalyticsQuery) hasAlias(alias string) bool {
+	return slices.ContainsFunc(q.selects, func(e analyticsExpr) bool { return e.alias == alias })
+}
+
+func (q *AnalyticsQuery) fail(format string, args ...any) *AnalyticsQuery {
+	if q.err == nil {
+		q.err = fmt.Errorf(format, args...)
+	}
+	return q
+}
+
+// columns returns the columns the query reads.
+func (q *AnalyticsQuery) columns() []string {
+	var cols []string
+	for _, e := range q.selects {
+		if e.column != "" {
+			cols = append(cols, e.column)
+		}
+	}
+	for _, f := range q.filters {
+		cols = append(cols, f.column)
+	}
+	return cols
+}
+
+// sql returns the query's SQL and arguments.
+func (q *AnalyticsQuery) sql() (string, []any) {
+	var sel, group []string
+	var args []any
+	for i, e := range q.selects {
+		col := pgx.Identifier{e.column}.Sanitize()
+		var expr string
+		switch e.fn {
+		case "":
+			expr = col
+		case "date_trunc":
+			expr = fmt.Sprintf("date_trunc('%s', %s)", e.unit, col)
+		case "label":
+			args = append(args, e.key)
+			expr = fmt.Sprintf("%s ->> $%d::TEXT", col, len(args))
+		case "count":
+			expr = "COUNT(*)"
+		case "count_distinct":
+			expr = fmt.Sprintf("COUNT(DISTINCT %s)", col)
+		case "min", "max":
+			expr = fmt.Sprintf("%s(%s)", strings.ToUpper(e.fn), col)
+		default:
+			expr = fmt.Sprintf("%s(%s)::DOUBLE PRECISION", strings.ToUpper(e.fn), col)
+		}
+		sel = append(sel, expr+" AS "+pgx.Identifier{e.alias}.Sanitize())
+		if i < q.groups {
+			group = append(group, fmt.Sprint(i+1))
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT %s FROM %s", strings.Join(sel, ", "), pgx.Identifier{q.table}.Sanitize())
+	for i, f := range q.filters {
+		args = append(args, f.value)
+		keyword := " AND "
+		if i == 0 {
+			keyword = " WHERE "
+		}
+		fmt.Fprintf(&b, "%s%s %s $%d", keyword, pgx.Identifier{f.column}.Sanitize(), f.op, len(args))
+	}
+	if len(group) > 0 {
+		b.WriteString(" GROUP BY " + strings.Join(group, ", "))
+	}
+	switch {
+	case len(q.order) > 0:
+		b.WriteString(" ORDER BY " + strings.Join(q.order, ", "))
+	case len(group) > 0:
+		b.WriteString(" ORDER BY " + strings.Join(group, ", "))
+	}
+	if q.limit > 0 {
+		args = append(args, q.limit)
+		fmt.Fprintf(&b, " LIMIT $%d", len(args))
+	}
+	return b.String(), args
+}
+
+// Query runs q in a read-only transaction. It fails before running anything if q has
+// a mistake or reads a column its table doesn't have.
+func (a *Analytics) Query(ctx context.Context, q *AnalyticsQuery) (*AnalyticsResult, error) {
+	if q.err != nil {
+		return nil, q.err
+	}
+	if len(q.selects) == 0 {
+		return nil, errors.New("ai: analytics: the query selects nothing")
+	}
+	query, args := q.sql()
+
+	var result *AnalyticsResult
+	err := a.store.retry(ctx, true, func() error {
+		return pgx.BeginTxFunc(ctx, a.store.db, pgx.TxOptions{AccessMode: pgx.ReadOnly}, func(tx pgx.Tx) error {
+			if a.timeout > 0 {
+				if _, err := tx.Exec(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", a.timeout.Milliseconds())); err != nil {
+					return err
+				}
+			}
+			if err := checkAnalyticsColumns(ctx, tx, q.table, q.columns()); err != nil {
+				return err
+			}
+
+			rows, err := tx.Query(ctx, query, args...)
+			if err != nil {
+				return err
+			}
+			defer rows.Close()
+
+			result = &AnalyticsResult{}
+			for _, f := range rows.FieldDescriptions() {
+				result.Columns = append(result.Columns, f.Name)
+			}
+			for rows.Next() {
+				values, err := rows.Values()
+				if err != nil {
+					return fmt.Errorf("scan analytics row: %w", err)
+				}
+				result.Rows = append(result.Rows, values)
+			}
+			return rows.Err()
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: analytics: %w", storeError(err))
+	}
+	return result, nil
+}
+
+// checkAnalyticsColumns returns an error if table doesn't exist or lacks a column.
+func checkAnalyticsColumns(ctx context.Context, tx pgx.Tx, table string, columns []string) error {
+	rows, err := tx.Query(ctx,
+		`SELECT column_name FROM information_schema.columns
+		 WHERE table_schema = current_schema() AND table_name = $1`,
+		table,
+	)
+	if err != nil {
+		return err
+	}
+	existing, err := pgx.CollectRows(rows, pgx.RowTo[string])
+	if err != nil {
+		return err
+	}
+	if len(existing) == 0 {
+		return fmt.Errorf("table %q does not exist", table)
+	}
+	for _, c := range columns {
+		if !slices.Contains(existing, c) {
+			return fmt.Errorf("table %s has no column %q", table, c)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+const pendingResultColumns = `id, session_id, request_id, prompt, content, content_ref, usage, status, reviewer, reason, created_at, decided_at`
+
+// AddPendingResult stores r in ai_pending_results, offloading large content like
+// messages. Returns ai.ErrSessionNotFound if its session doesn't exist.
+func (s *PGStore) AddPendingResult(ctx context.Context, r ai.PendingResult) (*ai.PendingResult, error) {
+	inline, ref, sum, err := s.offload(ctx, r.Content)
+	if err != nil {
+		return nil, fmt.Errorf("ai: add pending result: %w", err)
+	}
+	if r.ID == "" {
+		r.ID = s.newID()
+	}
+	r.Status, r.Reviewer, r.Reason, r.DecidedAt = ai.ApprovalPending, "", "", nil
+	r.CreatedAt = s.now()
+
+	err = s.retry(ctx, false, func() error {
+		var id string
+		return s.db.QueryRow(ctx,
+			`INSERT INTO ai_pending_results (id, session_id, request_id, prompt, content, content_ref, content_sha256, usage, status, created_at)
+			 SELECT $1, id, $3, $4, $5, $6, $7, $8, $9, $10 FROM ai_sessions WHERE id = $2
+			 RETURNING id`,
+			r.ID, r.SessionID, r.RequestID, r.Prompt, inline, ref, sum, r.Usage, r.Status, r.CreatedAt,
+		).Scan(&id)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: add pending result: %w", storeError(err))
+	}
+	return &r, nil
+}
+
+// GetPendingResult returns a result in any status, or ai.ErrApprovalNotFound.
+func (s *PGStore) GetPendingResult(ctx context.Context, id string) (*ai.PendingResult, error) {
+	var r ai.PendingResult
+	var ref string
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`SELECT `+pendingResultColumns+` FROM ai_pending_results WHERE id = $1`,
+			id,
+		).Scan(&r.ID, &r.SessionID, &r.RequestID, &r.Prompt, &r.Content, &ref, &r.Usage, &r.Status, &r.Reviewer, &r.Reason, &r.CreatedAt, &r.DecidedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ai.ErrApprovalNotFound, id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: get pending result: %w", storeError(err))
+	}
+
+	if r.Content, err = s.load(ctx, r.Content, ref); err != nil {
+		return nil, fmt.Errorf("ai: get pending result: %w", err)
+	}
+	return &r, nil
+}
+
+// ListPendingResults returns the results of a session awaiting review, oldest first.
+func (s *PGStore) ListPendingResults(ctx context.Context, sessionID string) ([]ai.PendingResult, error) {
+	var results []ai.PendingResult
+	var refs []string
+	err := s.retry(ctx, true, func() error {
+		results, refs = nil, nil
+		rows, err := s.db.Query(ctx,
+			`SELECT `+pendingResultColumns+` FROM ai_pending_results
+			 WHERE session_id = $1 AND status = $2
+			 ORDER BY created_at ASC, id ASC`,
+			sessionID, ai.ApprovalPending,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var r ai.PendingResult
+			var ref string
+			if err := rows.Scan(&r.ID, &r.SessionID, &r.RequestID, &r.Prompt, &r.Content, &ref, &r.Usage, &r.Status, &r.Reviewer, &r.Reason, &r.CreatedAt, &r.DecidedAt); err != nil {
+				return fmt.Errorf("scan pending result: %w", err)
+			}
+			results = append(results, r)
+			refs = append(refs, ref)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: list pending results: %w", storeError(err))
+	}
+
+	for i, r := range results {
+		if results[i].Content, err = s.load(ctx, r.Content, refs[i]); err != nil {
+			return nil, fmt.Errorf("ai: list pending results: %s: %w", r.ID, err)
+		}
+	}
+	return results, nil
+}
+
+// ApprovePendingResult marks a pending result approved by the caller attached to ctx
+// and, in the same transaction, appends its prompt and content to the session like
+// AddMessages, under the request ID the result was produced by. The approval is
+// audited when auditing is enabled.
+func (s *PGStore) ApprovePendingResult(ctx context.Context, id string) (*ai.PendingResult, []ai.Message, error) {
+	r, err := s.GetPendingResult(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+	if r.Status != ai.ApprovalPending {
+		return nil, nil, fmt.Errorf("%w: %s is %s", ai.ErrApprovalDecided, id, r.Status)
+	}
+
+	var msgs []ai.NewMessage
+	if r.Prompt != "" {
+		msgs = append(msgs, ai.NewMessage{Role: ai.RoleUser, Content: r.Prompt})
+	}
+	answer := ai.NewMessage{Role: ai.RoleAssistant, Content: r.Content}
+	if r.Usage != (ai.Usage{}) {
+		usage := r.Usage
+		answer.Usage = &usage
+	}
+	batch, err := s.prepareMessages(ctx, r.SessionID, append(msgs, answer))
+	if err != nil {
+		return nil, nil, fmt.Errorf("ai: approve pending result: %w", err)
+	}
+	batch.requestID = r.RequestID
+
+	err = s.retry(ctx, false, func() error {
+		return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			var locked string
+			err := tx.QueryRow(ctx, `SELECT id FROM ai_sessions WHERE id = $1 FOR UPDATE`, r.SessionID).Scan(&locked)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ai.ErrSessionNotFound
+			}
+			if err != nil {
+				return err
+			}
+
+			if err := s.decide(ctx, tx, r, ai.ApprovalApproved, ""); err != nil {
+				return err
+			}
+			if err := s.insertMessages(ctx, tx, batch); err != nil {
+				return err
+			}
+			if !s.audit {
+				return nil
+			}
+			return s.recordAudit(ctx, tx, ai.AuditApproveResult, r.SessionID, r.ID)
+		})
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("ai: approve pending result: %w", storeError(err))
+	}
+	return r, batch.out, nil
+}
+
+// RejectPendingResult marks a pending result rejected by the caller attached to ctx.
+// The rejection is audited when auditing is enabled.
+func (s *PGStore) RejectPendingResult(ctx context.Context, id string, reason string) (*ai.PendingResult, error) {
+	r, err := s.GetPendingResult(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.mutate(ctx, ai.AuditRejectResult, r.SessionID, func(q querier) (string, error) {
+		return r.ID, s.decide(ctx, q, r, ai.ApprovalRejected, reason)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: reject pending result: %w", storeError(err))
+	}
+	return r, nil
+}
+
+// decide moves r from pending to status, updating it in place. Returns
+// ai.ErrApprovalDecided if r was decided concurrently.
+func (s *PGStore) decide(ctx context.Context, q querier, r *ai.PendingResult, status string, reason string) error {
+	reviewer := ai.ActorFromContext(ctx).UserID
+	now := s.now()
+
+	tag, err := q.Exec(ctx,
+		`UPDATE ai_pending_results SET status = $2, reviewer = $3, reason = $4, decided_at = $5
+		 WHERE id = $1 AND status = $6`,
+		r.ID, status, reviewer, reason, now, ai.ApprovalPending,
+	)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("%w: %s", ai.ErrApprovalDecided, r.ID)
+	}
+
+	r.Status, r.Reviewer, r.Reason, r.DecidedAt = status, reviewer, reason, &now
+	return nil
+}
+
+// Ensure PGStore implements ai.ApprovalStore at compile time.
+var _ ai.ApprovalStore = (*PGStore)(nil)
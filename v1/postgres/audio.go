@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// AddMessageAudio attaches audio to a message in ai_message_audio. With blob offload
+// enabled the data is written to the blob store under its ai.BlobKey, whatever its
+// size; otherwise it is kept in the row.
+func (s *PGStore) AddMessageAudio(ctx context.Context, messageID string, audio ai.Audio, voice string) (*ai.MessageAudio, error) {
+	a := &ai.MessageAudio{
+		MessageID: messageID,
+		MIMEType:  audio.MIMEType,
+		Size:      len(audio.Data),
+		SHA256:    ai.ContentHash(string(audio.Data)),
+		Voice:     voice,
+	}
+	data := audio.Data
+	if s.blobs != nil {
+		a.BlobKey = ai.BlobKey(string(audio.Data))
+		if err := s.blobs.PutBlob(ctx, a.BlobKey, audio.Data); err != nil {
+			return nil, fmt.Errorf("ai: add message audio: %w", err)
+		}
+		data = nil
+	}
+
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`INSERT INTO ai_message_audio (message_id, mime_type, size, sha256, blob_key, data, voice, created_at)
+			 SELECT id, $2, $3, $4, $5, $6, $7, $8 FROM ai_messages WHERE id = $1
+			 ON CONFLICT (message_id) DO UPDATE
+			 SET mime_type = EXCLUDED.mime_type, size = EXCLUDED.size, sha256 = EXCLUDED.sha256,
+			     blob_key = EXCLUDED.blob_key, data = EXCLUDED.data, voice = EXCLUDED.voice, created_at = EXCLUDED.created_at
+			 RETURNING created_at`,
+			messageID, a.MIMEType, a.Size, a.SHA256, a.BlobKey, data, voice, s.now(),
+		).Scan(&a.CreatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrMessageNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: add message audio: %w", storeError(err))
+	}
+	return a, nil
+}
+
+// GetMessageAudio returns the audio metadata of a message, or ai.ErrAudioNotFound.
+func (s *PGStore) GetMessageAudio(ctx context.Context, messageID string) (*ai.MessageAudio, error) {
+	a := &ai.MessageAudio{MessageID: messageID}
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`SELECT mime_type, size, sha256, blob_key, voice, created_at FROM ai_message_audio WHERE message_id = $1`,
+			messageID,
+		).Scan(&a.MIMEType, &a.Size, &a.SHA256, &a.BlobKey, &a.Voice, &a.CreatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrAudioNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: get message audio: %w", storeError(err))
+	}
+	return a, nil
+}
+
+// GetMessageAudioData returns the audio of a message, reading it back from the blob
+// store when it was offloaded, or ai.ErrAudioNotFound.
+func (s *PGStore) GetMessageAudioData(ctx context.Context, messageID string) (*ai.Audio, error) {
+	var (
+		audio ai.Audio
+		ref   string
+	)
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`SELECT mime_type, blob_key, data FROM ai_message_audio WHERE message_id = $1`,
+			messageID,
+		).Scan(&audio.MIMEType, &ref, &audio.Data)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrAudioNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: get message audio: %w", storeError(err))
+	}
+
+	if ref != "" {
+		if s.blobs == nil {
+			return nil, fmt.Errorf("ai: get message audio: audio is in blob %s but no blob store is configured", ref)
+		}
+		if audio.Data, err = s.blobs.GetBlob(ctx, ref); err != nil {
+			return nil, fmt.Errorf("ai: get message audio: %w", err)
+		}
+	}
+	return &audio, nil
+}
+
+// Ensure PGStore implements ai.AudioStore at compile time.
+var _ ai.AudioStore = (*PGStore)(nil)
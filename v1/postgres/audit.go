@@ -0,0 +1,53 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// recordAudit inserts an audit entry for the caller attached to ctx.
+func (s *PGStore) recordAudit(ctx context.Context, q querier, action string, sessionID string, targetID string) error {
+	_, err := q.Exec(ctx,
+		`INSERT INTO ai_audit (id, actor, action, session_id, target_id, request_id, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		s.newID(), ai.ActorFromContext(ctx).UserID, action, sessionID, targetID, ai.RequestIDFromContext(ctx), s.now(),
+	)
+	if err != nil {
+		return fmt.Errorf("ai: record audit: %w", err)
+	}
+	return nil
+}
+
+// ListAuditEntries returns the audit trail of a session, oldest first. Entries
+// outlive the session, so deleted sessions can still be audited.
+func (s *PGStore) ListAuditEntries(ctx context.Context, sessionID string) ([]ai.AuditEntry, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id, actor, action, session_id, target_id, request_id, created_at
+		 FROM ai_audit WHERE session_id = $1 ORDER BY created_at ASC, id ASC`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list audit entries: %w", err)
+	}
+	defer rows.Close()
+
+	var entries []ai.AuditEntry
+	for rows.Next() {
+		var e ai.AuditEntry
+		if err := rows.Scan(&e.ID, &e.Actor, &e.Action, &e.SessionID, &e.TargetID, &e.RequestID, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ai: scan audit entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: list audit entries: %w", err)
+	}
+
+	return entries, nil
+}
+
+// Ensure PGStore implements ai.AuditStore at compile time.
+var _ ai.AuditStore = (*PGStore)(nil)
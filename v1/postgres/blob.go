@@ -0,0 +1,47 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// WithBlobOffload stores message contents and request-log responses longer than
+// threshold bytes in blobs, under their ai.BlobKey, keeping only the key and the
+// content's SHA-256 (recorded for every row) in Postgres. ListMessages and GetRequestLog fetch them back.
+// Blobs are written before the row, so a failed insert can leave an unreferenced blob.
+func (s *PGStore) WithBlobOffload(blobs ai.BlobStore, threshold int) *PGStore {
+	s.blobs = blobs
+	s.blobThreshold = threshold
+	return s
+}
+
+// offload returns the content to store inline, the blob key of content moved to a
+// blob, and the content's hash, which is recorded either way.
+func (s *PGStore) offload(ctx context.Context, content string) (inline, ref, sum string, err error) {
+	sum = ai.ContentHash(content)
+	if s.blobs == nil || len(content) <= s.blobThreshold {
+		return content, "", sum, nil
+	}
+	ref = ai.BlobKey(content)
+	if err := s.blobs.PutBlob(ctx, ref, []byte(content)); err != nil {
+		return "", "", "", fmt.Errorf("offload content: %w", err)
+	}
+	return "", ref, sum, nil
+}
+
+// load returns the stored content, fetching it from the blob store when ref is set.
+func (s *PGStore) load(ctx context.Context, inline, ref string) (string, error) {
+	if ref == "" {
+		return inline, nil
+	}
+	if s.blobs == nil {
+		return "", fmt.Errorf("content is in blob %s but no blob store is configured", ref)
+	}
+	data, err := s.blobs.GetBlob(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("load content: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,168 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// AddMessages appends msgs to a session with a single multi-row insert. The session is
+// locked while the seqs are allocated, so concurrent AddMessage calls can't interleave.
+// Each message is audited like AddMessage or AddEvent when auditing is enabled.
+func (s *PGStore) AddMessages(ctx context.Context, sessionID string, msgs []ai.NewMessage) ([]ai.Message, error) {
+	if len(msgs) == 0 {
+		return nil, nil
+	}
+
+	batch, err := s.prepareMessages(ctx, sessionID, msgs)
+	if err != nil {
+		return nil, fmt.Errorf("ai: add messages: %w", err)
+	}
+
+	err = s.retry(ctx, false, func() error {
+		return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			var locked string
+			err := tx.QueryRow(ctx, `SELECT id FROM ai_sessions WHERE id = $1 FOR UPDATE`, sessionID).Scan(&locked)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ai.ErrSessionNotFound
+			}
+			if err != nil {
+				return err
+			}
+			return s.insertMessages(ctx, tx, batch)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: add messages: %w", storeError(err))
+	}
+
+	return batch.out, nil
+}
+
+// messageBatch is the column arrays of messages to insert with insertMessages.
+type messageBatch struct {
+	sessionID, requestID                     string
+	out                                      []ai.Message
+	ids, roles, contents, eventTypes, models []string
+	prompt, response, total, thought         []int
+	cached, tool, audio                      []int
+	costs                                    []float64
+	refs, sums                               []string
+}
+
+// prepareMessages assigns IDs and costs to msgs and offloads their large contents.
+func (s *PGStore) prepareMessages(ctx context.Context, sessionID string, msgs []ai.NewMessage) (*messageBatch, error) {
+	n := len(msgs)
+	b := &messageBatch{
+		sessionID:  sessionID,
+		requestID:  ai.RequestIDFromContext(ctx),
+		out:        make([]ai.Message, n),
+		ids:        make([]string, n),
+		roles:      make([]string, n),
+		contents:   make([]string, n),
+		eventTypes: make([]string, n),
+		models:     make([]string, n),
+		prompt:     make([]int, n),
+		response:   make([]int, n),
+		total:      make([]int, n),
+		thought:    make([]int, n),
+		cached:     make([]int, n),
+		tool:       make([]int, n),
+		audio:      make([]int, n),
+		costs:      make([]float64, n),
+		refs:       make([]string, n),
+		sums:       make([]string, n),
+	}
+
+	for i, m := range msgs {
+		b.out[i] = ai.Message{
+			ID:        s.newID(),
+			SessionID: sessionID,
+			Role:      m.Role,
+			Content:   m.Content,
+			EventType: m.EventType,
+			RequestID: b.requestID,
+			Usage:     m.Usage,
+		}
+		var u ai.Usage
+		if m.Usage != nil {
+			u = *m.Usage
+			b.out[i].Cost = s.cost(u)
+		}
+		inline, ref, sum, err := s.offload(ctx, m.Content)
+		if err != nil {
+			return nil, err
+		}
+		b.ids[i], b.roles[i], b.contents[i], b.eventTypes[i], b.models[i] = b.out[i].ID, m.Role, inline, m.EventType, u.Model
+		b.refs[i], b.sums[i] = ref, sum
+		b.prompt[i], b.response[i], b.total[i], b.thought[i] = u.PromptTokens, u.ResponseTokens, u.TotalTokens, u.ThoughtTokens
+		b.cached[i], b.tool[i], b.audio[i] = u.CachedTokens, u.ToolTokens, u.AudioTokens
+		b.costs[i] = b.out[i].Cost
+	}
+	return b, nil
+}
+
+// insertMessages inserts b after the session's last message and audits each one. The
+// caller must hold the session's row lock, or have created the session in tx.
+func (s *PGStore) insertMessages(ctx context.Context, tx pgx.Tx, b *messageBatch) error {
+	rows, err := tx.Query(ctx,
+		`INSERT INTO ai_messages (id, session_id, seq, role, content, event_type, request_id,
+		                          prompt_tokens, response_tokens, total_tokens, thought_tokens, cached_tokens, tool_tokens, audio_tokens,
+		                          model, cost, created_at, content_ref, content_sha256)
+		 SELECT t.id, $1, COALESCE((SELECT MAX(seq) FROM ai_messages WHERE session_id = $1), 0) + t.n, t.role, t.content, t.event_type, $2,
+		        t.prompt_tokens, t.response_tokens, t.total_tokens, t.thought_tokens, t.cached_tokens, t.tool_tokens, t.audio_tokens,
+		        t.model, t.cost, $3, t.content_ref, t.content_sha256
+		 FROM unnest($4::text[], $5::text[], $6::text[], $7::text[],
+		             $8::int[], $9::int[], $10::int[], $11::int[], $12::int[], $13::int[], $14::int[],
+		             $15::text[], $16::numeric[], $17::text[], $18::text[])
+		      WITH ORDINALITY AS t(id, role, content, event_type,
+		                           prompt_tokens, response_tokens, total_tokens, thought_tokens, cached_tokens, tool_tokens, audio_tokens,
+		                           model, cost, content_ref, content_sha256, n)
+		 RETURNING id, seq, created_at`,
+		b.sessionID, b.requestID, s.now(),
+		b.ids, b.roles, b.contents, b.eventTypes,
+		b.prompt, b.response, b.total, b.thought, b.cached, b.tool, b.audio,
+		b.models, b.costs, b.refs, b.sums,
+	)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	index := make(map[string]int, len(b.ids))
+	for i, id := range b.ids {
+		index[id] = i
+	}
+	for rows.Next() {
+		var id string
+		var msg ai.Message
+		if err := rows.Scan(&id, &msg.Seq, &msg.CreatedAt); err != nil {
+			return err
+		}
+		b.out[index[id]].Seq, b.out[index[id]].CreatedAt, b.out[index[id]].UpdatedAt = msg.Seq, msg.CreatedAt, msg.CreatedAt
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	if !s.audit {
+		return nil
+	}
+	for _, msg := range b.out {
+		action := ai.AuditAddMessage
+		if msg.Role == ai.RoleEvent {
+			action = ai.AuditAddEvent
+		}
+		if err := s.recordAudit(ctx, tx, action, b.sessionID, msg.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Ensure PGStore implements ai.BulkMessageStore at compile time.
+var _ ai.BulkMessageStore = (*PGStore)(nil)
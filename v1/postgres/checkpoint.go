@@ -0,0 +1,58 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SaveCheckpoint inserts or updates a stream checkpoint.
+func (s *PGStore) SaveCheckpoint(ctx context.Context, cp ai.Checkpoint) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO ai_stream_checkpoints (id, session_id, request_log_id, prompt, content, complete, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		 ON CONFLICT (id) DO UPDATE
+		 SET content = EXCLUDED.content, complete = EXCLUDED.complete, updated_at = EXCLUDED.updated_at`,
+		cp.ID, cp.SessionID, cp.RequestLogID, cp.Prompt, cp.Content, cp.Complete, s.now(),
+	)
+	if err != nil {
+		return fmt.Errorf("ai: save checkpoint: %w", err)
+	}
+	return nil
+}
+
+// LatestIncompleteCheckpoint returns the newest incomplete checkpoint of a session.
+func (s *PGStore) LatestIncompleteCheckpoint(ctx context.Context, sessionID string) (*ai.Checkpoint, error) {
+	cp := &ai.Checkpoint{SessionID: sessionID}
+
+	err := s.db.QueryRow(ctx,
+		`SELECT id, request_log_id, prompt, content, complete, created_at, updated_at
+		 FROM ai_stream_checkpoints
+		 WHERE session_id = $1 AND NOT complete
+		 ORDER BY updated_at DESC LIMIT 1`,
+		sessionID,
+	).Scan(&cp.ID, &cp.RequestLogID, &cp.Prompt, &cp.Content, &cp.Complete, &cp.CreatedAt, &cp.UpdatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrNoIncompleteStream
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: get checkpoint: %w", err)
+	}
+
+	return cp, nil
+}
+
+// CompleteCheckpoint marks a checkpoint as complete.
+func (s *PGStore) CompleteCheckpoint(ctx context.Context, id string) error {
+	_, err := s.db.Exec(ctx, `UPDATE ai_stream_checkpoints SET complete = TRUE, updated_at = $1 WHERE id = $2`, s.now(), id)
+	if err != nil {
+		return fmt.Errorf("ai: complete checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Ensure PGStore implements ai.CheckpointStore at compile time.
+var _ ai.CheckpointStore = (*PGStore)(nil)
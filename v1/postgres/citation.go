@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// AddCitations appends citations to a message in ai_citations. The message row is
+// locked so concurrent calls get distinct positions.
+func (s *PGStore) AddCitations(ctx context.Context, messageID string, citations []ai.Citation) error {
+	if len(citations) == 0 {
+		return nil
+	}
+
+	n := len(citations)
+	uris, titles, texts, licenses := make([]string, n), make([]string, n), make([]string, n), make([]string, n)
+	starts, ends := make([]int, n), make([]int, n)
+	for i, c := range citations {
+		uris[i], titles[i], texts[i], licenses[i] = c.URI, c.Title, c.Text, c.License
+		starts[i], ends[i] = c.Start, c.End
+	}
+
+	err := s.retry(ctx, false, func() error {
+		return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			var id string
+			err := tx.QueryRow(ctx, `SELECT id FROM ai_messages WHERE id = $1 FOR UPDATE`, messageID).Scan(&id)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ai.ErrMessageNotFound
+			}
+			if err != nil {
+				return err
+			}
+
+			_, err = tx.Exec(ctx,
+				`INSERT INTO ai_citations (message_id, position, uri, title, text, start_index, end_index, license, created_at)
+				 SELECT $1, COALESCE((SELECT MAX(position) FROM ai_citations WHERE message_id = $1), 0) + t.n,
+				        t.uri, t.title, t.text, t.start_index, t.end_index, t.license, $2
+				 FROM unnest($3::text[], $4::text[], $5::text[], $6::int[], $7::int[], $8::text[])
+				      WITH ORDINALITY AS t(uri, title, text, start_index, end_index, license, n)`,
+				messageID, s.now(), uris, titles, texts, starts, ends, licenses,
+			)
+			return err
+		})
+	})
+	if err != nil {
+		return fmt.Errorf("ai: add citations: %w", storeError(err))
+	}
+	return nil
+}
+
+// ListCitations returns the citations of a message in the order they were added.
+func (s *PGStore) ListCitations(ctx context.Context, messageID string) ([]ai.Citation, error) {
+	citations, err := s.queryCitations(ctx,
+		`SELECT message_id, uri, title, text, start_index, end_index, license
+		 FROM ai_citations WHERE message_id = $1 ORDER BY position`,
+		messageID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list citations: %w", storeError(err))
+	}
+	return citations, nil
+}
+
+// ListSessionCitations returns the citations of every message of a session, ordered by
+// message seq and then position.
+func (s *PGStore) ListSessionCitations(ctx context.Context, sessionID string) ([]ai.Citation, error) {
+	citations, err := s.queryCitations(ctx,
+		`SELECT c.message_id, c.uri, c.title, c.text, c.start_index, c.end_index, c.license
+		 FROM ai_citations c JOIN ai_messages m ON m.id = c.message_id
+		 WHERE m.session_id = $1
+		 ORDER BY m.seq, c.position`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list session citations: %w", storeError(err))
+	}
+	return citations, nil
+}
+
+func (s *PGStore) queryCitations(ctx context.Context, query string, arg string) ([]ai.Citation, error) {
+	var citations []ai.Citation
+	err := s.retry(ctx, true, func() error {
+		citations = nil
+		rows, err := s.db.Query(ctx, query, arg)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var c ai.Citation
+			if err := rows.Scan(&c.MessageID, &c.URI, &c.Title, &c.Text, &c.Start, &c.End, &c.License); err != nil {
+				return fmt.Errorf("scan citation: %w", err)
+			}
+			citations = append(citations, c)
+		}
+		return rows.Err()
+	})
+	return citations, err
+}
+
+// Ensure PGStore implements ai.CitationStore at compile time.
+var _ ai.CitationStore = (*PGStore)(nil)
@@ -0,0 +1,120 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// CloneSession copies a session in one transaction. Only the IDs, seqs and roles of
+// the source's messages are read to apply opts; the selected rows are then copied with
+// a single INSERT ... SELECT, so contents never leave the database. Offloaded contents
+// keep their blob reference, and copies keep the usage, cost and request ID of the
+// original. The source is locked against appends while it is copied.
+func (s *PGStore) CloneSession(ctx context.Context, sessionID string, opts ai.CloneOptions) (*ai.Session, error) {
+	clone := &ai.Session{ID: s.newID(), CreatedAt: s.now()}
+
+	err := s.retry(ctx, false, func() error {
+		return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			source := &clone.Rules
+			err := tx.QueryRow(ctx,
+				`SELECT system_prompt, output_schema, max_tokens, policy, language, prompt, schema, labels, response_format
+				 FROM ai_sessions WHERE id = $1
+				 FOR SHARE`,
+				sessionID,
+			).Scan(&source.SystemPrompt, &source.OutputSchema, &source.MaxTokens, &source.Policy, &source.Language, &source.Prompt, &source.Schema, &source.Labels, &source.ResponseFormat)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ai.ErrSessionNotFound
+			}
+			if err != nil {
+				return err
+			}
+			if opts.Rules != nil {
+				clone.Rules = *opts.Rules
+			}
+
+			if err := insertSession(ctx, tx, clone); err != nil {
+				return err
+			}
+			if s.audit {
+				if err := s.recordAudit(ctx, tx, ai.AuditCreateSession, clone.ID, clone.ID); err != nil {
+					return err
+				}
+			}
+
+			selected, err := cloneSelection(ctx, tx, sessionID, opts)
+			if err != nil || len(selected) == 0 {
+				return err
+			}
+			oldIDs, newIDs := make([]string, len(selected)), make([]string, len(selected))
+			for i, m := range selected {
+				oldIDs[i], newIDs[i] = m.ID, s.newID()
+			}
+
+			_, err = tx.Exec(ctx,
+				`INSERT INTO ai_messages (id, session_id, seq, role, content, event_type, request_id,
+				                          prompt_tokens, response_tokens, total_tokens, thought_tokens, cached_tokens, tool_tokens, audio_tokens,
+				                          model, cost, created_at, content_ref, content_sha256)
+				 SELECT t.new_id, $1, t.n, m.role, m.content, m.event_type, m.request_id,
+				        m.prompt_tokens, m.response_tokens, m.total_tokens, m.thought_tokens, m.cached_tokens, m.tool_tokens, m.audio_tokens,
+				        m.model, m.cost, $2, m.content_ref, m.content_sha256
+				 FROM unnest($3::text[], $4::text[]) WITH ORDINALITY AS t(old_id, new_id, n)
+				 JOIN ai_messages m ON m.id = t.old_id`,
+				clone.ID, clone.CreatedAt, oldIDs, newIDs,
+			)
+			if err != nil || !s.audit {
+				return err
+			}
+			for i, m := range selected {
+				action := ai.AuditAddMessage
+				if m.Role == ai.RoleEvent {
+					action = ai.AuditAddEvent
+				}
+				if err := s.recordAudit(ctx, tx, action, clone.ID, newIDs[i]); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+	})
+	if errors.Is(err, ai.ErrSessionNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: clone session: %w", storeError(err))
+	}
+
+	return clone, nil
+}
+
+// cloneSelection returns the ID, seq and role of the messages of sessionID that opts
+// selects, in seq order.
+func cloneSelection(ctx context.Context, tx pgx.Tx, sessionID string, opts ai.CloneOptions) ([]ai.Message, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT id, seq, role FROM ai_messages WHERE session_id = $1 ORDER BY seq ASC`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []ai.Message
+	for rows.Next() {
+		var m ai.Message
+		if err := rows.Scan(&m.ID, &m.Seq, &m.Role); err != nil {
+			return nil, fmt.Errorf("scan message: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return opts.Select(msgs), nil
+}
+
+// Ensure PGStore implements ai.SessionCloner at compile time.
+var _ ai.SessionCloner = (*PGStore)(nil)
@@ -0,0 +1,57 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// MarkCollapsed records a collapsed range, replacing one with the same session and FromSeq.
+func (s *PGStore) MarkCollapsed(ctx context.Context, r ai.CollapsedRange) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO ai_collapsed_ranges (session_id, from_seq, to_seq, count, updated_at)
+		 VALUES ($1, $2, $3, $4, $5)
+		 ON CONFLICT (session_id, from_seq) DO UPDATE
+		 SET to_seq = EXCLUDED.to_seq,
+		     count = EXCLUDED.count,
+		     updated_at = EXCLUDED.updated_at`,
+		r.SessionID, r.FromSeq, r.ToSeq, r.Count, r.UpdatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("ai: mark collapsed: %w", err)
+	}
+	return nil
+}
+
+// ListCollapsed returns a session's collapsed ranges ordered by FromSeq.
+func (s *PGStore) ListCollapsed(ctx context.Context, sessionID string) ([]ai.CollapsedRange, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT session_id, from_seq, to_seq, count, updated_at
+		 FROM ai_collapsed_ranges WHERE session_id = $1
+		 ORDER BY from_seq ASC`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list collapsed: %w", err)
+	}
+	defer rows.Close()
+
+	var ranges []ai.CollapsedRange
+	for rows.Next() {
+		var r ai.CollapsedRange
+		if err := rows.Scan(&r.SessionID, &r.FromSeq, &r.ToSeq, &r.Count, &r.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("ai: scan collapsed range: %w", err)
+		}
+		ranges = append(ranges, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: list collapsed: %w", err)
+	}
+
+	return ranges, nil
+}
+
+// Ensure PGStore implements ai.CollapseStore at compile time.
+var _ ai.CollapseStore = (*PGStore)(nil)
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SetSessionDefaults stores the default send options of a session in
+// ai_session_defaults, or deletes them for zero options.
+func (s *PGStore) SetSessionDefaults(ctx context.Context, sessionID string, opts ai.SendOptions) error {
+	if opts.Model == "" && opts.Temperature == nil && opts.Policy == nil && opts.Transforms == nil {
+		err := s.retry(ctx, true, func() error {
+			_, err := s.db.Exec(ctx, `DELETE FROM ai_session_defaults WHERE session_id = $1`, sessionID)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("ai: set session defaults: %w", storeError(err))
+		}
+		return nil
+	}
+
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return fmt.Errorf("ai: set session defaults: %w", err)
+	}
+
+	var n int64
+	err = s.retry(ctx, true, func() error {
+		tag, err := s.db.Exec(ctx,
+			`INSERT INTO ai_session_defaults (session_id, options, updated_at)
+			 SELECT id, $2, $3 FROM ai_sessions WHERE id = $1
+			 ON CONFLICT (session_id) DO UPDATE SET options = EXCLUDED.options, updated_at = EXCLUDED.updated_at`,
+			sessionID, data, s.now(),
+		)
+		n = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: set session defaults: %w", storeError(err))
+	}
+	if n == 0 {
+		return ai.ErrSessionNotFound
+	}
+	return nil
+}
+
+// SessionDefaults returns the default send options of a session, or zero options.
+func (s *PGStore) SessionDefaults(ctx context.Context, sessionID string) (ai.SendOptions, error) {
+	var data []byte
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`SELECT options FROM ai_session_defaults WHERE session_id = $1`,
+			sessionID,
+		).Scan(&data)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ai.SendOptions{}, nil
+	}
+	if err != nil {
+		return ai.SendOptions{}, fmt.Errorf("ai: session defaults: %w", storeError(err))
+	}
+
+	var opts ai.SendOptions
+	if err := json.Unmarshal(data, &opts); err != nil {
+		return ai.SendOptions{}, fmt.Errorf("ai: session defaults: %w", err)
+	}
+	return opts, nil
+}
+
+// Ensure PGStore implements ai.SessionDefaultsStore at compile time.
+var _ ai.SessionDefaultsStore = (*PGStore)(nil)
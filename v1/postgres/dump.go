@@ -0,0 +1,113 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// migrationVersion returns the numeric prefix of a migration name ("007_add_x" -> 7).
+func migrationVersion(name string) int {
+	prefix, _, _ := strings.Cut(name, "_")
+	v, _ := strconv.Atoi(prefix)
+	return v
+}
+
+// migrationsUpTo returns the embedded migrations with version <= upToVersion, or all of
+// them when upToVersion is 0.
+func migrationsUpTo(upToVersion int) ([]migrationFile, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, fmt.Errorf("ai: load migrations: %w", err)
+	}
+	if upToVersion == 0 {
+		return migrations, nil
+	}
+
+	var selected []migrationFile
+	found := false
+	for _, m := range migrations {
+		v := migrationVersion(m.Name)
+		if v <= upToVersion {
+			selected = append(selected, m)
+		}
+		found = found || v == upToVersion
+	}
+	if !found {
+		return nil, fmt.Errorf("ai: no migration with version %d", upToVersion)
+	}
+	return selected, nil
+}
+
+// DumpMigrations writes the SQL of the migrations up to and including upToVersion (0 for
+// all) to w, in the order Migrate applies them, for review and manual execution. Each
+// migration runs in its own transaction and is preceded by its checksum. The ai_migrations
+// table is created first; after running the script, call MarkApplied with the same version
+// so Migrate and VerifySchema see the migrations as applied.
+func DumpMigrations(w io.Writer, upToVersion int) error {
+	migrations, err := migrationsUpTo(upToVersion)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "-- %d migrations", len(migrations))
+	if len(migrations) > 0 {
+		fmt.Fprintf(&b, ", %s to %s", migrations[0].Name, migrations[len(migrations)-1].Name)
+	}
+	b.WriteString("\n-- Record them afterwards with PGStore.MarkApplied.\n")
+	fmt.Fprintf(&b, "%s\n", createMigrationsTableSQL)
+
+	for _, m := range migrations {
+		fmt.Fprintf(&b, "\n-- Migration: %s\n-- Checksum: %s\nBEGIN;\n", m.Name, m.Checksum)
+		b.WriteString(strings.TrimRight(m.Up, "\n"))
+		b.WriteString("\nCOMMIT;\n")
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("ai: dump migrations: %w", err)
+	}
+	return nil
+}
+
+// MarkApplied records the migrations up to and including upToVersion (0 for all) as
+// applied without running them, for schemas migrated by hand from DumpMigrations.
+// Migrations already recorded are checked against their checksum. The ai_migrations
+// table is only created if it doesn't exist.
+func (s *PGStore) MarkApplied(ctx context.Context, upToVersion int) error {
+	migrations, err := migrationsUpTo(upToVersion)
+	if err != nil {
+		return err
+	}
+
+	var exists bool
+	if err := s.db.QueryRow(ctx, `SELECT to_regclass('ai_migrations') IS NOT NULL`).Scan(&exists); err != nil {
+		return fmt.Errorf("ai: mark applied: %w", err)
+	}
+	if !exists {
+		if err := s.ensureMigrationsTable(ctx); err != nil {
+			return fmt.Errorf("ai: ensure migrations table: %w", err)
+		}
+	}
+
+	applied, err := s.appliedMigrations(ctx)
+	if err != nil {
+		return fmt.Errorf("ai: get applied migrations: %w", err)
+	}
+
+	for _, m := range migrations {
+		if rec, ok := applied[m.Name]; ok {
+			if rec.Checksum != m.Checksum {
+				return fmt.Errorf("ai: migration %s checksum mismatch (expected %s, got %s)", m.Name, rec.Checksum, m.Checksum)
+			}
+			continue
+		}
+		if _, err := s.db.Exec(ctx, `INSERT INTO ai_migrations (name, checksum) VALUES ($1, $2)`, m.Name, m.Checksum); err != nil {
+			return fmt.Errorf("ai: record migration %s: %w", m.Name, err)
+		}
+	}
+
+	return nil
+}
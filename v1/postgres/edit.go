@@ -0,0 +1,118 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// EditMessage replaces the content of a message. In one transaction, the previous
+// content is copied to ai_message_edits (with its blob reference and hash, if
+// offloaded), the message is updated and, when auditing is enabled, the edit is audited.
+func (s *PGStore) EditMessage(ctx context.Context, messageID string, content string) (*ai.Message, error) {
+	inline, ref, sum, err := s.offload(ctx, content)
+	if err != nil {
+		return nil, fmt.Errorf("ai: edit message: %w", err)
+	}
+
+	msg := &ai.Message{ID: messageID, Content: content}
+	editedBy, requestID := ai.ActorFromContext(ctx).UserID, ai.RequestIDFromContext(ctx)
+
+	err = s.retry(ctx, false, func() error {
+		return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			now := s.now()
+
+			// Lock the message so concurrent edits each record the content they replace
+			err := tx.QueryRow(ctx,
+				`INSERT INTO ai_message_edits (id, message_id, session_id, seq, content, content_ref, content_sha256, edited_by, request_id, created_at)
+				 SELECT $1, id, session_id, seq, content, content_ref, content_sha256, $3, $4, $5
+				 FROM ai_messages WHERE id = $2
+				 FOR UPDATE
+				 RETURNING session_id`,
+				s.newID(), messageID, editedBy, requestID, now,
+			).Scan(&msg.SessionID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ai.ErrMessageNotFound
+			}
+			if err != nil {
+				return err
+			}
+
+			var u ai.Usage
+			err = tx.QueryRow(ctx,
+				`UPDATE ai_messages
+				 SET content = $2, content_ref = $3, content_sha256 = $4, updated_at = $5
+				 WHERE id = $1
+				 RETURNING seq, role, event_type, request_id,
+				           prompt_tokens, response_tokens, total_tokens, thought_tokens, cached_tokens, tool_tokens, audio_tokens,
+				           model, cost, created_at, updated_at`,
+				messageID, inline, ref, sum, now,
+			).Scan(&msg.Seq, &msg.Role, &msg.EventType, &msg.RequestID,
+				&u.PromptTokens, &u.ResponseTokens, &u.TotalTokens, &u.ThoughtTokens, &u.CachedTokens, &u.ToolTokens, &u.AudioTokens,
+				&u.Model, &msg.Cost, &msg.CreatedAt, &msg.UpdatedAt)
+			if err != nil {
+				return err
+			}
+			if u != (ai.Usage{}) {
+				msg.Usage = &u
+			}
+
+			if !s.audit {
+				return nil
+			}
+			return s.recordAudit(ctx, tx, ai.AuditEditMessage, msg.SessionID, messageID)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: edit message: %w", storeError(err))
+	}
+
+	return msg, nil
+}
+
+// ListMessageEdits returns the previous contents of a message, oldest first, reading
+// offloaded ones back from the blob store. Edits are deleted with their message.
+func (s *PGStore) ListMessageEdits(ctx context.Context, messageID string) ([]ai.MessageEdit, error) {
+	var edits []ai.MessageEdit
+	var refs []string
+	err := s.retry(ctx, true, func() error {
+		edits, refs = nil, nil
+		rows, err := s.db.Query(ctx,
+			`SELECT id, message_id, session_id, seq, content, content_ref, edited_by, request_id, created_at
+			 FROM ai_message_edits WHERE message_id = $1
+			 ORDER BY created_at ASC, id ASC`,
+			messageID,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var e ai.MessageEdit
+			var ref string
+			if err := rows.Scan(&e.ID, &e.MessageID, &e.SessionID, &e.Seq, &e.Content, &ref, &e.EditedBy, &e.RequestID, &e.EditedAt); err != nil {
+				return fmt.Errorf("scan message edit: %w", err)
+			}
+			edits = append(edits, e)
+			refs = append(refs, ref)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: list message edits: %w", storeError(err))
+	}
+
+	for i, e := range edits {
+		if edits[i].Content, err = s.load(ctx, e.Content, refs[i]); err != nil {
+			return nil, fmt.Errorf("ai: list message edits: %s: %w", e.ID, err)
+		}
+	}
+	return edits, nil
+}
+
+// Ensure PGStore implements ai.MessageEditor at compile time.
+var _ ai.MessageEditor = (*PGStore)(nil)
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// messageSeqConstraint is the name Postgres gives UNIQUE(session_id, seq) on ai_messages.
+const messageSeqConstraint = "ai_messages_session_id_seq_key"
+
+// storeError adds ai.ErrStoreUnavailable or ai.ErrDuplicateSeq to the chain of err when
+// it is a connection failure or a seq conflict, keeping the pgx error for callers that
+// need it. Other errors, including context cancellation, are returned unchanged.
+func storeError(err error) error {
+	switch {
+	case err == nil, errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err
+	case isDuplicateSeq(err):
+		return fmt.Errorf("%w: %w", ai.ErrDuplicateSeq, err)
+	case isUnavailable(err):
+		return fmt.Errorf("%w: %w", ai.ErrStoreUnavailable, err)
+	}
+	return err
+}
+
+func isDuplicateSeq(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505" && pgErr.ConstraintName == messageSeqConstraint
+}
+
+func isUnavailable(err error) bool {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		// Class 08 is connection exceptions; 57P01-57P03 are shutdowns and 53300 is
+		// too_many_connections.
+		return strings.HasPrefix(pgErr.Code, "08") || strings.HasPrefix(pgErr.Code, "57P") || pgErr.Code == "53300"
+	}
+
+	var connectErr *pgconn.ConnectError
+	var netErr net.Error
+	return errors.As(err, &connectErr) || errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,77 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SetGlossaryTerm creates or replaces a term in ai_glossary.
+func (s *PGStore) SetGlossaryTerm(ctx context.Context, t ai.GlossaryTerm) (*ai.GlossaryTerm, error) {
+	aliases := t.Aliases
+	if aliases == nil {
+		aliases = []string{}
+	}
+
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`INSERT INTO ai_glossary (tenant_id, term, definition, aliases, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $5)
+			 ON CONFLICT (tenant_id, term) DO UPDATE SET definition = EXCLUDED.definition, aliases = EXCLUDED.aliases, updated_at = EXCLUDED.updated_at
+			 RETURNING updated_at`,
+			t.TenantID, t.Term, t.Definition, aliases, s.now(),
+		).Scan(&t.UpdatedAt)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: set glossary term: %w", storeError(err))
+	}
+	return &t, nil
+}
+
+// ListGlossaryTerms returns the terms of exactly tenantID, ordered by term.
+func (s *PGStore) ListGlossaryTerms(ctx context.Context, tenantID string) ([]ai.GlossaryTerm, error) {
+	var terms []ai.GlossaryTerm
+	err := s.retry(ctx, true, func() error {
+		terms = nil
+		rows, err := s.db.Query(ctx,
+			`SELECT term, definition, aliases, updated_at FROM ai_glossary WHERE tenant_id = $1 ORDER BY term`,
+			tenantID,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			t := ai.GlossaryTerm{TenantID: tenantID}
+			if err := rows.Scan(&t.Term, &t.Definition, &t.Aliases, &t.UpdatedAt); err != nil {
+				return fmt.Errorf("scan glossary term: %w", err)
+			}
+			if len(t.Aliases) == 0 {
+				t.Aliases = nil
+			}
+			terms = append(terms, t)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: list glossary terms: %w", storeError(err))
+	}
+	return terms, nil
+}
+
+// DeleteGlossaryTerm removes a term. Deleting a missing term is not an error.
+func (s *PGStore) DeleteGlossaryTerm(ctx context.Context, tenantID string, term string) error {
+	err := s.retry(ctx, true, func() error {
+		_, err := s.db.Exec(ctx, `DELETE FROM ai_glossary WHERE tenant_id = $1 AND term = $2`, tenantID, term)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: delete glossary term: %w", storeError(err))
+	}
+	return nil
+}
+
+// Ensure PGStore implements ai.GlossaryStore at compile time.
+var _ ai.GlossaryStore = (*PGStore)(nil)
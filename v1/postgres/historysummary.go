@@ -0,0 +1,59 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// AddHistorySummary stores a history summary, replacing one with the same session and
+// ThroughSeq.
+func (s *PGStore) AddHistorySummary(ctx context.Context, sum ai.HistorySummary) error {
+	if sum.CreatedAt.IsZero() {
+		sum.CreatedAt = s.now()
+	}
+	err := s.retry(ctx, true, func() error {
+		_, err := s.db.Exec(ctx,
+			`INSERT INTO ai_history_summaries (session_id, through_seq, turns, content, created_at)
+			 VALUES ($1, $2, $3, $4, $5)
+			 ON CONFLICT (session_id, through_seq) DO UPDATE
+			 SET turns = EXCLUDED.turns,
+			     content = EXCLUDED.content,
+			     created_at = EXCLUDED.created_at`,
+			sum.SessionID, sum.ThroughSeq, sum.Turns, sum.Content, sum.CreatedAt,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: add history summary: %w", storeError(err))
+	}
+	return nil
+}
+
+// LatestHistorySummary returns the session's summary with the highest ThroughSeq.
+// Returns ai.ErrHistorySummaryNotFound if it has none.
+func (s *PGStore) LatestHistorySummary(ctx context.Context, sessionID string) (*ai.HistorySummary, error) {
+	sum := &ai.HistorySummary{SessionID: sessionID}
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`SELECT through_seq, turns, content, created_at
+			 FROM ai_history_summaries WHERE session_id = $1
+			 ORDER BY through_seq DESC
+			 LIMIT 1`,
+			sessionID,
+		).Scan(&sum.ThroughSeq, &sum.Turns, &sum.Content, &sum.CreatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrHistorySummaryNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: latest history summary: %w", storeError(err))
+	}
+	return sum, nil
+}
+
+// Ensure PGStore implements ai.HistorySummaryStore at compile time.
+var _ ai.HistorySummaryStore = (*PGStore)(nil)
@@ -0,0 +1,157 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// defaultImportBatch is the number of messages ImportSessions commits at a time.
+const defaultImportBatch = 50000
+
+var (
+	importSessionColumns = []string{"id", "system_prompt", "output_schema", "max_tokens", "policy", "language", "prompt", "schema", "labels", "response_format", "created_at"}
+	importMessageColumns = []string{"id", "session_id", "seq", "role", "content", "event_type", "request_id",
+		"prompt_tokens", "response_tokens", "total_tokens", "thought_tokens", "cached_tokens", "tool_tokens", "audio_tokens",
+		"model", "cost", "created_at", "content_sha256", "updated_at"}
+)
+
+// ImportOptions configures ImportSessions.
+type ImportOptions struct {
+	// BatchSize is the approximate number of messages per transaction; a session is
+	// never split across batches. Defaults to 50,000.
+	BatchSize int
+
+	// OnProgress is called after each committed batch with the running totals.
+	OnProgress func(ai.ImportProgress)
+}
+
+// ImportSessions loads sessions from src with COPY, for restoring exports or backfilling
+// from other systems. IDs, seqs and timestamps are kept; empty IDs are generated, zero
+// seqs follow the message order and zero timestamps are set to now. Message costs
+// are kept, or computed from the store's prices when zero. Imports are not audited.
+//
+// Batches are committed as they fill, so on error the returned progress counts what
+// was stored and the import can resume from the next session. A session whose ID
+// already exists fails its batch.
+func (s *PGStore) ImportSessions(ctx context.Context, src ai.SessionSource, opts ImportOptions) (ai.ImportProgress, error) {
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = defaultImportBatch
+	}
+
+	var progress ai.ImportProgress
+	var batch []*ai.SessionExport
+	pending := 0
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := s.copySessions(ctx, batch); err != nil {
+			return fmt.Errorf("ai: import sessions: %w", storeError(err))
+		}
+		progress.Sessions += len(batch)
+		progress.Messages += pending
+		batch, pending = batch[:0], 0
+		if opts.OnProgress != nil {
+			opts.OnProgress(progress)
+		}
+		return nil
+	}
+
+	for {
+		e, err := src.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return progress, err
+		}
+
+		s.normalizeImport(e)
+		batch = append(batch, e)
+		pending += len(e.Messages)
+		if pending >= opts.BatchSize {
+			if err := flush(); err != nil {
+				return progress, err
+			}
+		}
+	}
+
+	return progress, flush()
+}
+
+// normalizeImport fills in the IDs, seqs, timestamps and costs an export may lack.
+func (s *PGStore) normalizeImport(e *ai.SessionExport) {
+	if e.Session.ID == "" {
+		e.Session.ID = s.newID()
+	}
+	if e.Session.CreatedAt.IsZero() {
+		e.Session.CreatedAt = s.now()
+	}
+	for i := range e.Messages {
+		m := &e.Messages[i]
+		m.SessionID = e.Session.ID
+		if m.ID == "" {
+			m.ID = s.newID()
+		}
+		if m.Seq == 0 {
+			m.Seq = i + 1
+		}
+		if m.CreatedAt.IsZero() {
+			m.CreatedAt = e.Session.CreatedAt
+		}
+		if m.Cost == 0 && m.Usage != nil {
+			m.Cost = s.cost(*m.Usage)
+		}
+	}
+}
+
+// copySessions writes one batch of sessions and their messages in a transaction.
+func (s *PGStore) copySessions(ctx context.Context, batch []*ai.SessionExport) error {
+	var msgs []*ai.Message
+	for _, e := range batch {
+		for i := range e.Messages {
+			msgs = append(msgs, &e.Messages[i])
+		}
+	}
+
+	return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+		_, err := tx.CopyFrom(ctx, pgx.Identifier{"ai_sessions"}, importSessionColumns,
+			pgx.CopyFromSlice(len(batch), func(i int) ([]any, error) {
+				sess := batch[i].Session
+				r := sess.Rules
+				return []any{sess.ID, r.SystemPrompt, r.OutputSchema, r.MaxTokens, r.Policy, r.Language, r.Prompt, r.Schema, r.Labels, r.ResponseFormat, sess.CreatedAt}, nil
+			}),
+		)
+		if err != nil {
+			return fmt.Errorf("copy sessions: %w", err)
+		}
+
+		_, err = tx.CopyFrom(ctx, pgx.Identifier{"ai_messages"}, importMessageColumns,
+			pgx.CopyFromSlice(len(msgs), func(i int) ([]any, error) {
+				m := msgs[i]
+				var u ai.Usage
+				if m.Usage != nil {
+					u = *m.Usage
+				}
+				var updatedAt *time.Time // NULL unless the message was edited
+				if m.UpdatedAt.After(m.CreatedAt) {
+					updatedAt = &m.UpdatedAt
+				}
+				return []any{m.ID, m.SessionID, m.Seq, m.Role, m.Content, m.EventType, m.RequestID,
+					u.PromptTokens, u.ResponseTokens, u.TotalTokens, u.ThoughtTokens, u.CachedTokens, u.ToolTokens, u.AudioTokens,
+					u.Model, m.Cost, m.CreatedAt, ai.ContentHash(m.Content), updatedAt}, nil
+			}),
+		)
+		if err != nil {
+			return fmt.Errorf("copy messages: %w", err)
+		}
+		return nil
+	})
+}
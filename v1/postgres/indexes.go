@@ -0,0 +1,67 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+)
+
+// scaleIndex is an index built outside migrations, concurrently, by WithScaleIndexes.
+type scaleIndex struct {
+	Name string
+	SQL  string // CREATE INDEX CONCURRENTLY statement
+}
+
+// scaleIndexes are the indexes WithScaleIndexes maintains. BRIN indexes on created_at
+// are tiny and suit these append-only tables, whose rows arrive in time order.
+var scaleIndexes = []scaleIndex{
+	{
+		Name: "idx_ai_messages_created_brin",
+		SQL:  `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_ai_messages_created_brin ON ai_messages USING BRIN (created_at)`,
+	},
+	{
+		Name: "idx_ai_request_logs_created_brin",
+		SQL:  `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_ai_request_logs_created_brin ON ai_request_logs USING BRIN (created_at)`,
+	},
+	{
+		// Serves label containment filters, e.g. Analytics' WhereLabel.
+		Name: "idx_ai_request_logs_labels_gin",
+		SQL:  `CREATE INDEX CONCURRENTLY IF NOT EXISTS idx_ai_request_logs_labels_gin ON ai_request_logs USING GIN (labels jsonb_path_ops)`,
+	},
+}
+
+// WithScaleIndexes makes CreateSchema and Migrate also build indexes for large tables,
+// such as a BRIN index on ai_messages(created_at) for time-range scans and retention.
+// They are built with CREATE INDEX CONCURRENTLY after the migrations, so writes
+// continue while they build; migrations can't do that because they run in a transaction.
+func (s *PGStore) WithScaleIndexes() *PGStore {
+	s.scaleIndexes = true
+	return s
+}
+
+// ensureScaleIndexes builds any missing scale index. An index left invalid by an
+// interrupted concurrent build is dropped and rebuilt.
+func (s *PGStore) ensureScaleIndexes(ctx context.Context) error {
+	for _, idx := range scaleIndexes {
+		var valid *bool
+		err := s.db.QueryRow(ctx,
+			`SELECT (SELECT indisvalid FROM pg_index WHERE indexrelid = to_regclass($1))`,
+			idx.Name,
+		).Scan(&valid)
+		if err != nil {
+			return fmt.Errorf("ai: check index %s: %w", idx.Name, err)
+		}
+		if valid != nil && *valid {
+			continue
+		}
+
+		if valid != nil {
+			if _, err := s.db.Exec(ctx, `DROP INDEX CONCURRENTLY IF EXISTS `+idx.Name); err != nil {
+				return fmt.Errorf("ai: drop invalid index %s: %w", idx.Name, err)
+			}
+		}
+		if _, err := s.db.Exec(ctx, idx.SQL); err != nil {
+			return fmt.Errorf("ai: create index %s: %w", idx.Name, err)
+		}
+	}
+	return nil
+}
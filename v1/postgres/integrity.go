@@ -0,0 +1,103 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// storedContent is a message content or request-log response as stored.
+type storedContent struct {
+	messageID, requestLogID string
+	seq                     int
+	inline, ref, sum        string
+}
+
+// VerifyIntegrity rehashes the messages and request-log responses of a session,
+// reading offloaded content from the blob store, and reports every row whose content
+// doesn't match the SHA-256 recorded when it was written.
+func (s *PGStore) VerifyIntegrity(ctx context.Context, sessionID string) (*ai.IntegrityReport, error) {
+	var exists bool
+	if err := s.db.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM ai_sessions WHERE id = $1)`, sessionID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("ai: verify integrity: %w", storeError(err))
+	}
+	if !exists {
+		return nil, ai.ErrSessionNotFound
+	}
+
+	contents, err := s.storedContents(ctx, sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("ai: verify integrity: %w", storeError(err))
+	}
+
+	report := &ai.IntegrityReport{SessionID: sessionID}
+	for _, c := range contents {
+		if c.sum == "" {
+			report.Unhashed++
+			continue
+		}
+
+		problem := ai.IntegrityProblem{MessageID: c.messageID, Seq: c.seq, RequestLogID: c.requestLogID}
+		content, err := s.load(ctx, c.inline, c.ref)
+		switch {
+		case errors.Is(err, ai.ErrBlobNotFound):
+			problem.Problem, problem.Detail = ai.IntegrityBlobMissing, c.ref
+		case err != nil:
+			return nil, fmt.Errorf("ai: verify integrity: %w", err)
+		case ai.ContentHash(content) != c.sum:
+			problem.Problem, problem.Detail = ai.IntegrityHashMismatch, "recorded "+c.sum
+		default:
+			report.Verified++
+			continue
+		}
+		report.Problems = append(report.Problems, problem)
+	}
+	return report, nil
+}
+
+// storedContents returns the messages of a session in seq order, then its request logs.
+func (s *PGStore) storedContents(ctx context.Context, sessionID string) ([]storedContent, error) {
+	var out []storedContent
+
+	rows, err := s.db.Query(ctx,
+		`SELECT id, seq, content, content_ref, content_sha256 FROM ai_messages WHERE session_id = $1 ORDER BY seq`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var c storedContent
+		if err := rows.Scan(&c.messageID, &c.seq, &c.inline, &c.ref, &c.sum); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	rows, err = s.db.Query(ctx,
+		`SELECT id, response, response_ref, response_sha256 FROM ai_request_logs WHERE session_id = $1 ORDER BY created_at, id`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var c storedContent
+		if err := rows.Scan(&c.requestLogID, &c.inline, &c.ref, &c.sum); err != nil {
+			return nil, err
+		}
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
+
+// Ensure PGStore implements ai.IntegrityVerifier at compile time.
+var _ ai.IntegrityVerifier = (*PGStore)(nil)
@@ -0,0 +1,50 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// orphanedMessage is the error message of request logs failed as orphaned.
+const orphanedMessage = "ai: request orphaned: no outcome was recorded"
+
+// FailOrphanedRequestLogs marks the request logs pending without an update since
+// before as failed with ai.FailReasonOrphaned. The status index keeps the scan to
+// pending rows.
+func (s *PGStore) FailOrphanedRequestLogs(ctx context.Context, before time.Time) (int64, error) {
+	var n int64
+	err := s.retry(ctx, true, func() error {
+		tag, err := s.db.Exec(ctx, `
+			UPDATE ai_request_logs
+			SET final_status = $1, fail_reason = $2, error_message = $3, updated_at = $4
+			WHERE final_status = $5 AND updated_at < $6
+		`, ai.StatusFailed, ai.FailReasonOrphaned, orphanedMessage, s.now(), ai.StatusPending, before)
+		n = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ai: fail orphaned request logs: %w", storeError(err))
+	}
+	return n, nil
+}
+
+// CountPendingRequestLogs returns how many request logs are pending.
+func (s *PGStore) CountPendingRequestLogs(ctx context.Context) (int64, error) {
+	var n int64
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`SELECT COUNT(*) FROM ai_request_logs WHERE final_status = $1`,
+			ai.StatusPending,
+		).Scan(&n)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ai: count pending request logs: %w", storeError(err))
+	}
+	return n, nil
+}
+
+// Ensure PGStore implements ai.OrphanedRequestLogStore at compile time.
+var _ ai.OrphanedRequestLogStore = (*PGStore)(nil)
@@ -0,0 +1,224 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// LegacyMapping maps an application's own chat tables to sessions and messages, for
+// MigrateLegacy. Names are quoted as identifiers; tables may be schema-qualified, e.g.
+// "app.chat_messages". Optional columns left empty are not read.
+type LegacyMapping struct {
+	MessageTable  string
+	SessionColumn string // the conversation each message belongs to
+	RoleColumn    string
+	ContentColumn string
+
+	IDColumn             string // optional; IDs are generated without it
+	CreatedAtColumn      string // optional
+	OrderColumn          string // optional; messages are ordered by CreatedAtColumn, then IDColumn, without it
+	ModelColumn          string // optional
+	PromptTokensColumn   string // optional
+	ResponseTokensColumn string // optional
+
+	// SessionTable optionally holds the conversations, joined on SessionKeyColumn
+	// ("id" by default) = SessionColumn, for their creation time and system prompt.
+	SessionTable           string
+	SessionKeyColumn       string
+	SessionCreatedAtColumn string
+	SystemPromptColumn     string
+
+	// IDPrefix is prepended to legacy session and message IDs, e.g. "legacy-", in case
+	// they could collide with IDs of this package.
+	IDPrefix string
+
+	// Roles maps legacy roles to message roles, e.g. "bot" to ai.RoleAssistant. A role
+	// not in it fails the migration with ai.ErrUnknownRole. Nil keeps roles unchanged.
+	Roles ai.RoleMap
+}
+
+// MigrateLegacy copies the conversations of legacy chat tables in the store's database
+// into ai_sessions and ai_messages, through ImportSessions: batched by opts, with
+// costs computed from the store's prices. Sessions keep their legacy IDs, with the
+// IDPrefix, and conversations already imported are skipped, so an interrupted
+// migration is resumed by running it again. Conversations without messages aren't
+// migrated.
+//
+// The legacy messages are read in one pass ordered by SessionColumn, which should be
+// indexed. The legacy tables are only read.
+func (s *PGStore) MigrateLegacy(ctx context.Context, m LegacyMapping, opts ImportOptions) (ai.ImportProgress, error) {
+	query, err := m.query()
+	if err != nil {
+		return ai.ImportProgress{}, err
+	}
+	rows, err := s.db.Query(ctx, query, m.IDPrefix)
+	if err != nil {
+		return ai.ImportProgress{}, fmt.Errorf("ai: migrate legacy: %w", storeError(err))
+	}
+	defer rows.Close()
+
+	return s.ImportSessions(ctx, &legacySource{rows: rows, roles: m.Roles}, opts)
+}
+
+// query builds the SELECT reading the legacy messages, grouped by session. $1 is the
+// ID prefix.
+func (m LegacyMapping) query() (string, error) {
+	if m.MessageTable == "" || m.SessionColumn == "" || m.RoleColumn == "" || m.ContentColumn == "" {
+		return "", errors.New("ai: migrate legacy: MessageTable, SessionColumn, RoleColumn and ContentColumn are required")
+	}
+	msg := func(col, fallback, cast string) string {
+		if col == "" {
+			return fallback
+		}
+		return "m." + pgx.Identifier{col}.Sanitize() + cast
+	}
+	sess := func(col, fallback, cast string) string {
+		if col == "" || m.SessionTable == "" {
+			return fallback
+		}
+		return "t." + pgx.Identifier{col}.Sanitize() + cast
+	}
+	session := msg(m.SessionColumn, "", "")
+
+	id := "''"
+	if m.IDColumn != "" {
+		id = "$1 || " + msg(m.IDColumn, "", "::text")
+	}
+	order := []string{session}
+	switch {
+	case m.OrderColumn != "":
+		order = append(order, msg(m.OrderColumn, "", ""))
+	default:
+		if m.CreatedAtColumn != "" {
+			order = append(order, msg(m.CreatedAtColumn, "", ""))
+		}
+		if m.IDColumn != "" {
+			order = append(order, msg(m.IDColumn, "", ""))
+		}
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "SELECT $1 || %s::text, %s, COALESCE(%s, ''), COALESCE(%s, ''), %s, COALESCE(%s, ''), COALESCE(%s, 0), COALESCE(%s, 0), %s, COALESCE(%s, '')",
+		session, id,
+		msg(m.RoleColumn, "", "::text"),
+		msg(m.ContentColumn, "", "::text"),
+		msg(m.CreatedAtColumn, "NULL::timestamptz", "::timestamptz"),
+		msg(m.ModelColumn, "NULL::text", "::text"),
+		msg(m.PromptTokensColumn, "NULL::int", "::int"),
+		msg(m.ResponseTokensColumn, "NULL::int", "::int"),
+		sess(m.SessionCreatedAtColumn, "NULL::timestamptz", "::timestamptz"),
+		sess(m.SystemPromptColumn, "NULL::text", "::text"),
+	)
+	fmt.Fprintf(&b, " FROM %s m", legacyTable(m.MessageTable))
+	if m.SessionTable != "" {
+		key := m.SessionKeyColumn
+		if key == "" {
+			key = "id"
+		}
+		fmt.Fprintf(&b, " LEFT JOIN %s t ON t.%s = %s", legacyTable(m.SessionTable), pgx.Identifier{key}.Sanitize(), session)
+	}
+	fmt.Fprintf(&b, " WHERE NOT EXISTS (SELECT 1 FROM ai_sessions s WHERE s.id = $1 || %s::text)", session)
+	fmt.Fprintf(&b, " ORDER BY %s", strings.Join(order, ", "))
+	return b.String(), nil
+}
+
+// legacyTable quotes a table name that may be schema-qualified.
+func legacyTable(name string) string {
+	return pgx.Identifier(strings.Split(name, ".")).Sanitize()
+}
+
+// legacySource groups the rows of a legacy query, ordered by session, into exports.
+type legacySource struct {
+	rows  pgx.Rows
+	roles ai.RoleMap
+	next  *legacyRow // read ahead, the first message of the next session
+}
+
+type legacyRow struct {
+	sessionID      string
+	msg            ai.Message
+	sessionCreated *time.Time
+	systemPrompt   string
+}
+
+// Next returns the next legacy session.
+func (l *legacySource) Next() (*ai.SessionExport, error) {
+	first := l.next
+	if first == nil {
+		var err error
+		if first, err = l.read(); err != nil {
+			return nil, err
+		}
+	}
+
+	e := &ai.SessionExport{
+		Session:  ai.Session{ID: first.sessionID, Rules: ai.Rules{SystemPrompt: first.systemPrompt}},
+		Messages: []ai.Message{first.msg},
+	}
+	if first.sessionCreated != nil {
+		e.Session.CreatedAt = *first.sessionCreated
+	} else {
+		e.Session.CreatedAt = first.msg.CreatedAt
+	}
+	for {
+		row, err := l.read()
+		if errors.Is(err, io.EOF) {
+			l.next = nil
+			return e, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if row.sessionID != e.Session.ID {
+			l.next = row
+			return e, nil
+		}
+		e.Messages = append(e.Messages, row.msg)
+	}
+}
+
+// read scans the next row, or returns io.EOF after the last.
+func (l *legacySource) read() (*legacyRow, error) {
+	if !l.rows.Next() {
+		if err := l.rows.Err(); err != nil {
+			return nil, fmt.Errorf("ai: migrate legacy: %w", storeError(err))
+		}
+		return nil, io.EOF
+	}
+
+	var r legacyRow
+	var created *time.Time
+	var model string
+	var promptTokens, responseTokens int
+	err := l.rows.Scan(&r.sessionID, &r.msg.ID, &r.msg.Role, &r.msg.Content, &created, &model,
+		&promptTokens, &responseTokens, &r.sessionCreated, &r.systemPrompt)
+	if err != nil {
+		return nil, fmt.Errorf("ai: migrate legacy: scan message: %w", err)
+	}
+	if created != nil {
+		r.msg.CreatedAt = *created
+	}
+	if model != "" || promptTokens > 0 || responseTokens > 0 {
+		r.msg.Usage = &ai.Usage{
+			PromptTokens:   promptTokens,
+			ResponseTokens: responseTokens,
+			TotalTokens:    promptTokens + responseTokens,
+			Model:          model,
+		}
+	}
+	if l.roles != nil {
+		role, ok := l.roles[r.msg.Role]
+		if !ok {
+			return nil, fmt.Errorf("ai: migrate legacy: session %s: %w %q", r.sessionID, ai.ErrUnknownRole, r.msg.Role)
+		}
+		r.msg.Role = role
+	}
+	return &r, nil
+}
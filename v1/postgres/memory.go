@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SetSessionFact stores or replaces a fact for a session.
+func (s *PGStore) SetSessionFact(ctx context.Context, sessionID string, key string, value string) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO ai_session_memory (session_id, key, value, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $4)
+		 ON CONFLICT (session_id, key) DO UPDATE SET value = EXCLUDED.value, updated_at = EXCLUDED.updated_at`,
+		sessionID, key, value, s.now(),
+	)
+	if err != nil {
+		return fmt.Errorf("ai: set session fact: %w", err)
+	}
+	return nil
+}
+
+// ListSessionFacts returns a session's facts ordered by key.
+func (s *PGStore) ListSessionFacts(ctx context.Context, sessionID string) ([]ai.Fact, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT key, value, updated_at FROM ai_session_memory WHERE session_id = $1 ORDER BY key`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list session facts: %w", err)
+	}
+	defer rows.Close()
+
+	var facts []ai.Fact
+	for rows.Next() {
+		var f ai.Fact
+		if err := rows.Scan(&f.Key, &f.Value, &f.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("ai: scan session fact: %w", err)
+		}
+		facts = append(facts, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: list session facts: %w", err)
+	}
+
+	return facts, nil
+}
+
+// DeleteSessionFact removes a fact from a session.
+func (s *PGStore) DeleteSessionFact(ctx context.Context, sessionID string, key string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM ai_session_memory WHERE session_id = $1 AND key = $2`, sessionID, key)
+	if err != nil {
+		return fmt.Errorf("ai: delete session fact: %w", err)
+	}
+	return nil
+}
+
+// Ensure PGStore implements ai.MemoryStore at compile time.
+var _ ai.MemoryStore = (*PGStore)(nil)
+
+// SetUserMemory stores or replaces a memory for a user. A zero ttl never expires.
+func (s *PGStore) SetUserMemory(ctx context.Context, userID string, key string, value string, ttl time.Duration) error {
+	var expiresAt *time.Time
+	if ttl > 0 {
+		t := s.now().Add(ttl)
+		expiresAt = &t
+	}
+
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO ai_user_memory (user_id, key, value, expires_at, created_at, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $5)
+		 ON CONFLICT (user_id, key) DO UPDATE
+		 SET value = EXCLUDED.value, expires_at = EXCLUDED.expires_at, updated_at = EXCLUDED.updated_at`,
+		userID, key, value, expiresAt, s.now(),
+	)
+	if err != nil {
+		return fmt.Errorf("ai: set user memory: %w", err)
+	}
+	return nil
+}
+
+// ListUserMemories returns a user's unexpired memories, most recently updated first.
+func (s *PGStore) ListUserMemories(ctx context.Context, userID string) ([]ai.Fact, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT key, value, updated_at, expires_at FROM ai_user_memory
+		 WHERE user_id = $1 AND (expires_at IS NULL OR expires_at > $2)
+		 ORDER BY updated_at DESC`,
+		userID, s.now(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list user memories: %w", err)
+	}
+	defer rows.Close()
+
+	var facts []ai.Fact
+	for rows.Next() {
+		var f ai.Fact
+		if err := rows.Scan(&f.Key, &f.Value, &f.UpdatedAt, &f.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("ai: scan user memory: %w", err)
+		}
+		facts = append(facts, f)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: list user memories: %w", err)
+	}
+
+	return facts, nil
+}
+
+// DeleteUserMemory removes a memory for a user.
+func (s *PGStore) DeleteUserMemory(ctx context.Context, userID string, key string) error {
+	_, err := s.db.Exec(ctx, `DELETE FROM ai_user_memory WHERE user_id = $1 AND key = $2`, userID, key)
+	if err != nil {
+		return fmt.Errorf("ai: delete user memory: %w", err)
+	}
+	return nil
+}
+
+// ExpireUserMemories deletes expired memories and returns how many were removed.
+func (s *PGStore) ExpireUserMemories(ctx context.Context) (int64, error) {
+	tag, err := s.db.Exec(ctx, `DELETE FROM ai_user_memory WHERE expires_at IS NOT NULL AND expires_at <= $1`, s.now())
+	if err != nil {
+		return 0, fmt.Errorf("ai: expire user memories: %w", err)
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Ensure PGStore implements ai.UserMemoryStore at compile time.
+var _ ai.UserMemoryStore = (*PGStore)(nil)
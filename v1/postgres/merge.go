@@ -0,0 +1,184 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// MergeSessions merges src into dst in one transaction. Both sessions are locked
+// against appends; only the IDs, seqs and creation times of their messages are read to
+// order them, and the copies are made with a single INSERT ... SELECT, as CloneSession
+// does. Copies keep the usage, cost, request ID and creation time of the original.
+// Interleaving renumbers dst's messages and drops its collapsed ranges and history
+// summaries, whose seqs no longer hold; a HistorySummarizer then summarizes anew.
+func (s *PGStore) MergeSessions(ctx context.Context, dst, src string, strategy ai.MergeStrategy) (*ai.SessionMerge, error) {
+	if dst == src {
+		return nil, errors.New("ai: merge sessions: cannot merge a session into itself")
+	}
+	if strategy != ai.MergeAppend && strategy != ai.MergeInterleave {
+		return nil, fmt.Errorf("ai: merge sessions: unknown strategy %q", strategy)
+	}
+
+	var merge *ai.SessionMerge
+	err := s.retry(ctx, false, func() error {
+		return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			// Lock in ID order so concurrent merges of the same pair can't deadlock
+			var locked int
+			err := tx.QueryRow(ctx,
+				`SELECT COUNT(*) FROM (SELECT id FROM ai_sessions WHERE id = ANY($1) ORDER BY id FOR UPDATE) s`,
+				[]string{dst, src},
+			).Scan(&locked)
+			if err != nil {
+				return err
+			}
+			if locked != 2 {
+				return ai.ErrSessionNotFound
+			}
+
+			dstMsgs, err := mergeSelection(ctx, tx, dst)
+			if err != nil {
+				return err
+			}
+			srcMsgs, err := mergeSelection(ctx, tx, src)
+			if err != nil {
+				return err
+			}
+
+			merge = &ai.SessionMerge{SourceSessionID: src, Strategy: strategy, Messages: []ai.MessageOrigin{}, MergedAt: s.now()}
+			// Appending keeps dst's seqs; interleaving numbers both sessions from 1
+			order, base := ai.MergeOrder(dstMsgs, srcMsgs, strategy), 0
+			if strategy == ai.MergeAppend && len(dstMsgs) > 0 {
+				order, base = srcMsgs, dstMsgs[len(dstMsgs)-1].Seq
+			}
+			var renumberIDs, oldIDs, newIDs []string
+			var renumberSeqs, newSeqs []int
+			var roles []string
+			for i, m := range order {
+				seq := base + i + 1
+				if m.SessionID == dst {
+					if m.Seq != seq {
+						renumberIDs, renumberSeqs = append(renumberIDs, m.ID), append(renumberSeqs, seq)
+					}
+					continue
+				}
+				id := s.newID()
+				oldIDs, newIDs, newSeqs = append(oldIDs, m.ID), append(newIDs, id), append(newSeqs, seq)
+				roles = append(roles, m.Role)
+				merge.Messages = append(merge.Messages, ai.MessageOrigin{MessageID: id, SourceMessageID: m.ID, SourceSeq: m.Seq})
+			}
+
+			if len(renumberIDs) > 0 {
+				// Move the renumbered rows out of the way first, as UNIQUE(session_id, seq)
+				// is checked row by row
+				_, err := tx.Exec(ctx,
+					`UPDATE ai_messages SET seq = -seq WHERE id = ANY($1)`,
+					renumberIDs,
+				)
+				if err != nil {
+					return err
+				}
+				_, err = tx.Exec(ctx,
+					`UPDATE ai_messages m SET seq = t.seq
+					 FROM unnest($1::text[], $2::int[]) AS t(id, seq)
+					 WHERE m.id = t.id`,
+					renumberIDs, renumberSeqs,
+				)
+				if err != nil {
+					return err
+				}
+				if _, err := tx.Exec(ctx, `DELETE FROM ai_collapsed_ranges WHERE session_id = $1`, dst); err != nil {
+					return err
+				}
+				if _, err := tx.Exec(ctx, `DELETE FROM ai_history_summaries WHERE session_id = $1`, dst); err != nil {
+					return err
+				}
+			}
+
+			if len(oldIDs) > 0 {
+				_, err = tx.Exec(ctx,
+					`INSERT INTO ai_messages (id, session_id, seq, role, content, event_type, request_id,
+					                          prompt_tokens, response_tokens, total_tokens, thought_tokens, cached_tokens, tool_tokens, audio_tokens,
+					                          model, cost, created_at, updated_at, content_ref, content_sha256)
+					 SELECT t.new_id, $1, t.seq, m.role, m.content, m.event_type, m.request_id,
+					        m.prompt_tokens, m.response_tokens, m.total_tokens, m.thought_tokens, m.cached_tokens, m.tool_tokens, m.audio_tokens,
+					        m.model, m.cost, m.created_at, m.updated_at, m.content_ref, m.content_sha256
+					 FROM unnest($2::text[], $3::text[], $4::int[]) AS t(old_id, new_id, seq)
+					 JOIN ai_messages m ON m.id = t.old_id`,
+					dst, oldIDs, newIDs, newSeqs,
+				)
+				if err != nil {
+					return err
+				}
+			}
+
+			payload, err := json.Marshal(merge)
+			if err != nil {
+				return err
+			}
+			inline, ref, sum, err := s.offload(ctx, string(payload))
+			if err != nil {
+				return err
+			}
+			eventID := s.newID()
+			_, err = tx.Exec(ctx,
+				`INSERT INTO ai_messages (id, session_id, seq, role, content, event_type, request_id, created_at, content_ref, content_sha256)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+				eventID, dst, base+len(order)+1, ai.RoleEvent, inline, ai.EventSessionMerged, ai.RequestIDFromContext(ctx),
+				merge.MergedAt, ref, sum,
+			)
+			if err != nil || !s.audit {
+				return err
+			}
+
+			for i, id := range newIDs {
+				action := ai.AuditAddMessage
+				if roles[i] == ai.RoleEvent {
+					action = ai.AuditAddEvent
+				}
+				if err := s.recordAudit(ctx, tx, action, dst, id); err != nil {
+					return err
+				}
+			}
+			return s.recordAudit(ctx, tx, ai.AuditAddEvent, dst, eventID)
+		})
+	})
+	if errors.Is(err, ai.ErrSessionNotFound) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: merge sessions: %w", storeError(err))
+	}
+
+	return merge, nil
+}
+
+// mergeSelection returns the ID, session ID, seq, role and creation time of every
+// message of sessionID, in seq order.
+func mergeSelection(ctx context.Context, tx pgx.Tx, sessionID string) ([]ai.Message, error) {
+	rows, err := tx.Query(ctx,
+		`SELECT id, seq, role, created_at FROM ai_messages WHERE session_id = $1 ORDER BY seq ASC`,
+		sessionID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var msgs []ai.Message
+	for rows.Next() {
+		m := ai.Message{SessionID: sessionID}
+		if err := rows.Scan(&m.ID, &m.Seq, &m.Role, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("scan message: %w", err)
+		}
+		msgs = append(msgs, m)
+	}
+	return msgs, rows.Err()
+}
+
+// Ensure PGStore implements ai.SessionMerger at compile time.
+var _ ai.SessionMerger = (*PGStore)(nil)
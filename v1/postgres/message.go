@@ -2,82 +2,148 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/meikuraledutech/ai/v1"
 )
 
 // AddMessage appends a message to a session with auto-incremented seq.
 func (s *PGStore) AddMessage(ctx context.Context, sessionID string, role string, content string, usage *ai.Usage) (*ai.Message, error) {
+	msg, err := s.insertMessage(ctx, ai.AuditAddMessage, sessionID, role, "", content, usage)
+	if err != nil {
+		return nil, fmt.Errorf("ai: add message: %w", storeError(err))
+	}
+	return msg, nil
+}
+
+// AddEvent appends a system event to a session. Events share the message seq so the
+// session is a complete, ordered audit trail, but carry no usage.
+func (s *PGStore) AddEvent(ctx context.Context, sessionID string, eventType string, payload string) (*ai.Message, error) {
+	msg, err := s.insertMessage(ctx, ai.AuditAddEvent, sessionID, ai.RoleEvent, eventType, payload, nil)
+	if err != nil {
+		return nil, fmt.Errorf("ai: add event: %w", storeError(err))
+	}
+	return msg, nil
+}
+
+// insertMessage inserts a row into ai_messages with the next seq for the session,
+// stamped with the request ID attached to ctx, and audits it as action.
+func (s *PGStore) insertMessage(ctx context.Context, action string, sessionID string, role string, eventType string, content string, usage *ai.Usage) (*ai.Message, error) {
 	msg := &ai.Message{
-		ID:        uuid.New().String(),
+		ID:        s.newID(),
 		SessionID: sessionID,
 		Role:      role,
 		Content:   content,
+		EventType: eventType,
+		RequestID: ai.RequestIDFromContext(ctx),
 		Usage:     usage,
 	}
 
-	var promptTokens, responseTokens, totalTokens, thoughtTokens int
+	var u ai.Usage
 	if usage != nil {
-		promptTokens = usage.PromptTokens
-		responseTokens = usage.ResponseTokens
-		totalTokens = usage.TotalTokens
-		thoughtTokens = usage.ThoughtTokens
+		u = *usage
+		msg.Cost = s.cost(u)
+	}
+
+	inline, ref, sum, err := s.offload(ctx, content)
+	if err != nil {
+		return nil, err
 	}
 
-	err := s.db.QueryRow(ctx,
-		`INSERT INTO ai_messages (id, session_id, seq, role, content, prompt_tokens, response_tokens, total_tokens, thought_tokens)
-		 VALUES ($1, $2, COALESCE((SELECT MAX(seq) FROM ai_messages WHERE session_id = $2), 0) + 1, $3, $4, $5, $6, $7, $8)
-		 RETURNING seq, created_at`,
-		msg.ID, sessionID, role, content, promptTokens, responseTokens, totalTokens, thoughtTokens,
-	).Scan(&msg.Seq, &msg.CreatedAt)
+	err = s.mutate(ctx, action, sessionID, func(q querier) (string, error) {
+		// Lock the session so concurrent inserts can't compute the same seq
+		var locked string
+		err := q.QueryRow(ctx, `SELECT id FROM ai_sessions WHERE id = $1 FOR UPDATE`, sessionID).Scan(&locked)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return "", ai.ErrSessionNotFound
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return msg.ID, q.QueryRow(ctx,
+			`INSERT INTO ai_messages (id, session_id, seq, role, content, event_type, request_id,
+			                          prompt_tokens, response_tokens, total_tokens, thought_tokens, cached_tokens, tool_tokens, audio_tokens,
+			                          model, cost, created_at, content_ref, content_sha256)
+			 VALUES ($1, $2, COALESCE((SELECT MAX(seq) FROM ai_messages WHERE session_id = $2), 0) + 1, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)
+			 RETURNING seq, created_at`,
+			msg.ID, sessionID, role, inline, eventType, msg.RequestID,
+			u.PromptTokens, u.ResponseTokens, u.TotalTokens, u.ThoughtTokens, u.CachedTokens, u.ToolTokens, u.AudioTokens,
+			u.Model, msg.Cost, s.now(), ref, sum,
+		).Scan(&msg.Seq, &msg.CreatedAt)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("ai: add message: %w", err)
+		return nil, err
 	}
+	msg.UpdatedAt = msg.CreatedAt
 
 	return msg, nil
 }
 
 // ListMessages returns all messages for a session ordered by seq.
 func (s *PGStore) ListMessages(ctx context.Context, sessionID string) ([]ai.Message, error) {
+	var messages []ai.Message
+	var refs []string
+	err := s.retry(ctx, true, func() error {
+		var err error
+		messages, refs, err = s.listMessages(ctx, sessionID)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: list messages: %w", storeError(err))
+	}
+	for i, m := range messages {
+		if messages[i].Content, err = s.load(ctx, m.Content, refs[i]); err != nil {
+			return nil, fmt.Errorf("ai: list messages: seq %d: %w", m.Seq, err)
+		}
+	}
+	return messages, nil
+}
+
+// listMessages returns the messages of a session with the blob reference of each,
+// empty for content stored inline.
+func (s *PGStore) listMessages(ctx context.Context, sessionID string) ([]ai.Message, []string, error) {
 	rows, err := s.db.Query(ctx,
-		`SELECT id, session_id, seq, role, content, prompt_tokens, response_tokens, total_tokens, thought_tokens, created_at
+		`SELECT id, session_id, seq, role, content, event_type, request_id,
+		        prompt_tokens, response_tokens, total_tokens, thought_tokens, cached_tokens, tool_tokens, audio_tokens,
+		        model, cost, created_at, COALESCE(updated_at, created_at), content_ref
 		 FROM ai_messages WHERE session_id = $1 ORDER BY seq ASC`,
 		sessionID,
 	)
 	if err != nil {
-		return nil, fmt.Errorf("ai: list messages: %w", err)
+		return nil, nil, err
 	}
 	defer rows.Close()
 
 	var messages []ai.Message
+	var refs []string
 	for rows.Next() {
 		var msg ai.Message
-		var pt, rt, tt, tht int
+		var u ai.Usage
+		var ref string
 
-		err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Seq, &msg.Role, &msg.Content, &pt, &rt, &tt, &tht, &msg.CreatedAt)
+		err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Seq, &msg.Role, &msg.Content, &msg.EventType, &msg.RequestID,
+			&u.PromptTokens, &u.ResponseTokens, &u.TotalTokens, &u.ThoughtTokens, &u.CachedTokens, &u.ToolTokens, &u.AudioTokens,
+			&u.Model, &msg.Cost, &msg.CreatedAt, &msg.UpdatedAt, &ref)
 		if err != nil {
-			return nil, fmt.Errorf("ai: scan message: %w", err)
+			return nil, nil, fmt.Errorf("scan message: %w", err)
 		}
 
-		if pt > 0 || rt > 0 || tt > 0 || tht > 0 {
-			msg.Usage = &ai.Usage{
-				PromptTokens:   pt,
-				ResponseTokens: rt,
-				TotalTokens:    tt,
-				ThoughtTokens:  tht,
-			}
+		if u != (ai.Usage{}) {
+			msg.Usage = &u
 		}
 
 		messages = append(messages, msg)
+		refs = append(refs, ref)
 	}
 
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("ai: list messages: %w", err)
+		return nil, nil, err
 	}
 
-	return messages, nil
+	return messages, refs, nil
 }
 
 // Ensure PGStore implements ai.Store at compile time.
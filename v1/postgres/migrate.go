@@ -158,6 +158,9 @@ func (s *PGStore) Migrate(ctx context.Context) error {
 		}
 	}
 
+	if s.scaleIndexes {
+		return s.ensureScaleIndexes(ctx)
+	}
 	return nil
 }
 
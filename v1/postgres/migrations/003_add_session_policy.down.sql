@@ -0,0 +1 @@
+ALTER TABLE ai_sessions DROP COLUMN IF EXISTS policy;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS ai_key_usage (
+    key_id     TEXT NOT NULL,
+    day        DATE NOT NULL,
+    tokens     BIGINT NOT NULL DEFAULT 0,
+    requests   BIGINT NOT NULL DEFAULT 0,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (key_id, day)
+);
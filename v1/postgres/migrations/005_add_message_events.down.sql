@@ -0,0 +1 @@
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS event_type;
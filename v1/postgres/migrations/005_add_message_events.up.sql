@@ -0,0 +1 @@
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS event_type TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS ai_session_memory (
+    session_id TEXT NOT NULL REFERENCES ai_sessions(id) ON DELETE CASCADE,
+    key        TEXT NOT NULL,
+    value      TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (session_id, key)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ai_user_memory CASCADE;
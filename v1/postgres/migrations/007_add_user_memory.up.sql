@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS ai_user_memory (
+    user_id    TEXT NOT NULL,
+    key        TEXT NOT NULL,
+    value      TEXT NOT NULL,
+    expires_at TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_user_memory_expires ON ai_user_memory(expires_at) WHERE expires_at IS NOT NULL;
@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS ai_stream_checkpoints (
+    id             TEXT PRIMARY KEY,
+    session_id     TEXT NOT NULL REFERENCES ai_sessions(id) ON DELETE CASCADE,
+    request_log_id TEXT NOT NULL DEFAULT '',
+    prompt         TEXT NOT NULL,
+    content        TEXT NOT NULL DEFAULT '',
+    complete       BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_stream_checkpoints_incomplete ON ai_stream_checkpoints(session_id, updated_at) WHERE NOT complete;
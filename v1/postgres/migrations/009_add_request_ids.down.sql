@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_ai_messages_request_id;
+DROP INDEX IF EXISTS idx_ai_request_logs_request_id;
+
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS request_id;
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS request_id;
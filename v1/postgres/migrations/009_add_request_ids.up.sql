@@ -0,0 +1,5 @@
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS request_id TEXT NOT NULL DEFAULT '';
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS request_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_ai_request_logs_request_id ON ai_request_logs(request_id) WHERE request_id <> '';
+CREATE INDEX IF NOT EXISTS idx_ai_messages_request_id ON ai_messages(request_id) WHERE request_id <> '';
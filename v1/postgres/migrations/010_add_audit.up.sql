@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS ai_audit (
+    id         TEXT PRIMARY KEY,
+    actor      TEXT NOT NULL DEFAULT '',
+    action     TEXT NOT NULL,
+    session_id TEXT NOT NULL,
+    target_id  TEXT NOT NULL DEFAULT '',
+    request_id TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_audit_session ON ai_audit(session_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_ai_audit_actor   ON ai_audit(actor, created_at);
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS ai_request_payloads (
+    id             TEXT PRIMARY KEY,
+    request_log_id TEXT NOT NULL REFERENCES ai_request_logs(id) ON DELETE CASCADE,
+    attempt        INT NOT NULL DEFAULT 1,
+    status_code    INT NOT NULL DEFAULT 0,
+    request        BYTEA,
+    response       BYTEA,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_request_payloads_log ON ai_request_payloads(request_log_id);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_ai_request_logs_replay_of;
+
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS replay_of;
@@ -0,0 +1,3 @@
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS replay_of TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_ai_request_logs_replay_of ON ai_request_logs(replay_of) WHERE replay_of <> '';
@@ -0,0 +1,7 @@
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS audio_tokens;
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS tool_tokens;
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS cached_tokens;
+
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS audio_tokens;
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS tool_tokens;
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS cached_tokens;
@@ -0,0 +1,7 @@
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS cached_tokens INT NOT NULL DEFAULT 0;
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS tool_tokens   INT NOT NULL DEFAULT 0;
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS audio_tokens  INT NOT NULL DEFAULT 0;
+
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS cached_tokens INT NOT NULL DEFAULT 0;
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS tool_tokens   INT NOT NULL DEFAULT 0;
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS audio_tokens  INT NOT NULL DEFAULT 0;
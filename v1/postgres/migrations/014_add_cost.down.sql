@@ -0,0 +1,5 @@
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS cost;
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS model;
+
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS cost;
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS model;
@@ -0,0 +1,5 @@
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS model TEXT NOT NULL DEFAULT '';
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS cost  NUMERIC(14, 8) NOT NULL DEFAULT 0;
+
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS model TEXT NOT NULL DEFAULT '';
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS cost  NUMERIC(14, 8) NOT NULL DEFAULT 0;
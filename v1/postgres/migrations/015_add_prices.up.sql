@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS ai_prices (
+    model                    TEXT PRIMARY KEY,
+    input_per_million        DOUBLE PRECISION NOT NULL DEFAULT 0,
+    output_per_million       DOUBLE PRECISION NOT NULL DEFAULT 0,
+    thought_per_million      DOUBLE PRECISION NOT NULL DEFAULT 0,
+    cached_input_per_million DOUBLE PRECISION NOT NULL DEFAULT 0,
+    updated_at               TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
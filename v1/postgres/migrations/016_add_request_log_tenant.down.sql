@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_ai_request_logs_created;
+DROP INDEX IF EXISTS idx_ai_request_logs_tenant_created;
+
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS tenant_id;
@@ -0,0 +1,4 @@
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS tenant_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_ai_request_logs_tenant_created ON ai_request_logs(tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS idx_ai_request_logs_created ON ai_request_logs(created_at);
@@ -0,0 +1 @@
+ALTER TABLE ai_sessions ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS prompt_version;
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS prompt_name;
+
+ALTER TABLE ai_sessions DROP COLUMN IF EXISTS prompt;
+
+DROP TABLE IF EXISTS ai_prompts CASCADE;
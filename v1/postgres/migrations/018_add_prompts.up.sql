@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS ai_prompts (
+    name       TEXT NOT NULL,
+    tenant_id  TEXT NOT NULL DEFAULT '',
+    version    INT NOT NULL,
+    template   TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (name, tenant_id, version)
+);
+
+ALTER TABLE ai_sessions ADD COLUMN IF NOT EXISTS prompt JSONB;
+
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS prompt_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS prompt_version INT NOT NULL DEFAULT 0;
@@ -0,0 +1 @@
+ALTER TABLE ai_sessions ADD COLUMN IF NOT EXISTS response_format TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ai_collapsed_ranges CASCADE;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS ai_collapsed_ranges (
+    session_id TEXT NOT NULL REFERENCES ai_sessions(id) ON DELETE CASCADE,
+    from_seq   INT NOT NULL,
+    to_seq     INT NOT NULL,
+    count      INT NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (session_id, from_seq)
+);
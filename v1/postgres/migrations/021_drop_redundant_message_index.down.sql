@@ -0,0 +1 @@
+CREATE INDEX IF NOT EXISTS idx_ai_messages_session ON ai_messages(session_id);
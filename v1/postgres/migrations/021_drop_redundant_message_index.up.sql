@@ -0,0 +1,3 @@
+-- UNIQUE(session_id, seq) already indexes session lookups, and serves ListMessages'
+-- ORDER BY seq; the single-column index only added write cost.
+DROP INDEX IF EXISTS idx_ai_messages_session;
@@ -0,0 +1,3 @@
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS new_prompt_tokens;
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS history_tokens;
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS system_prompt_tokens;
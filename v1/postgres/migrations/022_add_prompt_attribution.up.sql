@@ -0,0 +1,3 @@
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS system_prompt_tokens INT NOT NULL DEFAULT 0;
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS history_tokens       INT NOT NULL DEFAULT 0;
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS new_prompt_tokens    INT NOT NULL DEFAULT 0;
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_ai_request_logs_user_created;
+
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS user_id;
@@ -0,0 +1,3 @@
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS user_id TEXT NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_ai_request_logs_user_created ON ai_request_logs(user_id, created_at);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS ai_watermarks (
+    name       TEXT PRIMARY KEY,
+    watermark  TIMESTAMPTZ NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
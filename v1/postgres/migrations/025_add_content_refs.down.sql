@@ -0,0 +1,5 @@
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS response_sha256;
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS response_ref;
+
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS content_sha256;
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS content_ref;
@@ -0,0 +1,5 @@
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS content_ref TEXT NOT NULL DEFAULT '';
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS content_sha256 TEXT NOT NULL DEFAULT '';
+
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS response_ref TEXT NOT NULL DEFAULT '';
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS response_sha256 TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS ai_session_presets (
+    name       TEXT PRIMARY KEY,
+    rules      JSONB NOT NULL,
+    messages   JSONB NOT NULL DEFAULT '[]',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
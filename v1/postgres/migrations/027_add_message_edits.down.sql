@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS ai_message_edits;
+
+ALTER TABLE ai_messages DROP COLUMN IF EXISTS updated_at;
@@ -0,0 +1,16 @@
+ALTER TABLE ai_messages ADD COLUMN IF NOT EXISTS updated_at TIMESTAMPTZ;
+
+CREATE TABLE IF NOT EXISTS ai_message_edits (
+    id             TEXT PRIMARY KEY,
+    message_id     TEXT NOT NULL REFERENCES ai_messages(id) ON DELETE CASCADE,
+    session_id     TEXT NOT NULL,
+    seq            INT NOT NULL,
+    content        TEXT NOT NULL,
+    content_ref    TEXT NOT NULL DEFAULT '',
+    content_sha256 TEXT NOT NULL DEFAULT '',
+    edited_by      TEXT NOT NULL DEFAULT '',
+    request_id     TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_message_edits_message ON ai_message_edits(message_id, created_at);
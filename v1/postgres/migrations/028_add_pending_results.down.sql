@@ -0,0 +1 @@
+DROP TABLE IF EXISTS ai_pending_results;
@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS ai_pending_results (
+    id             TEXT PRIMARY KEY,
+    session_id     TEXT NOT NULL REFERENCES ai_sessions(id) ON DELETE CASCADE,
+    request_id     TEXT NOT NULL DEFAULT '',
+    prompt         TEXT NOT NULL DEFAULT '',
+    content        TEXT NOT NULL,
+    content_ref    TEXT NOT NULL DEFAULT '',
+    content_sha256 TEXT NOT NULL DEFAULT '',
+    usage          JSONB NOT NULL DEFAULT '{}',
+    status         TEXT NOT NULL DEFAULT 'pending_approval',
+    reviewer       TEXT NOT NULL DEFAULT '',
+    reason         TEXT NOT NULL DEFAULT '',
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    decided_at     TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_pending_results_session ON ai_pending_results(session_id, status, created_at);
@@ -0,0 +1 @@
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS system_addendum TEXT NOT NULL DEFAULT '';
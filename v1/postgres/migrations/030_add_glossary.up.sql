@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS ai_glossary (
+    tenant_id  TEXT NOT NULL DEFAULT '',
+    term       TEXT NOT NULL,
+    definition TEXT NOT NULL,
+    aliases    TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (tenant_id, term)
+);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS ai_samples (
+    id             TEXT PRIMARY KEY,
+    tenant_id      TEXT NOT NULL DEFAULT '',
+    prompt_name    TEXT NOT NULL DEFAULT '',
+    prompt_version INT NOT NULL DEFAULT 0,
+    model          TEXT NOT NULL DEFAULT '',
+    system_prompt  TEXT NOT NULL DEFAULT '',
+    prompt         TEXT NOT NULL,
+    response       TEXT NOT NULL,
+    created_at     TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_samples_created ON ai_samples(created_at);
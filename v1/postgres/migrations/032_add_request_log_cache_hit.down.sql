@@ -0,0 +1,2 @@
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS cache_similarity;
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS cache_hit_of;
@@ -0,0 +1,2 @@
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS cache_hit_of TEXT NOT NULL DEFAULT '';
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS cache_similarity DOUBLE PRECISION NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS ai_session_personas;
+DROP TABLE IF EXISTS ai_personas;
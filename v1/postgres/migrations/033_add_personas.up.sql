@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS ai_personas (
+    name        TEXT PRIMARY KEY,
+    style       TEXT NOT NULL DEFAULT '',
+    constraints TEXT[] NOT NULL DEFAULT '{}',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS ai_session_personas (
+    session_id TEXT PRIMARY KEY REFERENCES ai_sessions(id) ON DELETE CASCADE,
+    persona    TEXT NOT NULL REFERENCES ai_personas(name) ON DELETE CASCADE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
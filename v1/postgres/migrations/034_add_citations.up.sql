@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS ai_citations (
+    message_id  TEXT NOT NULL REFERENCES ai_messages(id) ON DELETE CASCADE,
+    position    INT NOT NULL,
+    uri         TEXT NOT NULL,
+    title       TEXT NOT NULL DEFAULT '',
+    text        TEXT NOT NULL DEFAULT '',
+    start_index INT NOT NULL DEFAULT 0,
+    end_index   INT NOT NULL DEFAULT 0,
+    license     TEXT NOT NULL DEFAULT '',
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (message_id, position)
+);
@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS ai_message_audio (
+    message_id TEXT PRIMARY KEY REFERENCES ai_messages(id) ON DELETE CASCADE,
+    mime_type  TEXT NOT NULL,
+    size       INT NOT NULL,
+    sha256     TEXT NOT NULL,
+    blob_key   TEXT NOT NULL DEFAULT '',
+    data       BYTEA,
+    voice      TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,5 @@
+CREATE TABLE IF NOT EXISTS ai_session_defaults (
+    session_id TEXT PRIMARY KEY REFERENCES ai_sessions(id) ON DELETE CASCADE,
+    options    JSONB NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1 @@
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS cancelled_after_tokens;
@@ -0,0 +1 @@
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS cancelled_after_tokens INT NOT NULL DEFAULT 0;
@@ -0,0 +1,6 @@
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS schema_version;
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS schema_name;
+
+ALTER TABLE ai_sessions DROP COLUMN IF EXISTS schema;
+
+DROP TABLE IF EXISTS ai_schemas CASCADE;
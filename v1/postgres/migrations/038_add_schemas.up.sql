@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS ai_schemas (
+    name       TEXT NOT NULL,
+    version    INT NOT NULL,
+    definition TEXT NOT NULL,
+    breaking   BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (name, version)
+);
+
+ALTER TABLE ai_sessions ADD COLUMN IF NOT EXISTS schema JSONB;
+
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS schema_name TEXT NOT NULL DEFAULT '';
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS schema_version INT NOT NULL DEFAULT 0;
@@ -0,0 +1,21 @@
+CREATE TABLE IF NOT EXISTS ai_shadow_results (
+    id                 TEXT PRIMARY KEY,
+    session_id         TEXT NOT NULL DEFAULT '',
+    tenant_id          TEXT NOT NULL DEFAULT '',
+    prompt             TEXT NOT NULL DEFAULT '',
+    primary_request_id TEXT NOT NULL DEFAULT '',
+    primary_model      TEXT NOT NULL DEFAULT '',
+    primary_content    TEXT NOT NULL DEFAULT '',
+    primary_usage      JSONB NOT NULL DEFAULT '{}',
+    primary_latency_ms BIGINT NOT NULL DEFAULT 0,
+    shadow_request_id  TEXT NOT NULL DEFAULT '',
+    shadow_model       TEXT NOT NULL DEFAULT '',
+    shadow_content     TEXT NOT NULL DEFAULT '',
+    shadow_usage       JSONB NOT NULL DEFAULT '{}',
+    shadow_latency_ms  BIGINT NOT NULL DEFAULT 0,
+    shadow_error       TEXT NOT NULL DEFAULT '',
+    identical          BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at         TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_ai_shadow_results_created ON ai_shadow_results(created_at);
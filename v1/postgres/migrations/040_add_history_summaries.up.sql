@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS ai_history_summaries (
+    session_id  TEXT NOT NULL REFERENCES ai_sessions(id) ON DELETE CASCADE,
+    through_seq INT NOT NULL,
+    turns       INT NOT NULL DEFAULT 0,
+    content     TEXT NOT NULL,
+    created_at  TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (session_id, through_seq)
+);
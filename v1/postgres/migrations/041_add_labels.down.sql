@@ -0,0 +1,3 @@
+ALTER TABLE ai_request_logs DROP COLUMN IF EXISTS labels;
+
+ALTER TABLE ai_sessions DROP COLUMN IF EXISTS labels;
@@ -0,0 +1,3 @@
+ALTER TABLE ai_sessions ADD COLUMN IF NOT EXISTS labels JSONB;
+
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS labels JSONB;
@@ -0,0 +1 @@
+ALTER TABLE ai_request_logs ADD COLUMN IF NOT EXISTS retry_transcript JSONB NOT NULL DEFAULT '[]';
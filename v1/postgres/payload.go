@@ -0,0 +1,107 @@
+package postgres
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SavePayload stores a captured exchange with the request and response gzip-compressed.
+func (s *PGStore) SavePayload(ctx context.Context, p ai.Payload) error {
+	req, err := compress(p.Request)
+	if err != nil {
+		return fmt.Errorf("ai: save payload: %w", err)
+	}
+	resp, err := compress(p.Response)
+	if err != nil {
+		return fmt.Errorf("ai: save payload: %w", err)
+	}
+
+	if p.ID == "" {
+		p.ID = s.newID()
+	}
+	if p.CreatedAt.IsZero() {
+		p.CreatedAt = s.now()
+	}
+
+	_, err = s.db.Exec(ctx,
+		`INSERT INTO ai_request_payloads (id, request_log_id, attempt, status_code, request, response, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		p.ID, p.RequestLogID, p.Attempt, p.StatusCode, req, resp, p.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("ai: save payload: %w", err)
+	}
+	return nil
+}
+
+// ListPayloads returns the captured exchanges of a request log in attempt order.
+func (s *PGStore) ListPayloads(ctx context.Context, requestLogID string) ([]ai.Payload, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT id, request_log_id, attempt, status_code, request, response, created_at
+		 FROM ai_request_payloads WHERE request_log_id = $1 ORDER BY attempt ASC, created_at ASC`,
+		requestLogID,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list payloads: %w", err)
+	}
+	defer rows.Close()
+
+	var payloads []ai.Payload
+	for rows.Next() {
+		var p ai.Payload
+		var req, resp []byte
+		if err := rows.Scan(&p.ID, &p.RequestLogID, &p.Attempt, &p.StatusCode, &req, &resp, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ai: scan payload: %w", err)
+		}
+		if p.Request, err = decompress(req); err != nil {
+			return nil, fmt.Errorf("ai: decompress payload: %w", err)
+		}
+		if p.Response, err = decompress(resp); err != nil {
+			return nil, fmt.Errorf("ai: decompress payload: %w", err)
+		}
+		payloads = append(payloads, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: list payloads: %w", err)
+	}
+
+	return payloads, nil
+}
+
+// compress gzips b, leaving empty input empty.
+func compress(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(b); err != nil {
+		return nil, err
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decompress reverses compress.
+func decompress(b []byte) ([]byte, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	return io.ReadAll(zr)
+}
+
+// Ensure PGStore implements ai.PayloadStore at compile time.
+var _ ai.PayloadStore = (*PGStore)(nil)
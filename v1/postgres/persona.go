@@ -0,0 +1,154 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SavePersona creates or replaces a persona in ai_personas.
+func (s *PGStore) SavePersona(ctx context.Context, p ai.Persona) (*ai.Persona, error) {
+	constraints := p.Constraints
+	if constraints == nil {
+		constraints = []string{}
+	}
+
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`INSERT INTO ai_personas (name, style, constraints, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, $4)
+			 ON CONFLICT (name) DO UPDATE SET style = EXCLUDED.style, constraints = EXCLUDED.constraints, updated_at = EXCLUDED.updated_at
+			 RETURNING updated_at`,
+			p.Name, p.Style, constraints, s.now(),
+		).Scan(&p.UpdatedAt)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: save persona: %w", storeError(err))
+	}
+	return &p, nil
+}
+
+// GetPersona returns the persona called name, or ai.ErrPersonaNotFound.
+func (s *PGStore) GetPersona(ctx context.Context, name string) (*ai.Persona, error) {
+	p := &ai.Persona{Name: name}
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`SELECT style, constraints, updated_at FROM ai_personas WHERE name = $1`,
+			name,
+		).Scan(&p.Style, &p.Constraints, &p.UpdatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ai.ErrPersonaNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: get persona: %w", storeError(err))
+	}
+	if len(p.Constraints) == 0 {
+		p.Constraints = nil
+	}
+	return p, nil
+}
+
+// ListPersonas returns every persona, ordered by name.
+func (s *PGStore) ListPersonas(ctx context.Context) ([]ai.Persona, error) {
+	var personas []ai.Persona
+	err := s.retry(ctx, true, func() error {
+		personas = nil
+		rows, err := s.db.Query(ctx, `SELECT name, style, constraints, updated_at FROM ai_personas ORDER BY name`)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var p ai.Persona
+			if err := rows.Scan(&p.Name, &p.Style, &p.Constraints, &p.UpdatedAt); err != nil {
+				return fmt.Errorf("scan persona: %w", err)
+			}
+			if len(p.Constraints) == 0 {
+				p.Constraints = nil
+			}
+			personas = append(personas, p)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: list personas: %w", storeError(err))
+	}
+	return personas, nil
+}
+
+// DeletePersona removes a persona and, by cascade, its session assignments.
+func (s *PGStore) DeletePersona(ctx context.Context, name string) error {
+	err := s.retry(ctx, true, func() error {
+		_, err := s.db.Exec(ctx, `DELETE FROM ai_personas WHERE name = $1`, name)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: delete persona: %w", storeError(err))
+	}
+	return nil
+}
+
+// SetSessionPersona assigns a persona to a session in ai_session_personas, or clears
+// the assignment for "".
+func (s *PGStore) SetSessionPersona(ctx context.Context, sessionID string, name string) error {
+	if name == "" {
+		err := s.retry(ctx, true, func() error {
+			_, err := s.db.Exec(ctx, `DELETE FROM ai_session_personas WHERE session_id = $1`, sessionID)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("ai: set session persona: %w", storeError(err))
+		}
+		return nil
+	}
+
+	var n int64
+	err := s.retry(ctx, true, func() error {
+		tag, err := s.db.Exec(ctx,
+			`INSERT INTO ai_session_personas (session_id, persona, updated_at)
+			 SELECT $1, name, $3 FROM ai_personas WHERE name = $2
+			 ON CONFLICT (session_id) DO UPDATE SET persona = EXCLUDED.persona, updated_at = EXCLUDED.updated_at`,
+			sessionID, name, s.now(),
+		)
+		n = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: set session persona: %w", storeError(err))
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %s", ai.ErrPersonaNotFound, name)
+	}
+	return nil
+}
+
+// SessionPersona returns the persona assigned to a session, or nil.
+func (s *PGStore) SessionPersona(ctx context.Context, sessionID string) (*ai.Persona, error) {
+	var p ai.Persona
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`SELECT p.name, p.style, p.constraints, p.updated_at
+			 FROM ai_session_personas sp JOIN ai_personas p ON p.name = sp.persona
+			 WHERE sp.session_id = $1`,
+			sessionID,
+		).Scan(&p.Name, &p.Style, &p.Constraints, &p.UpdatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: session persona: %w", storeError(err))
+	}
+	if len(p.Constraints) == 0 {
+		p.Constraints = nil
+	}
+	return &p, nil
+}
+
+// Ensure PGStore implements ai.PersonaStore at compile time.
+var _ ai.PersonaStore = (*PGStore)(nil)
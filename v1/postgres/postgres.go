@@ -1,15 +1,89 @@
 package postgres
 
 import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/meikuraledutech/ai/v1"
 )
 
 // PGStore implements ai.Store using PostgreSQL via pgx.
 type PGStore struct {
-	db *pgxpool.Pool
+	db     *pgxpool.Pool
+	audit  bool
+	now    ai.Clock
+	newID  ai.IDGenerator
+	prices ai.PriceTable
+
+	scaleIndexes  bool
+	retryPolicy   RetryPolicy
+	blobs         ai.BlobStore
+	blobThreshold int
+	presets       ai.PresetSource
 }
 
 // New creates a new PGStore backed by the given pgx connection pool.
 func New(db *pgxpool.Pool) *PGStore {
-	return &PGStore{db: db}
+	return &PGStore{
+		db:    db,
+		now:   time.Now,
+		newID: func() string { return uuid.New().String() },
+	}
+}
+
+// WithClock sets the clock used for every timestamp the store writes.
+func (s *PGStore) WithClock(clock ai.Clock) *PGStore {
+	s.now = clock
+	return s
+}
+
+// WithPrices computes the cost of every stored message and request log from table,
+// by the model recorded in its usage. Costs are stored, so they survive price changes.
+func (s *PGStore) WithPrices(table ai.PriceTable) *PGStore {
+	s.prices = table
+	return s
+}
+
+// cost returns the price of u, or zero if its model isn't in the price table.
+func (s *PGStore) cost(u ai.Usage) float64 {
+	c, _ := s.prices.Cost(u)
+	return c
+}
+
+// WithIDGenerator sets the generator used for the IDs of new rows.
+func (s *PGStore) WithIDGenerator(gen ai.IDGenerator) *PGStore {
+	s.newID = gen
+	return s
+}
+
+// WithAudit records every session and message mutation in ai_audit, in the same
+// transaction as the mutation. The actor is the user ID of the ai.Actor attached to ctx.
+func (s *PGStore) WithAudit() *PGStore {
+	s.audit = true
+	return s
+}
+
+// querier is the subset of pgxpool.Pool and pgx.Tx used by queries that may run in a transaction.
+type querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// mutate runs fn in a transaction that, when auditing is enabled, also records action.
+// fn returns the ID of the affected row. The transaction is retried as a whole on
+// transient errors (see WithRetry).
+func (s *PGStore) mutate(ctx context.Context, action string, sessionID string, fn func(q querier) (string, error)) error {
+	return s.retry(ctx, false, func() error {
+		return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			targetID, err := fn(tx)
+			if err != nil || !s.audit {
+				return err
+			}
+			return s.recordAudit(ctx, tx, action, sessionID, targetID)
+		})
+	})
 }
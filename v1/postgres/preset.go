@@ -0,0 +1,158 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// WithPresets makes the presets of source, e.g. an ai.PresetRegistry filled in code,
+// available to CreateSessionFromPreset. Presets stored in ai_session_presets take
+// precedence, so a stored copy can override one shipped in code.
+func (s *PGStore) WithPresets(source ai.PresetSource) *PGStore {
+	s.presets = source
+	return s
+}
+
+// SavePreset creates p or replaces the stored preset with its name.
+func (s *PGStore) SavePreset(ctx context.Context, p ai.Preset) (*ai.Preset, error) {
+	messages := p.Messages
+	if messages == nil {
+		messages = []ai.NewMessage{}
+	}
+
+	now := s.now()
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`INSERT INTO ai_session_presets (name, rules, messages, created_at, updated_at)
+			 VALUES ($1, $2, $3, $4, $4)
+			 ON CONFLICT (name) DO UPDATE SET rules = EXCLUDED.rules, messages = EXCLUDED.messages, updated_at = EXCLUDED.updated_at
+			 RETURNING created_at, updated_at`,
+			p.Name, p.Rules, messages, now,
+		).Scan(&p.CreatedAt, &p.UpdatedAt)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: save preset: %w", storeError(err))
+	}
+	return &p, nil
+}
+
+// GetPreset returns the stored preset called name, or ai.ErrPresetNotFound. Presets
+// set with WithPresets are not consulted.
+func (s *PGStore) GetPreset(ctx context.Context, name string) (*ai.Preset, error) {
+	p := &ai.Preset{Name: name}
+
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`SELECT rules, messages, created_at, updated_at FROM ai_session_presets WHERE name = $1`,
+			name,
+		).Scan(&p.Rules, &p.Messages, &p.CreatedAt, &p.UpdatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %s", ai.ErrPresetNotFound, name)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: get preset: %w", storeError(err))
+	}
+	return p, nil
+}
+
+// ListPresets returns every stored preset, ordered by name.
+func (s *PGStore) ListPresets(ctx context.Context) ([]ai.Preset, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT name, rules, messages, created_at, updated_at FROM ai_session_presets ORDER BY name`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list presets: %w", storeError(err))
+	}
+	defer rows.Close()
+
+	var presets []ai.Preset
+	for rows.Next() {
+		var p ai.Preset
+		if err := rows.Scan(&p.Name, &p.Rules, &p.Messages, &p.CreatedAt, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("ai: scan preset: %w", err)
+		}
+		presets = append(presets, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: list presets: %w", storeError(err))
+	}
+
+	return presets, nil
+}
+
+// DeletePreset removes a stored preset. Deleting a missing preset is not an error.
+func (s *PGStore) DeletePreset(ctx context.Context, name string) error {
+	err := s.retry(ctx, true, func() error {
+		_, err := s.db.Exec(ctx, `DELETE FROM ai_session_presets WHERE name = $1`, name)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: delete preset: %w", storeError(err))
+	}
+	return nil
+}
+
+// CreateSessionFromPreset creates a session with the rules of the preset called name
+// and appends the preset's opening messages, in one transaction. The preset is looked
+// up in ai_session_presets, then in the source set with WithPresets. Returns an error
+// wrapping ai.ErrPresetNotFound if neither has it.
+func (s *PGStore) CreateSessionFromPreset(ctx context.Context, name string) (*ai.Session, error) {
+	var source ai.PresetSource = s
+	if s.presets != nil {
+		source = ai.ChainPresets(s, s.presets)
+	}
+	p, err := source.GetPreset(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+	if err := ai.CheckRules(nil, p.Rules); err != nil {
+		return nil, fmt.Errorf("ai: create session from preset: %w", err)
+	}
+
+	session := &ai.Session{
+		ID:        s.newID(),
+		Rules:     p.Rules,
+		CreatedAt: s.now(),
+	}
+
+	var batch *messageBatch
+	if len(p.Messages) > 0 {
+		if batch, err = s.prepareMessages(ctx, session.ID, p.Messages); err != nil {
+			return nil, fmt.Errorf("ai: create session from preset: %w", err)
+		}
+	}
+
+	err = s.retry(ctx, false, func() error {
+		return pgx.BeginFunc(ctx, s.db, func(tx pgx.Tx) error {
+			if err := insertSession(ctx, tx, session); err != nil {
+				return err
+			}
+			if s.audit {
+				if err := s.recordAudit(ctx, tx, ai.AuditCreateSession, session.ID, session.ID); err != nil {
+					return err
+				}
+			}
+			if batch == nil {
+				return nil
+			}
+			return s.insertMessages(ctx, tx, batch)
+		})
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: create session from preset: %w", storeError(err))
+	}
+
+	return session, nil
+}
+
+// Ensure PGStore implements ai.PresetStore and ai.SessionPresetCreator at compile time.
+var (
+	_ ai.PresetStore          = (*PGStore)(nil)
+	_ ai.SessionPresetCreator = (*PGStore)(nil)
+)
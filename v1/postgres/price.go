@@ -0,0 +1,65 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SetPrice stores or replaces the price override of a model.
+func (s *PGStore) SetPrice(ctx context.Context, model string, price ai.Price) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO ai_prices (model, input_per_million, output_per_million, thought_per_million, cached_input_per_million, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6)
+		 ON CONFLICT (model) DO UPDATE
+		 SET input_per_million = EXCLUDED.input_per_million,
+		     output_per_million = EXCLUDED.output_per_million,
+		     thought_per_million = EXCLUDED.thought_per_million,
+		     cached_input_per_million = EXCLUDED.cached_input_per_million,
+		     updated_at = EXCLUDED.updated_at`,
+		model, price.InputPerMillion, price.OutputPerMillion, price.ThoughtPerMillion, price.CachedInputPerMillion, s.now(),
+	)
+	if err != nil {
+		return fmt.Errorf("ai: set price: %w", err)
+	}
+	return nil
+}
+
+// DeletePrice removes the price override of a model.
+func (s *PGStore) DeletePrice(ctx context.Context, model string) error {
+	if _, err := s.db.Exec(ctx, `DELETE FROM ai_prices WHERE model = $1`, model); err != nil {
+		return fmt.Errorf("ai: delete price: %w", err)
+	}
+	return nil
+}
+
+// ListPrices returns all stored price overrides.
+func (s *PGStore) ListPrices(ctx context.Context) (ai.PriceTable, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT model, input_per_million, output_per_million, thought_per_million, cached_input_per_million FROM ai_prices`,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list prices: %w", err)
+	}
+	defer rows.Close()
+
+	prices := ai.PriceTable{}
+	for rows.Next() {
+		var model string
+		var p ai.Price
+		if err := rows.Scan(&model, &p.InputPerMillion, &p.OutputPerMillion, &p.ThoughtPerMillion, &p.CachedInputPerMillion); err != nil {
+			return nil, fmt.Errorf("ai: scan price: %w", err)
+		}
+		prices[model] = p
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: list prices: %w", err)
+	}
+
+	return prices, nil
+}
+
+// Ensure PGStore implements ai.PriceStore at compile time.
+var _ ai.PriceStore = (*PGStore)(nil)
@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SavePrompt stores p as the next version of its name and tenant.
+func (s *PGStore) SavePrompt(ctx context.Context, p ai.Prompt) (*ai.Prompt, error) {
+	err := s.db.QueryRow(ctx,
+		`INSERT INTO ai_prompts (name, tenant_id, version, template, created_at)
+		 VALUES ($1, $2, COALESCE((SELECT MAX(version) FROM ai_prompts WHERE name = $1 AND tenant_id = $2), 0) + 1, $3, $4)
+		 RETURNING version, created_at`,
+		p.Name, p.TenantID, p.Template, s.now(),
+	).Scan(&p.Version, &p.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ai: save prompt: %w", err)
+	}
+	return &p, nil
+}
+
+// GetPrompt returns a version of a prompt for tenantID, or the latest when version is 0.
+// Returns ai.ErrPromptNotFound if there is none.
+func (s *PGStore) GetPrompt(ctx context.Context, name string, tenantID string, version int) (*ai.Prompt, error) {
+	p := &ai.Prompt{Name: name, TenantID: tenantID}
+
+	err := s.db.QueryRow(ctx,
+		`SELECT version, template, created_at
+		 FROM ai_prompts
+		 WHERE name = $1 AND tenant_id = $2 AND ($3 = 0 OR version = $3)
+		 ORDER BY version DESC
+		 LIMIT 1`,
+		name, tenantID, version,
+	).Scan(&p.Version, &p.Template, &p.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrPromptNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: get prompt: %w", err)
+	}
+
+	return p, nil
+}
+
+// ListPrompts returns every version of a prompt across tenants, newest first.
+func (s *PGStore) ListPrompts(ctx context.Context, name string) ([]ai.Prompt, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT name, tenant_id, version, template, created_at
+		 FROM ai_prompts WHERE name = $1
+		 ORDER BY tenant_id, version DESC`,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list prompts: %w", err)
+	}
+	defer rows.Close()
+
+	var prompts []ai.Prompt
+	for rows.Next() {
+		var p ai.Prompt
+		if err := rows.Scan(&p.Name, &p.TenantID, &p.Version, &p.Template, &p.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ai: scan prompt: %w", err)
+		}
+		prompts = append(prompts, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: list prompts: %w", err)
+	}
+
+	return prompts, nil
+}
+
+// Ensure PGStore implements ai.PromptStore at compile time.
+var _ ai.PromptStore = (*PGStore)(nil)
@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// AddKeyUsage adds tokens to the key's counter for the UTC day of at.
+func (s *PGStore) AddKeyUsage(ctx context.Context, keyID string, tokens int, at time.Time) error {
+	_, err := s.db.Exec(ctx,
+		`INSERT INTO ai_key_usage (key_id, day, tokens, requests, updated_at)
+		 VALUES ($1, $2::date, $3, 1, $4)
+		 ON CONFLICT (key_id, day) DO UPDATE
+		 SET tokens = ai_key_usage.tokens + EXCLUDED.tokens,
+		     requests = ai_key_usage.requests + 1,
+		     updated_at = EXCLUDED.updated_at`,
+		keyID, at.UTC().Format("2006-01-02"), tokens, s.now(),
+	)
+	if err != nil {
+		return fmt.Errorf("ai: add key usage: %w", err)
+	}
+	return nil
+}
+
+// KeyUsage returns the tokens used by a key from the UTC day of since onwards.
+func (s *PGStore) KeyUsage(ctx context.Context, keyID string, since time.Time) (int64, error) {
+	var used int64
+	err := s.db.QueryRow(ctx,
+		`SELECT COALESCE(SUM(tokens), 0)::BIGINT FROM ai_key_usage WHERE key_id = $1 AND day >= $2::date`,
+		keyID, since.UTC().Format("2006-01-02"),
+	).Scan(&used)
+	if err != nil {
+		return 0, fmt.Errorf("ai: key usage: %w", err)
+	}
+	return used, nil
+}
+
+// Ensure PGStore implements ai.QuotaStore at compile time.
+var _ ai.QuotaStore = (*PGStore)(nil)
+
+// KeyUsageCounts returns the usage of every key from the UTC day of since onwards.
+func (s *PGStore) KeyUsageCounts(ctx context.Context, since time.Time) ([]ai.UsageCount, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT key_id, SUM(requests)::BIGINT, SUM(tokens)::BIGINT FROM ai_key_usage
+		 WHERE day >= $1::date GROUP BY key_id ORDER BY key_id`,
+		since.UTC().Format("2006-01-02"),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: key usage counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []ai.UsageCount
+	for rows.Next() {
+		var c ai.UsageCount
+		if err := rows.Scan(&c.KeyID, &c.Requests, &c.Tokens); err != nil {
+			return nil, fmt.Errorf("ai: scan key usage: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: key usage counts: %w", err)
+	}
+	return counts, nil
+}
+
+// ModelUsageCounts returns the usage of every model by the request logs created at or
+// after since.
+func (s *PGStore) ModelUsageCounts(ctx context.Context, since time.Time) ([]ai.UsageCount, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT model, COUNT(*), COALESCE(SUM(total_tokens), 0)::BIGINT FROM ai_request_logs
+		 WHERE created_at >= $1 GROUP BY model ORDER BY model`,
+		since,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: model usage counts: %w", err)
+	}
+	defer rows.Close()
+
+	var counts []ai.UsageCount
+	for rows.Next() {
+		var c ai.UsageCount
+		if err := rows.Scan(&c.Model, &c.Requests, &c.Tokens); err != nil {
+			return nil, fmt.Errorf("ai: scan model usage: %w", err)
+		}
+		counts = append(counts, c)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: model usage counts: %w", err)
+	}
+	return counts, nil
+}
+
+// Ensure PGStore implements ai.QuotaUsageStore at compile time.
+var _ ai.QuotaUsageStore = (*PGStore)(nil)
@@ -2,35 +2,66 @@ package postgres
 
 import (
 	"context"
-	"time"
+	"errors"
+	"fmt"
 
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/meikuraledutech/ai/v1"
 )
 
-// AddRequestLog inserts a new request log with pending status.
+// AddRequestLog inserts a new request log with pending status. A preset ID and
+// CreatedAt are kept, so logs can be written after the request (see ai.RequestLogOutbox).
 func (s *PGStore) AddRequestLog(ctx context.Context, log ai.RequestLog) (*ai.RequestLog, error) {
-	id := uuid.New().String()
-	now := time.Now()
-
-	err := s.db.QueryRow(ctx, `
-		INSERT INTO ai_request_logs (
-			id, session_id, prompt, response, attempt_number,
-			retry_count, final_status, fail_reason, error_message,
-			prompt_tokens, response_tokens, total_tokens, thought_tokens,
-			created_at, updated_at
-		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15)
-		RETURNING created_at, updated_at
-	`,
-		id, log.SessionID, log.Prompt, log.Response, log.AttemptNumber,
-		log.RetryCount, ai.StatusPending, "", "",
-		0, 0, 0, 0,
-		now, now,
-	).Scan(&log.CreatedAt, &log.UpdatedAt)
+	id := log.ID
+	if id == "" {
+		id = s.newID()
+	}
+	now := s.now()
+	created := log.CreatedAt
+	if created.IsZero() {
+		created = now
+	}
+	if log.RequestID == "" {
+		log.RequestID = ai.RequestIDFromContext(ctx)
+	}
+	if log.ReplayOf == "" {
+		log.ReplayOf = ai.ReplayOfFromContext(ctx)
+	}
+	if log.SystemAddendum == "" {
+		log.SystemAddendum = ai.SystemAddendumFromContext(ctx)
+	}
+	actor := ai.ActorFromContext(ctx)
+	if log.TenantID == "" {
+		log.TenantID = actor.TenantID
+	}
+	if log.UserID == "" {
+		log.UserID = actor.UserID
+	}
+
+	err := s.retry(ctx, false, func() error {
+		return s.db.QueryRow(ctx, `
+			INSERT INTO ai_request_logs (
+				id, session_id, prompt, response, attempt_number,
+				retry_count, final_status, fail_reason, error_message,
+				prompt_tokens, response_tokens, total_tokens, thought_tokens,
+				created_at, updated_at, request_id, replay_of, tenant_id,
+				prompt_name, prompt_version, user_id, response_sha256, system_addendum,
+				cache_hit_of, cache_similarity, schema_name, schema_version, labels
+			)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28)
+			RETURNING created_at, updated_at
+		`,
+			id, log.SessionID, log.Prompt, log.Response, log.AttemptNumber,
+			log.RetryCount, ai.StatusPending, "", "",
+			0, 0, 0, 0,
+			created, now, log.RequestID, log.ReplayOf, log.TenantID,
+			log.PromptName, log.PromptVersion, log.UserID, ai.ContentHash(log.Response), log.SystemAddendum,
+			log.CacheHitOf, log.CacheSimilarity, log.SchemaName, log.SchemaVersion, log.Labels,
+		).Scan(&log.CreatedAt, &log.UpdatedAt)
+	})
 
 	if err != nil {
-		return nil, err
+		return nil, fmt.Errorf("ai: add request log: %w", storeError(err))
 	}
 
 	log.ID = id
@@ -40,37 +71,145 @@ func (s *PGStore) AddRequestLog(ctx context.Context, log ai.RequestLog) (*ai.Req
 
 // UpdateRequestLog updates an existing request log with completion/retry details.
 func (s *PGStore) UpdateRequestLog(ctx context.Context, id string, response string, status string, failReason string, errorMsg string, retryCount int, usage *ai.Usage) error {
-	promptTokens := 0
-	responseTokens := 0
-	totalTokens := 0
-	thoughtTokens := 0
-
+	var u ai.Usage
 	if usage != nil {
-		promptTokens = usage.PromptTokens
-		responseTokens = usage.ResponseTokens
-		totalTokens = usage.TotalTokens
-		thoughtTokens = usage.ThoughtTokens
-	}
-
-	_, err := s.db.Exec(ctx, `
-		UPDATE ai_request_logs
-		SET
-			response = $1,
-			final_status = $2,
-			fail_reason = $3,
-			error_message = $4,
-			retry_count = $5,
-			prompt_tokens = $6,
-			response_tokens = $7,
-			total_tokens = $8,
-			thought_tokens = $9,
-			updated_at = NOW()
-		WHERE id = $10
-	`,
-		response, status, failReason, errorMsg, retryCount,
-		promptTokens, responseTokens, totalTokens, thoughtTokens,
-		id,
-	)
-
-	return err
+		u = *usage
+	}
+
+	inline, ref, sum, err := s.offload(ctx, response)
+	if err != nil {
+		return fmt.Errorf("ai: update request log: %w", err)
+	}
+
+	err = s.retry(ctx, true, func() error {
+		_, err := s.db.Exec(ctx, `
+			UPDATE ai_request_logs
+			SET
+				response = $1,
+				final_status = $2,
+				fail_reason = $3,
+				error_message = $4,
+				retry_count = $5,
+				prompt_tokens = $6,
+				response_tokens = $7,
+				total_tokens = $8,
+				thought_tokens = $9,
+				cached_tokens = $10,
+				tool_tokens = $11,
+				audio_tokens = $12,
+				model = $13,
+				cost = $14,
+				updated_at = $15,
+				response_ref = $17,
+				response_sha256 = $18
+			WHERE id = $16
+		`,
+			inline, status, failReason, errorMsg, retryCount,
+			u.PromptTokens, u.ResponseTokens, u.TotalTokens, u.ThoughtTokens,
+			u.CachedTokens, u.ToolTokens, u.AudioTokens,
+			u.Model, s.cost(u),
+			s.now(), id, ref, sum,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: update request log: %w", storeError(err))
+	}
+	return nil
 }
+
+// GetRequestLog returns a request log by ID.
+func (s *PGStore) GetRequestLog(ctx context.Context, id string) (*ai.RequestLog, error) {
+	log := &ai.RequestLog{ID: id}
+	var ref string
+
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx, `
+			SELECT
+				session_id, request_id, replay_of, tenant_id, user_id, prompt_name, prompt_version, prompt, response, attempt_number,
+				retry_count, final_status, fail_reason, error_message,
+				prompt_tokens, response_tokens, total_tokens, thought_tokens,
+				cached_tokens, tool_tokens, audio_tokens, model, cost,
+				created_at, updated_at,
+				system_prompt_tokens, history_tokens, new_prompt_tokens, response_ref, system_addendum,
+				cache_hit_of, cache_similarity, cancelled_after_tokens, schema_name, schema_version, labels, retry_transcript
+			FROM ai_request_logs WHERE id = $1
+		`, id).Scan(
+			&log.SessionID, &log.RequestID, &log.ReplayOf, &log.TenantID, &log.UserID, &log.PromptName, &log.PromptVersion, &log.Prompt, &log.Response, &log.AttemptNumber,
+			&log.RetryCount, &log.FinalStatus, &log.FailReason, &log.ErrorMessage,
+			&log.Usage.PromptTokens, &log.Usage.ResponseTokens, &log.Usage.TotalTokens, &log.Usage.ThoughtTokens,
+			&log.Usage.CachedTokens, &log.Usage.ToolTokens, &log.Usage.AudioTokens, &log.Usage.Model, &log.Cost,
+			&log.CreatedAt, &log.UpdatedAt,
+			&log.Attribution.SystemTokens, &log.Attribution.HistoryTokens, &log.Attribution.PromptTokens, &ref, &log.SystemAddendum,
+			&log.CacheHitOf, &log.CacheSimilarity, &log.CancelledAfterTokens, &log.SchemaName, &log.SchemaVersion, &log.Labels, &log.RetryTranscript,
+		)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrRequestLogNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: get request log: %w", storeError(err))
+	}
+
+	if log.Response, err = s.load(ctx, log.Response, ref); err != nil {
+		return nil, fmt.Errorf("ai: get request log: %w", err)
+	}
+	return log, nil
+}
+
+// SetPromptAttribution records the estimated prompt token split of a request log.
+func (s *PGStore) SetPromptAttribution(ctx context.Context, id string, a ai.PromptAttribution) error {
+	err := s.retry(ctx, true, func() error {
+		_, err := s.db.Exec(ctx, `
+			UPDATE ai_request_logs
+			SET system_prompt_tokens = $1, history_tokens = $2, new_prompt_tokens = $3
+			WHERE id = $4
+		`, a.SystemTokens, a.HistoryTokens, a.PromptTokens, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: set prompt attribution: %w", storeError(err))
+	}
+	return nil
+}
+
+// SetCancelledAfterTokens records how many response tokens a cancelled stream had
+// produced.
+func (s *PGStore) SetCancelledAfterTokens(ctx context.Context, id string, tokens int) error {
+	err := s.retry(ctx, true, func() error {
+		_, err := s.db.Exec(ctx, `
+			UPDATE ai_request_logs SET cancelled_after_tokens = $1 WHERE id = $2
+		`, tokens, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: set cancelled after tokens: %w", storeError(err))
+	}
+	return nil
+}
+
+// AddRetryTurn appends a correction exchange to the retry transcript of a request log.
+func (s *PGStore) AddRetryTurn(ctx context.Context, id string, turn ai.RetryTurn) error {
+	err := s.retry(ctx, false, func() error {
+		_, err := s.db.Exec(ctx, `
+			UPDATE ai_request_logs SET retry_transcript = retry_transcript || $1 WHERE id = $2
+		`, []ai.RetryTurn{turn}, id)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: add retry turn: %w", storeError(err))
+	}
+	return nil
+}
+
+// Ensure PGStore implements ai.PromptAttributionStore at compile time.
+var _ ai.PromptAttributionStore = (*PGStore)(nil)
+
+// Ensure PGStore implements ai.CancelledStreamStore at compile time.
+var _ ai.CancelledStreamStore = (*PGStore)(nil)
+
+// Ensure PGStore implements ai.RetryTranscriptStore at compile time.
+var _ ai.RetryTranscriptStore = (*PGStore)(nil)
+
+// Ensure PGStore implements ai.ReplayStore at compile time.
+var _ ai.ReplayStore = (*PGStore)(nil)
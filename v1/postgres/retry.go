@@ -0,0 +1,71 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// RetryPolicy configures how PGStore retries transient errors.
+type RetryPolicy struct {
+	MaxAttempts int           // total attempts, including the first; 1 or less disables retries
+	BaseDelay   time.Duration // wait before the first retry, doubled before each later one
+	MaxDelay    time.Duration // upper bound on the wait; zero for none
+}
+
+// DefaultRetryPolicy makes up to three attempts, 50ms and 100ms apart.
+var DefaultRetryPolicy = RetryPolicy{MaxAttempts: 3, BaseDelay: 50 * time.Millisecond, MaxDelay: time.Second}
+
+// WithRetry retries session, message and request-log operations that fail with a
+// transient error: a serialization failure or deadlock, or a connection failure.
+// Writes are only retried after a connection failure when the driver reports that
+// nothing was sent, so a write is never applied twice.
+func (s *PGStore) WithRetry(policy RetryPolicy) *PGStore {
+	s.retryPolicy = policy
+	return s
+}
+
+// retry runs fn until it succeeds, fails with an error that isn't transient, or the
+// policy's attempts are used up. idempotent reports whether fn may be rerun after a
+// connection failure that may have reached the server.
+func (s *PGStore) retry(ctx context.Context, idempotent bool, fn func() error) error {
+	delay := s.retryPolicy.BaseDelay
+	for attempt := 1; ; attempt++ {
+		err := fn()
+		if err == nil || attempt >= s.retryPolicy.MaxAttempts || !transient(err, idempotent) {
+			return err
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return err
+		case <-t.C:
+		}
+
+		delay *= 2
+		if s.retryPolicy.MaxDelay > 0 && delay > s.retryPolicy.MaxDelay {
+			delay = s.retryPolicy.MaxDelay
+		}
+	}
+}
+
+// transient reports whether err is worth retrying.
+func transient(err error, idempotent bool) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && (pgErr.Code == "40001" || pgErr.Code == "40P01") {
+		// serialization_failure and deadlock_detected roll the transaction back
+		return true
+	}
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+	return idempotent && isUnavailable(err)
+}
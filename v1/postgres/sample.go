@@ -0,0 +1,105 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// AddSample stores a sample in ai_samples.
+func (s *PGStore) AddSample(ctx context.Context, sample ai.Sample) error {
+	if sample.ID == "" {
+		sample.ID = s.newID()
+	}
+	if sample.CreatedAt.IsZero() {
+		sample.CreatedAt = s.now()
+	}
+
+	err := s.retry(ctx, true, func() error {
+		_, err := s.db.Exec(ctx,
+			`INSERT INTO ai_samples (id, tenant_id, prompt_name, prompt_version, model, system_prompt, prompt, response, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			 ON CONFLICT (id) DO NOTHING`,
+			sample.ID, sample.TenantID, sample.PromptName, sample.PromptVersion, sample.Model,
+			sample.SystemPrompt, sample.Prompt, sample.Response, sample.CreatedAt,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: add sample: %w", storeError(err))
+	}
+	return nil
+}
+
+// ListSamples returns the samples matching f, newest first.
+func (s *PGStore) ListSamples(ctx context.Context, f ai.SampleFilter) ([]ai.Sample, error) {
+	var where []string
+	var args []any
+	if f.PromptName != "" {
+		args = append(args, f.PromptName)
+		where = append(where, fmt.Sprintf("prompt_name = $%d", len(args)))
+	}
+	if f.TenantID != "" {
+		args = append(args, f.TenantID)
+		where = append(where, fmt.Sprintf("tenant_id = $%d", len(args)))
+	}
+	if !f.Since.IsZero() {
+		args = append(args, f.Since)
+		where = append(where, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	query := `SELECT id, tenant_id, prompt_name, prompt_version, model, system_prompt, prompt, response, created_at FROM ai_samples`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	var samples []ai.Sample
+	err := s.retry(ctx, true, func() error {
+		samples = nil
+		rows, err := s.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var sample ai.Sample
+			err := rows.Scan(&sample.ID, &sample.TenantID, &sample.PromptName, &sample.PromptVersion, &sample.Model,
+				&sample.SystemPrompt, &sample.Prompt, &sample.Response, &sample.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("scan sample: %w", err)
+			}
+			samples = append(samples, sample)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: list samples: %w", storeError(err))
+	}
+	return samples, nil
+}
+
+// DeleteSamplesBefore removes samples created before t, for retention.
+func (s *PGStore) DeleteSamplesBefore(ctx context.Context, t time.Time) (int64, error) {
+	var n int64
+	err := s.retry(ctx, true, func() error {
+		tag, err := s.db.Exec(ctx, `DELETE FROM ai_samples WHERE created_at < $1`, t)
+		n = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ai: delete samples: %w", storeError(err))
+	}
+	return n, nil
+}
+
+// Ensure PGStore implements ai.SampleStore at compile time.
+var _ ai.SampleStore = (*PGStore)(nil)
@@ -1,8 +1,42 @@
 package postgres
 
-import "context"
+import (
+	"context"
+	"fmt"
+
+	"github.com/meikuraledutech/ai/v1"
+)
 
 // CreateSchema applies all pending migrations. Delegates to Migrate for migration-based schema management.
 func (s *PGStore) CreateSchema(ctx context.Context) error {
 	return s.Migrate(ctx)
 }
+
+// DropSchema rolls back every applied migration, newest first, and drops ai_migrations.
+// Destructive: all data is lost.
+func (s *PGStore) DropSchema(ctx context.Context) error {
+	if err := s.ensureMigrationsTable(ctx); err != nil {
+		return fmt.Errorf("ai: ensure migrations table: %w", err)
+	}
+
+	for {
+		var applied int
+		if err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM ai_migrations`).Scan(&applied); err != nil {
+			return fmt.Errorf("ai: drop schema: %w", err)
+		}
+		if applied == 0 {
+			break
+		}
+		if err := s.Rollback(ctx); err != nil {
+			return err
+		}
+	}
+
+	if _, err := s.db.Exec(ctx, `DROP TABLE IF EXISTS ai_migrations`); err != nil {
+		return fmt.Errorf("ai: drop schema: %w", err)
+	}
+	return nil
+}
+
+// Ensure PGStore implements ai.SchemaDropper at compile time.
+var _ ai.SchemaDropper = (*PGStore)(nil)
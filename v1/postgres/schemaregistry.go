@@ -0,0 +1,79 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SaveSchema stores sc as the next version of its name.
+func (s *PGStore) SaveSchema(ctx context.Context, sc ai.Schema) (*ai.Schema, error) {
+	err := s.db.QueryRow(ctx,
+		`INSERT INTO ai_schemas (name, version, definition, breaking, created_at)
+		 VALUES ($1, COALESCE((SELECT MAX(version) FROM ai_schemas WHERE name = $1), 0) + 1, $2, $3, $4)
+		 RETURNING version, created_at`,
+		sc.Name, sc.Definition, sc.Breaking, s.now(),
+	).Scan(&sc.Version, &sc.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("ai: save schema: %w", err)
+	}
+	return &sc, nil
+}
+
+// GetSchema returns a version of a schema, or the latest when version is 0.
+// Returns ai.ErrSchemaNotFound if there is none.
+func (s *PGStore) GetSchema(ctx context.Context, name string, version int) (*ai.Schema, error) {
+	sc := &ai.Schema{Name: name}
+
+	err := s.db.QueryRow(ctx,
+		`SELECT version, definition, breaking, created_at
+		 FROM ai_schemas
+		 WHERE name = $1 AND ($2 = 0 OR version = $2)
+		 ORDER BY version DESC
+		 LIMIT 1`,
+		name, version,
+	).Scan(&sc.Version, &sc.Definition, &sc.Breaking, &sc.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrSchemaNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: get schema: %w", err)
+	}
+
+	return sc, nil
+}
+
+// ListSchemas returns every version of a schema, newest first.
+func (s *PGStore) ListSchemas(ctx context.Context, name string) ([]ai.Schema, error) {
+	rows, err := s.db.Query(ctx,
+		`SELECT name, version, definition, breaking, created_at
+		 FROM ai_schemas WHERE name = $1
+		 ORDER BY version DESC`,
+		name,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("ai: list schemas: %w", err)
+	}
+	defer rows.Close()
+
+	var schemas []ai.Schema
+	for rows.Next() {
+		var sc ai.Schema
+		if err := rows.Scan(&sc.Name, &sc.Version, &sc.Definition, &sc.Breaking, &sc.CreatedAt); err != nil {
+			return nil, fmt.Errorf("ai: scan schema: %w", err)
+		}
+		schemas = append(schemas, sc)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: list schemas: %w", err)
+	}
+
+	return schemas, nil
+}
+
+// Ensure PGStore implements ai.SchemaStore at compile time.
+var _ ai.SchemaStore = (*PGStore)(nil)
@@ -2,44 +2,86 @@ package postgres
 
 import (
 	"context"
+	"errors"
 	"fmt"
 
-	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/meikuraledutech/ai/v1"
 )
 
-// CreateSession creates a new session with the given rules.
+// CreateSession creates a new session with the given rules. Rules that ai.CheckRules
+// rejects are not stored, and its error is returned.
 func (s *PGStore) CreateSession(ctx context.Context, rules ai.Rules) (*ai.Session, error) {
+	if err := ai.CheckRules(nil, rules); err != nil {
+		return nil, fmt.Errorf("ai: create session: %w", err)
+	}
 	session := &ai.Session{
-		ID:    uuid.New().String(),
-		Rules: rules,
+		ID:        s.newID(),
+		Rules:     rules,
+		CreatedAt: s.now(),
 	}
 
-	err := s.db.QueryRow(ctx,
-		`INSERT INTO ai_sessions (id, system_prompt, output_schema, max_tokens)
-		 VALUES ($1, $2, $3, $4)
-		 RETURNING created_at`,
-		session.ID, rules.SystemPrompt, rules.OutputSchema, rules.MaxTokens,
-	).Scan(&session.CreatedAt)
+	err := s.mutate(ctx, ai.AuditCreateSession, session.ID, func(q querier) (string, error) {
+		return session.ID, insertSession(ctx, q, session)
+	})
 	if err != nil {
-		return nil, fmt.Errorf("ai: create session: %w", err)
+		return nil, fmt.Errorf("ai: create session: %w", storeError(err))
 	}
 
 	return session, nil
 }
 
+// insertSession inserts session and sets its CreatedAt to the stored value.
+func insertSession(ctx context.Context, q querier, session *ai.Session) error {
+	rules := session.Rules
+	return q.QueryRow(ctx,
+		`INSERT INTO ai_sessions (id, system_prompt, output_schema, max_tokens, policy, language, prompt, schema, labels, response_format, created_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		 RETURNING created_at`,
+		session.ID, rules.SystemPrompt, rules.OutputSchema, rules.MaxTokens, rules.Policy, rules.Language, rules.Prompt, rules.Schema, rules.Labels, rules.ResponseFormat, session.CreatedAt,
+	).Scan(&session.CreatedAt)
+}
+
 // GetSession retrieves a session by ID.
+// Returns ai.ErrSessionNotFound if the session doesn't exist.
 func (s *PGStore) GetSession(ctx context.Context, sessionID string) (*ai.Session, error) {
 	session := &ai.Session{ID: sessionID}
 
-	err := s.db.QueryRow(ctx,
-		`SELECT system_prompt, output_schema, max_tokens, created_at
-		 FROM ai_sessions WHERE id = $1`,
-		sessionID,
-	).Scan(&session.Rules.SystemPrompt, &session.Rules.OutputSchema, &session.Rules.MaxTokens, &session.CreatedAt)
+	err := s.retry(ctx, true, func() error {
+		return s.db.QueryRow(ctx,
+			`SELECT system_prompt, output_schema, max_tokens, policy, language, prompt, schema, labels, response_format, created_at
+			 FROM ai_sessions WHERE id = $1`,
+			sessionID,
+		).Scan(&session.Rules.SystemPrompt, &session.Rules.OutputSchema, &session.Rules.MaxTokens, &session.Rules.Policy, &session.Rules.Language, &session.Rules.Prompt, &session.Rules.Schema, &session.Rules.Labels, &session.Rules.ResponseFormat, &session.CreatedAt)
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrSessionNotFound
+	}
 	if err != nil {
-		return nil, fmt.Errorf("ai: get session: %w", err)
+		return nil, fmt.Errorf("ai: get session: %w", storeError(err))
 	}
 
 	return session, nil
 }
+
+// DeleteSession deletes a session with its messages, request logs and memory.
+// Returns ai.ErrSessionNotFound if the session doesn't exist.
+func (s *PGStore) DeleteSession(ctx context.Context, sessionID string) error {
+	err := s.mutate(ctx, ai.AuditDeleteSession, sessionID, func(q querier) (string, error) {
+		tag, err := q.Exec(ctx, `DELETE FROM ai_sessions WHERE id = $1`, sessionID)
+		if err != nil {
+			return "", err
+		}
+		if tag.RowsAffected() == 0 {
+			return "", ai.ErrSessionNotFound
+		}
+		return sessionID, nil
+	})
+	if errors.Is(err, ai.ErrSessionNotFound) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("ai: delete session: %w", storeError(err))
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+const shadowResultColumns = `id, session_id, tenant_id, prompt,
+	primary_request_id, primary_model, primary_content, primary_usage, primary_latency_ms,
+	shadow_request_id, shadow_model, shadow_content, shadow_usage, shadow_latency_ms, shadow_error,
+	identical, created_at`
+
+// AddShadowResult stores a shadow result in ai_shadow_results.
+func (s *PGStore) AddShadowResult(ctx context.Context, r ai.ShadowResult) error {
+	if r.ID == "" {
+		r.ID = s.newID()
+	}
+	if r.CreatedAt.IsZero() {
+		r.CreatedAt = s.now()
+	}
+
+	err := s.retry(ctx, true, func() error {
+		_, err := s.db.Exec(ctx,
+			`INSERT INTO ai_shadow_results (`+shadowResultColumns+`)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
+			 ON CONFLICT (id) DO NOTHING`,
+			r.ID, r.SessionID, r.TenantID, r.Prompt,
+			r.PrimaryRequestID, r.PrimaryModel, r.PrimaryContent, r.PrimaryUsage, r.PrimaryLatencyMs,
+			r.ShadowRequestID, r.ShadowModel, r.ShadowContent, r.ShadowUsage, r.ShadowLatencyMs, r.ShadowError,
+			r.Identical, r.CreatedAt,
+		)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("ai: add shadow result: %w", storeError(err))
+	}
+	return nil
+}
+
+// ListShadowResults returns the shadow results matching f, newest first.
+func (s *PGStore) ListShadowResults(ctx context.Context, f ai.ShadowFilter) ([]ai.ShadowResult, error) {
+	var where []string
+	var args []any
+	if f.ShadowModel != "" {
+		args = append(args, f.ShadowModel)
+		where = append(where, fmt.Sprintf("shadow_model = $%d", len(args)))
+	}
+	if f.TenantID != "" {
+		args = append(args, f.TenantID)
+		where = append(where, fmt.Sprintf("tenant_id = $%d", len(args)))
+	}
+	if !f.Since.IsZero() {
+		args = append(args, f.Since)
+		where = append(where, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	query := `SELECT ` + shadowResultColumns + ` FROM ai_shadow_results`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if f.Limit > 0 {
+		args = append(args, f.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
+	}
+
+	var results []ai.ShadowResult
+	err := s.retry(ctx, true, func() error {
+		results = nil
+		rows, err := s.db.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var r ai.ShadowResult
+			err := rows.Scan(&r.ID, &r.SessionID, &r.TenantID, &r.Prompt,
+				&r.PrimaryRequestID, &r.PrimaryModel, &r.PrimaryContent, &r.PrimaryUsage, &r.PrimaryLatencyMs,
+				&r.ShadowRequestID, &r.ShadowModel, &r.ShadowContent, &r.ShadowUsage, &r.ShadowLatencyMs, &r.ShadowError,
+				&r.Identical, &r.CreatedAt)
+			if err != nil {
+				return fmt.Errorf("scan shadow result: %w", err)
+			}
+			results = append(results, r)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: list shadow results: %w", storeError(err))
+	}
+	return results, nil
+}
+
+// DeleteShadowResultsBefore removes shadow results created before t, for retention.
+func (s *PGStore) DeleteShadowResultsBefore(ctx context.Context, t time.Time) (int64, error) {
+	var n int64
+	err := s.retry(ctx, true, func() error {
+		tag, err := s.db.Exec(ctx, `DELETE FROM ai_shadow_results WHERE created_at < $1`, t)
+		n = tag.RowsAffected()
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("ai: delete shadow results: %w", storeError(err))
+	}
+	return n, nil
+}
+
+// Ensure PGStore implements ai.ShadowStore at compile time.
+var _ ai.ShadowStore = (*PGStore)(nil)
@@ -0,0 +1,46 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// Spend returns the summed cost of the request logs matching filter.
+func (s *PGStore) Spend(ctx context.Context, filter ai.SpendFilter) (float64, error) {
+	var where []string
+	var args []any
+
+	if filter.SessionID != "" {
+		args = append(args, filter.SessionID)
+		where = append(where, fmt.Sprintf("session_id = $%d", len(args)))
+	}
+	if filter.TenantID != "" {
+		args = append(args, filter.TenantID)
+		where = append(where, fmt.Sprintf("tenant_id = $%d", len(args)))
+	}
+	if filter.UserID != "" {
+		args = append(args, filter.UserID)
+		where = append(where, fmt.Sprintf("user_id = $%d", len(args)))
+	}
+	if !filter.Since.IsZero() {
+		args = append(args, filter.Since)
+		where = append(where, fmt.Sprintf("created_at >= $%d", len(args)))
+	}
+
+	query := `SELECT COALESCE(SUM(cost), 0)::DOUBLE PRECISION FROM ai_request_logs`
+	if len(where) > 0 {
+		query += " WHERE " + strings.Join(where, " AND ")
+	}
+
+	var spent float64
+	if err := s.db.QueryRow(ctx, query, args...).Scan(&spent); err != nil {
+		return 0, fmt.Errorf("ai: spend: %w", err)
+	}
+	return spent, nil
+}
+
+// Ensure PGStore implements ai.SpendStore at compile time.
+var _ ai.SpendStore = (*PGStore)(nil)
@@ -0,0 +1,82 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// SessionStats returns message, token, cost and retry totals for a session in one query.
+func (s *PGStore) SessionStats(ctx context.Context, sessionID string) (*ai.SessionStats, error) {
+	stats := &ai.SessionStats{
+		SessionID:      sessionID,
+		MessagesByRole: map[string]int{},
+		TokensByRole:   map[string]int{},
+	}
+
+	var (
+		roles          []string
+		counts, tokens []int64
+		first, last    *time.Time
+		logsLast       *time.Time
+	)
+	err := s.db.QueryRow(ctx, `
+		WITH m AS (
+			SELECT role, COUNT(*) AS messages, COALESCE(SUM(total_tokens), 0) AS tokens,
+			       MIN(created_at) AS first_at, MAX(created_at) AS last_at
+			FROM ai_messages WHERE session_id = $1
+			GROUP BY role
+		), r AS (
+			SELECT COUNT(*) AS requests, COALESCE(SUM(retry_count), 0) AS retries,
+			       COUNT(*) FILTER (WHERE final_status = $2) AS failed,
+			       COALESCE(SUM(cost), 0) AS cost, MAX(updated_at) AS last_at
+			FROM ai_request_logs WHERE session_id = $1
+		)
+		SELECT s.created_at,
+		       COALESCE((SELECT array_agg(role ORDER BY role) FROM m), '{}'),
+		       COALESCE((SELECT array_agg(messages ORDER BY role) FROM m), '{}'),
+		       COALESCE((SELECT array_agg(tokens ORDER BY role) FROM m), '{}'),
+		       (SELECT MIN(first_at) FROM m), (SELECT MAX(last_at) FROM m),
+		       r.requests, r.retries, r.failed, r.cost, r.last_at
+		FROM ai_sessions s, r
+		WHERE s.id = $1
+	`, sessionID, ai.StatusFailed).Scan(
+		&stats.CreatedAt, &roles, &counts, &tokens, &first, &last,
+		&stats.Requests, &stats.Retries, &stats.FailedRequests, &stats.Cost, &logsLast,
+	)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, ai.ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("ai: session stats: %w", err)
+	}
+
+	for i, role := range roles {
+		stats.MessagesByRole[role] = int(counts[i])
+		stats.TokensByRole[role] = int(tokens[i])
+		stats.TotalTokens += int(tokens[i])
+		if role == ai.RoleEvent {
+			stats.Events += int(counts[i])
+		} else {
+			stats.Messages += int(counts[i])
+		}
+	}
+	if first != nil {
+		stats.FirstActivity = *first
+	}
+	if last != nil {
+		stats.LastActivity = *last
+	}
+	if logsLast != nil && logsLast.After(stats.LastActivity) {
+		stats.LastActivity = *logsLast
+	}
+
+	return stats, nil
+}
+
+// Ensure PGStore implements ai.SessionStatsStore at compile time.
+var _ ai.SessionStatsStore = (*PGStore)(nil)
@@ -0,0 +1,74 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// HistoryAt returns the messages of a session created at or before at, in one query.
+// Each message whose content was edited after at carries the content replaced by the
+// first such edit, read back from the blob store if it was offloaded.
+func (s *PGStore) HistoryAt(ctx context.Context, sessionID string, at time.Time) ([]ai.Message, error) {
+	var messages []ai.Message
+	var refs []string
+	err := s.retry(ctx, true, func() error {
+		messages, refs = nil, nil
+		rows, err := s.db.Query(ctx,
+			`SELECT m.id, m.session_id, m.seq, m.role, COALESCE(later.content, m.content), m.event_type, m.request_id,
+			        m.prompt_tokens, m.response_tokens, m.total_tokens, m.thought_tokens, m.cached_tokens, m.tool_tokens, m.audio_tokens,
+			        m.model, m.cost, m.created_at, COALESCE(earlier.edited_at, m.created_at),
+			        CASE WHEN later.id IS NULL THEN m.content_ref ELSE later.content_ref END
+			 FROM ai_messages m
+			 LEFT JOIN LATERAL (
+			     SELECT id, content, content_ref FROM ai_message_edits
+			     WHERE message_id = m.id AND created_at > $2
+			     ORDER BY created_at ASC, id ASC LIMIT 1
+			 ) later ON TRUE
+			 LEFT JOIN LATERAL (
+			     SELECT MAX(created_at) AS edited_at FROM ai_message_edits
+			     WHERE message_id = m.id AND created_at <= $2
+			 ) earlier ON TRUE
+			 WHERE m.session_id = $1 AND m.created_at <= $2
+			 ORDER BY m.seq ASC`,
+			sessionID, at,
+		)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var msg ai.Message
+			var u ai.Usage
+			var ref string
+			err := rows.Scan(&msg.ID, &msg.SessionID, &msg.Seq, &msg.Role, &msg.Content, &msg.EventType, &msg.RequestID,
+				&u.PromptTokens, &u.ResponseTokens, &u.TotalTokens, &u.ThoughtTokens, &u.CachedTokens, &u.ToolTokens, &u.AudioTokens,
+				&u.Model, &msg.Cost, &msg.CreatedAt, &msg.UpdatedAt, &ref)
+			if err != nil {
+				return fmt.Errorf("scan message: %w", err)
+			}
+			if u != (ai.Usage{}) {
+				msg.Usage = &u
+			}
+			messages = append(messages, msg)
+			refs = append(refs, ref)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("ai: history at: %w", storeError(err))
+	}
+
+	for i, m := range messages {
+		if messages[i].Content, err = s.load(ctx, m.Content, refs[i]); err != nil {
+			return nil, fmt.Errorf("ai: history at: seq %d: %w", m.Seq, err)
+		}
+	}
+	return messages, nil
+}
+
+// Ensure PGStore implements ai.HistoryReader at compile time.
+var _ ai.HistoryReader = (*PGStore)(nil)
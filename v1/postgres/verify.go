@@ -0,0 +1,206 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+var (
+	createTablePattern = regexp.MustCompile(`(?is)CREATE\s+TABLE\s+IF\s+NOT\s+EXISTS\s+(\w+)\s*\((.*?)\n\s*\);`)
+	addColumnPattern   = regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(\w+)\s+ADD\s+COLUMN\s+IF\s+NOT\s+EXISTS\s+(\w+)`)
+	dropColumnPattern  = regexp.MustCompile(`(?i)ALTER\s+TABLE\s+(\w+)\s+DROP\s+COLUMN\s+IF\s+EXISTS\s+(\w+)`)
+	createIndexPattern = regexp.MustCompile(`(?i)CREATE\s+(?:UNIQUE\s+)?INDEX\s+(?:CONCURRENTLY\s+)?IF\s+NOT\s+EXISTS\s+(\w+)`)
+	dropIndexPattern   = regexp.MustCompile(`(?i)DROP\s+INDEX\s+(?:CONCURRENTLY\s+)?IF\s+EXISTS\s+(\w+)`)
+	dropTablePattern   = regexp.MustCompile(`(?i)DROP\s+TABLE\s+IF\s+EXISTS\s+(\w+)`)
+)
+
+// tableConstraints are the leading keywords of CREATE TABLE lines that aren't columns.
+var tableConstraints = []string{"PRIMARY", "UNIQUE", "CONSTRAINT", "FOREIGN", "CHECK", "EXCLUDE"}
+
+// expectedSchema is the tables, columns and indexes the up migrations create.
+type expectedSchema struct {
+	columns map[string]map[string]bool // table -> column
+	indexes map[string]bool
+}
+
+// schemaFromMigrations replays the DDL statements of the up migrations, in order.
+func schemaFromMigrations(migrations []migrationFile) expectedSchema {
+	schema := expectedSchema{columns: make(map[string]map[string]bool), indexes: make(map[string]bool)}
+
+	for _, m := range migrations {
+		for _, match := range createTablePattern.FindAllStringSubmatch(m.Up, -1) {
+			table := strings.ToLower(match[1])
+			if schema.columns[table] == nil {
+				schema.columns[table] = make(map[string]bool)
+			}
+			for _, line := range strings.Split(match[2], "\n") {
+				fields := strings.Fields(line)
+				if len(fields) == 0 || strings.HasPrefix(fields[0], "--") || isTableConstraint(fields[0]) {
+					continue
+				}
+				schema.columns[table][strings.ToLower(fields[0])] = true
+			}
+		}
+		for _, match := range addColumnPattern.FindAllStringSubmatch(m.Up, -1) {
+			table := strings.ToLower(match[1])
+			if schema.columns[table] == nil {
+				schema.columns[table] = make(map[string]bool)
+			}
+			schema.columns[table][strings.ToLower(match[2])] = true
+		}
+		for _, match := range dropColumnPattern.FindAllStringSubmatch(m.Up, -1) {
+			delete(schema.columns[strings.ToLower(match[1])], strings.ToLower(match[2]))
+		}
+		for _, match := range createIndexPattern.FindAllStringSubmatch(m.Up, -1) {
+			schema.indexes[strings.ToLower(match[1])] = true
+		}
+		for _, match := range dropIndexPattern.FindAllStringSubmatch(m.Up, -1) {
+			delete(schema.indexes, strings.ToLower(match[1]))
+		}
+		for _, match := range dropTablePattern.FindAllStringSubmatch(m.Up, -1) {
+			delete(schema.columns, strings.ToLower(match[1]))
+		}
+	}
+
+	return schema
+}
+
+func isTableConstraint(word string) bool {
+	word = strings.ToUpper(word)
+	for _, c := range tableConstraints {
+		if strings.HasPrefix(word, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// VerifySchema checks that every migration is recorded as applied with a matching
+// checksum and that the tables, columns and indexes they create exist, plus the scale
+// indexes when WithScaleIndexes is set. It runs no DDL, so it works with a read-only
+// role. Problems are returned in one error wrapping ai.ErrSchemaMismatch.
+func (s *PGStore) VerifySchema(ctx context.Context) error {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return fmt.Errorf("ai: load migrations: %w", err)
+	}
+
+	var problems []string
+
+	var hasMigrations bool
+	if err := s.db.QueryRow(ctx, `SELECT to_regclass('ai_migrations') IS NOT NULL`).Scan(&hasMigrations); err != nil {
+		return fmt.Errorf("ai: verify schema: %w", err)
+	}
+	if !hasMigrations {
+		problems = append(problems, "table ai_migrations is missing")
+	} else {
+		applied, err := s.appliedMigrations(ctx)
+		if err != nil {
+			return fmt.Errorf("ai: get applied migrations: %w", err)
+		}
+		for _, m := range migrations {
+			rec, ok := applied[m.Name]
+			switch {
+			case !ok:
+				problems = append(problems, fmt.Sprintf("migration %s is not applied", m.Name))
+			case rec.Checksum != m.Checksum:
+				problems = append(problems, fmt.Sprintf("migration %s checksum mismatch (expected %s, got %s)", m.Name, m.Checksum, rec.Checksum))
+			}
+		}
+	}
+
+	columns, indexes, err := s.currentSchema(ctx)
+	if err != nil {
+		return fmt.Errorf("ai: verify schema: %w", err)
+	}
+
+	expected := schemaFromMigrations(migrations)
+	if s.scaleIndexes {
+		for _, idx := range scaleIndexes {
+			expected.indexes[idx.Name] = true
+		}
+	}
+	for _, table := range sortedKeys(expected.columns) {
+		have, ok := columns[table]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("table %s is missing", table))
+			continue
+		}
+		for _, column := range sortedKeys(expected.columns[table]) {
+			if !have[column] {
+				problems = append(problems, fmt.Sprintf("column %s.%s is missing", table, column))
+			}
+		}
+	}
+	for _, index := range sortedKeys(expected.indexes) {
+		if !indexes[index] {
+			problems = append(problems, fmt.Sprintf("index %s is missing", index))
+		}
+	}
+
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ai.ErrSchemaMismatch, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// currentSchema reads the columns and indexes of the ai_ tables in the current schema.
+func (s *PGStore) currentSchema(ctx context.Context) (map[string]map[string]bool, map[string]bool, error) {
+	columns := make(map[string]map[string]bool)
+	rows, err := s.db.Query(ctx,
+		`SELECT table_name, column_name FROM information_schema.columns
+		 WHERE table_schema = current_schema() AND table_name LIKE 'ai\_%'`,
+	)
+	if err != nil {
+		return nil, nil, err
+	}
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			rows.Close()
+			return nil, nil, err
+		}
+		if columns[table] == nil {
+			columns[table] = make(map[string]bool)
+		}
+		columns[table][column] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	indexes := make(map[string]bool)
+	rows, err = s.db.Query(ctx, `SELECT indexname FROM pg_indexes WHERE schemaname = current_schema()`)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var index string
+		if err := rows.Scan(&index); err != nil {
+			return nil, nil, err
+		}
+		indexes[index] = true
+	}
+
+	return columns, indexes, rows.Err()
+}
+
+// sortedKeys returns the keys of m in order, for stable error messages.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Ensure PGStore implements ai.SchemaVerifier at compile time.
+var _ ai.SchemaVerifier = (*PGStore)(nil)
@@ -0,0 +1,80 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// AggregateUsage sums the request logs created in [from, to) per UTC hour, tenant, user
+// and model.
+func (s *PGStore) AggregateUsage(ctx context.Context, from, to time.Time) ([]ai.UsageAggregate, error) {
+	rows, err := s.db.Query(ctx, `
+		SELECT date_trunc('hour', created_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC' AS hour,
+		       tenant_id, user_id, model,
+		       COUNT(*), COUNT(*) FILTER (WHERE final_status = $3),
+		       COALESCE(SUM(prompt_tokens), 0), COALESCE(SUM(response_tokens), 0),
+		       COALESCE(SUM(thought_tokens), 0), COALESCE(SUM(cached_tokens), 0),
+		       COALESCE(SUM(total_tokens), 0), COALESCE(SUM(cost), 0)::DOUBLE PRECISION
+		FROM ai_request_logs
+		WHERE created_at >= $1 AND created_at < $2
+		GROUP BY 1, tenant_id, user_id, model
+		ORDER BY 1, tenant_id, user_id, model
+	`, from, to, ai.StatusFailed)
+	if err != nil {
+		return nil, fmt.Errorf("ai: aggregate usage: %w", storeError(err))
+	}
+	defer rows.Close()
+
+	var out []ai.UsageAggregate
+	for rows.Next() {
+		var a ai.UsageAggregate
+		if err := rows.Scan(&a.Hour, &a.TenantID, &a.UserID, &a.Model,
+			&a.Requests, &a.FailedRequests,
+			&a.PromptTokens, &a.ResponseTokens, &a.ThoughtTokens, &a.CachedTokens,
+			&a.TotalTokens, &a.Cost); err != nil {
+			return nil, fmt.Errorf("ai: scan usage aggregate: %w", err)
+		}
+		a.Hour = a.Hour.UTC()
+		out = append(out, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("ai: aggregate usage: %w", storeError(err))
+	}
+	return out, nil
+}
+
+// GetWatermark returns the watermark stored under name, or the zero time.
+func (s *PGStore) GetWatermark(ctx context.Context, name string) (time.Time, error) {
+	var at time.Time
+	err := s.db.QueryRow(ctx, `SELECT watermark FROM ai_watermarks WHERE name = $1`, name).Scan(&at)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("ai: get watermark: %w", storeError(err))
+	}
+	return at, nil
+}
+
+// SetWatermark stores the watermark of name, replacing any previous one.
+func (s *PGStore) SetWatermark(ctx context.Context, name string, at time.Time) error {
+	_, err := s.db.Exec(ctx, `
+		INSERT INTO ai_watermarks (name, watermark, updated_at) VALUES ($1, $2, $3)
+		ON CONFLICT (name) DO UPDATE SET watermark = EXCLUDED.watermark, updated_at = EXCLUDED.updated_at
+	`, name, at, s.now())
+	if err != nil {
+		return fmt.Errorf("ai: set watermark: %w", storeError(err))
+	}
+	return nil
+}
+
+// Ensure PGStore implements ai.UsageAggregateStore and ai.WatermarkStore at compile time.
+var (
+	_ ai.UsageAggregateStore = (*PGStore)(nil)
+	_ ai.WatermarkStore      = (*PGStore)(nil)
+)
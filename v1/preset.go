@@ -0,0 +1,127 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrPresetNotFound = errors.New("ai: preset not found")
+)
+
+// Preset is a named starting point for sessions: the rules every session of a kind
+// uses, including its system prompt and output schema, and the messages it opens with.
+// Names are usually versioned, e.g. "form-builder-v2".
+type Preset struct {
+	Name      string       `json:"name"`
+	Rules     Rules        `json:"rules"`
+	Messages  []NewMessage `json:"messages,omitempty"`
+	CreatedAt time.Time    `json:"created_at"`
+	UpdatedAt time.Time    `json:"updated_at"`
+}
+
+// PresetSource looks up presets by name.
+type PresetSource interface {
+	// GetPreset returns the preset called name, or ErrPresetNotFound.
+	GetPreset(ctx context.Context, name string) (*Preset, error)
+}
+
+// PresetStore is the optional store capability persisting presets, so they can be
+// changed without a deploy.
+type PresetStore interface {
+	PresetSource
+	// SavePreset creates p or replaces the preset with its name, and returns it with
+	// CreatedAt and UpdatedAt set.
+	SavePreset(ctx context.Context, p Preset) (*Preset, error)
+	// ListPresets returns every stored preset, ordered by name.
+	ListPresets(ctx context.Context) ([]Preset, error)
+	// DeletePreset removes a preset. Sessions created from it are unaffected.
+	DeletePreset(ctx context.Context, name string) error
+}
+
+// SessionPresetCreator is the optional store capability creating a session and its
+// opening messages from a preset in one transaction.
+type SessionPresetCreator interface {
+	CreateSessionFromPreset(ctx context.Context, name string) (*Session, error)
+}
+
+// PresetRegistry is a PresetSource of presets registered in code. It is safe for
+// concurrent use.
+type PresetRegistry struct {
+	mu      sync.RWMutex
+	presets map[string]Preset
+}
+
+// NewPresetRegistry returns a registry holding presets.
+func NewPresetRegistry(presets ...Preset) *PresetRegistry {
+	r := &PresetRegistry{presets: make(map[string]Preset, len(presets))}
+	for _, p := range presets {
+		r.Register(p)
+	}
+	return r
+}
+
+// Register adds p, replacing any preset with its name.
+func (r *PresetRegistry) Register(p Preset) *PresetRegistry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.presets[p.Name] = p
+	return r
+}
+
+// GetPreset returns the preset registered as name, or ErrPresetNotFound.
+func (r *PresetRegistry) GetPreset(ctx context.Context, name string) (*Preset, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	p, ok := r.presets[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrPresetNotFound, name)
+	}
+	p.Messages = append([]NewMessage(nil), p.Messages...)
+	return &p, nil
+}
+
+// ChainPresets returns a PresetSource that looks name up in each source in turn, e.g.
+// stored presets overriding those registered in code.
+func ChainPresets(sources ...PresetSource) PresetSource {
+	return presetChain(sources)
+}
+
+type presetChain []PresetSource
+
+func (c presetChain) GetPreset(ctx context.Context, name string) (*Preset, error) {
+	for _, s := range c {
+		p, err := s.GetPreset(ctx, name)
+		if !errors.Is(err, ErrPresetNotFound) {
+			return p, err
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrPresetNotFound, name)
+}
+
+// CreateSessionFromPreset creates a session on store with the rules of the preset
+// called name in source and appends its opening messages. It is not atomic: if the
+// messages can't be added, the session is deleted again. Prefer the store's own
+// CreateSessionFromPreset when it implements SessionPresetCreator.
+func CreateSessionFromPreset(ctx context.Context, store Store, source PresetSource, name string) (*Session, error) {
+	p, err := source.GetPreset(ctx, name)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := store.CreateSession(ctx, p.Rules)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.Messages) == 0 {
+		return session, nil
+	}
+	if _, err := AddMessages(ctx, store, session.ID, p.Messages); err != nil {
+		DeleteSession(context.WithoutCancel(ctx), store, session.ID)
+		return nil, fmt.Errorf("ai: create session from preset %s: %w", name, err)
+	}
+	return session, nil
+}
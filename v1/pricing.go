@@ -0,0 +1,74 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"unicode/utf8"
+)
+
+// Price is a model's price in USD per million tokens. Tool tokens are billed as input.
+// Cached input and thought tokens use their own rates when set, and the input and
+// output rates otherwise.
+type Price struct {
+	InputPerMillion       float64 `json:"input_per_million"`
+	OutputPerMillion      float64 `json:"output_per_million"`
+	ThoughtPerMillion     float64 `json:"thought_per_million,omitempty"`
+	CachedInputPerMillion float64 `json:"cached_input_per_million,omitempty"`
+}
+
+// Cost returns the price of u in USD.
+func (p Price) Cost(u Usage) float64 {
+	cachedRate := p.CachedInputPerMillion
+	if cachedRate == 0 {
+		cachedRate = p.InputPerMillion
+	}
+	thoughtRate := p.ThoughtPerMillion
+	if thoughtRate == 0 {
+		thoughtRate = p.OutputPerMillion
+	}
+	input := float64(u.PromptTokens-u.CachedTokens+u.ToolTokens)*p.InputPerMillion + float64(u.CachedTokens)*cachedRate
+	output := float64(u.ResponseTokens)*p.OutputPerMillion + float64(u.ThoughtTokens)*thoughtRate
+	return (input + output) / 1e6
+}
+
+// PriceTable maps model IDs to prices.
+type PriceTable map[string]Price
+
+// Cost returns the price of u at the rate of u.Model, and false if the model has no price.
+func (t PriceTable) Cost(u Usage) (float64, bool) {
+	p, ok := t.Lookup(u.Model)
+	if !ok {
+		return 0, false
+	}
+	return p.Cost(u), true
+}
+
+// EstimateTokens approximates the token count of text at four characters per token.
+// It is meant for pre-flight checks; use the provider's reported usage for billing.
+func EstimateTokens(text string) int {
+	return (utf8.RuneCountInString(text) + 3) / 4
+}
+
+// DryRun describes a request that was built but not sent.
+type DryRun struct {
+	// Payload is the exact request body the provider would have sent.
+	Payload json.RawMessage `json:"payload"`
+	// PromptTokens is the estimated input size.
+	PromptTokens int `json:"prompt_tokens"`
+	// MaxOutputTokens bounds the response size used for EstimatedCost.
+	MaxOutputTokens int `json:"max_output_tokens"`
+	// EstimatedCost is the worst-case cost in USD, zero if the provider has no price.
+	EstimatedCost float64 `json:"estimated_cost"`
+}
+
+// WithDryRun makes providers build and validate requests made with ctx without
+// calling the API. Send returns a Result with DryRun set and no content.
+func WithDryRun(ctx context.Context) context.Context {
+	return context.WithValue(ctx, dryRunKey, true)
+}
+
+// IsDryRun reports whether ctx was created with WithDryRun.
+func IsDryRun(ctx context.Context) bool {
+	dry, _ := ctx.Value(dryRunKey).(bool)
+	return dry
+}
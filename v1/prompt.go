@@ -0,0 +1,97 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPromptNotFound = errors.New("ai: prompt not found")
+)
+
+// promptVariable matches {{name}} placeholders in a prompt template.
+var promptVariable = regexp.MustCompile(`\{\{\s*([A-Za-z_][A-Za-z0-9_]*)\s*\}\}`)
+
+// Prompt is one version of a named prompt template. Prompts with an empty TenantID are
+// the defaults; a prompt with a TenantID overrides the default for that tenant.
+type Prompt struct {
+	Name      string    `json:"name"`
+	TenantID  string    `json:"tenant_id,omitempty"`
+	Version   int       `json:"version"`
+	Template  string    `json:"template"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Variables returns the distinct placeholder names in the template, in order of appearance.
+func (p Prompt) Variables() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, m := range promptVariable.FindAllStringSubmatch(p.Template, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	return names
+}
+
+// Render substitutes vars into the template. Every placeholder must have a value.
+func (p Prompt) Render(vars map[string]string) (string, error) {
+	var missing []string
+	for _, name := range p.Variables() {
+		if _, ok := vars[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	if len(missing) > 0 {
+		return "", fmt.Errorf("ai: prompt %s v%d: missing variables %s", p.Name, p.Version, strings.Join(missing, ", "))
+	}
+
+	return promptVariable.ReplaceAllStringFunc(p.Template, func(m string) string {
+		return vars[promptVariable.FindStringSubmatch(m)[1]]
+	}), nil
+}
+
+// PromptRef selects a library prompt for Rules. Version 0 means the latest version.
+type PromptRef struct {
+	Name    string            `json:"name"`
+	Version int               `json:"version,omitempty"`
+	Vars    map[string]string `json:"vars,omitempty"`
+}
+
+// PromptStore is the optional store capability backing the prompt library.
+type PromptStore interface {
+	// SavePrompt stores p as the next version of its name and tenant and returns it
+	// with Version and CreatedAt set.
+	SavePrompt(ctx context.Context, p Prompt) (*Prompt, error)
+	// GetPrompt returns a version of a prompt for exactly tenantID, or the latest when
+	// version is 0. Returns ErrPromptNotFound if there is none.
+	GetPrompt(ctx context.Context, name string, tenantID string, version int) (*Prompt, error)
+	// ListPrompts returns every version of a prompt across tenants, newest first.
+	ListPrompts(ctx context.Context, name string) ([]Prompt, error)
+}
+
+// ResolvePrompt looks up ref for the tenant attached to ctx, falling back to the default
+// prompt when the tenant has no override, and renders it. It returns the prompt used so
+// callers can record its name and version.
+func ResolvePrompt(ctx context.Context, store PromptStore, ref PromptRef) (*Prompt, string, error) {
+	tenantID := TenantIDFromContext(ctx)
+
+	p, err := store.GetPrompt(ctx, ref.Name, tenantID, ref.Version)
+	if errors.Is(err, ErrPromptNotFound) && tenantID != "" {
+		p, err = store.GetPrompt(ctx, ref.Name, "", ref.Version)
+	}
+	if err != nil {
+		return nil, "", err
+	}
+
+	text, err := p.Render(ref.Vars)
+	if err != nil {
+		return nil, "", err
+	}
+	return p, text, nil
+}
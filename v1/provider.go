@@ -14,3 +14,21 @@ var (
 type Provider interface {
 	Send(ctx context.Context, rules Rules, history []Message, prompt string) (*Result, error)
 }
+
+// StreamChunk is an incremental piece of a streamed response. The final chunk has
+// Done set and carries the response's Usage; a failed stream ends with Err set.
+type StreamChunk struct {
+	Delta string `json:"delta,omitempty"`
+	Usage *Usage `json:"usage,omitempty"`
+	Done  bool   `json:"done,omitempty"`
+	Err   error  `json:"-"`
+
+	// Citations are set on the final chunk, like Result.Citations.
+	Citations []Citation `json:"citations,omitempty"`
+}
+
+// Streamer is implemented by providers that can stream responses. The returned channel
+// is closed after the final chunk.
+type Streamer interface {
+	Stream(ctx context.Context, rules Rules, history []Message, prompt string) (<-chan StreamChunk, error)
+}
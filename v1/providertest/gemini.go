@@ -0,0 +1,62 @@
+package providertest
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/meikuraledutech/ai/v1"
+	"github.com/meikuraledutech/ai/v1/gemini"
+)
+
+// Gemini returns the harness for the Gemini provider.
+func Gemini() Harness {
+	return Harness{
+		New: func(baseURL string) ai.Provider {
+			return gemini.New("test-key", "test-model").WithBaseURL(baseURL)
+		},
+		Reply: func(content string, usage ai.Usage) []byte {
+			b, _ := json.Marshal(map[string]any{
+				"candidates": []any{map[string]any{
+					"content": map[string]any{
+						"role":  "model",
+						"parts": []any{map[string]any{"text": content}},
+					},
+				}},
+				"usageMetadata": map[string]any{
+					"promptTokenCount":     usage.PromptTokens,
+					"candidatesTokenCount": usage.ResponseTokens,
+					"totalTokenCount":      usage.TotalTokens,
+					"thoughtsTokenCount":   usage.ThoughtTokens,
+				},
+			})
+			return b
+		},
+		Turns: func(body []byte) ([]ai.Message, error) {
+			var req struct {
+				Contents []struct {
+					Role  string `json:"role"`
+					Parts []struct {
+						Text string `json:"text"`
+					} `json:"parts"`
+				} `json:"contents"`
+			}
+			if err := json.Unmarshal(body, &req); err != nil {
+				return nil, err
+			}
+
+			turns := make([]ai.Message, 0, len(req.Contents))
+			for _, c := range req.Contents {
+				role := ai.RoleUser
+				if c.Role == "model" {
+					role = ai.RoleAssistant
+				}
+				var text strings.Builder
+				for _, p := range c.Parts {
+					text.WriteString(p.Text)
+				}
+				turns = append(turns, ai.Message{Role: role, Content: text.String()})
+			}
+			return turns, nil
+		},
+	}
+}
@@ -0,0 +1,198 @@
+// Package providertest provides behavioral tests for ai.Provider implementations,
+// run against a mock HTTP server.
+//
+//	func TestGemini(t *testing.T) {
+//		providertest.Run(t, providertest.Gemini())
+//	}
+package providertest
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// Harness adapts a provider and its wire format to the suite.
+type Harness struct {
+	// New returns a provider that sends every request to baseURL.
+	New func(baseURL string) ai.Provider
+	// Reply encodes a successful response with the given content and usage.
+	Reply func(content string, usage ai.Usage) []byte
+	// Turns decodes the conversation sent in a request body, in order, with roles
+	// normalized to ai.RoleUser and ai.RoleAssistant.
+	Turns func(body []byte) ([]ai.Message, error)
+}
+
+// server is a mock provider API that records request bodies.
+type server struct {
+	*httptest.Server
+
+	mu     sync.Mutex
+	bodies [][]byte
+}
+
+// newServer serves status and body for every request.
+func newServer(t *testing.T, status int, body []byte) *server {
+	t.Helper()
+	s := &server{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		s.bodies = append(s.bodies, b)
+		s.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		w.Write(body)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// requests returns the bodies received so far.
+func (s *server) requests() [][]byte {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([][]byte(nil), s.bodies...)
+}
+
+// Run runs the Provider behavior suite against the provider built by h.
+func Run(t *testing.T, h Harness) {
+	t.Helper()
+
+	t.Run("EmptyPrompt", func(t *testing.T) { testEmptyPrompt(t, h) })
+	t.Run("HistoryMapping", func(t *testing.T) { testHistoryMapping(t, h) })
+	t.Run("UsageExtraction", func(t *testing.T) { testUsageExtraction(t, h) })
+	t.Run("ErrorClassification", func(t *testing.T) { testErrorClassification(t, h) })
+	t.Run("CancelledContext", func(t *testing.T) { testCancelledContext(t, h) })
+}
+
+func testEmptyPrompt(t *testing.T, h Harness) {
+	srv := newServer(t, http.StatusOK, h.Reply(`{}`, ai.Usage{}))
+
+	_, err := h.New(srv.URL).Send(context.Background(), ai.Rules{}, nil, "")
+	if !errors.Is(err, ai.ErrEmptyPrompt) {
+		t.Fatalf("Send(\"\") = %v, want ai.ErrEmptyPrompt", err)
+	}
+	if n := len(srv.requests()); n != 0 {
+		t.Errorf("Send(\"\") made %d requests, want 0", n)
+	}
+}
+
+func testHistoryMapping(t *testing.T, h Harness) {
+	tests := []struct {
+		name    string
+		history []ai.Message
+	}{
+		{"NoHistory", nil},
+		{"OneTurn", []ai.Message{
+			{Role: ai.RoleUser, Content: "Create a form"},
+			{Role: ai.RoleAssistant, Content: `{"title":"Form"}`},
+		}},
+		{"SeveralTurns", []ai.Message{
+			{Role: ai.RoleUser, Content: "one"},
+			{Role: ai.RoleAssistant, Content: `{"n":1}`},
+			{Role: ai.RoleUser, Content: "two"},
+			{Role: ai.RoleAssistant, Content: `{"n":2}`},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t, http.StatusOK, h.Reply(`{"ok":true}`, ai.Usage{}))
+
+			if _, err := h.New(srv.URL).Send(context.Background(), ai.Rules{}, tt.history, "next"); err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+
+			reqs := srv.requests()
+			if len(reqs) != 1 {
+				t.Fatalf("Send made %d requests, want 1", len(reqs))
+			}
+			turns, err := h.Turns(reqs[0])
+			if err != nil {
+				t.Fatalf("decode request: %v", err)
+			}
+
+			want := append(append([]ai.Message(nil), tt.history...), ai.Message{Role: ai.RoleUser, Content: "next"})
+			if len(turns) != len(want) {
+				t.Fatalf("request has %d turns, want %d", len(turns), len(want))
+			}
+			for i := range want {
+				if turns[i].Role != want[i].Role || turns[i].Content != want[i].Content {
+					t.Errorf("turn %d = {%s %q}, want {%s %q}", i, turns[i].Role, turns[i].Content, want[i].Role, want[i].Content)
+				}
+			}
+		})
+	}
+}
+
+func testUsageExtraction(t *testing.T, h Harness) {
+	tests := []struct {
+		name  string
+		usage ai.Usage
+	}{
+		{"Zero", ai.Usage{}},
+		{"Basic", ai.Usage{PromptTokens: 12, ResponseTokens: 34, TotalTokens: 46}},
+		{"WithThoughts", ai.Usage{PromptTokens: 12, ResponseTokens: 34, TotalTokens: 96, ThoughtTokens: 50}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := newServer(t, http.StatusOK, h.Reply(`{"answer":42}`, tt.usage))
+
+			result, err := h.New(srv.URL).Send(context.Background(), ai.Rules{}, nil, "question")
+			if err != nil {
+				t.Fatalf("Send: %v", err)
+			}
+			if result.Content != `{"answer":42}` {
+				t.Errorf("Content = %q, want %q", result.Content, `{"answer":42}`)
+			}
+			// Providers may report the model; the suite only checks token counts
+			got := result.Usage
+			got.Model = ""
+			if got != tt.usage {
+				t.Errorf("Usage = %+v, want %+v", got, tt.usage)
+			}
+		})
+	}
+}
+
+func testErrorClassification(t *testing.T, h Harness) {
+	statuses := []int{
+		http.StatusBadRequest,
+		http.StatusUnauthorized,
+		http.StatusTooManyRequests,
+		http.StatusInternalServerError,
+		http.StatusServiceUnavailable,
+	}
+
+	for _, status := range statuses {
+		t.Run(http.StatusText(status), func(t *testing.T) {
+			srv := newServer(t, status, []byte(`{"error":{"message":"mock failure"}}`))
+
+			_, err := h.New(srv.URL).Send(context.Background(), ai.Rules{}, nil, "question")
+			if !errors.Is(err, ai.ErrProviderFailed) {
+				t.Errorf("Send with HTTP %d = %v, want ai.ErrProviderFailed", status, err)
+			}
+		})
+	}
+}
+
+func testCancelledContext(t *testing.T, h Harness) {
+	srv := newServer(t, http.StatusOK, h.Reply(`{}`, ai.Usage{}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, err := h.New(srv.URL).Send(ctx, ai.Rules{}, nil, "question")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("Send with cancelled context = %v, want context.Canceled", err)
+	}
+}
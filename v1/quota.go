@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("ai: token quota exceeded")
+)
+
+// Quota caps cumulative token usage per API key. Zero disables a limit.
+// Days and months are calendar periods in UTC.
+type Quota struct {
+	Daily   int64 `json:"daily"`
+	Monthly int64 `json:"monthly"`
+}
+
+// QuotaStore persists cumulative token usage per API key.
+type QuotaStore interface {
+	AddKeyUsage(ctx context.Context, keyID string, tokens int, at time.Time) error
+	KeyUsage(ctx context.Context, keyID string, since time.Time) (int64, error)
+}
+
+// KeyID returns a stable, non-reversible identifier for an API key so raw keys
+// are never written to the store.
+func KeyID(apiKey string) string {
+	sum := sha256.Sum256([]byte(apiKey))
+	return fmt.Sprintf("%x", sum[:8])
+}
+
+// Check returns an error wrapping ErrQuotaExceeded if keyID has used up its daily or
+// monthly quota as of now.
+func (q Quota) Check(ctx context.Context, store QuotaStore, keyID string, now time.Time) error {
+	now = now.UTC()
+
+	if q.Daily > 0 {
+		dayStart := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		used, err := store.KeyUsage(ctx, keyID, dayStart)
+		if err != nil {
+			return fmt.Errorf("ai: check quota: %w", err)
+		}
+		if used >= q.Daily {
+			return fmt.Errorf("%w: daily limit %d tokens, used %d", ErrQuotaExceeded, q.Daily, used)
+		}
+	}
+
+	if q.Monthly > 0 {
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		used, err := store.KeyUsage(ctx, keyID, monthStart)
+		if err != nil {
+			return fmt.Errorf("ai: check quota: %w", err)
+		}
+		if used >= q.Monthly {
+			return fmt.Errorf("%w: monthly limit %d tokens, used %d", ErrQuotaExceeded, q.Monthly, used)
+		}
+	}
+
+	return nil
+}
+
+// UsageCount is the number of requests and tokens a key or model used in a window.
+type UsageCount struct {
+	KeyID    string `json:"key_id,omitempty"`
+	Model    string `json:"model,omitempty"`
+	Requests int64  `json:"requests"`
+	Tokens   int64  `json:"tokens"`
+}
+
+// QuotaUsageStore is the optional store capability behind quota dashboards.
+type QuotaUsageStore interface {
+	// KeyUsageCounts returns the usage of every key from the UTC day of since onwards,
+	// from the counters AddKeyUsage maintains, ordered by key.
+	KeyUsageCounts(ctx context.Context, since time.Time) ([]UsageCount, error)
+	// ModelUsageCounts returns the usage of every model by the requests logged at or
+	// after since, ordered by model. Requests still pending have an empty Model.
+	ModelUsageCounts(ctx context.Context, since time.Time) ([]UsageCount, error)
+}
+
+// QuotaSnapshot is the current usage shown on a quota dashboard.
+type QuotaSnapshot struct {
+	At           time.Time    `json:"at"`
+	KeysToday    []UsageCount `json:"keys_today"`    // per key, since the start of the UTC day
+	ModelsToday  []UsageCount `json:"models_today"`  // per model, since the start of the UTC day
+	ModelsMinute []UsageCount `json:"models_minute"` // per model, since the start of the current minute
+}
+
+// TakeQuotaSnapshot reads the usage of the current UTC day and minute as of now.
+func TakeQuotaSnapshot(ctx context.Context, store QuotaUsageStore, now time.Time) (*QuotaSnapshot, error) {
+	now = now.UTC()
+	day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	snap := &QuotaSnapshot{At: now}
+	var err error
+	if snap.KeysToday, err = store.KeyUsageCounts(ctx, day); err != nil {
+		return nil, fmt.Errorf("ai: quota snapshot: %w", err)
+	}
+	if snap.ModelsToday, err = store.ModelUsageCounts(ctx, day); err != nil {
+		return nil, fmt.Errorf("ai: quota snapshot: %w", err)
+	}
+	if snap.ModelsMinute, err = store.ModelUsageCounts(ctx, now.Truncate(time.Minute)); err != nil {
+		return nil, fmt.Errorf("ai: quota snapshot: %w", err)
+	}
+	return snap, nil
+}
+
+// DailyRemaining returns the tokens left under the daily limit after used, or 0 once
+// it is used up. It is meaningless when Daily is zero.
+func (q Quota) DailyRemaining(used int64) int64 {
+	return max(q.Daily-used, 0)
+}
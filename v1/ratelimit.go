@@ -0,0 +1,174 @@
+package ai
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var (
+	ErrRateLimited = errors.New("ai: rate limited")
+)
+
+// RateLimits are the per-minute limits of an API key, e.g. the RPM and TPM of a Gemini
+// tier. Zero disables a limit.
+type RateLimits struct {
+	RequestsPerMinute int `json:"requests_per_minute,omitempty"`
+	TokensPerMinute   int `json:"tokens_per_minute,omitempty"`
+}
+
+// LimiterStatus is the rate-limit state of a key at one moment, for showing callers
+// when to try again.
+type LimiterStatus struct {
+	At                time.Time `json:"at"`
+	RemainingRequests int       `json:"remaining_requests"` // this minute; -1 without a limit
+	RemainingTokens   int       `json:"remaining_tokens"`   // this minute; -1 without a limit
+	ResetAt           time.Time `json:"reset_at"`           // end of the current minute
+
+	// CooldownUntil is when the provider's last rate-limit response asked to wait
+	// until; nil when not cooling down.
+	CooldownUntil *time.Time `json:"cooldown_until,omitempty"`
+}
+
+// Limited reports whether a request made now would be refused.
+func (s LimiterStatus) Limited() bool {
+	return s.CooldownUntil != nil || s.RemainingRequests == 0 || s.RemainingTokens == 0
+}
+
+// RetryAfter returns how long to wait before a request can be made, zero if it can be
+// made now.
+func (s LimiterStatus) RetryAfter() time.Duration {
+	var wait time.Duration
+	if s.RemainingRequests == 0 || s.RemainingTokens == 0 {
+		wait = s.ResetAt.Sub(s.At)
+	}
+	if s.CooldownUntil != nil {
+		wait = max(wait, s.CooldownUntil.Sub(s.At))
+	}
+	return max(wait, 0)
+}
+
+// RateLimitError is returned when a call is refused for rate limiting, either by the
+// provider's RateLimiter before it is sent or by the API (wrapped in Err). It carries
+// the limiter's state so frontends can show "try again in 20s".
+type RateLimitError struct {
+	Status LimiterStatus
+	Err    error // the API's rate-limit response, nil when refused before sending
+}
+
+func (e *RateLimitError) Error() string {
+	msg := fmt.Sprintf("%v: try again in %s", ErrRateLimited, e.Status.RetryAfter().Round(time.Second))
+	if e.Err != nil {
+		msg += ": " + e.Err.Error()
+	}
+	return msg
+}
+
+// Unwrap returns ErrRateLimited and Err, so errors.Is and errors.As also find the
+// APIError of a rate-limit response.
+func (e *RateLimitError) Unwrap() []error {
+	if e.Err == nil {
+		return []error{ErrRateLimited}
+	}
+	return []error{ErrRateLimited, e.Err}
+}
+
+// RateLimiter tracks the requests and tokens a key used in the current minute, and
+// the cooldown its provider asked for, in memory. Providers consult it before each
+// request, so callers get a *RateLimitError with the wait instead of a failed call.
+// It is safe for concurrent use.
+type RateLimiter struct {
+	limits RateLimits
+	now    Clock
+
+	mu       sync.Mutex
+	window   time.Time // start of the minute counted
+	requests int
+	tokens   int
+	cooldown time.Time
+}
+
+// NewRateLimiter returns a limiter enforcing limits.
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	return &RateLimiter{limits: limits, now: time.Now}
+}
+
+// WithClock sets the clock the minute windows and cooldowns are measured with.
+func (l *RateLimiter) WithClock(clock Clock) *RateLimiter {
+	l.now = clock
+	return l
+}
+
+// Reserve counts a request, or returns a *RateLimitError if the key is cooling down
+// or used up its requests or tokens this minute.
+func (l *RateLimiter) Reserve() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	status := l.status()
+	if status.Limited() {
+		return &RateLimitError{Status: status}
+	}
+	l.requests++
+	return nil
+}
+
+// AddTokens counts tokens used by a response against the current minute.
+func (l *RateLimiter) AddTokens(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.status()
+	l.tokens += n
+}
+
+// Cooldown records a rate-limit response err from the provider, which asked to wait
+// for retryAfter, and returns the error to report for it. Without a wait, the cooldown
+// lasts until the current minute ends.
+func (l *RateLimiter) Cooldown(retryAfter time.Duration, err error) *RateLimitError {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	until := now.Add(retryAfter)
+	if retryAfter <= 0 {
+		until = now.Truncate(time.Minute).Add(time.Minute)
+	}
+	if until.After(l.cooldown) {
+		l.cooldown = until
+	}
+	return &RateLimitError{Status: l.status(), Err: err}
+}
+
+// Status returns the current state.
+func (l *RateLimiter) Status() LimiterStatus {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.status()
+}
+
+// status moves the counters to the current minute and returns the state. l.mu must be
+// held.
+func (l *RateLimiter) status() LimiterStatus {
+	now := l.now()
+	if window := now.Truncate(time.Minute); !window.Equal(l.window) {
+		l.window, l.requests, l.tokens = window, 0, 0
+	}
+
+	s := LimiterStatus{At: now, RemainingRequests: -1, RemainingTokens: -1, ResetAt: l.window.Add(time.Minute)}
+	if l.limits.RequestsPerMinute > 0 {
+		s.RemainingRequests = max(l.limits.RequestsPerMinute-l.requests, 0)
+	}
+	if l.limits.TokensPerMinute > 0 {
+		s.RemainingTokens = max(l.limits.TokensPerMinute-l.tokens, 0)
+	}
+	if l.cooldown.After(now) {
+		until := l.cooldown
+		s.CooldownUntil = &until
+	}
+	return s
+}
+
+// RateLimitReporter is implemented by providers that track rate limits, for status
+// endpoints and dashboards.
+type RateLimitReporter interface {
+	LimiterStatus() LimiterStatus
+}
@@ -0,0 +1,82 @@
+package ai
+
+import (
+	"context"
+	"errors"
+)
+
+var (
+	ErrRequestLogNotFound = errors.New("ai: request log not found")
+)
+
+// RequestLogReader reads request logs back.
+type RequestLogReader interface {
+	// GetRequestLog returns a request log by ID, or ErrRequestLogNotFound.
+	GetRequestLog(ctx context.Context, id string) (*RequestLog, error)
+}
+
+// ReplayStore is what Replay needs to reconstruct a request.
+type ReplayStore interface {
+	Store
+	RequestLogReader
+}
+
+// WithReplayOf marks requests made with ctx as replays of a request log. Stores record
+// it on the new request log so replays can be compared with the original.
+func WithReplayOf(ctx context.Context, requestLogID string) context.Context {
+	return context.WithValue(ctx, replayOfKey, requestLogID)
+}
+
+// ReplayOfFromContext returns the request log ID attached with WithReplayOf.
+func ReplayOfFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(replayOfKey).(string)
+	return id
+}
+
+// Replay re-executes a logged request against provider, which may use a different
+// model than the original. It rebuilds the session rules, the history as it was when
+// the request was made, the original prompt and its system addendum, and links the
+// new request log to the original with WithReplayOf. Nothing is added to the session.
+func Replay(ctx context.Context, store ReplayStore, provider Provider, requestLogID string) (*Result, error) {
+	log, err := store.GetRequestLog(ctx, requestLogID)
+	if err != nil {
+		return nil, err
+	}
+
+	session, err := store.GetSession(ctx, log.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	msgs, err := store.ListMessages(ctx, log.SessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx = WithReplayOf(WithSessionID(ctx, log.SessionID), log.ID)
+	ctx = WithSystemAddendum(ctx, log.SystemAddendum)
+	return provider.Send(ctx, session.Rules, historyAt(msgs, log), log.Prompt)
+}
+
+// historyAt returns the messages that preceded the request. Messages stored with the
+// request's ID mark its start; otherwise anything created after the log is dropped. A
+// trailing copy of the prompt is removed, since Send appends the prompt itself.
+func historyAt(msgs []Message, log *RequestLog) []Message {
+	end := len(msgs)
+	for i, m := range msgs {
+		if log.RequestID != "" && log.Prompt != "" && m.RequestID == log.RequestID {
+			end = i
+			break
+		}
+		if m.CreatedAt.After(log.CreatedAt) {
+			end = i
+			break
+		}
+	}
+
+	history := msgs[:end]
+	if n := len(history); n > 0 && log.Prompt != "" && history[n-1].Role == RoleUser && history[n-1].Content == log.Prompt {
+		history = history[:n-1]
+	}
+	return history
+}
@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// RequestIDHeader is the HTTP header providers send the request ID in.
+const RequestIDHeader = "X-Request-ID"
+
+// NewRequestID returns a random 128-bit request ID in hex.
+func NewRequestID() string {
+	var b [16]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// WithRequestID attaches a request ID used to correlate the request log, the
+// messages stored with ctx, outbound provider calls and returned errors.
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestIDFromContext returns the request ID attached with WithRequestID.
+func RequestIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// EnsureRequestID returns ctx with a request ID, generating one if none is attached.
+func EnsureRequestID(ctx context.Context) (context.Context, string) {
+	if id := RequestIDFromContext(ctx); id != "" {
+		return ctx, id
+	}
+	id := NewRequestID()
+	return WithRequestID(ctx, id), id
+}
+
+// RequestError annotates an error with the ID of the request that produced it.
+type RequestError struct {
+	RequestID string
+	Err       error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%v (request %s)", e.Err, e.RequestID)
+}
+
+func (e *RequestError) Unwrap() error { return e.Err }
@@ -0,0 +1,23 @@
+package ai
+
+import (
+	"errors"
+	"maps"
+)
+
+var (
+	ErrUnknownRole = errors.New("ai: unknown message role")
+)
+
+// RoleMap maps the roles of history messages to the roles of a provider's API, e.g.
+// RoleAssistant to Gemini's "model". Providers start from a default map of their own
+// and let callers add or replace entries, e.g. for roles of imported conversations.
+type RoleMap map[string]string
+
+// Merge returns a copy of m with the entries of over added or replaced.
+func (m RoleMap) Merge(over RoleMap) RoleMap {
+	out := make(RoleMap, len(m)+len(over))
+	maps.Copy(out, m)
+	maps.Copy(out, over)
+	return out
+}
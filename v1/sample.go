@@ -0,0 +1,145 @@
+package ai
+
+import (
+	"context"
+	"hash/fnv"
+	"regexp"
+	"time"
+)
+
+// Sample is an anonymized copy of a successful exchange, kept for offline prompt
+// review. It has no session or user ID, so reviewers can't trace it back to a learner.
+type Sample struct {
+	ID            string    `json:"id"`
+	TenantID      string    `json:"tenant_id,omitempty"`
+	PromptName    string    `json:"prompt_name,omitempty"`
+	PromptVersion int       `json:"prompt_version,omitempty"`
+	Model         string    `json:"model,omitempty"`
+	SystemPrompt  string    `json:"system_prompt"`
+	Prompt        string    `json:"prompt"`
+	Response      string    `json:"response"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// SampleFilter selects samples for ListSamples. Zero fields don't filter.
+type SampleFilter struct {
+	PromptName string
+	TenantID   string
+	Since      time.Time
+	Limit      int
+}
+
+// SampleStore is the optional store capability keeping samples.
+type SampleStore interface {
+	AddSample(ctx context.Context, s Sample) error
+	// ListSamples returns the samples matching f, newest first.
+	ListSamples(ctx context.Context, f SampleFilter) ([]Sample, error)
+	// DeleteSamplesBefore removes samples created before t and returns how many.
+	DeleteSamplesBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+// WithSamplingConsent records whether the user a request is made for agreed to their
+// exchanges being reviewed. A Sampler only samples requests that consented.
+func WithSamplingConsent(ctx context.Context, consent bool) context.Context {
+	return context.WithValue(ctx, samplingConsentKey, consent)
+}
+
+// SamplingConsentFromContext reports whether consent was given with WithSamplingConsent.
+func SamplingConsentFromContext(ctx context.Context) bool {
+	consent, _ := ctx.Value(samplingConsentKey).(bool)
+	return consent
+}
+
+var (
+	emailPattern = regexp.MustCompile(`[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`)
+	phonePattern = regexp.MustCompile(`\+?\d[\d\s().-]{7,}\d`)
+)
+
+// RedactPII replaces email addresses, and digit runs long enough to be phone or ID
+// numbers (9 digits or more, ignoring separators), in s with placeholders. It is the
+// default redaction of a Sampler; names and other identifiers need a redactor that
+// knows the domain.
+func RedactPII(s string) string {
+	s = emailPattern.ReplaceAllString(s, "[email]")
+	return phonePattern.ReplaceAllStringFunc(s, func(m string) string {
+		digits := 0
+		for _, r := range m {
+			if r >= '0' && r <= '9' {
+				digits++
+			}
+		}
+		if digits < 9 {
+			return m
+		}
+		return "[number]"
+	})
+}
+
+// Sampler copies a percentage of successful exchanges, redacted, into a SampleStore,
+// so prompts can be improved without querying production logs. Only requests made
+// with WithSamplingConsent(ctx, true) are sampled.
+type Sampler struct {
+	store  SampleStore
+	rate   float64
+	redact func(string) string
+	now    Clock
+	newID  IDGenerator
+}
+
+// NewSampler returns a sampler keeping rate (0-1) of consenting exchanges in store.
+func NewSampler(store SampleStore, rate float64) *Sampler {
+	return &Sampler{store: store, rate: rate, redact: RedactPII, now: time.Now, newID: NewRequestID}
+}
+
+// WithRedactor replaces RedactPII as the redaction applied to the system prompt,
+// prompt and response of every sample.
+func (s *Sampler) WithRedactor(redact func(string) string) *Sampler {
+	s.redact = redact
+	return s
+}
+
+// WithClock sets the clock that stamps samples.
+func (s *Sampler) WithClock(clock Clock) *Sampler {
+	s.now = clock
+	return s
+}
+
+// WithIDGenerator sets the generator of sample IDs. Defaults to NewRequestID.
+func (s *Sampler) WithIDGenerator(gen IDGenerator) *Sampler {
+	s.newID = gen
+	return s
+}
+
+// Sampled reports whether the request on ctx is sampled: it consented and falls within
+// the rate. The decision hashes the request ID, so samples are spread evenly and every
+// attempt of a request is decided the same way.
+func (s *Sampler) Sampled(ctx context.Context) bool {
+	if s == nil || s.rate <= 0 || !SamplingConsentFromContext(ctx) {
+		return false
+	}
+	if s.rate >= 1 {
+		return true
+	}
+	h := fnv.New64a()
+	h.Write([]byte(RequestIDFromContext(ctx)))
+	return float64(h.Sum64()%10000) < s.rate*10000
+}
+
+// Record stores sample, redacted and stamped, if the request on ctx is sampled.
+// Providers call it with a sample of each successful exchange.
+func (s *Sampler) Record(ctx context.Context, sample Sample) error {
+	if !s.Sampled(ctx) {
+		return nil
+	}
+	sample.ID = s.newID()
+	sample.CreatedAt = s.now()
+	if sample.TenantID == "" {
+		sample.TenantID = TenantIDFromContext(ctx)
+	}
+	if s.redact != nil {
+		sample.SystemPrompt = s.redact(sample.SystemPrompt)
+		sample.Prompt = s.redact(sample.Prompt)
+		sample.Response = s.redact(sample.Response)
+	}
+	return s.store.AddSample(ctx, sample)
+}
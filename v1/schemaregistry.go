@@ -0,0 +1,244 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrSchemaNotFound     = errors.New("ai: schema not found")
+	ErrIncompatibleSchema = errors.New("ai: incompatible schema change")
+)
+
+// Schema is one version of a named OutputSchema in the schema registry.
+type Schema struct {
+	Name       string    `json:"name"`
+	Version    int       `json:"version"`
+	Definition string    `json:"definition"` // JSON Schema
+	Breaking   bool      `json:"breaking"`   // registered despite breaking changes from the previous version
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// SchemaRef selects a registry schema as the OutputSchema of Rules. Version 0 means the
+// latest version.
+type SchemaRef struct {
+	Name    string `json:"name"`
+	Version int    `json:"version,omitempty"`
+}
+
+// SchemaStore is the optional store capability backing the schema registry.
+type SchemaStore interface {
+	// SaveSchema stores s as the next version of its name and returns it with Version
+	// and CreatedAt set. It doesn't check compatibility; use RegisterSchema.
+	SaveSchema(ctx context.Context, s Schema) (*Schema, error)
+	// GetSchema returns a version of a schema, or the latest when version is 0.
+	// Returns ErrSchemaNotFound if there is none.
+	GetSchema(ctx context.Context, name string, version int) (*Schema, error)
+	// ListSchemas returns every version of a schema, newest first.
+	ListSchemas(ctx context.Context, name string) ([]Schema, error)
+}
+
+// RegisterSchema checks definition against the latest version of name and stores it as
+// the next version. It returns the changes found. If any is breaking, it returns an
+// error wrapping ErrIncompatibleSchema and stores nothing, unless allowBreaking is set,
+// in which case the version is stored with Breaking set.
+func RegisterSchema(ctx context.Context, store SchemaStore, name, definition string, allowBreaking bool) (*Schema, []SchemaChange, error) {
+	if !json.Valid([]byte(definition)) {
+		return nil, nil, fmt.Errorf("ai: schema %s: definition is not valid JSON", name)
+	}
+
+	var changes []SchemaChange
+	latest, err := store.GetSchema(ctx, name, 0)
+	switch {
+	case errors.Is(err, ErrSchemaNotFound):
+	case err != nil:
+		return nil, nil, err
+	default:
+		if changes, err = CompareSchemas(latest.Definition, definition); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	breaking := slices.ContainsFunc(changes, func(c SchemaChange) bool { return c.Breaking })
+	if breaking && !allowBreaking {
+		return nil, changes, fmt.Errorf("%w: schema %s v%d: %s", ErrIncompatibleSchema, name, latest.Version, breakingSummary(changes))
+	}
+	saved, err := store.SaveSchema(ctx, Schema{Name: name, Definition: definition, Breaking: breaking})
+	if err != nil {
+		return nil, changes, err
+	}
+	return saved, changes, nil
+}
+
+// ResolveSchema looks up ref in store.
+func ResolveSchema(ctx context.Context, store SchemaStore, ref SchemaRef) (*Schema, error) {
+	return store.GetSchema(ctx, ref.Name, ref.Version)
+}
+
+// Schema change kinds reported by CompareSchemas.
+const (
+	SchemaPropertyAdded    = "property_added"
+	SchemaPropertyRemoved  = "property_removed"
+	SchemaRequiredAdded    = "required_added"
+	SchemaRequiredRemoved  = "required_removed"
+	SchemaTypeChanged      = "type_changed"
+	SchemaEnumChanged      = "enum_changed"
+	SchemaAdditionalClosed = "additional_properties_closed"
+)
+
+// SchemaChange is one difference between two versions of a schema. Path locates it,
+// e.g. "fields[].label", and is empty for the root.
+type SchemaChange struct {
+	Path     string `json:"path"`
+	Kind     string `json:"kind"`
+	Breaking bool   `json:"breaking"`
+	Detail   string `json:"detail,omitempty"`
+}
+
+// CompareSchemas reports the structural changes from the JSON Schema old to new:
+// properties, required properties, types, enums, additionalProperties and array items.
+// Only additions are compatible: a new optional property, or opening a closed object.
+// Anything that could make responses valid under old invalid under new, or surprise
+// code written for old, is breaking: removing or requiring a property, no longer
+// requiring one, changing a type, changing an enum or closing an object. Keywords such
+// as descriptions and length limits aren't compared.
+func CompareSchemas(old, new string) ([]SchemaChange, error) {
+	var a, b map[string]any
+	if err := json.Unmarshal([]byte(old), &a); err != nil {
+		return nil, fmt.Errorf("ai: compare schemas: old schema: %w", err)
+	}
+	if err := json.Unmarshal([]byte(new), &b); err != nil {
+		return nil, fmt.Errorf("ai: compare schemas: new schema: %w", err)
+	}
+	var changes []SchemaChange
+	compareSchema("", a, b, &changes)
+	return changes, nil
+}
+
+func compareSchema(path string, a, b map[string]any, changes *[]SchemaChange) {
+	add := func(at, kind string, breaking bool, detail string) {
+		*changes = append(*changes, SchemaChange{Path: at, Kind: kind, Breaking: breaking, Detail: detail})
+	}
+
+	if ta, tb := schemaTypes(a["type"]), schemaTypes(b["type"]); !slices.Equal(ta, tb) {
+		add(path, SchemaTypeChanged, true, fmt.Sprintf("%s to %s", strings.Join(ta, "|"), strings.Join(tb, "|")))
+	}
+	if ea, eb := schemaEnum(a["enum"]), schemaEnum(b["enum"]); !slices.Equal(ea, eb) {
+		add(path, SchemaEnumChanged, true, fmt.Sprintf("%s to %s", strings.Join(ea, ", "), strings.Join(eb, ", ")))
+	}
+	if a["additionalProperties"] != false && b["additionalProperties"] == false {
+		add(path, SchemaAdditionalClosed, true, "")
+	}
+
+	propsA, _ := a["properties"].(map[string]any)
+	propsB, _ := b["properties"].(map[string]any)
+	reqA, reqB := schemaRequired(a), schemaRequired(b)
+	for _, name := range sortedKeys(propsA, propsB) {
+		at := name
+		if path != "" {
+			at = path + "." + name
+		}
+		pa, inA := propsA[name].(map[string]any)
+		pb, inB := propsB[name].(map[string]any)
+		switch {
+		case !inA:
+			if reqB[name] {
+				add(at, SchemaRequiredAdded, true, "new required property")
+			} else {
+				add(at, SchemaPropertyAdded, false, "")
+			}
+		case !inB:
+			add(at, SchemaPropertyRemoved, true, "")
+		default:
+			if reqB[name] && !reqA[name] {
+				add(at, SchemaRequiredAdded, true, "")
+			} else if reqA[name] && !reqB[name] {
+				add(at, SchemaRequiredRemoved, true, "")
+			}
+			compareSchema(at, pa, pb, changes)
+		}
+	}
+
+	if ia, ok := a["items"].(map[string]any); ok {
+		if ib, ok := b["items"].(map[string]any); ok {
+			compareSchema(path+"[]", ia, ib, changes)
+		}
+	}
+}
+
+// schemaTypes returns the sorted types of a "type" keyword, a string or an array.
+func schemaTypes(v any) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var out []string
+		for _, s := range t {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		slices.Sort(out)
+		return out
+	}
+	return nil
+}
+
+// schemaEnum returns the sorted JSON encodings of an "enum" keyword's values.
+func schemaEnum(v any) []string {
+	values, _ := v.([]any)
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		data, _ := json.Marshal(value)
+		out = append(out, string(data))
+	}
+	slices.Sort(out)
+	return out
+}
+
+func schemaRequired(s map[string]any) map[string]bool {
+	out := map[string]bool{}
+	list, _ := s["required"].([]any)
+	for _, name := range list {
+		if name, ok := name.(string); ok {
+			out[name] = true
+		}
+	}
+	return out
+}
+
+// sortedKeys returns the keys of a and b, sorted.
+func sortedKeys(a, b map[string]any) []string {
+	var keys []string
+	for k := range a {
+		keys = append(keys, k)
+	}
+	for k := range b {
+		if _, ok := a[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// breakingSummary lists the breaking changes, for errors.
+func breakingSummary(changes []SchemaChange) string {
+	var parts []string
+	for _, c := range changes {
+		if !c.Breaking {
+			continue
+		}
+		part := c.Kind
+		if c.Path != "" {
+			part = c.Path + ": " + part
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, "; ")
+}
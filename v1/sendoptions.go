@@ -0,0 +1,75 @@
+package ai
+
+import (
+	"context"
+)
+
+// SendOptions are request settings for Send and Stream beyond a session's rules. Unset
+// fields leave the provider's configuration alone, so options layer: the options of a
+// call override its session's defaults field by field.
+type SendOptions struct {
+	Model       string        `json:"model,omitempty"`       // model to call instead of the provider's
+	Temperature *float64      `json:"temperature,omitempty"` // sampling temperature
+	Policy      *OutputPolicy `json:"policy,omitempty"`      // output checks, replacing Rules.Policy
+	// Transforms names history transforms registered with the provider, applied after
+	// its own, in order.
+	Transforms []string `json:"transforms,omitempty"`
+}
+
+// Merge returns o with every field set in over replaced by over's.
+func (o SendOptions) Merge(over SendOptions) SendOptions {
+	if over.Model != "" {
+		o.Model = over.Model
+	}
+	if over.Temperature != nil {
+		o.Temperature = over.Temperature
+	}
+	if over.Policy != nil {
+		o.Policy = over.Policy
+	}
+	if over.Transforms != nil {
+		o.Transforms = over.Transforms
+	}
+	return o
+}
+
+// Temperature returns a pointer to t, for SendOptions.Temperature.
+func Temperature(t float64) *float64 {
+	return &t
+}
+
+// WithSendOptions attaches options to requests made with ctx, merged over any
+// attached before.
+func WithSendOptions(ctx context.Context, opts SendOptions) context.Context {
+	return context.WithValue(ctx, sendOptionsKey, SendOptionsFromContext(ctx).Merge(opts))
+}
+
+// SendOptionsFromContext returns the options attached with WithSendOptions.
+func SendOptionsFromContext(ctx context.Context) SendOptions {
+	opts, _ := ctx.Value(sendOptionsKey).(SendOptions)
+	return opts
+}
+
+// SessionDefaultsStore is the optional store capability keeping default SendOptions
+// per session, so callers don't repeat them on every call.
+type SessionDefaultsStore interface {
+	// SetSessionDefaults replaces the defaults of a session; zero options clear them.
+	// It returns ErrSessionNotFound for an unknown session.
+	SetSessionDefaults(ctx context.Context, sessionID string, opts SendOptions) error
+	// SessionDefaults returns the defaults of a session, or zero options if it has none.
+	SessionDefaults(ctx context.Context, sessionID string) (SendOptions, error)
+}
+
+// ResolveSendOptions returns the options of a call of sessionID: the session's
+// defaults from store, if any, with the options attached to ctx merged over them.
+func ResolveSendOptions(ctx context.Context, store SessionDefaultsStore, sessionID string) (SendOptions, error) {
+	opts := SendOptionsFromContext(ctx)
+	if store == nil || sessionID == "" {
+		return opts, nil
+	}
+	defaults, err := store.SessionDefaults(ctx, sessionID)
+	if err != nil {
+		return SendOptions{}, err
+	}
+	return defaults.Merge(opts), nil
+}
@@ -0,0 +1,445 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// defaultWriteQueue is the number of writes a session can have pending before writers
+// block.
+const defaultWriteQueue = 64
+
+const (
+	// defaultLogTTL is how long the session of a pending request log is remembered
+	// after its last write.
+	defaultLogTTL = time.Hour
+
+	// logGrace is how long the session of a finished request log is remembered, for
+	// writes that follow the final update, such as cancelled-stream counts. Expired
+	// entries are dropped at most this often.
+	logGrace = time.Minute
+)
+
+// SessionWriter is a Store and CheckpointStore that serializes the writes of each
+// session through a queue, so a busy stream doesn't hammer the database with
+// concurrent tiny updates:
+//
+//   - Writes of a session run one at a time, in the order they were made. Writes of
+//     different sessions run concurrently.
+//   - SaveCheckpoint and UpdateRequestLog return once queued. A queued write made
+//     obsolete by a newer one for the same checkpoint or request log is replaced by it,
+//     so a stream checkpointing faster than the store keeps up costs one write per
+//     round trip rather than one per checkpoint.
+//   - Other writes wait for their turn and return the store's result.
+//   - Reads of a session first wait for its queued writes, so they see them.
+//
+// A session's queue holds a bounded number of writes; once it is full, writers block
+// until there is room or their context is done, pushing back on the producer.
+// Errors of queued writes go to the OnError handler. Call Close before exiting to
+// finish queued writes. It is safe for concurrent use.
+//
+// Prompt attributions, retry turns and cancelled-stream counts are queued like
+// UpdateRequestLog when the wrapped store records them. Writes of a request log follow
+// the writes of its session while the log is remembered: for an hour after its last
+// update while it is pending (see WithLogTTL), and a minute once it has finished. Later
+// writes of the log are still made, without that ordering.
+type SessionWriter struct {
+	store       Store
+	checkpoints CheckpointStore
+	limit       int
+	logTTL      time.Duration
+	onError     func(sessionID string, err error)
+	now         Clock
+
+	mu          sync.Mutex
+	queues      map[string]*writeQueue
+	logSessions map[string]logSession // by request log ID
+	swept       time.Time             // when expired logSessions were last dropped
+	cpSessions  map[string]string     // checkpoint ID to session ID, until completed
+}
+
+// logSession is the session of a request log, remembered until expires.
+type logSession struct {
+	sessionID string
+	expires   time.Time
+}
+
+// writeQueue is the pending writes of one session. Its worker goroutine runs while it
+// isn't empty.
+type writeQueue struct {
+	ops   []*writeOp
+	space chan struct{} // closed and replaced each time an op is taken
+}
+
+type writeOp struct {
+	key  string // coalescing key; queued ops with the same key are replaced
+	run  func() error
+	done chan error // nil for ops that don't wait
+}
+
+// NewSessionWriter returns a writer in front of store. If store is a CheckpointStore,
+// checkpoints are written to it; otherwise set one with WithCheckpoints.
+func NewSessionWriter(store Store) *SessionWriter {
+	w := &SessionWriter{
+		store:       store,
+		limit:       defaultWriteQueue,
+		logTTL:      defaultLogTTL,
+		now:         time.Now,
+		queues:      make(map[string]*writeQueue),
+		logSessions: make(map[string]logSession),
+		cpSessions:  make(map[string]string),
+	}
+	w.checkpoints, _ = store.(CheckpointStore)
+	return w
+}
+
+// WithCheckpoints sets the store checkpoints are written to.
+func (w *SessionWriter) WithCheckpoints(store CheckpointStore) *SessionWriter {
+	w.checkpoints = store
+	return w
+}
+
+// WithQueueSize sets how many writes a session can have pending before writers block,
+// 64 by default.
+func (w *SessionWriter) WithQueueSize(n int) *SessionWriter {
+	w.limit = max(n, 1)
+	return w
+}
+
+// WithLogTTL sets how long the session of a pending request log is remembered after its
+// last write, an hour by default. Longer keeps the writes of slow requests in order;
+// shorter bounds the memory of requests that never finish.
+func (w *SessionWriter) WithLogTTL(d time.Duration) *SessionWriter {
+	w.logTTL = d
+	return w
+}
+
+// OnError sets the handler of errors from writes that returned once queued. By
+// default they are dropped, as providers drop log and checkpoint write errors.
+func (w *SessionWriter) OnError(fn func(sessionID string, err error)) *SessionWriter {
+	w.onError = fn
+	return w
+}
+
+// enqueue adds op to the queue of sessionID, replacing a queued op with the same key,
+// and starts the queue's worker if it is idle. It blocks while the queue is full.
+func (w *SessionWriter) enqueue(ctx context.Context, sessionID string, op *writeOp) error {
+	w.mu.Lock()
+	for {
+		q := w.queues[sessionID]
+		if q == nil {
+			q = &writeQueue{space: make(chan struct{})}
+			w.queues[sessionID] = q
+			go w.work(sessionID, q)
+		}
+		if op.key != "" {
+			for i, queued := range q.ops {
+				if queued.key == op.key && queued.done == nil && op.done == nil {
+					q.ops[i] = op
+					w.mu.Unlock()
+					return nil
+				}
+			}
+		}
+		if len(q.ops) < w.limit {
+			q.ops = append(q.ops, op)
+			w.mu.Unlock()
+			return nil
+		}
+
+		space := q.space
+		w.mu.Unlock()
+		select {
+		case <-space:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		w.mu.Lock()
+	}
+}
+
+// work runs the ops of q in order until it is empty, then removes it.
+func (w *SessionWriter) work(sessionID string, q *writeQueue) {
+	for {
+		w.mu.Lock()
+		if len(q.ops) == 0 {
+			delete(w.queues, sessionID)
+			w.mu.Unlock()
+			return
+		}
+		op := q.ops[0]
+		q.ops[0] = nil
+		q.ops = q.ops[1:]
+		close(q.space)
+		q.space = make(chan struct{})
+		w.mu.Unlock()
+
+		err := op.run()
+		switch {
+		case op.done != nil:
+			op.done <- err
+		case err != nil && w.onError != nil:
+			w.onError(sessionID, err)
+		}
+	}
+}
+
+// do queues run for sessionID and waits for its result.
+func (w *SessionWriter) do(ctx context.Context, sessionID string, run func() error) error {
+	op := &writeOp{run: run, done: make(chan error, 1)}
+	if err := w.enqueue(ctx, sessionID, op); err != nil {
+		return err
+	}
+	select {
+	case err := <-op.done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Flush waits until the writes of sessionID queued before the call are done.
+func (w *SessionWriter) Flush(ctx context.Context, sessionID string) error {
+	return w.do(ctx, sessionID, func() error { return nil })
+}
+
+// Close waits until every queued write is done, or ctx is done.
+func (w *SessionWriter) Close(ctx context.Context) error {
+	w.mu.Lock()
+	sessions := make([]string, 0, len(w.queues))
+	for id := range w.queues {
+		sessions = append(sessions, id)
+	}
+	w.mu.Unlock()
+
+	var errs []error
+	for _, id := range sessions {
+		if err := w.Flush(ctx, id); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// CreateSchema creates the wrapped store's schema.
+func (w *SessionWriter) CreateSchema(ctx context.Context) error {
+	return w.store.CreateSchema(ctx)
+}
+
+// CreateSession creates a session in the wrapped store. Rules that CheckRules rejects
+// are not passed on, and its error is returned.
+func (w *SessionWriter) CreateSession(ctx context.Context, rules Rules) (*Session, error) {
+	if err := CheckRules(nil, rules); err != nil {
+		return nil, fmt.Errorf("ai: create session: %w", err)
+	}
+	return w.store.CreateSession(ctx, rules)
+}
+
+// GetSession returns a session from the wrapped store.
+func (w *SessionWriter) GetSession(ctx context.Context, sessionID string) (*Session, error) {
+	return w.store.GetSession(ctx, sessionID)
+}
+
+// DeleteSession deletes a session after its queued writes. It fails with
+// ErrUnsupported when the wrapped store isn't a SessionDeleter.
+func (w *SessionWriter) DeleteSession(ctx context.Context, sessionID string) error {
+	return w.do(ctx, sessionID, func() error {
+		return DeleteSession(context.WithoutCancel(ctx), w.store, sessionID)
+	})
+}
+
+// AddMessage adds a message after the session's queued writes.
+func (w *SessionWriter) AddMessage(ctx context.Context, sessionID string, role string, content string, usage *Usage) (*Message, error) {
+	var msg *Message
+	err := w.do(ctx, sessionID, func() error {
+		var err error
+		msg, err = w.store.AddMessage(context.WithoutCancel(ctx), sessionID, role, content, usage)
+		return err
+	})
+	return msg, err
+}
+
+// ListMessages lists the messages of a session once its queued writes are done.
+func (w *SessionWriter) ListMessages(ctx context.Context, sessionID string) ([]Message, error) {
+	if err := w.Flush(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	return w.store.ListMessages(ctx, sessionID)
+}
+
+// AddEvent adds an event after the session's queued writes. It fails with
+// ErrUnsupported when the wrapped store isn't an EventStore.
+func (w *SessionWriter) AddEvent(ctx context.Context, sessionID string, eventType string, payload string) (*Message, error) {
+	var msg *Message
+	err := w.do(ctx, sessionID, func() error {
+		var err error
+		msg, err = AddEvent(context.WithoutCancel(ctx), w.store, sessionID, eventType, payload)
+		return err
+	})
+	return msg, err
+}
+
+// AddRequestLog adds a request log after the queued writes of its session.
+func (w *SessionWriter) AddRequestLog(ctx context.Context, log RequestLog) (*RequestLog, error) {
+	var added *RequestLog
+	err := w.do(ctx, log.SessionID, func() error {
+		var err error
+		added, err = w.store.AddRequestLog(context.WithoutCancel(ctx), log)
+		return err
+	})
+	if err == nil {
+		w.mu.Lock()
+		w.rememberLog(added.ID, log.SessionID, w.logTTL)
+		w.mu.Unlock()
+	}
+	return added, err
+}
+
+// UpdateRequestLog queues an update of a request log and returns. A queued update of
+// the same log that hasn't started is replaced, since every update sets the whole row.
+func (w *SessionWriter) UpdateRequestLog(ctx context.Context, id string, response string, status string, failReason string, errorMsg string, retryCount int, usage *Usage) error {
+	ttl := w.logTTL
+	if status != StatusPending {
+		ttl = logGrace
+	}
+	w.mu.Lock()
+	sessionID := w.rememberLog(id, "", ttl)
+	w.mu.Unlock()
+
+	if usage != nil {
+		u := *usage
+		usage = &u
+	}
+	writeCtx := context.WithoutCancel(ctx)
+	return w.enqueue(ctx, sessionID, &writeOp{
+		key: "request_log:" + id,
+		run: func() error {
+			return w.store.UpdateRequestLog(writeCtx, id, response, status, failReason, errorMsg, retryCount, usage)
+		},
+	})
+}
+
+// SetPromptAttribution queues the attribution after the queued writes of the request
+// log's session and returns, when the wrapped store implements PromptAttributionStore.
+// It does nothing otherwise.
+func (w *SessionWriter) SetPromptAttribution(ctx context.Context, requestLogID string, a PromptAttribution) error {
+	store, ok := w.store.(PromptAttributionStore)
+	if !ok {
+		return nil
+	}
+	return w.enqueueLog(ctx, requestLogID, func(ctx context.Context) error {
+		return store.SetPromptAttribution(ctx, requestLogID, a)
+	})
+}
+
+// SetCancelledAfterTokens queues the count after the queued writes of the request log's
+// session and returns, when the wrapped store implements CancelledStreamStore. It does
+// nothing otherwise.
+func (w *SessionWriter) SetCancelledAfterTokens(ctx context.Context, requestLogID string, tokens int) error {
+	store, ok := w.store.(CancelledStreamStore)
+	if !ok {
+		return nil
+	}
+	return w.enqueueLog(ctx, requestLogID, func(ctx context.Context) error {
+		return store.SetCancelledAfterTokens(ctx, requestLogID, tokens)
+	})
+}
+
+// AddRetryTurn queues the turn after the queued writes of the request log's session and
+// returns, when the wrapped store implements RetryTranscriptStore. It does nothing
+// otherwise.
+func (w *SessionWriter) AddRetryTurn(ctx context.Context, requestLogID string, turn RetryTurn) error {
+	store, ok := w.store.(RetryTranscriptStore)
+	if !ok {
+		return nil
+	}
+	return w.enqueueLog(ctx, requestLogID, func(ctx context.Context) error {
+		return store.AddRetryTurn(ctx, requestLogID, turn)
+	})
+}
+
+// enqueueLog queues write after the queued writes of the session of request log id.
+func (w *SessionWriter) enqueueLog(ctx context.Context, id string, write func(ctx context.Context) error) error {
+	w.mu.Lock()
+	sessionID := w.logSessions[id].sessionID
+	w.mu.Unlock()
+
+	writeCtx := context.WithoutCancel(ctx)
+	return w.enqueue(ctx, sessionID, &writeOp{
+		run: func() error { return write(writeCtx) },
+	})
+}
+
+// rememberLog keeps the session of request log id for ttl from now and returns it. An
+// empty sessionID keeps the one already remembered, if any. It drops expired entries at
+// most once per logGrace. w.mu must be held.
+func (w *SessionWriter) rememberLog(id, sessionID string, ttl time.Duration) string {
+	now := w.now()
+	if now.Sub(w.swept) >= logGrace {
+		for logID, e := range w.logSessions {
+			if now.After(e.expires) {
+				delete(w.logSessions, logID)
+			}
+		}
+		w.swept = now
+	}
+
+	e, ok := w.logSessions[id]
+	if sessionID != "" {
+		e.sessionID = sessionID
+	} else if !ok {
+		return ""
+	}
+	e.expires = now.Add(ttl)
+	w.logSessions[id] = e
+	return e.sessionID
+}
+
+// SaveCheckpoint queues a save of cp and returns. A queued save of the same checkpoint
+// that hasn't started is replaced.
+func (w *SessionWriter) SaveCheckpoint(ctx context.Context, cp Checkpoint) error {
+	if w.checkpoints == nil {
+		return errors.New("ai: session writer has no checkpoint store")
+	}
+	w.mu.Lock()
+	w.cpSessions[cp.ID] = cp.SessionID
+	w.mu.Unlock()
+
+	writeCtx := context.WithoutCancel(ctx)
+	return w.enqueue(ctx, cp.SessionID, &writeOp{
+		key: "checkpoint:" + cp.ID,
+		run: func() error {
+			return w.checkpoints.SaveCheckpoint(writeCtx, cp)
+		},
+	})
+}
+
+// LatestIncompleteCheckpoint returns the newest incomplete checkpoint of a session
+// once its queued writes are done.
+func (w *SessionWriter) LatestIncompleteCheckpoint(ctx context.Context, sessionID string) (*Checkpoint, error) {
+	if w.checkpoints == nil {
+		return nil, errors.New("ai: session writer has no checkpoint store")
+	}
+	if err := w.Flush(ctx, sessionID); err != nil {
+		return nil, err
+	}
+	return w.checkpoints.LatestIncompleteCheckpoint(ctx, sessionID)
+}
+
+// CompleteCheckpoint marks a checkpoint complete after the queued saves of it.
+func (w *SessionWriter) CompleteCheckpoint(ctx context.Context, id string) error {
+	if w.checkpoints == nil {
+		return errors.New("ai: session writer has no checkpoint store")
+	}
+	w.mu.Lock()
+	sessionID := w.cpSessions[id]
+	delete(w.cpSessions, id)
+	w.mu.Unlock()
+
+	return w.do(ctx, sessionID, func() error {
+		return w.checkpoints.CompleteCheckpoint(context.WithoutCancel(ctx), id)
+	})
+}
@@ -0,0 +1,281 @@
+package ai
+
+import (
+	"context"
+	"hash/fnv"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// defaultShadowTimeout bounds a shadow call, which no caller waits for.
+const defaultShadowTimeout = 2 * time.Minute
+
+// defaultShadowInFlight is how many shadow calls may run at once before requests stop
+// being shadowed.
+const defaultShadowInFlight = 8
+
+// ShadowResult pairs the response a user got with the shadow provider's response to
+// the same request, for evaluating a model before switching to it. The two request IDs
+// link it to the request logs of both calls.
+type ShadowResult struct {
+	ID        string `json:"id"`
+	SessionID string `json:"session_id,omitempty"`
+	TenantID  string `json:"tenant_id,omitempty"`
+	Prompt    string `json:"prompt"`
+
+	PrimaryRequestID string `json:"primary_request_id,omitempty"`
+	PrimaryModel     string `json:"primary_model,omitempty"`
+	PrimaryContent   string `json:"primary_content"`
+	PrimaryUsage     Usage  `json:"primary_usage"`
+	PrimaryLatencyMs int64  `json:"primary_latency_ms"`
+
+	ShadowRequestID string `json:"shadow_request_id"`
+	ShadowModel     string `json:"shadow_model,omitempty"`
+	ShadowContent   string `json:"shadow_content"`
+	ShadowUsage     Usage  `json:"shadow_usage"`
+	ShadowLatencyMs int64  `json:"shadow_latency_ms"`
+	ShadowError     string `json:"shadow_error,omitempty"` // set when the shadow call failed
+
+	// Identical reports whether the contents are equal, compared with CanonicalJSON when
+	// both are JSON.
+	Identical bool      `json:"identical"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ShadowFilter selects shadow results for ListShadowResults. Zero fields don't filter.
+type ShadowFilter struct {
+	ShadowModel string
+	TenantID    string
+	Since       time.Time
+	Limit       int
+}
+
+// ShadowStore is the optional store capability keeping shadow results.
+type ShadowStore interface {
+	AddShadowResult(ctx context.Context, r ShadowResult) error
+	// ListShadowResults returns the results matching f, newest first.
+	ListShadowResults(ctx context.Context, f ShadowFilter) ([]ShadowResult, error)
+	// DeleteShadowResultsBefore removes results created before t and returns how many.
+	DeleteShadowResultsBefore(ctx context.Context, t time.Time) (int64, error)
+}
+
+// ShadowStats are the counters of a ShadowProvider.
+type ShadowStats struct {
+	Shadowed int64 `json:"shadowed"` // shadow calls made
+	Failed   int64 `json:"failed"`   // shadow calls that returned an error
+	Dropped  int64 `json:"dropped"`  // sampled requests not shadowed because too many calls were in flight
+}
+
+// ShadowProvider is a Provider answering from its primary provider while sending a
+// sampled share of successful requests to a shadow provider in the background, e.g. a
+// newer model being evaluated in production. The shadow call starts once the primary
+// provider has responded and runs with its own request ID, deadline and a context
+// that isn't cancelled with the request; its response, failure or latency never reach
+// the caller. Both responses are stored together as a ShadowResult.
+//
+// The shadow provider logs its own calls as configured, in the request's session. Give
+// it a separate store, or none, to keep its costs out of the session's. Dry runs,
+// cached responses and responses held for approval aren't shadowed. Streams aren't
+// shadowed either, as ShadowProvider only implements Send.
+type ShadowProvider struct {
+	primary  Provider
+	shadow   Provider
+	store    ShadowStore
+	rate     float64
+	timeout  time.Duration
+	sem      chan struct{}
+	onError  func(err error)
+	now      Clock
+	newID    IDGenerator
+	inFlight sync.WaitGroup
+
+	shadowed, failed, dropped atomic.Int64
+}
+
+// Shadow returns a provider answering with primary and shadowing every successful
+// request to shadow. Set the share of requests shadowed with WithRate.
+func Shadow(primary, shadow Provider) *ShadowProvider {
+	return &ShadowProvider{
+		primary: primary,
+		shadow:  shadow,
+		rate:    1,
+		timeout: defaultShadowTimeout,
+		sem:     make(chan struct{}, defaultShadowInFlight),
+		now:     time.Now,
+		newID:   NewRequestID,
+	}
+}
+
+// WithRate sets the share (0-1) of requests shadowed. The decision hashes the request
+// ID, so retries of a request are decided the same way.
+func (p *ShadowProvider) WithRate(rate float64) *ShadowProvider {
+	p.rate = rate
+	return p
+}
+
+// WithStore keeps a ShadowResult of every shadow call in store. Without one, shadow
+// calls are only visible in the shadow provider's request logs.
+func (p *ShadowProvider) WithStore(store ShadowStore) *ShadowProvider {
+	p.store = store
+	return p
+}
+
+// WithTimeout bounds each shadow call, 2 minutes by default.
+func (p *ShadowProvider) WithTimeout(timeout time.Duration) *ShadowProvider {
+	p.timeout = timeout
+	return p
+}
+
+// WithMaxInFlight sets how many shadow calls may run at once, 8 by default. Sampled
+// requests beyond it aren't shadowed and are counted in ShadowStats.Dropped, so a slow
+// shadow provider can't pile up calls.
+func (p *ShadowProvider) WithMaxInFlight(n int) *ShadowProvider {
+	p.sem = make(chan struct{}, max(n, 1))
+	return p
+}
+
+// WithErrorHandler sets a function called with the errors of storing shadow results.
+// Failed shadow calls are recorded in ShadowResult.ShadowError rather than reported.
+func (p *ShadowProvider) WithErrorHandler(fn func(err error)) *ShadowProvider {
+	p.onError = fn
+	return p
+}
+
+// WithClock sets the clock latencies are measured and results stamped with.
+func (p *ShadowProvider) WithClock(clock Clock) *ShadowProvider {
+	p.now = clock
+	return p
+}
+
+// WithIDGenerator sets the generator of shadow request and result IDs. Defaults to
+// NewRequestID.
+func (p *ShadowProvider) WithIDGenerator(gen IDGenerator) *ShadowProvider {
+	p.newID = gen
+	return p
+}
+
+// Send returns the primary provider's response and, if the request is sampled, starts
+// a shadow call in the background.
+func (p *ShadowProvider) Send(ctx context.Context, rules Rules, history []Message, prompt string) (*Result, error) {
+	start := p.now()
+	result, err := p.primary.Send(ctx, rules, history, prompt)
+	if err != nil || result.DryRun != nil || result.CacheHit != nil || result.ApprovalID != "" {
+		return result, err
+	}
+	if !p.sampled(ctx, result) {
+		return result, nil
+	}
+
+	select {
+	case p.sem <- struct{}{}:
+	default:
+		p.dropped.Add(1)
+		return result, nil
+	}
+	primary, history := *result, slices.Clone(history) // the caller may reuse history
+	latency := p.now().Sub(start)
+	p.inFlight.Add(1)
+	go func() {
+		defer func() { <-p.sem; p.inFlight.Done() }()
+		p.run(context.WithoutCancel(ctx), rules, history, prompt, &primary, latency)
+	}()
+	return result, nil
+}
+
+// Cancel stops the in-flight calls of requestID on the primary provider.
+func (p *ShadowProvider) Cancel(ctx context.Context, requestID string) error {
+	return CancelRequest(ctx, p.primary, requestID)
+}
+
+// Wait blocks until the shadow calls in flight have finished and been stored, e.g.
+// before shutting down.
+func (p *ShadowProvider) Wait() {
+	p.inFlight.Wait()
+}
+
+// Stats returns the counters so far.
+func (p *ShadowProvider) Stats() ShadowStats {
+	return ShadowStats{Shadowed: p.shadowed.Load(), Failed: p.failed.Load(), Dropped: p.dropped.Load()}
+}
+
+// sampled reports whether the request falls within the rate, hashing its request ID.
+func (p *ShadowProvider) sampled(ctx context.Context, result *Result) bool {
+	if p.rate <= 0 {
+		return false
+	}
+	if p.rate >= 1 {
+		return true
+	}
+	requestID := result.RequestID
+	if requestID == "" {
+		requestID = RequestIDFromContext(ctx)
+	}
+	h := fnv.New64a()
+	h.Write([]byte(requestID))
+	return float64(h.Sum64()%10000) < p.rate*10000
+}
+
+// run makes the shadow call and stores the result.
+func (p *ShadowProvider) run(ctx context.Context, rules Rules, history []Message, prompt string, primary *Result, latency time.Duration) {
+	shadowID := p.newID()
+	ctx = WithRequestID(ctx, shadowID)
+	sessionID := SessionIDFromContext(ctx)
+	if sessionID == "" && len(history) > 0 {
+		sessionID = history[0].SessionID
+	}
+
+	callCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	start := p.now()
+	shadow, err := p.shadow.Send(callCtx, rules, history, prompt)
+	shadowLatency := p.now().Sub(start)
+	cancel()
+
+	p.shadowed.Add(1)
+	r := ShadowResult{
+		ID:               p.newID(),
+		SessionID:        sessionID,
+		TenantID:         TenantIDFromContext(ctx),
+		Prompt:           prompt,
+		PrimaryRequestID: primary.RequestID,
+		PrimaryModel:     primary.Usage.Model,
+		PrimaryContent:   primary.Content,
+		PrimaryUsage:     primary.Usage,
+		PrimaryLatencyMs: latency.Milliseconds(),
+		ShadowRequestID:  shadowID,
+		ShadowLatencyMs:  shadowLatency.Milliseconds(),
+		CreatedAt:        p.now(),
+	}
+	if err != nil {
+		p.failed.Add(1)
+		r.ShadowError = err.Error()
+	} else {
+		r.ShadowModel, r.ShadowContent, r.ShadowUsage = shadow.Usage.Model, shadow.Content, shadow.Usage
+		if shadow.RequestID != "" {
+			r.ShadowRequestID = shadow.RequestID
+		}
+		r.Identical = sameContent(primary.Content, shadow.Content)
+	}
+
+	if p.store == nil {
+		return
+	}
+	if err := p.store.AddShadowResult(ctx, r); err != nil && p.onError != nil {
+		p.onError(err)
+	}
+}
+
+// sameContent reports whether a and b are equal, or equal in canonical form when both
+// are JSON documents.
+func sameContent(a, b string) bool {
+	if a == b {
+		return true
+	}
+	ca, err := CanonicalJSON(a)
+	if err != nil {
+		return false
+	}
+	cb, err := CanonicalJSON(b)
+	return err == nil && ca == cb
+}
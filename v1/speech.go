@@ -0,0 +1,104 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+var (
+	ErrAudioNotFound = errors.New("ai: audio not found")
+)
+
+// Audio is an encoded audio clip, e.g. a recorded voice turn or synthesized speech.
+type Audio struct {
+	Data     []byte `json:"-"`
+	MIMEType string `json:"mime_type"` // e.g. "audio/webm" or "audio/L16;codec=pcm;rate=24000"
+}
+
+// Transcript is the text of an audio clip.
+type Transcript struct {
+	Text  string `json:"text"`
+	Usage Usage  `json:"usage"`
+}
+
+// Speech is text rendered as audio.
+type Speech struct {
+	Audio Audio `json:"audio"`
+	Usage Usage `json:"usage"`
+}
+
+// Transcriber is implemented by providers that can turn speech into text.
+type Transcriber interface {
+	Transcribe(ctx context.Context, audio Audio) (*Transcript, error)
+}
+
+// Synthesizer is implemented by providers that can turn text into speech. voice names
+// one of the provider's voices; "" selects its default.
+type Synthesizer interface {
+	Synthesize(ctx context.Context, text string, voice string) (*Speech, error)
+}
+
+// MessageAudio is the audio of a voice turn. The message's content is its transcript,
+// so history and search keep working on text.
+type MessageAudio struct {
+	MessageID string    `json:"message_id"`
+	MIMEType  string    `json:"mime_type"`
+	Size      int       `json:"size"`               // bytes
+	SHA256    string    `json:"sha256"`             // hex digest of the audio data
+	BlobKey   string    `json:"blob_key,omitempty"` // set when the data is in the store's blob store
+	Voice     string    `json:"voice,omitempty"`    // synthesized speech only
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AudioStore is the optional store capability keeping the audio of voice turns linked
+// to their messages.
+type AudioStore interface {
+	// AddMessageAudio attaches audio to a message, replacing any audio it had. voice is
+	// recorded for synthesized speech. It returns ErrMessageNotFound for an unknown
+	// message.
+	AddMessageAudio(ctx context.Context, messageID string, audio Audio, voice string) (*MessageAudio, error)
+	// GetMessageAudio returns the audio metadata of a message, or ErrAudioNotFound.
+	GetMessageAudio(ctx context.Context, messageID string) (*MessageAudio, error)
+	// GetMessageAudioData returns the audio of a message, or ErrAudioNotFound.
+	GetMessageAudioData(ctx context.Context, messageID string) (*Audio, error)
+}
+
+// AddVoiceTurn transcribes audio and stores the transcript as a user message of
+// sessionID. If the store is an AudioStore, the audio is attached to the message.
+func AddVoiceTurn(ctx context.Context, store Store, transcriber Transcriber, sessionID string, audio Audio) (*Message, *Transcript, error) {
+	transcript, err := transcriber.Transcribe(ctx, audio)
+	if err != nil {
+		return nil, nil, err
+	}
+	msg, err := store.AddMessage(ctx, sessionID, RoleUser, transcript.Text, nil)
+	if err != nil {
+		return nil, transcript, err
+	}
+	if as, ok := store.(AudioStore); ok {
+		if _, err := as.AddMessageAudio(ctx, msg.ID, audio, ""); err != nil {
+			return msg, transcript, err
+		}
+	}
+	return msg, transcript, nil
+}
+
+// AddSpokenReply synthesizes result's content in voice and stores result as an
+// assistant message of sessionID with AddResultMessage. If the store is an
+// AudioStore, the speech is attached to the message.
+func AddSpokenReply(ctx context.Context, store Store, synthesizer Synthesizer, sessionID string, result *Result, voice string) (*Message, *Speech, error) {
+	speech, err := synthesizer.Synthesize(ctx, result.Content, voice)
+	if err != nil {
+		return nil, nil, err
+	}
+	msg, err := AddResultMessage(ctx, store, sessionID, result)
+	if err != nil {
+		return msg, speech, err
+	}
+	if as, ok := store.(AudioStore); ok {
+		if _, err := as.AddMessageAudio(ctx, msg.ID, speech.Audio, voice); err != nil {
+			return msg, speech, err
+		}
+	}
+	return msg, speech, nil
+}
@@ -0,0 +1,32 @@
+package ai
+
+import (
+	"context"
+	"time"
+)
+
+// SessionStats summarizes a session for display.
+type SessionStats struct {
+	SessionID      string         `json:"session_id"`
+	Messages       int            `json:"messages"` // conversation turns, excluding events
+	Events         int            `json:"events"`
+	MessagesByRole map[string]int `json:"messages_by_role"`
+	TokensByRole   map[string]int `json:"tokens_by_role"` // total tokens of the messages of each role
+	TotalTokens    int            `json:"total_tokens"`
+
+	// From the request logs: every attempt counts, so Cost includes retries and failures.
+	Requests       int     `json:"requests"`
+	Retries        int     `json:"retries"`
+	FailedRequests int     `json:"failed_requests"`
+	Cost           float64 `json:"cost"`
+
+	CreatedAt     time.Time `json:"created_at"`
+	FirstActivity time.Time `json:"first_activity"` // first message; zero without messages
+	LastActivity  time.Time `json:"last_activity"`  // latest message or request log update
+}
+
+// SessionStatsStore is the optional store capability for session summaries.
+type SessionStatsStore interface {
+	// SessionStats returns the statistics of a session, or ErrSessionNotFound.
+	SessionStats(ctx context.Context, sessionID string) (*SessionStats, error)
+}
@@ -3,10 +3,21 @@ package ai
 import (
 	"context"
 	"errors"
+	"fmt"
 )
 
 var (
 	ErrSessionNotFound = errors.New("ai: session not found")
+	ErrSchemaMismatch  = errors.New("ai: schema mismatch")
+
+	// ErrDuplicateSeq is returned when a message is written with a seq already used in
+	// its session, e.g. by an import that overlaps existing history.
+	ErrDuplicateSeq = errors.New("ai: duplicate message seq")
+
+	// ErrStoreUnavailable is returned when the store can't be reached: a refused or
+	// dropped connection, or a database that is shutting down or out of connections.
+	// The underlying driver error stays in the chain.
+	ErrStoreUnavailable = errors.New("ai: store unavailable")
 )
 
 // Store defines the contract for persisting sessions and messages.
@@ -23,6 +34,62 @@ type Store interface {
 	ListMessages(ctx context.Context, sessionID string) ([]Message, error)
 
 	// Request Logs
+	RequestLogger
+}
+
+// RequestLogger receives request-log writes. Every Store is one; so are sinks that only
+// forward logs elsewhere, such as an analytics pipeline.
+type RequestLogger interface {
+	// AddRequestLog generates an ID unless log.ID is set, and keeps a preset CreatedAt.
 	AddRequestLog(ctx context.Context, log RequestLog) (*RequestLog, error)
 	UpdateRequestLog(ctx context.Context, id string, response string, status string, failReason string, errorMsg string, retryCount int, usage *Usage) error
 }
+
+// SessionDeleter is the optional store capability for deleting sessions.
+type SessionDeleter interface {
+	// DeleteSession deletes a session with its messages and request logs. Returns
+	// ErrSessionNotFound if the session doesn't exist.
+	DeleteSession(ctx context.Context, sessionID string) error
+}
+
+// DeleteSession deletes a session with store's DeleteSession, and fails with
+// ErrUnsupported when store isn't a SessionDeleter.
+func DeleteSession(ctx context.Context, store Store, sessionID string) error {
+	deleter, ok := store.(SessionDeleter)
+	if !ok {
+		return fmt.Errorf("%w: store can't delete sessions", ErrUnsupported)
+	}
+	return deleter.DeleteSession(ctx, sessionID)
+}
+
+// EventStore is the optional store capability for recording events in message history.
+type EventStore interface {
+	// AddEvent appends an event with the next seq of the session. Returns
+	// ErrSessionNotFound if the session doesn't exist.
+	AddEvent(ctx context.Context, sessionID string, eventType string, payload string) (*Message, error)
+}
+
+// AddEvent appends an event with store's AddEvent, and fails with ErrUnsupported when
+// store isn't an EventStore.
+func AddEvent(ctx context.Context, store Store, sessionID string, eventType string, payload string) (*Message, error) {
+	events, ok := store.(EventStore)
+	if !ok {
+		return nil, fmt.Errorf("%w: store can't record events", ErrUnsupported)
+	}
+	return events.AddEvent(ctx, sessionID, eventType, payload)
+}
+
+// SchemaDropper is implemented by stores that can remove their schema. It is kept out
+// of Store because it destroys all data. Code written against the pre-v1 Store, which
+// included DropSchema, can require interface{ Store; SchemaDropper }.
+type SchemaDropper interface {
+	DropSchema(ctx context.Context) error
+}
+
+// SchemaVerifier is implemented by stores that can check their schema without changing
+// it, for deployments where migrations run separately and the app has no DDL rights.
+type SchemaVerifier interface {
+	// VerifySchema returns an error wrapping ErrSchemaMismatch that lists every missing
+	// or outdated part of the schema.
+	VerifySchema(ctx context.Context) error
+}
@@ -0,0 +1,355 @@
+// Package storetest provides a conformance suite for ai.Store implementations.
+//
+//	func TestConformance(t *testing.T) {
+//		storetest.RunConformance(t, func() ai.Store { return mystore.New(db) })
+//	}
+package storetest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// concurrentWriters is how many goroutines append to one session in the concurrency test.
+const concurrentWriters = 20
+
+// RunConformance runs the Store contract against stores returned by newStore. Each
+// subtest gets a fresh store and creates its own sessions, so stores may share a
+// database. CreateSchema is called on every store before use.
+func RunConformance(t *testing.T, newStore func() ai.Store) {
+	t.Helper()
+
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, ctx context.Context, s ai.Store)
+	}{
+		{"SchemaIsIdempotent", testSchema},
+		{"SessionRoundTrip", testSessionRoundTrip},
+		{"GetMissingSession", testGetMissingSession},
+		{"InvalidRulesRejected", testInvalidRulesRejected},
+		{"DeleteSession", testDeleteSession},
+		{"MessageOrder", testMessageOrder},
+		{"MessageUsage", testMessageUsage},
+		{"MessagesAreIsolated", testMessagesAreIsolated},
+		{"EventsShareSeq", testEventsShareSeq},
+		{"AddToMissingSession", testAddToMissingSession},
+		{"RequestLogLifecycle", testRequestLogLifecycle},
+		{"RequestLogPresetID", testRequestLogPresetID},
+		{"ConcurrentAddMessage", testConcurrentAddMessage},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			s := newStore()
+			if err := s.CreateSchema(ctx); err != nil {
+				t.Fatalf("CreateSchema: %v", err)
+			}
+			tt.fn(t, ctx, s)
+		})
+	}
+}
+
+func testSchema(t *testing.T, ctx context.Context, s ai.Store) {
+	if err := s.CreateSchema(ctx); err != nil {
+		t.Fatalf("second CreateSchema: %v", err)
+	}
+}
+
+func testSessionRoundTrip(t *testing.T, ctx context.Context, s ai.Store) {
+	rules := ai.Rules{
+		SystemPrompt: "You are a form builder.",
+		OutputSchema: `{"type":"object"}`,
+		MaxTokens:    1234,
+	}
+
+	created, err := s.CreateSession(ctx, rules)
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if created.ID == "" {
+		t.Fatal("CreateSession returned an empty ID")
+	}
+	if created.CreatedAt.IsZero() {
+		t.Error("CreateSession returned a zero CreatedAt")
+	}
+
+	got, err := s.GetSession(ctx, created.ID)
+	if err != nil {
+		t.Fatalf("GetSession: %v", err)
+	}
+	if got.ID != created.ID {
+		t.Errorf("ID = %q, want %q", got.ID, created.ID)
+	}
+	if got.Rules.SystemPrompt != rules.SystemPrompt || got.Rules.OutputSchema != rules.OutputSchema || got.Rules.MaxTokens != rules.MaxTokens {
+		t.Errorf("Rules = %+v, want %+v", got.Rules, rules)
+	}
+}
+
+func testInvalidRulesRejected(t *testing.T, ctx context.Context, s ai.Store) {
+	tests := map[string]ai.Rules{
+		"unknown response format": {ResponseFormat: "storetest-unknown"},
+		"invalid policy pattern":  {Policy: &ai.OutputPolicy{BannedPatterns: []string{"("}}},
+	}
+	for name, rules := range tests {
+		if session, err := s.CreateSession(ctx, rules); err == nil {
+			t.Errorf("CreateSession with an %s = %s, want an error", name, session.ID)
+		}
+	}
+}
+
+func testGetMissingSession(t *testing.T, ctx context.Context, s ai.Store) {
+	if _, err := s.GetSession(ctx, "storetest-missing-session"); !errors.Is(err, ai.ErrSessionNotFound) {
+		t.Fatalf("GetSession of a missing session = %v, want ai.ErrSessionNotFound", err)
+	}
+}
+
+func testDeleteSession(t *testing.T, ctx context.Context, s ai.Store) {
+	deleter, ok := s.(ai.SessionDeleter)
+	if !ok {
+		t.Skip("store is not an ai.SessionDeleter")
+	}
+	session := mustSession(t, ctx, s)
+	mustAdd(t, ctx, s, session.ID, ai.RoleUser, "hello")
+
+	if err := deleter.DeleteSession(ctx, session.ID); err != nil {
+		t.Fatalf("DeleteSession: %v", err)
+	}
+	if _, err := s.GetSession(ctx, session.ID); err == nil {
+		t.Error("GetSession succeeded after DeleteSession")
+	}
+	msgs, err := s.ListMessages(ctx, session.ID)
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	if len(msgs) != 0 {
+		t.Errorf("ListMessages returned %d messages after DeleteSession", len(msgs))
+	}
+
+	if err := deleter.DeleteSession(ctx, session.ID); !errors.Is(err, ai.ErrSessionNotFound) {
+		t.Errorf("second DeleteSession = %v, want ai.ErrSessionNotFound", err)
+	}
+}
+
+func testMessageOrder(t *testing.T, ctx context.Context, s ai.Store) {
+	session := mustSession(t, ctx, s)
+
+	want := []ai.Message{
+		{Role: ai.RoleUser, Content: "one"},
+		{Role: ai.RoleAssistant, Content: `{"n":2}`},
+		{Role: ai.RoleUser, Content: "three"},
+		{Role: ai.RoleAssistant, Content: `{"n":4}`},
+	}
+	for i, m := range want {
+		added := mustAdd(t, ctx, s, session.ID, m.Role, m.Content)
+		if added.Seq != i+1 {
+			t.Errorf("message %d: Seq = %d, want %d", i, added.Seq, i+1)
+		}
+		if added.ID == "" {
+			t.Errorf("message %d: empty ID", i)
+		}
+	}
+
+	got := mustList(t, ctx, s, session.ID)
+	if len(got) != len(want) {
+		t.Fatalf("ListMessages returned %d messages, want %d", len(got), len(want))
+	}
+	for i, m := range got {
+		if m.Seq != i+1 || m.Role != want[i].Role || m.Content != want[i].Content || m.SessionID != session.ID {
+			t.Errorf("message %d = {Seq:%d Role:%q Content:%q SessionID:%q}, want {Seq:%d Role:%q Content:%q SessionID:%q}",
+				i, m.Seq, m.Role, m.Content, m.SessionID, i+1, want[i].Role, want[i].Content, session.ID)
+		}
+	}
+}
+
+func testMessageUsage(t *testing.T, ctx context.Context, s ai.Store) {
+	session := mustSession(t, ctx, s)
+	usage := &ai.Usage{PromptTokens: 10, ResponseTokens: 20, TotalTokens: 35, ThoughtTokens: 5}
+
+	if _, err := s.AddMessage(ctx, session.ID, ai.RoleUser, "prompt", nil); err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+	if _, err := s.AddMessage(ctx, session.ID, ai.RoleAssistant, "{}", usage); err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+
+	got := mustList(t, ctx, s, session.ID)
+	if len(got) != 2 {
+		t.Fatalf("ListMessages returned %d messages, want 2", len(got))
+	}
+	if got[0].Usage != nil {
+		t.Errorf("user message Usage = %+v, want nil", *got[0].Usage)
+	}
+	if got[1].Usage == nil || *got[1].Usage != *usage {
+		t.Errorf("assistant message Usage = %+v, want %+v", got[1].Usage, *usage)
+	}
+}
+
+func testMessagesAreIsolated(t *testing.T, ctx context.Context, s ai.Store) {
+	a := mustSession(t, ctx, s)
+	b := mustSession(t, ctx, s)
+
+	mustAdd(t, ctx, s, a.ID, ai.RoleUser, "a1")
+	mustAdd(t, ctx, s, b.ID, ai.RoleUser, "b1")
+	mustAdd(t, ctx, s, a.ID, ai.RoleAssistant, "a2")
+
+	if got := mustList(t, ctx, s, a.ID); len(got) != 2 || got[1].Seq != 2 {
+		t.Errorf("session a: got %d messages, want 2 with seq 1..2", len(got))
+	}
+	if got := mustList(t, ctx, s, b.ID); len(got) != 1 || got[0].Seq != 1 {
+		t.Errorf("session b: got %d messages, want 1 with seq 1", len(got))
+	}
+}
+
+func testEventsShareSeq(t *testing.T, ctx context.Context, s ai.Store) {
+	events, ok := s.(ai.EventStore)
+	if !ok {
+		t.Skip("store is not an ai.EventStore")
+	}
+	session := mustSession(t, ctx, s)
+
+	mustAdd(t, ctx, s, session.ID, ai.RoleUser, "hello")
+	event, err := events.AddEvent(ctx, session.ID, "form_applied", `{"version":3}`)
+	if err != nil {
+		t.Fatalf("AddEvent: %v", err)
+	}
+	if event.Seq != 2 || event.Role != ai.RoleEvent || event.EventType != "form_applied" {
+		t.Errorf("AddEvent = {Seq:%d Role:%q EventType:%q}, want {Seq:2 Role:%q EventType:%q}",
+			event.Seq, event.Role, event.EventType, ai.RoleEvent, "form_applied")
+	}
+	mustAdd(t, ctx, s, session.ID, ai.RoleAssistant, "{}")
+
+	got := mustList(t, ctx, s, session.ID)
+	if len(got) != 3 || !got[1].IsEvent() || got[1].Content != `{"version":3}` {
+		t.Fatalf("ListMessages did not return the event in order: %+v", got)
+	}
+	if history := ai.ConversationHistory(got); len(history) != 2 {
+		t.Errorf("ConversationHistory returned %d messages, want 2", len(history))
+	}
+}
+
+func testAddToMissingSession(t *testing.T, ctx context.Context, s ai.Store) {
+	if _, err := s.AddMessage(ctx, "storetest-missing-session", ai.RoleUser, "hello", nil); err == nil {
+		t.Fatal("AddMessage to a missing session succeeded")
+	}
+}
+
+func testRequestLogLifecycle(t *testing.T, ctx context.Context, s ai.Store) {
+	session := mustSession(t, ctx, s)
+
+	log, err := s.AddRequestLog(ctx, ai.RequestLog{
+		SessionID:     session.ID,
+		Prompt:        "Create a form",
+		AttemptNumber: 1,
+	})
+	if err != nil {
+		t.Fatalf("AddRequestLog: %v", err)
+	}
+	if log.ID == "" {
+		t.Fatal("AddRequestLog returned an empty ID")
+	}
+	if log.FinalStatus != ai.StatusPending {
+		t.Errorf("FinalStatus = %q, want %q", log.FinalStatus, ai.StatusPending)
+	}
+	if log.SessionID != session.ID || log.Prompt != "Create a form" {
+		t.Errorf("AddRequestLog = %+v, want session %q and the prompt", log, session.ID)
+	}
+
+	usage := &ai.Usage{PromptTokens: 1, ResponseTokens: 2, TotalTokens: 3}
+	if err := s.UpdateRequestLog(ctx, log.ID, "{}", ai.StatusPending, ai.FailReasonIncompleteJSON, "retrying", 1, usage); err != nil {
+		t.Fatalf("UpdateRequestLog (retry): %v", err)
+	}
+	if err := s.UpdateRequestLog(ctx, log.ID, "{}", ai.StatusSuccess, "", "", 1, usage); err != nil {
+		t.Fatalf("UpdateRequestLog (success): %v", err)
+	}
+
+	if r, ok := s.(ai.RequestLogReader); ok {
+		got, err := r.GetRequestLog(ctx, log.ID)
+		if err != nil {
+			t.Fatalf("GetRequestLog: %v", err)
+		}
+		if got.FinalStatus != ai.StatusSuccess || got.RetryCount != 1 || got.Usage != *usage || got.Response != "{}" {
+			t.Errorf("GetRequestLog = %+v, want the final update", got)
+		}
+	}
+}
+
+func testRequestLogPresetID(t *testing.T, ctx context.Context, s ai.Store) {
+	session := mustSession(t, ctx, s)
+	id := "storetest-log-" + session.ID
+
+	log, err := s.AddRequestLog(ctx, ai.RequestLog{ID: id, SessionID: session.ID, Prompt: "hi", AttemptNumber: 1})
+	if err != nil {
+		t.Fatalf("AddRequestLog: %v", err)
+	}
+	if log.ID != id {
+		t.Errorf("ID = %q, want the preset %q", log.ID, id)
+	}
+	if err := s.UpdateRequestLog(ctx, id, "{}", ai.StatusSuccess, "", "", 0, nil); err != nil {
+		t.Fatalf("UpdateRequestLog: %v", err)
+	}
+}
+
+func testConcurrentAddMessage(t *testing.T, ctx context.Context, s ai.Store) {
+	session := mustSession(t, ctx, s)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, concurrentWriters)
+	for i := 0; i < concurrentWriters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			if _, err := s.AddMessage(ctx, session.ID, ai.RoleUser, fmt.Sprintf("message %d", i), nil); err != nil {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Errorf("concurrent AddMessage: %v", err)
+	}
+
+	got := mustList(t, ctx, s, session.ID)
+	if len(got) != concurrentWriters {
+		t.Fatalf("ListMessages returned %d messages, want %d", len(got), concurrentWriters)
+	}
+	for i, m := range got {
+		if m.Seq != i+1 {
+			t.Fatalf("message %d has Seq %d: seqs must be gapless and unique", i, m.Seq)
+		}
+	}
+}
+
+func mustSession(t *testing.T, ctx context.Context, s ai.Store) *ai.Session {
+	t.Helper()
+	session, err := s.CreateSession(ctx, ai.Rules{SystemPrompt: "storetest", MaxTokens: 100})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	return session
+}
+
+func mustAdd(t *testing.T, ctx context.Context, s ai.Store, sessionID string, role string, content string) *ai.Message {
+	t.Helper()
+	msg, err := s.AddMessage(ctx, sessionID, role, content, nil)
+	if err != nil {
+		t.Fatalf("AddMessage: %v", err)
+	}
+	return msg
+}
+
+func mustList(t *testing.T, ctx context.Context, s ai.Store, sessionID string) []ai.Message {
+	t.Helper()
+	msgs, err := s.ListMessages(ctx, sessionID)
+	if err != nil {
+		t.Fatalf("ListMessages: %v", err)
+	}
+	return msgs
+}
@@ -0,0 +1,89 @@
+// Package stream adapts ai.StreamChunk channels to HTTP streaming formats.
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// Event names used by WriteSSE and the "type" field used by WriteNDJSON.
+const (
+	EventDelta = "delta"
+	EventDone  = "done"
+	EventError = "error"
+)
+
+// Frame is one encoded stream event.
+type Frame struct {
+	Type  string    `json:"type"`
+	Delta string    `json:"delta,omitempty"`
+	Usage *ai.Usage `json:"usage,omitempty"`
+	Error string    `json:"error,omitempty"`
+
+	Citations []ai.Citation `json:"citations,omitempty"` // on the done frame
+}
+
+// frameOf converts a chunk to its wire frame.
+func frameOf(c ai.StreamChunk) Frame {
+	switch {
+	case c.Err != nil:
+		return Frame{Type: EventError, Error: c.Err.Error(), Usage: c.Usage}
+	case c.Done:
+		return Frame{Type: EventDone, Usage: c.Usage, Citations: c.Citations}
+	default:
+		return Frame{Type: EventDelta, Delta: c.Delta}
+	}
+}
+
+// WriteSSE writes chunks from ch to w as Server-Sent Events until ch is closed,
+// flushing after each event. Each event is named by its type ("delta", "done" or
+// "error") and its data is a JSON Frame. It returns the first write error; the
+// channel is drained in that case so the producer isn't blocked.
+func WriteSSE(w http.ResponseWriter, ch <-chan ai.StreamChunk) error {
+	h := w.Header()
+	h.Set("Content-Type", "text/event-stream")
+	h.Set("Cache-Control", "no-cache")
+	h.Set("Connection", "keep-alive")
+	h.Set("X-Accel-Buffering", "no")
+
+	return write(w, ch, func(f Frame) error {
+		data, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Fprintf(w, "event: %s\ndata: %s\n\n", f.Type, data)
+		return err
+	})
+}
+
+// WriteNDJSON writes chunks from ch to w as newline-delimited JSON Frames until ch
+// is closed, flushing after each line.
+func WriteNDJSON(w http.ResponseWriter, ch <-chan ai.StreamChunk) error {
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Cache-Control", "no-cache")
+
+	enc := json.NewEncoder(w)
+	return write(w, ch, func(f Frame) error {
+		return enc.Encode(f)
+	})
+}
+
+// write encodes every chunk with encode, flushing w when it supports it.
+func write(w http.ResponseWriter, ch <-chan ai.StreamChunk, encode func(Frame) error) error {
+	flusher, _ := w.(http.Flusher)
+
+	for c := range ch {
+		if err := encode(frameOf(c)); err != nil {
+			for range ch {
+			}
+			return fmt.Errorf("ai: write stream: %w", err)
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
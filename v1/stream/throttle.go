@@ -0,0 +1,105 @@
+package stream
+
+import (
+	"context"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// Throttle smooths the delta text of ch to at most charsPerSec characters a second,
+// with a token bucket holding up to burst characters: text is re-chunked and released
+// steadily instead of in the bursts a provider emits, for UIs that render as they
+// receive and for text-to-speech. A chunk is at most burst characters; burst below 1
+// means 1. Buffered text is released before the final or error chunk, which is passed
+// on unchanged. If charsPerSec is not positive, ch is returned as is.
+//
+// When ctx is done, the returned channel is closed and ch is drained so the producer
+// isn't blocked.
+func Throttle(ctx context.Context, ch <-chan ai.StreamChunk, charsPerSec float64, burst int) <-chan ai.StreamChunk {
+	if charsPerSec <= 0 {
+		return ch
+	}
+	burst = max(burst, 1)
+
+	out := make(chan ai.StreamChunk)
+	go func() {
+		defer close(out)
+
+		in := ch
+		var (
+			pending []rune
+			final   *ai.StreamChunk // the final or error chunk, once received
+			tokens  = float64(burst)
+			last    = time.Now()
+			timer   *time.Timer
+		)
+		defer func() {
+			if timer != nil {
+				timer.Stop()
+			}
+			if in != nil {
+				for range in {
+				}
+			}
+		}()
+
+		for {
+			if len(pending) == 0 && in == nil {
+				if final != nil {
+					select {
+					case out <- *final:
+					case <-ctx.Done():
+					}
+				}
+				return
+			}
+
+			// Refill the bucket, and send as much as it allows
+			now := time.Now()
+			tokens = min(float64(burst), tokens+now.Sub(last).Seconds()*charsPerSec)
+			last = now
+
+			want := min(len(pending), burst)
+			if want > 0 && tokens >= float64(want) {
+				select {
+				case out <- ai.StreamChunk{Delta: string(pending[:want])}:
+				case <-ctx.Done():
+					return
+				}
+				pending = pending[want:]
+				tokens -= float64(want)
+				continue
+			}
+
+			// Wait for the bucket to hold the next chunk, or for more input
+			var refill <-chan time.Time
+			if want > 0 {
+				d := time.Duration((float64(want) - tokens) / charsPerSec * float64(time.Second))
+				if timer == nil {
+					timer = time.NewTimer(d)
+				} else {
+					timer.Reset(d)
+				}
+				refill = timer.C
+			}
+
+			select {
+			case c, ok := <-in:
+				if !ok {
+					in = nil
+					break
+				}
+				pending = append(pending, []rune(c.Delta)...)
+				if c.Done || c.Err != nil {
+					c.Delta = ""
+					final, in = &c, nil
+				}
+			case <-refill:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out
+}
@@ -0,0 +1,79 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// HistoryReader is the optional store capability reconstructing a session as it was
+// at a point in time.
+type HistoryReader interface {
+	// HistoryAt returns the messages of a session that existed at at, ordered by seq,
+	// with the content and UpdatedAt they had then. Messages added later are left out
+	// and later edits are undone.
+	HistoryAt(ctx context.Context, sessionID string, at time.Time) ([]Message, error)
+}
+
+// HistoryAt returns the history of a session as it was at at, e.g. to see what the
+// model was sent by a request that went wrong. It uses store's HistoryAt when it
+// implements HistoryReader. Otherwise it filters ListMessages by CreatedAt and, when
+// store is a MessageEditor, undoes later edits with ListMessageEdits.
+func HistoryAt(ctx context.Context, store Store, sessionID string, at time.Time) ([]Message, error) {
+	if r, ok := store.(HistoryReader); ok {
+		return r.HistoryAt(ctx, sessionID, at)
+	}
+
+	msgs, err := store.ListMessages(ctx, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	editor, _ := store.(MessageEditor)
+
+	var out []Message
+	for _, m := range msgs {
+		if m.CreatedAt.After(at) {
+			continue
+		}
+		if editor != nil && m.UpdatedAt.After(at) {
+			edits, err := editor.ListMessageEdits(ctx, m.ID)
+			if err != nil {
+				return nil, fmt.Errorf("ai: history at: %w", err)
+			}
+			m = MessageAt(m, edits, at)
+		}
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+// MessageAt returns m with the content it had at at, given its edits ordered oldest
+// first: the content replaced by the first edit after at, or m's current content if
+// there is none. UpdatedAt becomes the time of the last edit before at, or CreatedAt.
+func MessageAt(m Message, edits []MessageEdit, at time.Time) Message {
+	m.UpdatedAt = m.CreatedAt
+	for _, e := range edits {
+		if e.EditedAt.After(at) {
+			m.Content = e.Content
+			return m
+		}
+		m.UpdatedAt = e.EditedAt
+	}
+	return m
+}
+
+// DocumentAt returns the latest assistant message of a session as it was at at, the
+// version of the document a generate-and-refine conversation had then. Returns an
+// error wrapping ErrMessageNotFound if the session had no assistant message yet.
+func DocumentAt(ctx context.Context, store Store, sessionID string, at time.Time) (*Message, error) {
+	msgs, err := HistoryAt(ctx, store, sessionID, at)
+	if err != nil {
+		return nil, err
+	}
+	for i := len(msgs) - 1; i >= 0; i-- {
+		if msgs[i].Role == RoleAssistant {
+			return &msgs[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: no document in session %s at %s", ErrMessageNotFound, sessionID, at.Format(time.RFC3339))
+}
@@ -0,0 +1,89 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+)
+
+// Tool-calling roles. Tool calls and results are stored as regular messages with
+// JSON content, so any Store can persist them without schema changes.
+const (
+	RoleToolCall = "tool_call" // assistant turn requesting tool calls; content is a JSON []ToolCall
+	RoleTool     = "tool"      // tool output fed back to the model; content is a JSON ToolResult
+)
+
+// Tool declares a function the model may call. Parameters is a JSON schema object.
+type Tool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description"`
+	Parameters  map[string]any `json:"parameters,omitempty"`
+}
+
+// ToolCall is a function call requested by the model.
+type ToolCall struct {
+	ID   string         `json:"id"`
+	Name string         `json:"name"`
+	Args map[string]any `json:"args"`
+}
+
+// ToolResult is the output of a ToolCall, sent back to the model.
+type ToolResult struct {
+	CallID   string `json:"call_id"`
+	Name     string `json:"name"`
+	Response any    `json:"response,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// WithTools declares tools the model may call for requests made with ctx.
+func WithTools(ctx context.Context, tools ...Tool) context.Context {
+	return context.WithValue(ctx, toolsKey, tools)
+}
+
+// ToolsFromContext returns the tools attached with WithTools.
+func ToolsFromContext(ctx context.Context) []Tool {
+	tools, _ := ctx.Value(toolsKey).([]Tool)
+	return tools
+}
+
+// ToolCallMessage builds the history message for an assistant turn that requested calls.
+func ToolCallMessage(calls []ToolCall) (Message, error) {
+	data, err := json.Marshal(calls)
+	if err != nil {
+		return Message{}, fmt.Errorf("ai: marshal tool calls: %w", err)
+	}
+	return Message{Role: RoleToolCall, Content: string(data)}, nil
+}
+
+// ToolResultMessage builds the history message carrying a tool's output.
+func ToolResultMessage(result ToolResult) (Message, error) {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return Message{}, fmt.Errorf("ai: marshal tool result: %w", err)
+	}
+	return Message{Role: RoleTool, Content: string(data)}, nil
+}
+
+// ToolCalls decodes the calls of a RoleToolCall message.
+func (m Message) ToolCalls() ([]ToolCall, error) {
+	if m.Role != RoleToolCall {
+		return nil, nil
+	}
+	var calls []ToolCall
+	if err := json.Unmarshal([]byte(m.Content), &calls); err != nil {
+		return nil, fmt.Errorf("ai: decode tool calls: %w", err)
+	}
+	return calls, nil
+}
+
+// ToolResult decodes the result carried by a RoleTool message.
+func (m Message) ToolResult() (*ToolResult, error) {
+	if m.Role != RoleTool {
+		return nil, nil
+	}
+	var result ToolResult
+	if err := json.Unmarshal([]byte(m.Content), &result); err != nil {
+		return nil, fmt.Errorf("ai: decode tool result: %w", err)
+	}
+	return &result, nil
+}
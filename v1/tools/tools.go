@@ -0,0 +1,235 @@
+// Package tools dispatches model tool calls to registered Go functions.
+package tools
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+var (
+	ErrUnknownTool = errors.New("ai: unknown tool")
+	ErrMaxRounds   = errors.New("ai: tool call rounds exceeded")
+)
+
+const (
+	defaultTimeout   = 30 * time.Second
+	defaultMaxRounds = 8
+)
+
+// Func implements a tool. The returned value is sent back to the model as JSON.
+type Func func(ctx context.Context, args map[string]any) (any, error)
+
+type registration struct {
+	tool    ai.Tool
+	fn      Func
+	timeout time.Duration
+}
+
+// Executor runs tool calls concurrently and loops with the provider until the model
+// returns a final answer.
+type Executor struct {
+	provider  ai.Provider
+	store     ai.Store
+	tools     map[string]registration
+	order     []string
+	timeout   time.Duration
+	maxRounds int
+}
+
+// NewExecutor creates an Executor that calls provider.
+func NewExecutor(provider ai.Provider) *Executor {
+	return &Executor{
+		provider:  provider,
+		tools:     make(map[string]registration),
+		timeout:   defaultTimeout,
+		maxRounds: defaultMaxRounds,
+	}
+}
+
+// WithStore persists every round trip (prompt, tool calls, tool results, final answer)
+// as session messages, stored together once a run succeeds.
+func (e *Executor) WithStore(store ai.Store) *Executor {
+	e.store = store
+	return e
+}
+
+// WithTimeout sets the default per-tool timeout.
+func (e *Executor) WithTimeout(d time.Duration) *Executor {
+	e.timeout = d
+	return e
+}
+
+// WithMaxRounds limits how many tool-call rounds Run performs before giving up.
+func (e *Executor) WithMaxRounds(n int) *Executor {
+	e.maxRounds = n
+	return e
+}
+
+// Register adds a tool using the executor's default timeout.
+func (e *Executor) Register(tool ai.Tool, fn Func) *Executor {
+	return e.RegisterWithTimeout(tool, 0, fn)
+}
+
+// RegisterWithTimeout adds a tool with its own timeout. Zero uses the default.
+func (e *Executor) RegisterWithTimeout(tool ai.Tool, timeout time.Duration, fn Func) *Executor {
+	if _, ok := e.tools[tool.Name]; !ok {
+		e.order = append(e.order, tool.Name)
+	}
+	e.tools[tool.Name] = registration{tool: tool, fn: fn, timeout: timeout}
+	return e
+}
+
+// Tools returns the registered tool declarations in registration order.
+func (e *Executor) Tools() []ai.Tool {
+	tools := make([]ai.Tool, 0, len(e.order))
+	for _, name := range e.order {
+		tools = append(tools, e.tools[name].tool)
+	}
+	return tools
+}
+
+// Execute runs calls concurrently and returns their results in call order.
+// Tool errors, panics and timeouts are reported in ToolResult.Error, never returned.
+func (e *Executor) Execute(ctx context.Context, calls []ai.ToolCall) []ai.ToolResult {
+	results := make([]ai.ToolResult, len(calls))
+
+	var wg sync.WaitGroup
+	for i, call := range calls {
+		wg.Add(1)
+		go func(i int, call ai.ToolCall) {
+			defer wg.Done()
+			results[i] = e.call(ctx, call)
+		}(i, call)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// call runs a single tool call with its timeout.
+func (e *Executor) call(ctx context.Context, call ai.ToolCall) ai.ToolResult {
+	result := ai.ToolResult{CallID: call.ID, Name: call.Name}
+
+	reg, ok := e.tools[call.Name]
+	if !ok {
+		result.Error = fmt.Sprintf("%v: %s", ErrUnknownTool, call.Name)
+		return result
+	}
+
+	timeout := reg.timeout
+	if timeout <= 0 {
+		timeout = e.timeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		value any
+		err   error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- outcome{err: fmt.Errorf("ai: tool %s panicked: %v", call.Name, r)}
+			}
+		}()
+		v, err := reg.fn(ctx, call.Args)
+		done <- outcome{value: v, err: err}
+	}()
+
+	select {
+	case out := <-done:
+		if out.err != nil {
+			result.Error = out.err.Error()
+		} else {
+			result.Response = out.value
+		}
+	case <-ctx.Done():
+		result.Error = fmt.Sprintf("ai: tool %s: %v", call.Name, ctx.Err())
+	}
+
+	return result
+}
+
+// Run sends prompt with the registered tools declared and executes every round of tool
+// calls until the model answers. The returned Result carries the final content with
+// usage summed over all rounds. All rounds share one request ID. With a store, the
+// prompt and every turn are stored together once the model has answered, atomically
+// when the store is an ai.BulkMessageStore, so a failed run leaves no partial exchange
+// in the session. It fails with ai.ErrUnsupported if the provider reports it can't call
+// tools.
+func (e *Executor) Run(ctx context.Context, sessionID string, rules ai.Rules, history []ai.Message, prompt string) (*ai.Result, error) {
+	if caps, known := ai.CapabilitiesOf(e.provider); known && !caps.Tools {
+		return nil, fmt.Errorf("%w: tool calling", ai.ErrUnsupported)
+	}
+	ctx = ai.WithTools(ai.WithSessionID(ctx, sessionID), e.Tools()...)
+	ctx, _ = ai.EnsureRequestID(ctx)
+
+	var turns []ai.NewMessage
+	add := func(msg ai.Message, usage *ai.Usage) {
+		if msg.Content != "" {
+			turns = append(turns, ai.NewMessage{Role: msg.Role, Content: msg.Content, Usage: usage})
+		}
+	}
+	add(ai.Message{Role: ai.RoleUser, Content: prompt}, nil)
+
+	var total ai.Usage
+	for round := 0; round < e.maxRounds; round++ {
+		result, err := e.provider.Send(ctx, rules, history, prompt)
+		if err != nil {
+			return nil, err
+		}
+		total.Add(result.Usage)
+		usage := result.Usage
+
+		if len(result.ToolCalls) == 0 {
+			add(ai.Message{Role: ai.RoleAssistant, Content: result.Content}, &usage)
+			if err := e.persist(ctx, sessionID, turns); err != nil {
+				return nil, err
+			}
+			result.Usage = total
+			return result, nil
+		}
+
+		// The prompt is part of history from the second round on.
+		if prompt != "" {
+			history = append(history, ai.Message{SessionID: sessionID, Role: ai.RoleUser, Content: prompt})
+			prompt = ""
+		}
+
+		callMsg, err := ai.ToolCallMessage(result.ToolCalls)
+		if err != nil {
+			return nil, err
+		}
+		callMsg.SessionID = sessionID
+		add(callMsg, &usage)
+		history = append(history, callMsg)
+
+		for _, r := range e.Execute(ctx, result.ToolCalls) {
+			resultMsg, err := ai.ToolResultMessage(r)
+			if err != nil {
+				return nil, err
+			}
+			resultMsg.SessionID = sessionID
+			add(resultMsg, nil)
+			history = append(history, resultMsg)
+		}
+	}
+
+	return nil, fmt.Errorf("%w: %d rounds", ErrMaxRounds, e.maxRounds)
+}
+
+// persist stores the turns of a run in one batch when a store is configured.
+func (e *Executor) persist(ctx context.Context, sessionID string, turns []ai.NewMessage) error {
+	if e.store == nil || len(turns) == 0 {
+		return nil
+	}
+	_, err := ai.AddMessages(ctx, e.store, sessionID, turns)
+	return err
+}
@@ -0,0 +1,90 @@
+package tools
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/meikuraledutech/ai/v1"
+	"github.com/meikuraledutech/ai/v1/jsonschema"
+)
+
+var (
+	ErrInvalidToolFunc = errors.New("ai: invalid tool function")
+)
+
+var (
+	contextType = reflect.TypeOf((*context.Context)(nil)).Elem()
+	errorType   = reflect.TypeOf((*error)(nil)).Elem()
+)
+
+// Typed builds a tool from a Go function of the form
+//
+//	func(ctx context.Context, args T) (R, error)
+//
+// where T is a struct. The parameter schema is derived from T with the jsonschema
+// package; call arguments are decoded into T through JSON.
+func Typed(name, description string, fn any) (ai.Tool, Func, error) {
+	v := reflect.ValueOf(fn)
+	if !v.IsValid() || v.Kind() != reflect.Func || v.IsNil() {
+		return ai.Tool{}, nil, fmt.Errorf("%w: %s must be a non-nil func(context.Context, T) (R, error), got %T", ErrInvalidToolFunc, name, fn)
+	}
+	t := v.Type()
+
+	if t.Kind() != reflect.Func || t.NumIn() != 2 || t.NumOut() != 2 ||
+		t.In(0) != contextType || !t.Out(1).Implements(errorType) {
+		return ai.Tool{}, nil, fmt.Errorf("%w: %s must be func(context.Context, T) (R, error), got %s", ErrInvalidToolFunc, name, t)
+	}
+
+	argsType := t.In(1)
+	base := argsType
+	if base.Kind() == reflect.Pointer {
+		base = base.Elem()
+	}
+	if base.Kind() != reflect.Struct {
+		return ai.Tool{}, nil, fmt.Errorf("%w: %s arguments must be a struct, got %s", ErrInvalidToolFunc, name, argsType)
+	}
+
+	tool := ai.Tool{
+		Name:        name,
+		Description: description,
+		Parameters:  jsonschema.For(base),
+	}
+
+	call := func(ctx context.Context, args map[string]any) (any, error) {
+		data, err := json.Marshal(args)
+		if err != nil {
+			return nil, fmt.Errorf("ai: tool %s: encode args: %w", name, err)
+		}
+
+		ptr := reflect.New(base)
+		if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+			return nil, fmt.Errorf("ai: tool %s: decode args: %w", name, err)
+		}
+
+		arg := ptr.Elem()
+		if argsType.Kind() == reflect.Pointer {
+			arg = ptr
+		}
+
+		out := v.Call([]reflect.Value{reflect.ValueOf(ctx), arg})
+		if errV := out[1]; !errV.IsNil() {
+			return nil, errV.Interface().(error)
+		}
+		return out[0].Interface(), nil
+	}
+
+	return tool, call, nil
+}
+
+// RegisterFunc registers a typed Go function as a tool. See Typed for the accepted signature.
+func (e *Executor) RegisterFunc(name, description string, fn any) error {
+	tool, call, err := Typed(name, description, fn)
+	if err != nil {
+		return err
+	}
+	e.Register(tool, call)
+	return nil
+}
@@ -0,0 +1,195 @@
+// Package transcript renders sessions as human-readable Markdown or HTML transcripts,
+// for sharing conversations with people who don't read request logs.
+package transcript
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/meikuraledutech/ai/v1"
+)
+
+// Options control what a transcript includes.
+type Options struct {
+	Title      string // defaults to "Session <id>"
+	HideUsage  bool   // omit per-message token and cost annotations and the totals
+	ShowEvents bool   // include system events, which are omitted by default
+}
+
+// entry is one rendered message.
+type entry struct {
+	Heading    string
+	Annotation string
+	Body       string
+	Code       bool // Body is JSON, rendered as a code block
+}
+
+// page is the data shared by both renderers.
+type page struct {
+	Title   string
+	Created string
+	Rules   string // system prompt
+	Entries []entry
+	Totals  string
+}
+
+// build prepares the entries of e under opts.
+func build(e *ai.SessionExport, opts Options) page {
+	p := page{
+		Title:   opts.Title,
+		Created: e.Session.CreatedAt.UTC().Format(time.RFC3339),
+		Rules:   e.Session.Rules.SystemPrompt,
+	}
+	if p.Title == "" {
+		p.Title = "Session " + e.Session.ID
+	}
+
+	var total ai.Usage
+	var cost float64
+	for _, m := range e.Messages {
+		if m.IsEvent() && !opts.ShowEvents {
+			continue
+		}
+
+		heading := fmt.Sprintf("#%d %s", m.Seq, roleName(m))
+		body, code := formatContent(m.Content)
+		en := entry{Heading: heading, Body: body, Code: code}
+		if !opts.HideUsage && m.Usage != nil {
+			en.Annotation = annotate(*m.Usage, m.Cost)
+			total.Add(*m.Usage)
+			cost += m.Cost
+		}
+		if !m.CreatedAt.IsZero() {
+			en.Annotation = strings.TrimPrefix(en.Annotation+" · "+m.CreatedAt.UTC().Format(time.RFC3339), " · ")
+		}
+		p.Entries = append(p.Entries, en)
+	}
+
+	if !opts.HideUsage && total != (ai.Usage{}) {
+		p.Totals = "Total: " + annotate(total, cost)
+	}
+	return p
+}
+
+// roleName is the heading of a message.
+func roleName(m ai.Message) string {
+	switch {
+	case m.IsEvent():
+		return "Event: " + m.EventType
+	case m.Role == ai.RoleUser:
+		return "User"
+	case m.Role == ai.RoleAssistant:
+		return "Assistant"
+	case m.Role == ai.RoleTool:
+		return "Tool"
+	default:
+		return m.Role
+	}
+}
+
+// formatContent pretty-prints JSON content and reports whether it is JSON.
+func formatContent(content string) (string, bool) {
+	trimmed := strings.TrimSpace(content)
+	if !strings.HasPrefix(trimmed, "{") && !strings.HasPrefix(trimmed, "[") {
+		return content, false
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, []byte(trimmed), "", "  "); err != nil {
+		return content, false
+	}
+	return buf.String(), true
+}
+
+// annotate describes usage and cost on one line.
+func annotate(u ai.Usage, cost float64) string {
+	s := fmt.Sprintf("%d tokens (%d in, %d out", u.TotalTokens, u.PromptTokens, u.ResponseTokens)
+	if u.ThoughtTokens > 0 {
+		s += fmt.Sprintf(", %d thinking", u.ThoughtTokens)
+	}
+	s += ")"
+	if cost > 0 {
+		s += fmt.Sprintf(" · $%.4f", cost)
+	}
+	if u.Model != "" {
+		s += " · " + u.Model
+	}
+	return s
+}
+
+// Markdown writes e to w as a Markdown transcript.
+func Markdown(w io.Writer, e *ai.SessionExport, opts Options) error {
+	p := build(e, opts)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\nCreated %s\n", p.Title, p.Created)
+	if p.Rules != "" {
+		fmt.Fprintf(&b, "\n> **System prompt:** %s\n", strings.ReplaceAll(p.Rules, "\n", "\n> "))
+	}
+	for _, en := range p.Entries {
+		fmt.Fprintf(&b, "\n## %s\n\n", en.Heading)
+		if en.Annotation != "" {
+			fmt.Fprintf(&b, "_%s_\n\n", en.Annotation)
+		}
+		if en.Code {
+			fence := "```"
+			for strings.Contains(en.Body, fence) {
+				fence += "`"
+			}
+			fmt.Fprintf(&b, "%sjson\n%s\n%s\n", fence, en.Body, fence)
+		} else {
+			fmt.Fprintf(&b, "%s\n", en.Body)
+		}
+	}
+	if p.Totals != "" {
+		fmt.Fprintf(&b, "\n---\n\n**%s**\n", p.Totals)
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("ai: write markdown transcript: %w", err)
+	}
+	return nil
+}
+
+// htmlTemplate is a standalone page with inline styles, so the file can be attached or
+// opened directly.
+var htmlTemplate = template.Must(template.New("transcript").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: system-ui, sans-serif; max-width: 860px; margin: 2rem auto; padding: 0 1rem; color: #222; }
+.meta, .note { color: #666; font-size: 0.85rem; }
+.system { border-left: 3px solid #ccc; padding-left: 1rem; color: #555; white-space: pre-wrap; }
+.msg { border: 1px solid #e3e3e3; border-radius: 6px; padding: 0.75rem 1rem; margin: 1rem 0; }
+.msg h2 { font-size: 1rem; margin: 0 0 0.25rem; }
+.body { white-space: pre-wrap; }
+pre { background: #f6f8fa; padding: 0.75rem; overflow-x: auto; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="meta">Created {{.Created}}</p>
+{{if .Rules}}<div class="system"><strong>System prompt:</strong> {{.Rules}}</div>{{end}}
+{{range .Entries}}<div class="msg">
+<h2>{{.Heading}}</h2>
+{{if .Annotation}}<p class="note">{{.Annotation}}</p>{{end}}
+{{if .Code}}<pre><code>{{.Body}}</code></pre>{{else}}<div class="body">{{.Body}}</div>{{end}}
+</div>
+{{end}}{{if .Totals}}<p><strong>{{.Totals}}</strong></p>{{end}}
+</body>
+</html>
+`))
+
+// HTML writes e to w as a standalone HTML page. All content is escaped.
+func HTML(w io.Writer, e *ai.SessionExport, opts Options) error {
+	if err := htmlTemplate.Execute(w, build(e, opts)); err != nil {
+		return fmt.Errorf("ai: write html transcript: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	ErrURLNotAllowed = errors.New("ai: url not allowed")
+)
+
+// defaultMaxURLBytes caps how much of a fetched page is read.
+const defaultMaxURLBytes = 512 << 10
+
+var (
+	htmlDropPattern   = regexp.MustCompile(`(?is)<(script|style|noscript|head)[^>]*>.*?</(script|style|noscript|head)>`)
+	htmlTagPattern    = regexp.MustCompile(`(?s)<[^>]+>`)
+	whitespacePattern = regexp.MustCompile(`[ \t]*\n[\s]*`)
+	spacePattern      = regexp.MustCompile(`[ \t]+`)
+)
+
+// URLFetcher downloads pages on the library side so they can be inlined as prompt
+// context. Only hosts in Allowlist are fetched, including the targets of redirects.
+type URLFetcher struct {
+	Allowlist []string     // host names; "*.example.com" also matches subdomains
+	Client    *http.Client // defaults to http.DefaultClient; redirects must stay on the Allowlist
+	MaxBytes  int64        // max bytes read per page; defaults to 512KB
+}
+
+// Allowed reports whether rawURL is an http(s) URL on an allowlisted host.
+func (f *URLFetcher) Allowed(rawURL string) bool {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+
+	host := strings.ToLower(u.Hostname())
+	for _, allowed := range f.Allowlist {
+		allowed = strings.ToLower(allowed)
+		if suffix, ok := strings.CutPrefix(allowed, "*."); ok {
+			if host == suffix || strings.HasSuffix(host, "."+suffix) {
+				return true
+			}
+			continue
+		}
+		if host == allowed {
+			return true
+		}
+	}
+	return false
+}
+
+// Fetch downloads rawURL and returns its text content. HTML is reduced to visible text.
+func (f *URLFetcher) Fetch(ctx context.Context, rawURL string) (string, error) {
+	if !f.Allowed(rawURL) {
+		return "", fmt.Errorf("%w: %s", ErrURLNotAllowed, rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rawURL, nil)
+	if err != nil {
+		return "", fmt.Errorf("ai: fetch url: %w", err)
+	}
+
+	resp, err := f.client().Do(req)
+	if err != nil {
+		return "", fmt.Errorf("ai: fetch url: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("ai: fetch url %s: status %d", rawURL, resp.StatusCode)
+	}
+
+	maxBytes := f.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = defaultMaxURLBytes
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxBytes))
+	if err != nil {
+		return "", fmt.Errorf("ai: fetch url: %w", err)
+	}
+
+	text := string(body)
+	if strings.Contains(resp.Header.Get("Content-Type"), "html") {
+		text = htmlToText(text)
+	}
+	return strings.TrimSpace(text), nil
+}
+
+// client returns a copy of Client, or of http.DefaultClient, that follows a redirect
+// only to an allowlisted URL, so an allowed host can't redirect a fetch to an internal
+// address. Client's own CheckRedirect, if any, still runs after the check.
+func (f *URLFetcher) client() *http.Client {
+	base := f.Client
+	if base == nil {
+		base = http.DefaultClient
+	}
+	client := *base
+	check := base.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if !f.Allowed(req.URL.String()) {
+			return fmt.Errorf("%w: redirect to %s", ErrURLNotAllowed, req.URL.Redacted())
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		if len(via) >= 10 {
+			return errors.New("stopped after 10 redirects")
+		}
+		return nil
+	}
+	return &client
+}
+
+// htmlToText strips markup from an HTML document, keeping line structure.
+func htmlToText(s string) string {
+	s = htmlDropPattern.ReplaceAllString(s, "")
+	s = htmlTagPattern.ReplaceAllString(s, "\n")
+	s = html.UnescapeString(s)
+	s = spacePattern.ReplaceAllString(s, " ")
+	return whitespacePattern.ReplaceAllString(s, "\n")
+}
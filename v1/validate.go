@@ -0,0 +1,36 @@
+package ai
+
+import (
+	"context"
+	"time"
+)
+
+// ValidationError reports why a provider response was rejected. Providers retry with
+// Correction appended to the conversation and record Reason as the request log fail reason.
+type ValidationError struct {
+	Reason     string // fail reason constant, e.g. FailReasonIncompleteJSON
+	Message    string // human-readable description
+	Correction string // corrective user message sent on retry
+}
+
+func (e *ValidationError) Error() string {
+	return "ai: " + e.Message
+}
+
+// RetryTurn is one correction exchange of a request: a response rejected by validation
+// and the corrective message sent back with it for the next attempt. The exchange is
+// part of the retried request only and never added to the session's messages.
+type RetryTurn struct {
+	Attempt    int       `json:"attempt"`     // attempt whose response was rejected
+	Response   string    `json:"response"`    // the rejected response, resent as an assistant turn
+	FailReason string    `json:"fail_reason"` // FailReason constant
+	Error      string    `json:"error"`       // ValidationError.Message
+	Correction string    `json:"correction"`  // the corrective user turn
+	At         time.Time `json:"at"`
+}
+
+// RetryTranscriptStore is the optional store capability recording the correction
+// exchanges of a request on its log, in order, as RequestLog.RetryTranscript.
+type RetryTranscriptStore interface {
+	AddRetryTurn(ctx context.Context, requestLogID string, turn RetryTurn) error
+}
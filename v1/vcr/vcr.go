@@ -0,0 +1,206 @@
+// Package vcr records HTTP exchanges to cassette files and replays them, so provider
+// code can be tested deterministically without live API keys.
+//
+//	rec, err := vcr.New("testdata/send.json", vcr.ModeAuto)
+//	if err != nil { t.Fatal(err) }
+//	defer rec.Stop()
+//	provider := gemini.New(os.Getenv("GEMINI_API"), modelID).WithHTTPClient(rec.Client())
+package vcr
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+var (
+	ErrNoInteraction = errors.New("ai: vcr: no recorded interaction")
+)
+
+// Mode selects whether a Recorder records or replays.
+type Mode int
+
+const (
+	// ModeReplay serves responses from the cassette and never touches the network.
+	ModeReplay Mode = iota
+	// ModeRecord sends requests and overwrites the cassette on Stop.
+	ModeRecord
+	// ModeAuto replays if the cassette exists and records otherwise.
+	ModeAuto
+)
+
+// secretParams are query parameters removed before requests are matched or saved.
+var secretParams = []string{"key", "api_key", "access_token"}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Body       string      `json:"body"`
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Response   string      `json:"response"`
+}
+
+// Recorder is an http.RoundTripper that records to or replays from a cassette file.
+type Recorder struct {
+	path string
+	mode Mode
+	real http.RoundTripper
+
+	mu           sync.Mutex
+	interactions []Interaction
+	used         []bool
+}
+
+// New returns a Recorder for the cassette at path. In replay mode the cassette must exist.
+func New(path string, mode Mode) (*Recorder, error) {
+	r := &Recorder{path: path, mode: mode, real: http.DefaultTransport}
+
+	if mode == ModeAuto {
+		r.mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			r.mode = ModeReplay
+		}
+	}
+
+	if r.mode == ModeReplay {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("ai: vcr: load cassette: %w", err)
+		}
+		if err := json.Unmarshal(data, &r.interactions); err != nil {
+			return nil, fmt.Errorf("ai: vcr: parse cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.interactions))
+	}
+
+	return r, nil
+}
+
+// WithTransport sets the transport used to make real requests while recording.
+func (r *Recorder) WithTransport(rt http.RoundTripper) *Recorder {
+	r.real = rt
+	return r
+}
+
+// Recording reports whether the recorder is recording rather than replaying.
+func (r *Recorder) Recording() bool {
+	return r.mode == ModeRecord
+}
+
+// Client returns an HTTP client that uses the recorder as its transport.
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// RoundTrip records or replays req. Replayed requests match on method, URL without
+// secrets and body; each recorded interaction is served once, in order.
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("ai: vcr: read request: %w", err)
+		}
+		req.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	u := sanitize(req.URL)
+
+	if r.mode == ModeReplay {
+		return r.replay(req, u, string(body))
+	}
+
+	resp, err := r.real.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ai: vcr: read response: %w", err)
+	}
+
+	r.mu.Lock()
+	r.interactions = append(r.interactions, Interaction{
+		Method:     req.Method,
+		URL:        u,
+		Body:       string(body),
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header,
+		Response:   string(respBody),
+	})
+	r.mu.Unlock()
+
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	return resp, nil
+}
+
+// replay serves the first unused interaction matching the request.
+func (r *Recorder) replay(req *http.Request, u string, body string) (*http.Response, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, in := range r.interactions {
+		if r.used[i] || in.Method != req.Method || in.URL != u || in.Body != body {
+			continue
+		}
+		r.used[i] = true
+		return &http.Response{
+			StatusCode:    in.StatusCode,
+			Status:        fmt.Sprintf("%d %s", in.StatusCode, http.StatusText(in.StatusCode)),
+			Header:        in.Header.Clone(),
+			Body:          io.NopCloser(bytes.NewReader([]byte(in.Response))),
+			ContentLength: int64(len(in.Response)),
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Request:       req,
+		}, nil
+	}
+
+	return nil, fmt.Errorf("%w: %s %s", ErrNoInteraction, req.Method, u)
+}
+
+// Stop saves the cassette when recording. Replaying recorders have nothing to save.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+
+	r.mu.Lock()
+	data, err := json.MarshalIndent(r.interactions, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("ai: vcr: encode cassette: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("ai: vcr: save cassette: %w", err)
+	}
+	if err := os.WriteFile(r.path, data, 0o644); err != nil {
+		return fmt.Errorf("ai: vcr: save cassette: %w", err)
+	}
+	return nil
+}
+
+// sanitize returns u without secret query parameters.
+func sanitize(u *url.URL) string {
+	clean := *u
+	q := clean.Query()
+	for _, p := range secretParams {
+		q.Del(p)
+	}
+	clean.RawQuery = q.Encode()
+	return clean.String()
+}
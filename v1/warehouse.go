@@ -0,0 +1,158 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// UsageAggregate is the request-log usage of one tenant, user and model over one hour.
+// Every attempt counts, including retries and failures.
+type UsageAggregate struct {
+	Hour     time.Time `json:"hour"` // start of the hour, UTC
+	TenantID string    `json:"tenant_id"`
+	UserID   string    `json:"user_id"`
+	Model    string    `json:"model"`
+
+	Requests       int     `json:"requests"`
+	FailedRequests int     `json:"failed_requests"`
+	PromptTokens   int     `json:"prompt_tokens"`
+	ResponseTokens int     `json:"response_tokens"`
+	ThoughtTokens  int     `json:"thought_tokens"`
+	CachedTokens   int     `json:"cached_tokens"`
+	TotalTokens    int     `json:"total_tokens"`
+	Cost           float64 `json:"cost"`
+}
+
+// UsageAggregateStore is the optional store capability for hourly usage aggregates.
+type UsageAggregateStore interface {
+	// AggregateUsage returns the aggregates of the request logs created in [from, to),
+	// ordered by hour, tenant, user and model.
+	AggregateUsage(ctx context.Context, from, to time.Time) ([]UsageAggregate, error)
+}
+
+// WatermarkStore records how far named background jobs have progressed.
+type WatermarkStore interface {
+	// GetWatermark returns the watermark of a job, or the zero time if it has none.
+	GetWatermark(ctx context.Context, name string) (time.Time, error)
+	SetWatermark(ctx context.Context, name string, at time.Time) error
+}
+
+// UsageWriter ships aggregates to a warehouse, e.g. a BigQuery inserter.
+type UsageWriter interface {
+	WriteUsage(ctx context.Context, rows []UsageAggregate) error
+}
+
+// UsageWriterFunc adapts a function to UsageWriter.
+type UsageWriterFunc func(ctx context.Context, rows []UsageAggregate) error
+
+// WriteUsage calls f.
+func (f UsageWriterFunc) WriteUsage(ctx context.Context, rows []UsageAggregate) error {
+	return f(ctx, rows)
+}
+
+// JSONLinesUsageWriter returns a UsageWriter that writes one JSON object per row to w,
+// the newline-delimited format warehouse load jobs accept.
+func JSONLinesUsageWriter(w io.Writer) UsageWriter {
+	enc := json.NewEncoder(w)
+	return UsageWriterFunc(func(ctx context.Context, rows []UsageAggregate) error {
+		for _, row := range rows {
+			if err := enc.Encode(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// UsageExporter periodically ships the hourly usage aggregates of completed hours to a
+// UsageWriter, remembering the last exported hour as a watermark so each hour is sent
+// once. Delivery is at least once: if the watermark can't be saved after a successful
+// write, those hours are sent again, so warehouses should merge rows on
+// (hour, tenant_id, user_id, model).
+type UsageExporter struct {
+	store      UsageAggregateStore
+	watermarks WatermarkStore
+	writer     UsageWriter
+	name       string
+	lag        time.Duration
+	now        Clock
+}
+
+// NewUsageExporter returns an exporter reading aggregates from store and tracking its
+// progress in watermarks under the name "usage_export".
+func NewUsageExporter(store UsageAggregateStore, watermarks WatermarkStore, writer UsageWriter) *UsageExporter {
+	return &UsageExporter{
+		store:      store,
+		watermarks: watermarks,
+		writer:     writer,
+		name:       "usage_export",
+		lag:        time.Hour,
+		now:        time.Now,
+	}
+}
+
+// WithName sets the watermark name, so several exporters can share a store.
+func (e *UsageExporter) WithName(name string) *UsageExporter {
+	e.name = name
+	return e
+}
+
+// WithLag sets how long after an hour ends it is exported, leaving time for requests
+// in flight to record their usage. Defaults to one hour.
+func (e *UsageExporter) WithLag(lag time.Duration) *UsageExporter {
+	e.lag = lag
+	return e
+}
+
+// WithClock sets the clock that decides which hours are complete.
+func (e *UsageExporter) WithClock(clock Clock) *UsageExporter {
+	e.now = clock
+	return e
+}
+
+// ExportOnce ships every complete hour after the watermark and advances it. It returns
+// the number of rows written.
+func (e *UsageExporter) ExportOnce(ctx context.Context) (int, error) {
+	from, err := e.watermarks.GetWatermark(ctx, e.name)
+	if err != nil {
+		return 0, fmt.Errorf("ai: usage export: get watermark: %w", err)
+	}
+	to := e.now().Add(-e.lag).UTC().Truncate(time.Hour)
+	if !to.After(from) {
+		return 0, nil
+	}
+
+	rows, err := e.store.AggregateUsage(ctx, from, to)
+	if err != nil {
+		return 0, fmt.Errorf("ai: usage export: %w", err)
+	}
+	if len(rows) > 0 {
+		if err := e.writer.WriteUsage(ctx, rows); err != nil {
+			return 0, fmt.Errorf("ai: usage export: write: %w", err)
+		}
+	}
+	if err := e.watermarks.SetWatermark(ctx, e.name, to); err != nil {
+		return len(rows), fmt.Errorf("ai: usage export: set watermark: %w", err)
+	}
+	return len(rows), nil
+}
+
+// Run calls ExportOnce every interval until ctx is done, passing its errors to onError
+// (which may be nil) and continuing. It returns ctx's error.
+func (e *UsageExporter) Run(ctx context.Context, interval time.Duration, onError func(err error)) error {
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		if _, err := e.ExportOnce(ctx); err != nil && onError != nil && ctx.Err() == nil {
+			onError(err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package ai
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// WebhookSignatureHeader is the header carrying the signature of a webhook body, e.g.
+// "t=1700000000,v1=5257a8...". There is one v1 entry per signing secret.
+const WebhookSignatureHeader = "X-Webhook-Signature"
+
+// defaultWebhookTolerance is how far a signature's timestamp may be from the
+// receiver's clock.
+const defaultWebhookTolerance = 5 * time.Minute
+
+var (
+	ErrWebhookSignature = errors.New("ai: invalid webhook signature")
+)
+
+// WebhookSigner signs webhook bodies with HMAC-SHA256 and verifies them. The sender
+// and the receiver each hold one with the shared secrets. The signed message is the
+// Unix timestamp, a dot and the body, so a captured request can't be replayed once
+// its timestamp is outside the tolerance.
+//
+// Secrets rotate without downtime: the sender signs with every secret it holds and
+// the receiver accepts a signature by any secret it holds. Add the new secret to the
+// sender, then to the receivers, then drop the old one from both. It is safe for
+// concurrent use.
+type WebhookSigner struct {
+	mu        sync.RWMutex
+	secrets   [][]byte
+	tolerance time.Duration
+	now       Clock
+}
+
+// NewWebhookSigner returns a signer with secrets, newest first.
+func NewWebhookSigner(secrets ...string) *WebhookSigner {
+	s := &WebhookSigner{tolerance: defaultWebhookTolerance, now: time.Now}
+	s.SetSecrets(secrets...)
+	return s
+}
+
+// WithTolerance sets how far a signature's timestamp may be from the current time
+// for Verify to accept it, 5 minutes by default.
+func (s *WebhookSigner) WithTolerance(d time.Duration) *WebhookSigner {
+	s.tolerance = d
+	return s
+}
+
+// WithClock sets the clock signatures are timestamped and checked with.
+func (s *WebhookSigner) WithClock(clock Clock) *WebhookSigner {
+	s.now = clock
+	return s
+}
+
+// SetSecrets replaces the secrets, e.g. when rotating them while running.
+func (s *WebhookSigner) SetSecrets(secrets ...string) {
+	keys := make([][]byte, 0, len(secrets))
+	for _, secret := range secrets {
+		if secret != "" {
+			keys = append(keys, []byte(secret))
+		}
+	}
+	s.mu.Lock()
+	s.secrets = keys
+	s.mu.Unlock()
+}
+
+// Sign returns the WebhookSignatureHeader value of body, timestamped now.
+func (s *WebhookSigner) Sign(body []byte) string {
+	ts := strconv.FormatInt(s.now().Unix(), 10)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var b strings.Builder
+	b.WriteString("t=" + ts)
+	for _, key := range s.secrets {
+		b.WriteString(",v1=" + hex.EncodeToString(webhookMAC(key, ts, body)))
+	}
+	return b.String()
+}
+
+// Verify checks header, a WebhookSignatureHeader value, against body. It returns an
+// error wrapping ErrWebhookSignature if the header is malformed, its timestamp is
+// outside the tolerance or no signature matches any secret.
+func (s *WebhookSigner) Verify(header string, body []byte) error {
+	var ts string
+	var sigs [][]byte
+	for _, field := range strings.Split(header, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), "=")
+		switch key {
+		case "t":
+			ts = value
+		case "v1":
+			if sig, err := hex.DecodeString(value); err == nil {
+				sigs = append(sigs, sig)
+			}
+		}
+	}
+	unix, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil || len(sigs) == 0 {
+		return fmt.Errorf("%w: malformed %s header", ErrWebhookSignature, WebhookSignatureHeader)
+	}
+	if age := s.now().Sub(time.Unix(unix, 0)); s.tolerance > 0 && (age > s.tolerance || age < -s.tolerance) {
+		return fmt.Errorf("%w: timestamp is %s off", ErrWebhookSignature, age.Round(time.Second))
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, key := range s.secrets {
+		want := webhookMAC(key, ts, body)
+		for _, sig := range sigs {
+			if hmac.Equal(sig, want) {
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("%w: no signature matches", ErrWebhookSignature)
+}
+
+// VerifyRequest reads the body of a webhook request and verifies it. It returns the
+// body, so handlers decode exactly the bytes that were signed. Limit the body size
+// with http.MaxBytesReader first if the endpoint is public.
+func (s *WebhookSigner) VerifyRequest(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ai: read webhook body: %w", err)
+	}
+	if err := s.Verify(r.Header.Get(WebhookSignatureHeader), body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// Client returns a copy of base, or of http.DefaultClient if nil, that signs the body
+// of every request it sends. Pass it to WebhookApproval or WebhookAlert to sign their
+// deliveries.
+func (s *WebhookSigner) Client(base *http.Client) *http.Client {
+	if base == nil {
+		base = http.DefaultClient
+	}
+	client := *base
+	transport := client.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	client.Transport = &signingTransport{signer: s, base: transport}
+	return &client
+}
+
+// signingTransport sets the WebhookSignatureHeader of each request.
+type signingTransport struct {
+	signer *WebhookSigner
+	base   http.RoundTripper
+}
+
+func (t *signingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var body []byte
+	if req.Body != nil {
+		var err error
+		body, err = io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, fmt.Errorf("ai: sign webhook: %w", err)
+		}
+	}
+
+	signed := req.Clone(req.Context())
+	signed.Body = io.NopCloser(bytes.NewReader(body))
+	signed.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(body)), nil }
+	signed.ContentLength = int64(len(body))
+	signed.Header.Set(WebhookSignatureHeader, t.signer.Sign(body))
+	return t.base.RoundTrip(signed)
+}
+
+// webhookMAC returns the HMAC-SHA256 of ts, a dot and body under key.
+func webhookMAC(key []byte, ts string, body []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(ts))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return mac.Sum(nil)
+}